@@ -35,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/era"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -76,6 +77,7 @@ if one is set.  Otherwise it prints the genesis from the datadir.`,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
+			utils.StateSchemeFlag,
 			utils.SnapshotFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
@@ -117,6 +119,38 @@ Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing. If the file ends with .gz, the output will
 be gzipped.`,
+	}
+	importHistoryCommand = &cli.Command{
+		Action:    importHistory,
+		Name:      "import-history",
+		Usage:     "Import an Era archive",
+		ArgsUsage: "<dir>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.TxLookupLimitFlag,
+		},
+			utils.DatabasePathFlags,
+			utils.NetworkFlags,
+		),
+		Description: `
+The import-history command will import blocks and their corresponding receipts
+from Era1 archives into an empty ancient store. The archives of the selected
+network are read from the given directory, and each of them is verified against
+the checksums.txt file of the directory and its own accumulator before import.
+`,
+	}
+	exportHistoryCommand = &cli.Command{
+		Action:    exportHistory,
+		Name:      "export-history",
+		Usage:     "Export blockchain history to Era archives",
+		ArgsUsage: "<dir> <first> <last>",
+		Flags:     flags.Merge(utils.DatabasePathFlags),
+		Description: `
+The export-history command will export blocks and their corresponding receipts
+from the ancient store into Era1 archives of 8192 blocks each, along with a
+checksums.txt file. Only blocks already moved into the ancient store can be
+exported. The first block should be a multiple of 8192 for the archives to be
+importable.
+`,
 	}
 	importPreimagesCommand = &cli.Command{
 		Action:    importPreimages,
@@ -162,6 +196,28 @@ It's deprecated, please use "geth db export" instead.
 		}, utils.DatabasePathFlags),
 		Description: `
 This command dumps out the state for a given block (or latest, if none provided).
+`,
+	}
+	pruneHistoryBeforeFlag = &cli.Uint64Flag{
+		Name:     "before",
+		Usage:    "Number of the first block whose body and receipts are retained",
+		Required: true,
+	}
+	pruneHistoryCommand = &cli.Command{
+		Action:    pruneHistory,
+		Name:      "prune-history",
+		Usage:     "Prune the block bodies and receipts before a given block",
+		ArgsUsage: "",
+		Flags: flags.Merge([]cli.Flag{
+			pruneHistoryBeforeFlag,
+		}, utils.DatabasePathFlags),
+		Description: `
+geth prune-history --before <block>
+
+will drop the block bodies and receipts of all the blocks below the given one
+from the ancient store, retaining the headers. Only the blocks already moved
+into the ancient store are pruned. RPC requests for the dropped history fail
+with a "pruned history unavailable" error afterwards.
 `,
 	}
 )
@@ -362,6 +418,92 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+// pruneHistory drops the ancient block bodies and receipts before the block
+// specified by the user.
+func pruneHistory(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	config := rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+	if config == nil {
+		utils.Fatalf("Chain config not found")
+	}
+	var (
+		before = ctx.Uint64(pruneHistoryBeforeFlag.Name)
+		start  = time.Now()
+	)
+	tail, err := rawdb.PruneChainHistory(db, config, before, nil)
+	if err != nil {
+		utils.Fatalf("Failed to prune chain history: %v", err)
+	}
+	if tail < before {
+		log.Warn("Chain history only pruned up to the ancient store", "tail", tail, "requested", before)
+	}
+	log.Info("Pruned chain history", "tail", tail, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// importHistory imports the Era1 archives of the given directory into the
+// ancient store.
+func importHistory(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
+	}
+
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack, false)
+	defer db.Close()
+
+	var (
+		start   = time.Now()
+		dir     = ctx.Args().Get(0)
+		network = utils.NetworkName(chain.Genesis().Hash())
+	)
+	if err := utils.ImportHistory(chain, db, dir, network); err != nil {
+		return err
+	}
+	chain.Stop()
+	fmt.Printf("Import done in %v\n", time.Since(start))
+	return nil
+}
+
+// exportHistory exports chain history in Era archives at a specified
+// directory.
+func exportHistory(ctx *cli.Context) error {
+	if ctx.Args().Len() != 3 {
+		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
+	}
+
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+	start := time.Now()
+
+	var (
+		dir         = ctx.Args().Get(0)
+		first, ferr = strconv.ParseInt(ctx.Args().Get(1), 10, 64)
+		last, lerr  = strconv.ParseInt(ctx.Args().Get(2), 10, 64)
+	)
+	if ferr != nil || lerr != nil {
+		utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
+	}
+	if first < 0 || last < 0 {
+		utils.Fatalf("Export error: block number must be greater than 0\n")
+	}
+	if err := utils.ExportHistory(db, dir, uint64(first), uint64(last), uint64(era.MaxEra1Size)); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
 // importPreimages imports preimage data from the specified file.
 func importPreimages(ctx *cli.Context) error {
 	if ctx.Args().Len() < 1 {
@@ -18,6 +18,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -32,11 +33,13 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/console/prompt"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/scrub"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli/v2"
@@ -69,6 +72,13 @@ Remove blockchain and state databases`,
 			dbExportCmd,
 			dbMetadataCmd,
 			dbCheckStateContentCmd,
+			dbBackupCmd,
+			dbRestoreCmd,
+			dbFreezerRecompressCmd,
+			dbScrubCmd,
+			dbMigrateAncientCmd,
+			dbAncientTierCmd,
+			dbReindexTxCmd,
 		},
 	}
 	dbInspectCmd = &cli.Command{
@@ -164,6 +174,59 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabasePathFlags),
 		Description: "This command displays information about the freezer index.",
 	}
+	dbFreezerRecompressCmd = &cli.Command{
+		Action:    freezerRecompress,
+		Name:      "freezer-recompress",
+		Usage:     "Recompress a chain freezer table with a different codec",
+		ArgsUsage: "<table-type>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			freezerCodecFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command rewrites all the items of a compressed chain freezer table (e.g.
+headers, bodies or receipts) with the given codec. The table is migrated in place, an
+interrupted run is resumed the next time the command is invoked. The codec is recorded
+in the table metadata, so the node doesn't need to be configured for it.`,
+	}
+	freezerCodecFlag = &cli.StringFlag{
+		Name:  "codec",
+		Usage: "Compression codec of the freezer table (snappy, zstd)",
+		Value: "zstd",
+	}
+	dbScrubCmd = &cli.Command{
+		Action: scrubDatabase,
+		Name:   "scrub",
+		Usage:  "Verify the integrity of the chain data in the database",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			scrubReportFlag,
+			scrubRepairFlag,
+			scrubRestartFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command verifies that the canonical headers link up by hash, that the block
+bodies and receipts match the roots of their headers, both in the key-value store
+and the freezer, that the transaction lookup entries point to the right blocks and
+that the freezer index files are consistent with their data files.
+
+The findings are written as JSON lines to the report file, or to stdout. An
+interrupted scrub is resumed on the next run, unless --restart is given.
+
+With --repair, corrupt key-value entries are deleted, the freezer is truncated
+below corrupt items and the chain head is rewound below the first corrupt block,
+so that the missing data is synced again. Transaction lookup entries are rewritten.`,
+	}
+	scrubReportFlag = &cli.StringFlag{
+		Name:  "report",
+		Usage: "File to write the findings to as JSON lines (default = stdout)",
+	}
+	scrubRepairFlag = &cli.BoolFlag{
+		Name:  "repair",
+		Usage: "Delete the corrupt entries and rewind the chain below them",
+	}
+	scrubRestartFlag = &cli.BoolFlag{
+		Name:  "restart",
+		Usage: "Verify the chain from genesis instead of resuming an interrupted scrub",
+	}
 	dbImportCmd = &cli.Command{
 		Action:    importLDBdata,
 		Name:      "import",
@@ -193,6 +256,98 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabasePathFlags),
 		Description: "Shows metadata about the chain status.",
 	}
+	dbMigrateAncientCmd = &cli.Command{
+		Action: migrateAncient,
+		Name:   "migrate-ancient",
+		Usage:  "Move the ancient store into a different directory",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			migrateAncientToFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command copies the ancient store into the given empty directory, verifies
+the copy and records the new location in the database, before deleting the old
+copy. The recorded location supersedes --datadir.ancient from then on. The node
+must not be running.`,
+	}
+	migrateAncientToFlag = &cli.StringFlag{
+		Name:  "to",
+		Usage: "Directory to move the ancient store into",
+	}
+	dbAncientTierCmd = &cli.Command{
+		Action: ancientTier,
+		Name:   "ancient-tier",
+		Usage:  "Split the ancient store into a hot and a cold tier",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			ancientTierColdFlag,
+			ancientTierHotFilesFlag,
+			ancientTierDisableFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command configures the ancient store to keep only the most recent data files
+of every table in its directory, e.g. on a fast disk. The older ones are moved into
+the cold directory, e.g. on a slow volume, by the running node and read from there
+transparently. With --disable, the data files are moved back out of the cold tier.
+The node must not be running.`,
+	}
+	ancientTierColdFlag = &cli.StringFlag{
+		Name:  "cold",
+		Usage: "Directory to move the older ancient data files into",
+	}
+	ancientTierHotFilesFlag = &cli.UintFlag{
+		Name:  "hot-files",
+		Usage: "Number of most recent data files per table kept in the hot tier",
+		Value: 8,
+	}
+	ancientTierDisableFlag = &cli.BoolFlag{
+		Name:  "disable",
+		Usage: "Move all data files back into the hot tier",
+	}
+	dbReindexTxCmd = &cli.Command{
+		Action: reindexTx,
+		Name:   "reindex-tx",
+		Usage:  "Rebuild the transaction index of a block range",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			reindexTxFromFlag,
+			reindexTxToFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command rewrites the transaction lookup entries of the given block range,
+both by hash and by sender and nonce. It can be used to backfill the sender lookups
+of a database indexed before they were introduced. By default, all the blocks from
+the current index tail up to the head are reindexed. A range starting below the
+index tail extends the indexed blocks down to it. An interrupted run can simply
+be restarted. The node must not be running.`,
+	}
+	reindexTxFromFlag = &cli.Uint64Flag{
+		Name:  "from",
+		Usage: "Number of the first block to reindex (default = current index tail)",
+	}
+	reindexTxToFlag = &cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Number of the last block to reindex (default = head block)",
+	}
+	dbBackupCmd = &cli.Command{
+		Action:    backupDB,
+		Name:      "backup",
+		Usage:     "Create a consistent backup of the chain database",
+		ArgsUsage: "<dir>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command copies the key-value store and the ancient store up to a
+consistent item count into the given directory. If the directory already holds a
+backup of the same chain, only the ancient data appended since is copied. Use the
+admin_backup RPC method to back up a running node. The state diff history is not
+backed up, a restored node rebuilds it from the restored head on.`,
+	}
+	dbRestoreCmd = &cli.Command{
+		Action:      restoreDB,
+		Name:        "restore",
+		Usage:       "Restore the chain database from a backup",
+		ArgsUsage:   "<dir>",
+		Flags:       flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: "This command restores a backup created by 'geth db backup' or admin_backup into an empty datadir.",
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -551,9 +706,87 @@ func freezerInspect(ctx *cli.Context) error {
 		return err
 	}
 	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db, err := stack.OpenDatabase("chaindata", 0, 0, "", true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
 	ancient := stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
-	stack.Close()
-	return rawdb.InspectFreezerTable(ancient, freezer, table, start, end)
+	return rawdb.InspectFreezerTable(db, ancient, freezer, table, start, end)
+}
+
+func freezerRecompress(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	codec, err := rawdb.ParseFreezerCodec(ctx.String(freezerCodecFlag.Name))
+	if err != nil {
+		return err
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db, err := stack.OpenDatabase("chaindata", 0, 0, "", true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ancient := stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+	start := time.Now()
+	if err := rawdb.RecompressFreezerTable(db, ancient, ctx.Args().Get(0), codec); err != nil {
+		return err
+	}
+	log.Info("Recompressed freezer table", "table", ctx.Args().Get(0), "codec", codec, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func scrubDatabase(ctx *cli.Context) error {
+	if ctx.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", ctx.Args().Slice())
+	}
+	report := os.Stdout
+	if path := ctx.String(scrubReportFlag.Name); path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		report = f
+	}
+	var (
+		stack, _  = makeConfigNode(ctx)
+		interrupt = make(chan os.Signal, 1)
+		stop      = make(chan struct{})
+	)
+	defer stack.Close()
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	defer close(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during scrub, saving progress")
+		}
+		close(stop)
+	}()
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	scrubber, err := scrub.New(db, report, ctx.Bool(scrubRepairFlag.Name))
+	if err != nil {
+		return err
+	}
+	result, err := scrubber.Run(ctx.Bool(scrubRestartFlag.Name), stop)
+	if err != nil {
+		return err
+	}
+	if result.Findings > 0 && !ctx.Bool(scrubRepairFlag.Name) {
+		return fmt.Errorf("found %d corrupt entries in blocks [%d, %d)", result.Findings, result.From, result.Next)
+	}
+	return nil
 }
 
 func importLDBdata(ctx *cli.Context) error {
@@ -713,3 +946,159 @@ func showMetaData(ctx *cli.Context) error {
 	table.Render()
 	return nil
 }
+
+func backupDB(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	_, err := rawdb.Backup(db, ctx.Args().Get(0))
+	return err
+}
+
+// openAncientLayout opens the key-value store of the chain database, returning
+// it along with the directory the ancient store currently resides in.
+func openAncientLayout(ctx *cli.Context, stack *node.Node) (ethdb.Database, string, error) {
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	ancient, err := db.AncientDatadir()
+	db.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	kvdb, err := stack.OpenDatabase("chaindata", 0, 0, "", false)
+	if err != nil {
+		return nil, "", err
+	}
+	return kvdb, ancient, nil
+}
+
+func migrateAncient(ctx *cli.Context) error {
+	to := ctx.String(migrateAncientToFlag.Name)
+	if to == "" {
+		return fmt.Errorf("missing target directory (--%s)", migrateAncientToFlag.Name)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db, ancient, err := openAncientLayout(ctx, stack)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	start := time.Now()
+	if err := rawdb.MigrateAncientDirectory(db, ancient, to); err != nil {
+		return err
+	}
+	log.Info("Moved ancient store", "from", ancient, "to", to, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func ancientTier(ctx *cli.Context) error {
+	cold := ctx.String(ancientTierColdFlag.Name)
+	if ctx.Bool(ancientTierDisableFlag.Name) {
+		if cold != "" {
+			return fmt.Errorf("--%s conflicts with --%s", ancientTierColdFlag.Name, ancientTierDisableFlag.Name)
+		}
+	} else if cold == "" {
+		return fmt.Errorf("missing cold directory (--%s)", ancientTierColdFlag.Name)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db, ancient, err := openAncientLayout(ctx, stack)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	hotFiles := uint32(ctx.Uint(ancientTierHotFilesFlag.Name))
+	if err := rawdb.ConfigureAncientTier(db, ancient, cold, hotFiles); err != nil {
+		return err
+	}
+	if cold != "" {
+		log.Info("Configured ancient store tiering", "hot", ancient, "cold", cold, "hotfiles", hotFiles)
+	} else {
+		log.Info("Disabled ancient store tiering", "path", ancient)
+	}
+	return nil
+}
+
+func reindexTx(ctx *cli.Context) error {
+	if ctx.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", ctx.Args().Slice())
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	head := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadBlockHash(db))
+	if head == nil {
+		return errors.New("head block not found")
+	}
+	config := rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+	if config == nil {
+		return errors.New("chain config not found")
+	}
+	var from, to uint64 = 0, *head
+	if tail := rawdb.ReadTxIndexTail(db); tail != nil {
+		from = *tail
+	}
+	if ctx.IsSet(reindexTxFromFlag.Name) {
+		from = ctx.Uint64(reindexTxFromFlag.Name)
+	}
+	if ctx.IsSet(reindexTxToFlag.Name) {
+		to = ctx.Uint64(reindexTxToFlag.Name)
+	}
+	if pruned, _ := db.Tail(); from < pruned {
+		return fmt.Errorf("blocks below %d are pruned", pruned)
+	}
+	if from > to || to > *head {
+		return fmt.Errorf("invalid block range [%d, %d], head %d", from, to, *head)
+	}
+	var (
+		interrupt = make(chan os.Signal, 1)
+		stop      = make(chan struct{})
+	)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	defer close(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during reindexing, stopping")
+		}
+		close(stop)
+	}()
+	start := time.Now()
+	rawdb.ReindexTransactions(db, config, from, to+1, stop)
+
+	select {
+	case <-stop:
+		return errors.New("reindexing interrupted")
+	default:
+	}
+	log.Info("Reindexed transactions", "from", from, "to", to, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func restoreDB(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	var (
+		chaindata = stack.ResolvePath("chaindata")
+		ancient   = stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+	)
+	_, err := rawdb.Restore(ctx.Args().Get(0), chaindata, ancient)
+	return err
+}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"errors"
@@ -151,6 +152,37 @@ as the backend data source, making this command a lot faster.
 
 The argument is interpreted as block number or hash. If none is provided, the latest
 block is used.
+`,
+			},
+			{
+				Name:      "export",
+				Usage:     "Export the state at a given root into a portable flat file",
+				ArgsUsage: "<file> [<root>]",
+				Action:    exportSnapshot,
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `
+geth snapshot export <file> [<state-root>]
+will write the accounts, storage slots and contract codes of the specified state
+into a chunked, checksummed flat file, based on the snapshot. The default export
+target is the HEAD state.
+
+The file can be loaded into another node with 'geth snapshot import'.
+`,
+			},
+			{
+				Name:      "import",
+				Usage:     "Import the state from a portable flat file",
+				ArgsUsage: "<file>",
+				Action:    importSnapshot,
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `
+geth snapshot import <file>
+will rebuild the snapshot and the state trie from a flat file created by
+'geth snapshot export', verifying the resulting state root against the one
+recorded in the file.
+
+The database must not contain a snapshot yet and must use the hash-based
+state scheme.
 `,
 			},
 		},
@@ -558,6 +590,92 @@ func dumpState(ctx *cli.Context) error {
 	return nil
 }
 
+// exportSnapshot writes the state at the given root into a flat file.
+func exportSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 2 {
+		return errors.New("need <file> [<root>] args")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, true)
+	defer chaindb.Close()
+
+	headBlock := rawdb.ReadHeadBlock(chaindb)
+	if headBlock == nil {
+		log.Error("Failed to load head block")
+		return errors.New("no head block")
+	}
+	snapconfig := snapshot.Config{
+		CacheSize:  256,
+		Recovery:   false,
+		NoBuild:    true,
+		AsyncBuild: false,
+	}
+	snaptree, err := snapshot.New(snapconfig, chaindb, trie.NewDatabase(chaindb), headBlock.Root())
+	if err != nil {
+		log.Error("Failed to open snapshot tree", "err", err)
+		return err
+	}
+	var root = headBlock.Root()
+	if ctx.NArg() == 2 {
+		root, err = parseRoot(ctx.Args().Get(1))
+		if err != nil {
+			log.Error("Failed to resolve state root", "err", err)
+			return err
+		}
+	}
+	fn := ctx.Args().First()
+	out, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	buf := bufio.NewWriter(out)
+	if err = snaptree.Export(root, chaindb, buf); err == nil {
+		err = buf.Flush()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Error("Failed to export snapshot", "root", root, "err", err)
+		os.Remove(fn)
+		return err
+	}
+	return nil
+}
+
+// importSnapshot rebuilds the snapshot and the state trie from a flat file.
+func importSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("need <file> arg")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, false)
+	defer chaindb.Close()
+
+	if scheme := rawdb.ReadStateScheme(chaindb); scheme == rawdb.PathScheme {
+		return errors.New("snapshot import is not supported with path-based state scheme")
+	}
+	in, err := os.Open(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	root, err := snapshot.Import(chaindb, in)
+	if err != nil {
+		log.Error("Failed to import snapshot", "err", err)
+		return err
+	}
+	if headBlock := rawdb.ReadHeadBlock(chaindb); headBlock != nil && headBlock.Root() != root {
+		log.Warn("Imported state does not match head block", "root", root, "number", headBlock.NumberU64(), "head", headBlock.Root())
+	}
+	return nil
+}
+
 // checkAccount iterates the snap data layers, and looks up the given account
 // across all layers.
 func checkAccount(ctx *cli.Context) error {
@@ -20,11 +20,15 @@ package utils
 import (
 	"bufio"
 	"compress/gzip"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"syscall"
@@ -38,8 +42,10 @@ import (
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/debug"
+	"github.com/ethereum/go-ethereum/internal/era"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/urfave/cli/v2"
 )
@@ -296,6 +302,262 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 	return nil
 }
 
+// ImportHistory imports the Era1 archives of the given network found in a
+// directory, writing the blocks and receipts directly into the ancient store.
+// Every archive is checked against the checksums file of the directory and its
+// own accumulator before any of its content is imported.
+func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, network string) error {
+	if frozen, err := db.Ancients(); err != nil {
+		return err
+	} else if frozen > 1 {
+		return fmt.Errorf("ancient store not empty: %d items", frozen)
+	}
+	entries, err := era.ReadDir(dir, network)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", dir, err)
+	}
+	checksums, err := readList(filepath.Join(dir, "checksums.txt"))
+	if err != nil {
+		return fmt.Errorf("unable to read checksums.txt: %w", err)
+	}
+	if len(checksums) != len(entries) {
+		return fmt.Errorf("expected equal number of checksums and entries, have: %d checksums, %d entries", len(checksums), len(entries))
+	}
+	var (
+		start    = time.Now()
+		reported = time.Now()
+		imported = 0
+	)
+	for i, filename := range entries {
+		err := func() error {
+			f, err := os.Open(filepath.Join(dir, filename))
+			if err != nil {
+				return fmt.Errorf("unable to open era: %w", err)
+			}
+			defer f.Close()
+
+			// Validate checksum and the integrity of the archive.
+			h := sha256.New()
+			if _, err := io.Copy(h, f); err != nil {
+				return fmt.Errorf("unable to recalculate checksum: %w", err)
+			}
+			if have, want := common.BytesToHash(h.Sum(nil)).Hex(), checksums[i]; have != want {
+				return fmt.Errorf("checksum mismatch: have %s, want %s", have, want)
+			}
+			e, err := era.From(f)
+			if err != nil {
+				return fmt.Errorf("error opening era: %w", err)
+			}
+			if err := e.Verify(); err != nil {
+				return fmt.Errorf("error verifying %s: %w", filename, err)
+			}
+			// Import all block data from Era1.
+			it, err := era.NewIterator(e)
+			if err != nil {
+				return fmt.Errorf("error making era reader: %w", err)
+			}
+			var (
+				blocks   types.Blocks
+				receipts []types.Receipts
+				tds      []*big.Int
+			)
+			for it.Next() {
+				block, rs, err := it.BlockAndReceipts()
+				if err != nil {
+					return fmt.Errorf("error reading block %d: %w", it.Number(), err)
+				}
+				td, err := it.TotalDifficulty()
+				if err != nil {
+					return fmt.Errorf("error reading total difficulty %d: %w", it.Number(), err)
+				}
+				if block.NumberU64() == 0 {
+					if block.Hash() != chain.Genesis().Hash() {
+						return fmt.Errorf("genesis mismatch: have %x, want %x", block.Hash(), chain.Genesis().Hash())
+					}
+					continue
+				}
+				blocks, receipts, tds = append(blocks, block), append(receipts, rs), append(tds, td)
+				if len(blocks) == importBatchSize {
+					if err := importHistoryBatch(chain, blocks, receipts, tds); err != nil {
+						return err
+					}
+					imported += len(blocks)
+					blocks, receipts, tds = blocks[:0], receipts[:0], tds[:0]
+				}
+				if time.Since(reported) >= 8*time.Second {
+					log.Info("Importing Era files", "head", it.Number(), "imported", imported, "elapsed", common.PrettyDuration(time.Since(start)))
+					reported = time.Now()
+				}
+			}
+			if err := it.Error(); err != nil {
+				return err
+			}
+			if len(blocks) > 0 {
+				if err := importHistoryBatch(chain, blocks, receipts, tds); err != nil {
+					return err
+				}
+				imported += len(blocks)
+			}
+			return nil
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	log.Info("Imported Era files", "count", len(entries), "blocks", imported, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// importHistoryBatch inserts the headers of a batch of blocks into the chain,
+// cross checks the resulting total difficulties with the archived ones and then
+// writes the block bodies and receipts into the ancient store.
+func importHistoryBatch(chain *core.BlockChain, blocks types.Blocks, receipts []types.Receipts, tds []*big.Int) error {
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if n, err := chain.InsertHeaderChain(headers); err != nil {
+		return fmt.Errorf("error inserting header %d: %w", blocks[n].NumberU64(), err)
+	}
+	last := blocks[len(blocks)-1]
+	if td := chain.GetTd(last.Hash(), last.NumberU64()); td == nil || td.Cmp(tds[len(tds)-1]) != 0 {
+		return fmt.Errorf("total difficulty mismatch at %d: have %v, want %v", last.NumberU64(), td, tds[len(tds)-1])
+	}
+	if n, err := chain.InsertReceiptChain(blocks, receipts, math.MaxUint64); err != nil {
+		return fmt.Errorf("error inserting body %d: %w", blocks[n].NumberU64(), err)
+	}
+	return nil
+}
+
+// ExportHistory exports the blocks [first, last] from the ancient store into
+// Era1 archives of step blocks each, named after the network. A checksums.txt
+// file listing the sha256 hash of every archive is written alongside them.
+func ExportHistory(db ethdb.Database, dir string, first, last, step uint64) error {
+	log.Info("Exporting blockchain history", "dir", dir)
+	if frozen, err := db.Ancients(); err != nil {
+		return err
+	} else if last >= frozen {
+		return fmt.Errorf("last block %d not in the ancient store (frozen %d)", last, frozen)
+	}
+	if tail, err := db.Tail(); err != nil {
+		return err
+	} else if first < tail {
+		return fmt.Errorf("first block %d pruned from the ancient store (tail %d)", first, tail)
+	}
+	if first > last {
+		return fmt.Errorf("invalid range: first %d beyond last %d", first, last)
+	}
+	if step == 0 || step > uint64(era.MaxEra1Size) {
+		return fmt.Errorf("invalid step size %d", step)
+	}
+	var (
+		genesis = rawdb.ReadCanonicalHash(db, 0)
+		config  = rawdb.ReadChainConfig(db, genesis)
+		network = NetworkName(genesis)
+	)
+	if config == nil {
+		return errors.New("chain config not found")
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	var (
+		start     = time.Now()
+		reported  = time.Now()
+		h         = sha256.New()
+		checksums []string
+	)
+	for i := first; i <= last; i += step {
+		err := func() error {
+			filename := filepath.Join(dir, era.Filename(network, int(i/step), common.Hash{}))
+			f, err := os.Create(filename)
+			if err != nil {
+				return fmt.Errorf("could not create era file: %w", err)
+			}
+			defer f.Close()
+
+			w := era.NewBuilder(f)
+			for j := uint64(0); j < step && j <= last-i; j++ {
+				var (
+					n     = i + j
+					hash  = rawdb.ReadCanonicalHash(db, n)
+					block = rawdb.ReadBlock(db, hash, n)
+				)
+				if block == nil {
+					return fmt.Errorf("export failed on #%d: not found", n)
+				}
+				receipts := rawdb.ReadReceipts(db, hash, n, block.Time(), config)
+				if receipts == nil {
+					return fmt.Errorf("export failed on #%d: receipts not found", n)
+				}
+				td := rawdb.ReadTd(db, hash, n)
+				if td == nil {
+					return fmt.Errorf("export failed on #%d: total difficulty not found", n)
+				}
+				if err := w.Add(block, receipts, td); err != nil {
+					return err
+				}
+			}
+			root, err := w.Finalize()
+			if err != nil {
+				return fmt.Errorf("export failed to finalize %d: %w", i/step, err)
+			}
+			// Set correct filename with root.
+			if err := os.Rename(filename, filepath.Join(dir, era.Filename(network, int(i/step), root))); err != nil {
+				return err
+			}
+
+			// Compute checksum of entire Era1.
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			if _, err := io.Copy(h, f); err != nil {
+				return fmt.Errorf("unable to calculate checksum: %w", err)
+			}
+			checksums = append(checksums, common.BytesToHash(h.Sum(nil)).Hex())
+			h.Reset()
+			return nil
+		}()
+		if err != nil {
+			return err
+		}
+		if time.Since(reported) >= 8*time.Second {
+			log.Info("Exporting blocks", "exported", i, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "checksums.txt"), []byte(strings.Join(checksums, "\n")), os.ModePerm); err != nil {
+		return err
+	}
+
+	log.Info("Exported blockchain to", "dir", dir)
+	return nil
+}
+
+// NetworkName returns the name Era1 archives of the chain with the given
+// genesis hash are labelled with.
+func NetworkName(genesis common.Hash) string {
+	switch genesis {
+	case params.MainnetGenesisHash:
+		return "mainnet"
+	case params.SepoliaGenesisHash:
+		return "sepolia"
+	case params.GoerliGenesisHash:
+		return "goerli"
+	default:
+		return "unknown"
+	}
+}
+
+// readList reads a newline separated list of values from a file.
+func readList(filename string) ([]string, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(b), "\n"), nil
+}
+
 // ImportPreimages imports a batch of exported hash preimages into the database.
 // It's a part of the deprecated functionality, should be removed in the future.
 func ImportPreimages(db ethdb.Database, fn string) error {
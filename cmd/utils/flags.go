@@ -22,6 +22,7 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -41,7 +42,8 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
-	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
+	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -54,6 +56,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
 	"github.com/ethereum/go-ethereum/ethdb/remotedb"
 	"github.com/ethereum/go-ethereum/ethstats"
 	"github.com/ethereum/go-ethereum/graphql"
@@ -96,7 +99,7 @@ var (
 	}
 	RemoteDBFlag = &cli.StringFlag{
 		Name:     "remotedb",
-		Usage:    "URL for remote database",
+		Usage:    "URL of a node serving the remotedb API, used as a read-only database",
 		Category: flags.LoggingCategory,
 	}
 	DBEngineFlag = &cli.StringFlag{
@@ -105,6 +108,46 @@ var (
 		Value:    node.DefaultConfig.DBEngine,
 		Category: flags.EthCategory,
 	}
+	DBPebbleMemTablesFlag = &cli.IntFlag{
+		Name:     "db.pebble.memtables",
+		Usage:    "Number of pebble memory tables, including the frozen ones (default = 2)",
+		Category: flags.PerfCategory,
+	}
+	DBPebbleMemTableSizeFlag = &cli.IntFlag{
+		Name:     "db.pebble.memtablesize",
+		Usage:    "Megabytes of memory allocated to a single pebble memory table (default = half the database cache, split among the tables)",
+		Category: flags.PerfCategory,
+	}
+	DBPebbleLBaseSizeFlag = &cli.IntFlag{
+		Name:     "db.pebble.lbasesize",
+		Usage:    "Maximum size in megabytes of the pebble base level, further levels grow tenfold (default = 64)",
+		Category: flags.PerfCategory,
+	}
+	DBPebbleFileSizeFlag = &cli.IntFlag{
+		Name:     "db.pebble.filesize",
+		Usage:    "Target size in megabytes of the pebble sstables (default = 2)",
+		Category: flags.PerfCategory,
+	}
+	DBPebbleCompactionsFlag = &cli.IntFlag{
+		Name:     "db.pebble.compactions",
+		Usage:    "Maximum number of concurrent pebble compactions (default = number of CPUs)",
+		Category: flags.PerfCategory,
+	}
+	DBPebbleBloomBitsFlag = &cli.IntFlag{
+		Name:     "db.pebble.bloombits",
+		Usage:    "Bits per key of the pebble bloom filters (default = 10)",
+		Category: flags.PerfCategory,
+	}
+	DBPebbleWALDirFlag = &flags.DirectoryFlag{
+		Name:     "db.pebble.waldir",
+		Usage:    "Directory of the pebble write-ahead log (default = inside chaindata)",
+		Category: flags.PerfCategory,
+	}
+	DBPebbleWALBytesPerSyncFlag = &cli.IntFlag{
+		Name:     "db.pebble.walbytespersync",
+		Usage:    "Bytes of pebble write-ahead log written between background syncs (default = 0, disabled)",
+		Category: flags.PerfCategory,
+	}
 	AncientFlag = &flags.DirectoryFlag{
 		Name:     "datadir.ancient",
 		Usage:    "Root directory for ancient data (default = inside chaindata)",
@@ -230,6 +273,28 @@ var (
 		Value:    "full",
 		Category: flags.EthCategory,
 	}
+	StateSchemeFlag = &cli.StringFlag{
+		Name:     "state.scheme",
+		Usage:    "Scheme to use for storing ethereum state ('hash' or 'path')",
+		Category: flags.EthCategory,
+	}
+	StateHistoryFlag = &cli.Uint64Flag{
+		Name:     "history.state",
+		Usage:    "Number of recent blocks to retain state history for (default = 90,000 blocks, 0 = entire chain)",
+		Value:    ethconfig.Defaults.StateHistory,
+		Category: flags.EthCategory,
+	}
+	StateDiffsFlag = &cli.BoolFlag{
+		Name:     "statediffs",
+		Usage:    "Maintain per-block state reverse diffs to serve historic state without re-execution (requires snapshot)",
+		Category: flags.EthCategory,
+	}
+	StateDiffHistoryFlag = &cli.Uint64Flag{
+		Name:     "history.statediffs",
+		Usage:    "Number of recent blocks to retain state reverse diffs for (default = 90,000 blocks, 0 = entire chain)",
+		Value:    ethconfig.Defaults.StateDiffHistory,
+		Category: flags.EthCategory,
+	}
 	SnapshotFlag = &cli.BoolFlag{
 		Name:     "snapshot",
 		Usage:    `Enables snapshot-database mode (default = enable)`,
@@ -242,6 +307,16 @@ var (
 		Value:    ethconfig.Defaults.TxLookupLimit,
 		Category: flags.EthCategory,
 	}
+	ChainHistoryFlag = &cli.Uint64Flag{
+		Name:     "history.chain",
+		Usage:    "Number of recent blocks to maintain bodies and receipts for, only frozen blocks are pruned (default = 0, entire chain)",
+		Category: flags.EthCategory,
+	}
+	LogIndexFlag = &cli.BoolFlag{
+		Name:     "logindex",
+		Usage:    "Maintain a persistent address/topic index of the chain's logs to serve eth_getLogs exactly",
+		Category: flags.EthCategory,
+	}
 	LightKDFFlag = &cli.BoolFlag{
 		Name:     "lightkdf",
 		Usage:    "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -334,13 +409,13 @@ var (
 	TxPoolJournalFlag = &cli.StringFlag{
 		Name:     "txpool.journal",
 		Usage:    "Disk journal for local transaction to survive node restarts",
-		Value:    txpool.DefaultConfig.Journal,
+		Value:    ethconfig.Defaults.TxPool.Journal,
 		Category: flags.TxPoolCategory,
 	}
 	TxPoolRejournalFlag = &cli.DurationFlag{
 		Name:     "txpool.rejournal",
 		Usage:    "Time interval to regenerate the local transaction journal",
-		Value:    txpool.DefaultConfig.Rejournal,
+		Value:    ethconfig.Defaults.TxPool.Rejournal,
 		Category: flags.TxPoolCategory,
 	}
 	TxPoolPriceLimitFlag = &cli.Uint64Flag{
@@ -385,6 +460,25 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	// Blob transaction pool settings
+	BlobPoolDataDirFlag = &cli.StringFlag{
+		Name:     "blobpool.datadir",
+		Usage:    "Data directory to store blob transactions in",
+		Value:    ethconfig.Defaults.BlobPool.Datadir,
+		Category: flags.BlobPoolCategory,
+	}
+	BlobPoolDataCapFlag = &cli.Uint64Flag{
+		Name:     "blobpool.datacap",
+		Usage:    "Disk space to allocate for pending blob transactions (soft limit)",
+		Value:    ethconfig.Defaults.BlobPool.Datacap,
+		Category: flags.BlobPoolCategory,
+	}
+	BlobPoolPriceBumpFlag = &cli.Uint64Flag{
+		Name:     "blobpool.pricebump",
+		Usage:    "Price bump percentage to replace an already existing blob transaction",
+		Value:    ethconfig.Defaults.BlobPool.PriceBump,
+		Category: flags.BlobPoolCategory,
+	}
 
 	// Performance tuning settings
 	CacheFlag = &cli.IntFlag{
@@ -529,6 +623,16 @@ var (
 		Usage:    "Record information useful for VM and contract debugging",
 		Category: flags.VMCategory,
 	}
+	VMTraceFlag = &cli.StringFlag{
+		Name:     "vmtrace",
+		Usage:    "Name of the tracer to trace the imported blocks with live",
+		Category: flags.VMCategory,
+	}
+	VMTraceConfigFlag = &cli.StringFlag{
+		Name:     "vmtrace.config",
+		Usage:    "Tracer configuration (JSON)",
+		Category: flags.VMCategory,
+	}
 
 	// API options.
 	RPCGlobalGasCapFlag = &cli.Uint64Flag{
@@ -543,6 +647,12 @@ var (
 		Value:    ethconfig.Defaults.RPCEVMTimeout,
 		Category: flags.APICategory,
 	}
+	RPCTraceFilterRangeFlag = &cli.Uint64Flag{
+		Name:     "rpc.tracefilterrange",
+		Usage:    "Sets a cap on the number of blocks trace_filter can trace in one request (0=infinite)",
+		Value:    ethconfig.Defaults.RPCTraceFilterRange,
+		Category: flags.APICategory,
+	}
 	RPCGlobalTxFeeCapFlag = &cli.Float64Flag{
 		Name:     "rpc.txfeecap",
 		Usage:    "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
@@ -605,6 +715,16 @@ var (
 		Usage:    "Filename for IPC socket/pipe within the datadir (explicit paths escape it)",
 		Category: flags.APICategory,
 	}
+	IPCApiAllowFlag = &cli.StringFlag{
+		Name:     "ipc.api.allow",
+		Usage:    "Comma separated list of methods served over the IPC-RPC interface, others are blocked. Accepts '*' wildcards.",
+		Category: flags.APICategory,
+	}
+	IPCApiDenyFlag = &cli.StringFlag{
+		Name:     "ipc.api.deny",
+		Usage:    "Comma separated list of methods blocked on the IPC-RPC interface. Accepts '*' wildcards.",
+		Category: flags.APICategory,
+	}
 	HTTPEnabledFlag = &cli.BoolFlag{
 		Name:     "http",
 		Usage:    "Enable the HTTP-RPC server",
@@ -640,6 +760,16 @@ var (
 		Value:    "",
 		Category: flags.APICategory,
 	}
+	HTTPApiAllowFlag = &cli.StringFlag{
+		Name:     "http.api.allow",
+		Usage:    "Comma separated list of methods served over the HTTP-RPC interface, others are blocked. Accepts '*' wildcards.",
+		Category: flags.APICategory,
+	}
+	HTTPApiDenyFlag = &cli.StringFlag{
+		Name:     "http.api.deny",
+		Usage:    "Comma separated list of methods blocked on the HTTP-RPC interface. Accepts '*' wildcards.",
+		Category: flags.APICategory,
+	}
 	HTTPPathPrefixFlag = &cli.StringFlag{
 		Name:     "http.rpcprefix",
 		Usage:    "HTTP path path prefix on which JSON-RPC is served. Use '/' to serve on all paths.",
@@ -686,6 +816,16 @@ var (
 		Value:    "",
 		Category: flags.APICategory,
 	}
+	WSApiAllowFlag = &cli.StringFlag{
+		Name:     "ws.api.allow",
+		Usage:    "Comma separated list of methods served over the WS-RPC interface, others are blocked. Accepts '*' wildcards.",
+		Category: flags.APICategory,
+	}
+	WSApiDenyFlag = &cli.StringFlag{
+		Name:     "ws.api.deny",
+		Usage:    "Comma separated list of methods blocked on the WS-RPC interface. Accepts '*' wildcards.",
+		Category: flags.APICategory,
+	}
 	WSAllowedOriginsFlag = &cli.StringFlag{
 		Name:     "ws.origins",
 		Usage:    "Origins from which to accept websockets requests",
@@ -713,6 +853,54 @@ var (
 		Usage:    "Allow for unprotected (non EIP155 signed) transactions to be submitted via RPC",
 		Category: flags.APICategory,
 	}
+	BatchRequestLimit = &cli.IntFlag{
+		Name:     "rpc.batch-request-limit",
+		Usage:    "Maximum number of requests in a batch (0 = unlimited)",
+		Value:    node.DefaultConfig.BatchRequestLimit,
+		Category: flags.APICategory,
+	}
+	BatchResponseMaxSize = &cli.IntFlag{
+		Name:     "rpc.batch-response-max-size",
+		Usage:    "Maximum number of bytes returned from a batched call (0 = unlimited)",
+		Value:    node.DefaultConfig.BatchResponseMaxSize,
+		Category: flags.APICategory,
+	}
+	RPCRateLimitFlag = &cli.Float64Flag{
+		Name:     "rpc.ratelimit",
+		Usage:    "Tokens refilled per second into the call quota of every HTTP/WS client (0 = unlimited)",
+		Category: flags.APICategory,
+	}
+	RPCRateLimitBurstFlag = &cli.Float64Flag{
+		Name:     "rpc.ratelimit.burst",
+		Usage:    "Maximum tokens in the call quota of every HTTP/WS client (default = rate)",
+		Category: flags.APICategory,
+	}
+	RPCRateLimitJWTSecretFlag = &flags.DirectoryFlag{
+		Name:     "rpc.ratelimit.jwtsecret",
+		Usage:    "Path to a JWT secret with which HTTP/WS clients may authenticate, to be rate limited by token subject instead of IP",
+		Category: flags.APICategory,
+	}
+	RPCAuditLogFlag = &cli.StringFlag{
+		Name:     "rpc.auditlog",
+		Usage:    "Write a JSON audit log of the served RPC calls to a rotated file, or to 'stdout'",
+		Category: flags.APICategory,
+	}
+	RPCAuditLogSlowFlag = &cli.DurationFlag{
+		Name:     "rpc.auditlog.slow",
+		Usage:    "Duration above which RPC calls are audit logged with their full parameters (0 = never)",
+		Category: flags.APICategory,
+	}
+	RPCAuditLogMaxSizeFlag = &cli.IntFlag{
+		Name:     "rpc.auditlog.maxsize",
+		Usage:    "Size in megabytes of an RPC audit log file before it is rotated",
+		Value:    100,
+		Category: flags.APICategory,
+	}
+	RPCAuditLogMaxBackupsFlag = &cli.IntFlag{
+		Name:     "rpc.auditlog.maxbackups",
+		Usage:    "Number of rotated RPC audit log files to retain (0 = all)",
+		Category: flags.APICategory,
+	}
 	EnablePersonal = &cli.BoolFlag{
 		Name:     "rpc.enabledeprecatedpersonal",
 		Usage:    "Enables the (deprecated) personal namespace",
@@ -943,7 +1131,17 @@ var (
 
 func init() {
 	if rawdb.PebbleEnabled {
-		DatabasePathFlags = append(DatabasePathFlags, DBEngineFlag)
+		DatabasePathFlags = append(DatabasePathFlags,
+			DBEngineFlag,
+			DBPebbleMemTablesFlag,
+			DBPebbleMemTableSizeFlag,
+			DBPebbleLBaseSizeFlag,
+			DBPebbleFileSizeFlag,
+			DBPebbleCompactionsFlag,
+			DBPebbleBloomBitsFlag,
+			DBPebbleWALDirFlag,
+			DBPebbleWALBytesPerSyncFlag,
+		)
 	}
 }
 
@@ -1119,6 +1317,12 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(HTTPApiFlag.Name) {
 		cfg.HTTPModules = SplitAndTrim(ctx.String(HTTPApiFlag.Name))
 	}
+	if ctx.IsSet(HTTPApiAllowFlag.Name) {
+		cfg.HTTPAllowedMethods = SplitAndTrim(ctx.String(HTTPApiAllowFlag.Name))
+	}
+	if ctx.IsSet(HTTPApiDenyFlag.Name) {
+		cfg.HTTPDeniedMethods = SplitAndTrim(ctx.String(HTTPApiDenyFlag.Name))
+	}
 
 	if ctx.IsSet(HTTPVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = SplitAndTrim(ctx.String(HTTPVirtualHostsFlag.Name))
@@ -1130,6 +1334,21 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(AllowUnprotectedTxs.Name) {
 		cfg.AllowUnprotectedTxs = ctx.Bool(AllowUnprotectedTxs.Name)
 	}
+	if ctx.IsSet(BatchRequestLimit.Name) {
+		cfg.BatchRequestLimit = ctx.Int(BatchRequestLimit.Name)
+	}
+	if ctx.IsSet(BatchResponseMaxSize.Name) {
+		cfg.BatchResponseMaxSize = ctx.Int(BatchResponseMaxSize.Name)
+	}
+	if ctx.IsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCRateLimit.Rate = ctx.Float64(RPCRateLimitFlag.Name)
+	}
+	if ctx.IsSet(RPCRateLimitBurstFlag.Name) {
+		cfg.RPCRateLimit.Burst = ctx.Float64(RPCRateLimitBurstFlag.Name)
+	}
+	if ctx.IsSet(RPCRateLimitJWTSecretFlag.Name) {
+		cfg.RPCClientJWTSecret = ctx.String(RPCRateLimitJWTSecretFlag.Name)
+	}
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -1163,12 +1382,34 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(WSApiFlag.Name) {
 		cfg.WSModules = SplitAndTrim(ctx.String(WSApiFlag.Name))
 	}
+	if ctx.IsSet(WSApiAllowFlag.Name) {
+		cfg.WSAllowedMethods = SplitAndTrim(ctx.String(WSApiAllowFlag.Name))
+	}
+	if ctx.IsSet(WSApiDenyFlag.Name) {
+		cfg.WSDeniedMethods = SplitAndTrim(ctx.String(WSApiDenyFlag.Name))
+	}
 
 	if ctx.IsSet(WSPathPrefixFlag.Name) {
 		cfg.WSPathPrefix = ctx.String(WSPathPrefixFlag.Name)
 	}
 }
 
+// setRPCAuditLog configures the RPC audit log from the set command line flags.
+func setRPCAuditLog(ctx *cli.Context, cfg *node.Config) {
+	if ctx.IsSet(RPCAuditLogFlag.Name) {
+		cfg.RPCAuditLog.Output = ctx.String(RPCAuditLogFlag.Name)
+	}
+	if ctx.IsSet(RPCAuditLogSlowFlag.Name) {
+		cfg.RPCAuditLog.SlowCallThreshold = ctx.Duration(RPCAuditLogSlowFlag.Name)
+	}
+	if ctx.IsSet(RPCAuditLogMaxSizeFlag.Name) {
+		cfg.RPCAuditLog.MaxSize = ctx.Int(RPCAuditLogMaxSizeFlag.Name)
+	}
+	if ctx.IsSet(RPCAuditLogMaxBackupsFlag.Name) {
+		cfg.RPCAuditLog.MaxBackups = ctx.Int(RPCAuditLogMaxBackupsFlag.Name)
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -1179,6 +1420,12 @@ func setIPC(ctx *cli.Context, cfg *node.Config) {
 	case ctx.IsSet(IPCPathFlag.Name):
 		cfg.IPCPath = ctx.String(IPCPathFlag.Name)
 	}
+	if ctx.IsSet(IPCApiAllowFlag.Name) {
+		cfg.IPCAllowedMethods = SplitAndTrim(ctx.String(IPCApiAllowFlag.Name))
+	}
+	if ctx.IsSet(IPCApiDenyFlag.Name) {
+		cfg.IPCDeniedMethods = SplitAndTrim(ctx.String(IPCApiDenyFlag.Name))
+	}
 }
 
 // setLes configures the les server and ultra light client settings from the command line flags.
@@ -1383,6 +1630,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCAuditLog(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	SetDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
@@ -1425,6 +1673,42 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		log.Info(fmt.Sprintf("Using %s as db engine", dbEngine))
 		cfg.DBEngine = dbEngine
 	}
+	setPebble(ctx, cfg)
+}
+
+// setPebble applies the pebble tuning flags to the node config.
+func setPebble(ctx *cli.Context, cfg *node.Config) {
+	config := new(pebble.Config)
+	if cfg.DBPebble != nil {
+		*config = *cfg.DBPebble
+	}
+	if ctx.IsSet(DBPebbleMemTablesFlag.Name) {
+		config.MemTableCount = ctx.Int(DBPebbleMemTablesFlag.Name)
+	}
+	if ctx.IsSet(DBPebbleMemTableSizeFlag.Name) {
+		config.MemTableSize = ctx.Int(DBPebbleMemTableSizeFlag.Name) * 1024 * 1024
+	}
+	if ctx.IsSet(DBPebbleLBaseSizeFlag.Name) {
+		config.LBaseMaxBytes = int64(ctx.Int(DBPebbleLBaseSizeFlag.Name)) * 1024 * 1024
+	}
+	if ctx.IsSet(DBPebbleFileSizeFlag.Name) {
+		config.TargetFileSize = int64(ctx.Int(DBPebbleFileSizeFlag.Name)) * 1024 * 1024
+	}
+	if ctx.IsSet(DBPebbleCompactionsFlag.Name) {
+		config.MaxConcurrentCompactions = ctx.Int(DBPebbleCompactionsFlag.Name)
+	}
+	if ctx.IsSet(DBPebbleBloomBitsFlag.Name) {
+		config.BloomBitsPerKey = ctx.Int(DBPebbleBloomBitsFlag.Name)
+	}
+	if ctx.IsSet(DBPebbleWALDirFlag.Name) {
+		config.WALDir = ctx.String(DBPebbleWALDirFlag.Name)
+	}
+	if ctx.IsSet(DBPebbleWALBytesPerSyncFlag.Name) {
+		config.WALBytesPerSync = ctx.Int(DBPebbleWALBytesPerSyncFlag.Name)
+	}
+	if *config != (pebble.Config{}) {
+		cfg.DBPebble = config
+	}
 }
 
 func setSmartCard(ctx *cli.Context, cfg *node.Config) {
@@ -1480,7 +1764,7 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config, light bool) {
 	}
 }
 
-func setTxPool(ctx *cli.Context, cfg *txpool.Config) {
+func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolLocalsFlag.Name) {
 		locals := strings.Split(ctx.String(TxPoolLocalsFlag.Name), ",")
 		for _, account := range locals {
@@ -1523,6 +1807,18 @@ func setTxPool(ctx *cli.Context, cfg *txpool.Config) {
 	}
 }
 
+func setBlobPool(ctx *cli.Context, cfg *blobpool.Config) {
+	if ctx.IsSet(BlobPoolDataDirFlag.Name) {
+		cfg.Datadir = ctx.String(BlobPoolDataDirFlag.Name)
+	}
+	if ctx.IsSet(BlobPoolDataCapFlag.Name) {
+		cfg.Datacap = ctx.Uint64(BlobPoolDataCapFlag.Name)
+	}
+	if ctx.IsSet(BlobPoolPriceBumpFlag.Name) {
+		cfg.PriceBump = ctx.Uint64(BlobPoolPriceBumpFlag.Name)
+	}
+}
+
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.IsSet(MinerExtraDataFlag.Name) {
 		cfg.ExtraData = []byte(ctx.String(MinerExtraDataFlag.Name))
@@ -1626,6 +1922,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setEtherbase(ctx, cfg)
 	setGPO(ctx, &cfg.GPO, ctx.String(SyncModeFlag.Name) == "light")
 	setTxPool(ctx, &cfg.TxPool)
+	setBlobPool(ctx, &cfg.BlobPool)
 	setMiner(ctx, &cfg.Miner)
 	setRequiredBlocks(ctx, cfg)
 	setLes(ctx, cfg)
@@ -1670,6 +1967,21 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(GCModeFlag.Name) {
 		cfg.NoPruning = ctx.String(GCModeFlag.Name) == "archive"
 	}
+	if ctx.IsSet(StateSchemeFlag.Name) {
+		cfg.StateScheme = parseStateScheme(ctx)
+	}
+	if cfg.StateScheme == rawdb.PathScheme && cfg.NoPruning {
+		Fatalf("--%s=archive is not supported with --%s=path", GCModeFlag.Name, StateSchemeFlag.Name)
+	}
+	if ctx.IsSet(StateHistoryFlag.Name) {
+		cfg.StateHistory = ctx.Uint64(StateHistoryFlag.Name)
+	}
+	if ctx.IsSet(StateDiffsFlag.Name) {
+		cfg.StateDiffs = ctx.Bool(StateDiffsFlag.Name)
+	}
+	if ctx.IsSet(StateDiffHistoryFlag.Name) {
+		cfg.StateDiffHistory = ctx.Uint64(StateDiffHistoryFlag.Name)
+	}
 	if ctx.IsSet(CacheNoPrefetchFlag.Name) {
 		cfg.NoPrefetch = ctx.Bool(CacheNoPrefetchFlag.Name)
 	}
@@ -1682,6 +1994,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.Uint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.IsSet(ChainHistoryFlag.Name) {
+		cfg.ChainHistory = ctx.Uint64(ChainHistoryFlag.Name)
+	}
+	if ctx.IsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.Bool(LogIndexFlag.Name)
+	}
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.Int(CacheFlag.Name) * ctx.Int(CacheTrieFlag.Name) / 100
 	}
@@ -1716,6 +2034,10 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.Bool(VMEnableDebugFlag.Name)
 	}
+	if ctx.IsSet(VMTraceFlag.Name) {
+		cfg.VMTrace = ctx.String(VMTraceFlag.Name)
+		cfg.VMTraceConfig = ctx.String(VMTraceConfigFlag.Name)
+	}
 
 	if ctx.IsSet(RPCGlobalGasCapFlag.Name) {
 		cfg.RPCGasCap = ctx.Uint64(RPCGlobalGasCapFlag.Name)
@@ -1728,6 +2050,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(RPCGlobalEVMTimeoutFlag.Name) {
 		cfg.RPCEVMTimeout = ctx.Duration(RPCGlobalEVMTimeoutFlag.Name)
 	}
+	if ctx.IsSet(RPCTraceFilterRangeFlag.Name) {
+		cfg.RPCTraceFilterRange = ctx.Uint64(RPCTraceFilterRangeFlag.Name)
+	}
 	if ctx.IsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.Float64(RPCGlobalTxFeeCapFlag.Name)
 	}
@@ -2028,7 +2353,8 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly bool) ethdb.
 	switch {
 	case ctx.IsSet(RemoteDBFlag.Name):
 		log.Info("Using remote db", "url", ctx.String(RemoteDBFlag.Name), "headers", len(ctx.StringSlice(HttpHeaderFlag.Name)))
-		client, err := DialRPCWithHeaders(ctx.String(RemoteDBFlag.Name), ctx.StringSlice(HttpHeaderFlag.Name))
+		var client *rpc.Client
+		client, err = DialRPCWithHeaders(ctx.String(RemoteDBFlag.Name), ctx.StringSlice(HttpHeaderFlag.Name))
 		if err != nil {
 			break
 		}
@@ -2126,9 +2452,27 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 	if !ctx.Bool(SnapshotFlag.Name) {
 		cache.SnapshotLimit = 0 // Disabled
 	}
+	// Resolve the state scheme, ensure it's compatible with the persistent state.
+	var provided string
+	if ctx.IsSet(StateSchemeFlag.Name) {
+		provided = parseStateScheme(ctx)
+	}
+	scheme, err := rawdb.ParseStateScheme(provided, chainDb)
+	if err != nil {
+		Fatalf("%v", err)
+	}
+	if scheme == rawdb.PathScheme && cache.TrieDirtyDisabled {
+		Fatalf("--%s=archive is not supported with path-based state scheme", GCModeFlag.Name)
+	}
+	cache.StateScheme = scheme
+	cache.StateHistory = ctx.Uint64(StateHistoryFlag.Name)
+
 	// If we're in readonly, do not bother generating snapshot data.
 	if readonly {
 		cache.SnapshotNoBuild = true
+	} else {
+		cache.StateDiffs = ctx.Bool(StateDiffsFlag.Name)
+		cache.StateDiffHistory = ctx.Uint64(StateDiffHistoryFlag.Name)
 	}
 
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheTrieFlag.Name) {
@@ -2138,6 +2482,17 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		cache.TrieDirtyLimit = ctx.Int(CacheFlag.Name) * ctx.Int(CacheGCFlag.Name) / 100
 	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.Bool(VMEnableDebugFlag.Name)}
+	if name := ctx.String(VMTraceFlag.Name); name != "" {
+		var config json.RawMessage
+		if ctx.IsSet(VMTraceConfigFlag.Name) {
+			config = json.RawMessage(ctx.String(VMTraceConfigFlag.Name))
+		}
+		t, err := tracers.LiveDirectory.New(name, config)
+		if err != nil {
+			Fatalf("Failed to create tracer %q: %v", name, err)
+		}
+		vmcfg.Tracer = t
+	}
 
 	// Disable transaction indexing/unindexing by default.
 	chain, err := core.NewBlockChain(chainDb, cache, gspec, nil, engine, vmcfg, nil, nil)
@@ -2147,6 +2502,20 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 	return chain, chainDb
 }
 
+// parseStateScheme resolves the state scheme specified by the user, mapping
+// it to the scheme identifier used in the database.
+func parseStateScheme(ctx *cli.Context) string {
+	switch scheme := ctx.String(StateSchemeFlag.Name); scheme {
+	case "hash":
+		return rawdb.HashScheme
+	case "path":
+		return rawdb.PathScheme
+	default:
+		Fatalf("--%s must be either 'hash' or 'path', got %q", StateSchemeFlag.Name, scheme)
+	}
+	return ""
+}
+
 // MakeConsolePreloads retrieves the absolute paths for the console JavaScript
 // scripts to preload before starting.
 func MakeConsolePreloads(ctx *cli.Context) []string {
@@ -0,0 +1,178 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+package utils
+
+import (
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	count uint64 = 128
+	step  uint64 = 16
+)
+
+// newHistoryChain creates a chain backed by a freezer database.
+func newHistoryChain(t *testing.T, gspec *core.Genesis) (*core.BlockChain, ethdb.Database) {
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	chain, err := core.NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	return chain, db
+}
+
+func TestHistoryImportAndExport(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(1000000000000000000)}},
+		}
+		signer = types.LatestSigner(genesis.Config)
+	)
+	// Generate chain and write it directly into the ancient store.
+	_, blocks, receipts := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), int(count-1), func(i int, g *core.BlockGen) {
+		if i == 0 {
+			return
+		}
+		tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   genesis.Config.ChainID,
+			Nonce:     uint64(i - 1),
+			GasTipCap: common.Big0,
+			GasFeeCap: g.PrevBlock(0).BaseFee(),
+			Gas:       50000,
+			To:        &common.Address{0xaa},
+			Value:     big.NewInt(int64(i)),
+		})
+		if err != nil {
+			t.Fatalf("error creating tx: %v", err)
+		}
+		g.AddTx(tx)
+	})
+	chain, db := newHistoryChain(t, genesis)
+	defer chain.Stop()
+
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if _, err := chain.InsertHeaderChain(headers); err != nil {
+		t.Fatalf("error inserting headers: %v", err)
+	}
+	if _, err := chain.InsertReceiptChain(blocks, receipts, math.MaxUint64); err != nil {
+		t.Fatalf("error inserting chain: %v", err)
+	}
+	// Make temp directory for era files.
+	dir := t.TempDir()
+
+	// Export history to temp directory.
+	if err := ExportHistory(db, dir, 0, count, step); err == nil {
+		t.Fatalf("expected error exporting unfrozen blocks")
+	}
+	if err := ExportHistory(db, dir, 0, count-1, step); err != nil {
+		t.Fatalf("error exporting history: %v", err)
+	}
+	// Read checksums.
+	checksums, err := readList(filepath.Join(dir, "checksums.txt"))
+	if err != nil {
+		t.Fatalf("failed to read checksums: %v", err)
+	}
+	// Verify each Era.
+	entries, _ := era.ReadDir(dir, "unknown")
+	if len(entries) != int(count/step) || len(checksums) != len(entries) {
+		t.Fatalf("era file count mismatch: have %d (%d checksums), want %d", len(entries), len(checksums), count/step)
+	}
+	for i, filename := range entries {
+		e, err := era.Open(filepath.Join(dir, filename))
+		if err != nil {
+			t.Fatalf("error opening era file: %v", err)
+		}
+		if err := e.Verify(); err != nil {
+			t.Fatalf("era %d failed verification: %v", i, err)
+		}
+		if e.Start() != uint64(i)*step || e.Count() != step {
+			t.Fatalf("era %d range mismatch: have [%d, +%d]", i, e.Start(), e.Count())
+		}
+		for j := uint64(0); j < e.Count(); j++ {
+			n := e.Start() + j
+			want := chain.GetBlockByNumber(n)
+			if have, err := e.GetBlockByNumber(n); err != nil || have.Hash() != want.Hash() {
+				t.Fatalf("block %d mismatch (%v)", n, err)
+			}
+		}
+		e.Close()
+	}
+	// Now import Era.
+	imported, idb := newHistoryChain(t, genesis)
+	defer imported.Stop()
+
+	if err := ImportHistory(imported, idb, dir, "unknown"); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	if have, want := imported.CurrentSnapBlock().Number.Uint64(), count-1; have != want {
+		t.Fatalf("imported head mismatch: have %d, want %d", have, want)
+	}
+	if frozen, _ := idb.Ancients(); frozen != count {
+		t.Fatalf("imported ancient count mismatch: have %d, want %d", frozen, count)
+	}
+	for n := uint64(0); n < count; n++ {
+		var (
+			want = chain.GetBlockByNumber(n)
+			have = imported.GetBlockByNumber(n)
+		)
+		if have == nil || have.Hash() != want.Hash() {
+			t.Fatalf("imported block %d mismatch", n)
+		}
+		if types.DeriveSha(have.Transactions(), trie.NewStackTrie(nil)) != want.TxHash() {
+			t.Fatalf("imported block %d body mismatch", n)
+		}
+		rs := rawdb.ReadReceipts(idb, have.Hash(), n, have.Time(), genesis.Config)
+		if types.DeriveSha(rs, trie.NewStackTrie(nil)) != want.ReceiptHash() {
+			t.Fatalf("imported block %d receipts mismatch", n)
+		}
+	}
+	// Importing into a non-empty ancient store is rejected.
+	if err := ImportHistory(imported, idb, dir, "unknown"); err == nil {
+		t.Fatalf("expected error importing twice")
+	}
+	// Tampering with an archive is detected through the checksums.
+	os.WriteFile(filepath.Join(dir, entries[1]), []byte{}, 0644)
+	fresh, fdb := newHistoryChain(t, genesis)
+	defer fresh.Stop()
+	if err := ImportHistory(fresh, fdb, dir, "unknown"); err == nil {
+		t.Fatalf("expected checksum mismatch")
+	}
+}
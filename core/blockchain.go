@@ -18,6 +18,7 @@
 package core
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -42,12 +43,15 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/syncx"
+	"github.com/ethereum/go-ethereum/internal/telemetry"
 	"github.com/ethereum/go-ethereum/internal/version"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/triedb/pathdb"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
@@ -100,6 +104,9 @@ const (
 	maxTimeFutureBlocks = 30
 	TriesInMemory       = 128
 
+	// tracerName is the name of the tracer creating the spans of block imports.
+	tracerName = "github.com/ethereum/go-ethereum/core"
+
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	//
 	// Changelog:
@@ -138,11 +145,32 @@ type CacheConfig struct {
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	StateHistory        uint64        // Number of blocks from head whose state histories are reserved.
+	StateDiffs          bool          // Whether to maintain the state reverse diffs for historic state access
+	StateDiffHistory    uint64        // Number of blocks from head whose state reverse diffs are reserved (0 = entire chain)
+	StateScheme         string        // Scheme used to store ethereum states and merkle tree nodes on top
+	ChainHistory        uint64        // Number of blocks from head whose bodies and receipts are reserved (0 = entire chain)
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
 
+// triedbConfig derives the configures for trie database.
+func (c *CacheConfig) triedbConfig() *trie.Config {
+	config := &trie.Config{
+		Cache:     c.TrieCleanLimit,
+		Journal:   c.TrieCleanJournal,
+		Preimages: c.Preimages,
+	}
+	if c.StateScheme == rawdb.PathScheme {
+		config.PathDB = &pathdb.Config{
+			StateLimit: c.StateHistory,
+			DirtySize:  c.TrieDirtyLimit * 1024 * 1024,
+		}
+	}
+	return config
+}
+
 // defaultCacheConfig are the default caching values if none are specified by the
 // user (also used during testing).
 var defaultCacheConfig = &CacheConfig{
@@ -179,13 +207,16 @@ type BlockChain struct {
 	flushInterval atomic.Int64                     // Time interval (processing time) after which to flush a state
 	triedb        *trie.Database                   // The database handler for maintaining trie nodes.
 	stateCache    state.Database                   // State database to reuse between imports (contains state cache)
+	stateDiffs    *stateDiffHistory                // State reverse diffs for historic state access, nil if disabled
 
 	// txLookupLimit is the maximum number of blocks from head whose tx indices
 	// are reserved:
 	//  * 0:   means no limit and regenerate any missing indexes
 	//  * N:   means N block limit [HEAD-N+1, HEAD] and delete extra indexes
 	//  * nil: disable tx reindexer/deleter, but still index new blocks
-	txLookupLimit uint64
+	txLookupLimit  uint64
+	txIndexEnabled bool        // Whether the tx reindexer/deleter is enabled
+	txIndexRunning atomic.Bool // Whether the tx reindexer/deleter is currently active
 
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
@@ -226,6 +257,7 @@ type BlockChain struct {
 	processor  Processor // Block transaction processor interface
 	forker     *ForkChoice
 	vmConfig   vm.Config
+	logger     BlockchainLogger // Live tracer notified of the imported blocks, nil if disabled
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -236,11 +268,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		cacheConfig = defaultCacheConfig
 	}
 	// Open trie database with provided config
-	triedb := trie.NewDatabaseWithConfig(db, &trie.Config{
-		Cache:     cacheConfig.TrieCleanLimit,
-		Journal:   cacheConfig.TrieCleanJournal,
-		Preimages: cacheConfig.Preimages,
-	})
+	triedb := trie.NewDatabaseWithConfig(db, cacheConfig.triedbConfig())
 	// Setup the genesis block, commit the provided genesis specification
 	// to database if the genesis block is not present yet, or load the
 	// stored one from database.
@@ -273,6 +301,12 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		engine:        engine,
 		vmConfig:      vmConfig,
 	}
+	// A live tracer is only attached to the block processing, detach it from the
+	// configuration shared with calls, block building and prefetching.
+	if logger, ok := vmConfig.Tracer.(BlockchainLogger); ok {
+		bc.logger = logger
+		bc.vmConfig.Tracer = nil
+	}
 	bc.flushInterval.Store(int64(cacheConfig.TrieTimeLimit))
 	bc.forker = NewForkChoice(bc, shouldPreserve)
 	bc.stateCache = state.NewDatabaseWithNodeDB(bc.db, bc.triedb)
@@ -406,6 +440,14 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		}
 		bc.snaps, _ = snapshot.New(snapconfig, bc.db, bc.triedb, head.Root)
 	}
+	// Open the state diff history if requested, it is built on the snapshot
+	if bc.cacheConfig.StateDiffs {
+		if bc.snaps == nil {
+			log.Warn("State diff history requires the snapshot, disabling")
+		} else if bc.stateDiffs, err = newStateDiffHistory(bc.db, bc.cacheConfig.StateDiffHistory); err != nil {
+			return nil, err
+		}
+	}
 
 	// Start future block processor.
 	bc.wg.Add(1)
@@ -436,10 +478,16 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	// Start tx indexer/unindexer if required.
 	if txLookupLimit != nil {
 		bc.txLookupLimit = *txLookupLimit
+		bc.txIndexEnabled = true
 
 		bc.wg.Add(1)
 		go bc.maintainTxIndex()
 	}
+	// Start the chain history pruner if required.
+	if bc.cacheConfig.ChainHistory > 0 {
+		bc.wg.Add(1)
+		go bc.maintainChainHistory()
+	}
 	return bc, nil
 }
 
@@ -646,6 +694,14 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 					if root != (common.Hash{}) && !beyondRoot && newHeadBlock.Root() == root {
 						beyondRoot, rootNumber = true, newHeadBlock.NumberU64()
 					}
+					// If the state is missing but can be restored by applying the
+					// trie histories(path-based scheme only), recover it here.
+					if !bc.HasState(newHeadBlock.Root()) && bc.stateRecoverable(newHeadBlock.Root()) {
+						if err := bc.triedb.Recover(newHeadBlock.Root()); err != nil {
+							log.Crit("Failed to rollback state", "err", err)
+						}
+						log.Debug("Rewound to block with recovered state", "number", newHeadBlock.NumberU64(), "hash", newHeadBlock.Hash())
+					}
 					if !bc.HasState(newHeadBlock.Root()) {
 						log.Trace("Block state missing, rewinding further", "number", newHeadBlock.NumberU64(), "hash", newHeadBlock.Hash())
 						if pivot == nil || newHeadBlock.NumberU64() > *pivot {
@@ -893,7 +949,7 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 	rawdb.WriteHeadHeaderHash(batch, block.Hash())
 	rawdb.WriteHeadFastBlockHash(batch, block.Hash())
 	rawdb.WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
-	rawdb.WriteTxLookupEntriesByBlock(batch, block)
+	rawdb.WriteTxLookupEntriesByBlock(batch, block, types.MakeSigner(bc.chainConfig, block.Number(), block.Time()))
 	rawdb.WriteHeadBlockHash(batch, block.Hash())
 
 	// Flush the whole batch into the disk, exit the node if failed
@@ -908,6 +964,10 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 
 	bc.currentBlock.Store(block.Header())
 	headBlockGauge.Update(int64(block.NumberU64()))
+
+	if bc.stateDiffs != nil {
+		bc.stateDiffs.freeze(block.NumberU64())
+	}
 }
 
 // stopWithoutSaving stops the blockchain service. If any imports are currently in progress
@@ -952,12 +1012,17 @@ func (bc *BlockChain) Stop() {
 		}
 	}
 
-	// Ensure the state of a recent block is also stored to disk before exiting.
-	// We're writing three different states to catch different restart scenarios:
-	//  - HEAD:     So we don't need to reprocess any blocks in the general case
-	//  - HEAD-1:   So we don't do large reorgs if our HEAD becomes an uncle
-	//  - HEAD-127: So we have a hard limit on the number of blocks reexecuted
-	if !bc.cacheConfig.TrieDirtyDisabled {
+	// Ensure that the in-memory trie nodes are journaled to disk properly.
+	if bc.triedb.Scheme() == rawdb.PathScheme {
+		if err := bc.triedb.Journal(bc.CurrentBlock().Root); err != nil {
+			log.Info("Failed to journal in-memory trie nodes", "err", err)
+		}
+	} else if !bc.cacheConfig.TrieDirtyDisabled {
+		// Ensure the state of a recent block is also stored to disk before exiting.
+		// We're writing three different states to catch different restart scenarios:
+		//  - HEAD:     So we don't need to reprocess any blocks in the general case
+		//  - HEAD-1:   So we don't do large reorgs if our HEAD becomes an uncle
+		//  - HEAD-127: So we have a hard limit on the number of blocks reexecuted
 		triedb := bc.triedb
 
 		for _, offset := range []uint64{0, 1, TriesInMemory - 1} {
@@ -992,6 +1057,12 @@ func (bc *BlockChain) Stop() {
 	if bc.cacheConfig.TrieCleanJournal != "" {
 		bc.triedb.SaveCache(bc.cacheConfig.TrieCleanJournal)
 	}
+	// Release the state diff freezer
+	if bc.stateDiffs != nil {
+		if err := bc.stateDiffs.close(); err != nil {
+			log.Error("Failed to close state diff history", "err", err)
+		}
+	}
 	log.Info("Blockchain stopped")
 }
 
@@ -1145,9 +1216,9 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		var batch = bc.db.NewBatch()
 		for i, block := range blockChain {
 			if bc.txLookupLimit == 0 || ancientLimit <= bc.txLookupLimit || block.NumberU64() >= ancientLimit-bc.txLookupLimit {
-				rawdb.WriteTxLookupEntriesByBlock(batch, block)
+				rawdb.WriteTxLookupEntriesByBlock(batch, block, types.MakeSigner(bc.chainConfig, block.Number(), block.Time()))
 			} else if rawdb.ReadTxIndexTail(bc.db) != nil {
-				rawdb.WriteTxLookupEntriesByBlock(batch, block)
+				rawdb.WriteTxLookupEntriesByBlock(batch, block, types.MakeSigner(bc.chainConfig, block.Number(), block.Time()))
 			}
 			stats.processed++
 
@@ -1230,7 +1301,7 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 			// Write all the data out into the database
 			rawdb.WriteBody(batch, block.Hash(), block.NumberU64(), block.Body())
 			rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receiptChain[i])
-			rawdb.WriteTxLookupEntriesByBlock(batch, block) // Always write tx indices for live blocks, we assume they are needed
+			rawdb.WriteTxLookupEntriesByBlock(batch, block, types.MakeSigner(bc.chainConfig, block.Number(), block.Time())) // Always write tx indices for live blocks, we assume they are needed
 
 			// Write everything belongs to the blocks into the database. So that
 			// we can ensure all components of body is completed(body, receipts,
@@ -1355,11 +1426,23 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	if err := blockBatch.Write(); err != nil {
 		log.Crit("Failed to write block into disk", "err", err)
 	}
-	// Commit all cached state changes into underlying memory database.
+	// Commit all cached state changes into underlying memory database. Reverse
+	// diffs can only be recorded from a complete snapshot, the history restarts
+	// once its generation is done.
+	if bc.stateDiffs != nil && bc.snaps != nil {
+		if generating, err := bc.snaps.Generating(); err == nil && !generating {
+			state.EnableDiffRecording()
+		}
+	}
 	root, err := state.Commit(bc.chainConfig.IsEIP158(block.Number()))
 	if err != nil {
 		return err
 	}
+	// If node is running in path mode, skip explicit gc operation
+	// which is unnecessary in this mode.
+	if bc.triedb.Scheme() == rawdb.PathScheme {
+		return nil
+	}
 	// If we're running an archive node, always flush
 	if bc.cacheConfig.TrieDirtyDisabled {
 		return bc.triedb.Commit(root, false)
@@ -1415,6 +1498,24 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	return nil
 }
 
+// writeStateDiff stores the reverse diff recorded while committing the state of
+// the given block, if any.
+func (bc *BlockChain) writeStateDiff(block *types.Block, state *state.StateDB) error {
+	diff, err := state.ReverseDiff()
+	if err != nil {
+		return fmt.Errorf("failed to record state diff of block #%d: %w", block.NumberU64(), err)
+	}
+	if diff == nil {
+		return nil
+	}
+	blob, err := diff.Encode()
+	if err != nil {
+		return err
+	}
+	rawdb.WriteStateDiff(bc.db, block.NumberU64(), block.Hash(), blob)
+	return nil
+}
+
 // WriteBlockAndSetHead writes the given block and all associated state to the database,
 // and applies the block as the new chain head.
 func (bc *BlockChain) WriteBlockAndSetHead(block *types.Block, receipts []*types.Receipt, logs []*types.Log, state *state.StateDB, emitHeadEvent bool) (status WriteStatus, err error) {
@@ -1438,6 +1539,10 @@ func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types
 		return NonStatTy, err
 	}
 	if reorg {
+		// The state diff is only kept for canonical blocks
+		if err := bc.writeStateDiff(block, state); err != nil {
+			return NonStatTy, err
+		}
 		// Reorganise the chain if the parent is not the head block
 		if block.ParentHash() != currentBlock.Hash() {
 			if err := bc.reorg(currentBlock, block); err != nil {
@@ -1749,19 +1854,42 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 			}
 		}
 
+		// Notify the live tracer of the block, attaching it to the processing
+		vmConfig := bc.vmConfig
+		if bc.logger != nil {
+			td := bc.GetTd(block.ParentHash(), block.NumberU64()-1)
+			bc.logger.OnBlockStart(block, td, bc.CurrentFinalBlock(), bc.CurrentSafeBlock())
+			statedb.SetLogger(bc.logger)
+			vmConfig.Tracer = bc.logger
+		}
+		// Trace the import phases of the block if telemetry is enabled
+		spanCtx, blockSpan := telemetry.StartSpan(context.Background(), tracerName, "insertBlock",
+			attribute.Int64("block.number", block.Number().Int64()),
+			attribute.String("block.hash", block.Hash().Hex()),
+			attribute.Int("block.txs", len(block.Transactions())),
+		)
 		// Process block using the parent state as reference point
 		pstart := time.Now()
-		receipts, logs, usedGas, err := bc.processor.Process(block, statedb, bc.vmConfig)
+		_, span := telemetry.StartSpan(spanCtx, tracerName, "execution")
+		receipts, logs, usedGas, err := bc.processor.Process(block, statedb, vmConfig)
+		telemetry.EndSpan(span, err)
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
+			bc.traceBlockEnd(err)
+			telemetry.EndSpan(blockSpan, err)
 			followupInterrupt.Store(true)
 			return it.index, err
 		}
 		ptime := time.Since(pstart)
 
 		vstart := time.Now()
-		if err := bc.validator.ValidateState(block, statedb, receipts, usedGas); err != nil {
+		_, span = telemetry.StartSpan(spanCtx, tracerName, "validation")
+		err = bc.validator.ValidateState(block, statedb, receipts, usedGas)
+		telemetry.EndSpan(span, err)
+		if err != nil {
 			bc.reportBlock(block, receipts, err)
+			bc.traceBlockEnd(err)
+			telemetry.EndSpan(blockSpan, err)
 			followupInterrupt.Store(true)
 			return it.index, err
 		}
@@ -1789,12 +1917,19 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 			wstart = time.Now()
 			status WriteStatus
 		)
+		_, span = telemetry.StartSpan(spanCtx, tracerName, "commit")
 		if !setHead {
-			// Don't set the head, only insert the block
-			err = bc.writeBlockWithState(block, receipts, statedb)
+			// Don't set the head, only insert the block. It is expected to become
+			// canonical by a later head update, so its state diff is kept.
+			if err = bc.writeBlockWithState(block, receipts, statedb); err == nil {
+				err = bc.writeStateDiff(block, statedb)
+			}
 		} else {
 			status, err = bc.writeBlockAndSetHead(block, receipts, logs, statedb, false)
 		}
+		telemetry.EndSpan(span, err)
+		bc.traceBlockEnd(err)
+		telemetry.EndSpan(blockSpan, err)
 		followupInterrupt.Store(true)
 		if err != nil {
 			return it.index, err
@@ -2350,6 +2485,14 @@ func (bc *BlockChain) skipBlock(err error, it *insertIterator) bool {
 func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{}) {
 	defer func() { close(done) }()
 
+	// The bodies below the chain history tail are pruned, never index them.
+	pruned, _ := bc.db.Tail()
+	index := func(from, to uint64) {
+		if from < pruned {
+			from = pruned
+		}
+		rawdb.IndexTransactions(bc.db, bc.chainConfig, from, to, bc.quit)
+	}
 	// The tail flag is not existent, it means the node is just initialized
 	// and all blocks(may from ancient store) are not indexed yet.
 	if tail == nil {
@@ -2357,7 +2500,7 @@ func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{})
 		if bc.txLookupLimit != 0 && head >= bc.txLookupLimit {
 			from = head - bc.txLookupLimit + 1
 		}
-		rawdb.IndexTransactions(bc.db, from, head+1, bc.quit)
+		index(from, head+1)
 		return
 	}
 	// The tail flag is existent, but the whole chain is required to be indexed.
@@ -2370,17 +2513,17 @@ func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{})
 			if end > head+1 {
 				end = head + 1
 			}
-			rawdb.IndexTransactions(bc.db, 0, end, bc.quit)
+			index(0, end)
 		}
 		return
 	}
 	// Update the transaction index to the new chain state
 	if head-bc.txLookupLimit+1 < *tail {
 		// Reindex a part of missing indices and rewind index tail to HEAD-limit
-		rawdb.IndexTransactions(bc.db, head-bc.txLookupLimit+1, *tail, bc.quit)
+		index(head-bc.txLookupLimit+1, *tail)
 	} else {
 		// Unindex a part of stale indices and forward index tail to HEAD-limit
-		rawdb.UnindexTransactions(bc.db, *tail, head-bc.txLookupLimit+1, bc.quit)
+		rawdb.UnindexTransactions(bc.db, bc.chainConfig, *tail, head-bc.txLookupLimit+1, bc.quit)
 	}
 }
 
@@ -2413,10 +2556,12 @@ func (bc *BlockChain) maintainTxIndex() {
 		case head := <-headCh:
 			if done == nil {
 				done = make(chan struct{})
+				bc.txIndexRunning.Store(true)
 				go bc.indexBlocks(rawdb.ReadTxIndexTail(bc.db), head.Block.NumberU64(), done)
 			}
 		case <-done:
 			done = nil
+			bc.txIndexRunning.Store(false)
 		case <-bc.quit:
 			if done != nil {
 				log.Info("Waiting background transaction indexer to exit")
@@ -2427,6 +2572,109 @@ func (bc *BlockChain) maintainTxIndex() {
 	}
 }
 
+// TxIndexProgress is the progress of the background transaction indexing.
+type TxIndexProgress struct {
+	Head      uint64  // Number of the chain head the indexed range is derived from
+	Tail      *uint64 // Number of the oldest indexed block, nil if never indexed
+	Limit     uint64  // Number of recent blocks to index, 0 for the entire chain
+	Indexed   uint64  // Number of blocks whose transactions are indexed
+	Remaining uint64  // Number of blocks whose transactions are yet to be indexed
+	Running   bool    // Whether the indexer is currently indexing or unindexing
+}
+
+// Done returns an indicator if the transaction indexing is finished.
+func (p TxIndexProgress) Done() bool {
+	return p.Remaining == 0
+}
+
+// TxIndexProgress retrieves the progress of the background transaction indexing.
+// An error is returned if the reindexer/deleter is disabled.
+func (bc *BlockChain) TxIndexProgress() (TxIndexProgress, error) {
+	if !bc.txIndexEnabled {
+		return TxIndexProgress{}, errors.New("tx indexer is not enabled")
+	}
+	progress := TxIndexProgress{
+		Head:    bc.CurrentBlock().Number.Uint64(),
+		Tail:    rawdb.ReadTxIndexTail(bc.db),
+		Limit:   bc.txLookupLimit,
+		Running: bc.txIndexRunning.Load(),
+	}
+	// The indexed range is limited by the lookup limit and the pruned history
+	from := uint64(0)
+	if bc.txLookupLimit != 0 && progress.Head >= bc.txLookupLimit {
+		from = progress.Head - bc.txLookupLimit + 1
+	}
+	if pruned, _ := bc.db.Tail(); pruned > from {
+		from = pruned
+	}
+	var total uint64
+	if from <= progress.Head {
+		total = progress.Head + 1 - from
+	}
+	if progress.Tail != nil && *progress.Tail <= progress.Head {
+		progress.Indexed = progress.Head + 1 - *progress.Tail
+	}
+	if progress.Indexed < total {
+		progress.Remaining = total - progress.Indexed
+	}
+	return progress, nil
+}
+
+// pruneChainHistory drops the bodies and receipts of the blocks below the given
+// one, closing the done channel when finished.
+func (bc *BlockChain) pruneChainHistory(before uint64, done chan struct{}) {
+	defer close(done)
+
+	if _, err := rawdb.PruneChainHistory(bc.db, bc.chainConfig, before, bc.quit); err != nil {
+		log.Error("Failed to prune chain history", "before", before, "err", err)
+	}
+}
+
+// maintainChainHistory is responsible for pruning the bodies and receipts of
+// the blocks falling out of the retention window configured by the user. Only
+// the history moved into the freezer is pruned, the retention window is thus
+// effectively never shorter than the immutability threshold.
+func (bc *BlockChain) maintainChainHistory() {
+	defer bc.wg.Done()
+
+	var (
+		done   chan struct{}                  // Non-nil if background pruning routine is active.
+		headCh = make(chan ChainHeadEvent, 1) // Buffered to avoid locking up the event feed
+	)
+	sub := bc.SubscribeChainHeadEvent(headCh)
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case head := <-headCh:
+			number := head.Block.NumberU64()
+			if done == nil && number >= bc.cacheConfig.ChainHistory {
+				done = make(chan struct{})
+				go bc.pruneChainHistory(number-bc.cacheConfig.ChainHistory+1, done)
+			}
+		case <-done:
+			done = nil
+		case <-bc.quit:
+			if done != nil {
+				log.Info("Waiting background chain history pruner to exit")
+				<-done
+			}
+			return
+		}
+	}
+}
+
+// traceBlockEnd notifies the live tracer, if any, that the processing of the
+// current block finished.
+func (bc *BlockChain) traceBlockEnd(err error) {
+	if bc.logger != nil {
+		bc.logger.OnBlockEnd(err)
+	}
+}
+
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
 	rawdb.WriteBadBlock(bc.db, block)
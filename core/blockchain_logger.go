@@ -0,0 +1,54 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// BlockchainLogger is used to collect traces live during block import, without
+// re-executing the blocks. Besides the EVM execution and the state modifications,
+// it is notified of the block and transaction boundaries.
+//
+// A BlockchainLogger set as the tracer of the vm.Config the chain is created with
+// is only invoked for the blocks processed by the chain, and not for any other
+// execution using the chain's configuration, such as calls or block building.
+type BlockchainLogger interface {
+	vm.EVMLogger
+	state.StateLogger
+
+	// OnBlockStart is called before processing a block, td being the total
+	// difficulty of its parent. The finalized and safe blocks may be nil.
+	OnBlockStart(block *types.Block, td *big.Int, finalized, safe *types.Header)
+
+	// OnBlockEnd is called after the block is processed, validated and written,
+	// or as soon as any of these steps fails.
+	OnBlockEnd(err error)
+
+	// OnTxStart is called before a transaction of the block is executed, with the
+	// EVM already set up with the transaction context.
+	OnTxStart(env *vm.EVM, tx *types.Transaction, from common.Address)
+
+	// OnTxEnd is called after the transaction is executed. The receipt is nil if
+	// the transaction could not be applied.
+	OnTxEnd(receipt *types.Receipt, err error)
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// recordingLogger is a live tracer recording the block, transaction and state
+// hooks invoked, ignoring the opcode level ones.
+type recordingLogger struct {
+	events []string
+	calls  int
+}
+
+func (l *recordingLogger) CaptureTxStart(gasLimit uint64) {}
+func (l *recordingLogger) CaptureTxEnd(restGas uint64)    {}
+func (l *recordingLogger) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	l.calls++
+}
+func (l *recordingLogger) CaptureEnd(output []byte, gasUsed uint64, err error) {}
+func (l *recordingLogger) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+func (l *recordingLogger) CaptureExit(output []byte, gasUsed uint64, err error) {}
+func (l *recordingLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+func (l *recordingLogger) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (l *recordingLogger) OnBlockStart(block *types.Block, td *big.Int, finalized, safe *types.Header) {
+	l.events = append(l.events, fmt.Sprintf("block start %d td %v", block.NumberU64(), td))
+}
+func (l *recordingLogger) OnBlockEnd(err error) {
+	l.events = append(l.events, fmt.Sprintf("block end %v", err))
+}
+func (l *recordingLogger) OnTxStart(env *vm.EVM, tx *types.Transaction, from common.Address) {
+	l.events = append(l.events, fmt.Sprintf("tx start %x origin %x", tx.Hash(), env.TxContext.Origin))
+}
+func (l *recordingLogger) OnTxEnd(receipt *types.Receipt, err error) {
+	l.events = append(l.events, fmt.Sprintf("tx end %d %v", receipt.Status, err))
+}
+func (l *recordingLogger) OnBalanceChange(addr common.Address, prev, new *big.Int) {
+	l.events = append(l.events, fmt.Sprintf("balance %x %v -> %v", addr, prev, new))
+}
+func (l *recordingLogger) OnNonceChange(addr common.Address, prev, new uint64) {
+	l.events = append(l.events, fmt.Sprintf("nonce %x %d -> %d", addr, prev, new))
+}
+func (l *recordingLogger) OnStorageChange(addr common.Address, slot common.Hash, prev, new common.Hash) {
+	l.events = append(l.events, fmt.Sprintf("storage %x %x %x -> %x", addr, slot, prev, new))
+}
+func (l *recordingLogger) OnLog(log *types.Log) {
+	l.events = append(l.events, fmt.Sprintf("log %x %x", log.Address, log.Data))
+}
+
+// Tests that a live tracer is notified of the block and transaction boundaries
+// and the state changes during block import.
+func TestBlockchainLogger(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		coinbase = common.Address{0x02}
+		gspec    = &Genesis{
+			Config: params.AllEthashProtocolChanges,
+			Alloc: GenesisAlloc{
+				sender: {Balance: big.NewInt(params.Ether)},
+				// PUSH1 1 PUSH1 0 SSTORE PUSH1 0xff PUSH1 0 MSTORE8 PUSH1 1 PUSH1 0 LOG0 STOP
+				contract: {Balance: common.Big0, Code: common.FromHex("0x600160005560ff60005360016000a000")},
+			},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 1, func(i int, b *BlockGen) {
+		b.SetCoinbase(coinbase)
+		tx, _ := types.SignTx(types.NewTransaction(0, contract, big.NewInt(1), 100000, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	logger := new(recordingLogger)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{Tracer: logger}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if chain.GetVMConfig().Tracer != nil {
+		t.Fatalf("live tracer leaked into the shared vm config")
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	var (
+		block    = blocks[0]
+		tx       = block.Transactions()[0]
+		receipts = chain.GetReceiptsByHash(block.Hash())
+		gasUsed  = new(big.Int).SetUint64(receipts[0].GasUsed)
+		gasCost  = new(big.Int).Mul(big.NewInt(100000), block.BaseFee())
+		refund   = new(big.Int).Mul(new(big.Int).Sub(big.NewInt(100000), gasUsed), block.BaseFee())
+		balance  = big.NewInt(params.Ether)
+	)
+	afterBuy := new(big.Int).Sub(balance, gasCost)
+	afterValue := new(big.Int).Sub(afterBuy, common.Big1)
+	want := []string{
+		fmt.Sprintf("block start 1 td %v", gspec.ToBlock().Difficulty()),
+		fmt.Sprintf("tx start %x origin %x", tx.Hash(), sender),
+		fmt.Sprintf("balance %x %v -> %v", sender, balance, afterBuy),
+		fmt.Sprintf("nonce %x 0 -> 1", sender),
+		fmt.Sprintf("balance %x %v -> %v", sender, afterBuy, afterValue),
+		fmt.Sprintf("balance %x 0 -> 1", contract),
+		fmt.Sprintf("storage %x %x %x -> %x", contract, common.Hash{}, common.Hash{}, common.BigToHash(common.Big1)),
+		fmt.Sprintf("log %x ff", contract),
+		fmt.Sprintf("balance %x %v -> %v", sender, afterValue, new(big.Int).Add(afterValue, refund)),
+		"tx end 1 <nil>",
+		fmt.Sprintf("balance %x 0 -> %v", coinbase, ethash.ConstantinopleBlockReward),
+		"block end <nil>",
+	}
+	if !reflect.DeepEqual(logger.events, want) {
+		t.Errorf("event mismatch:\nhave %q\nwant %q", logger.events, want)
+	}
+	if logger.calls != 1 {
+		t.Errorf("call count mismatch: have %d, want %d", logger.calls, 1)
+	}
+}
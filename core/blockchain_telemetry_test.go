@@ -0,0 +1,84 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInsertChainSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(context.Background())
+
+	prevProvider := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prevProvider) })
+	otel.SetTracerProvider(provider)
+
+	var (
+		gspec = &Genesis{
+			Config:  params.AllEthashProtocolChanges,
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 2, nil)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	var (
+		names []string
+		want  = []string{"execution", "validation", "commit", "insertBlock", "execution", "validation", "commit", "insertBlock"}
+		spans = recorder.Ended()
+	)
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("wrong spans: have %v, want %v", names, want)
+	}
+	for i := 0; i < len(spans); i += 4 {
+		block := spans[i+3]
+		for _, phase := range spans[i : i+3] {
+			if phase.Parent().SpanID() != block.SpanContext().SpanID() {
+				t.Errorf("span %s of block %d not linked to the block span", phase.Name(), i/4+1)
+			}
+		}
+		for _, attr := range block.Attributes() {
+			if attr.Key == "block.number" && attr.Value.AsInt64() != int64(i/4+1) {
+				t.Errorf("wrong block number: have %d, want %d", attr.Value.AsInt64(), i/4+1)
+			}
+		}
+	}
+}
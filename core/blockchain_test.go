@@ -2562,6 +2562,9 @@ func TestTransactionIndices(t *testing.T) {
 					if index := rawdb.ReadTxLookupEntry(chain.db, tx.Hash()); index == nil {
 						t.Fatalf("Miss transaction indice, number %d hash %s", i, tx.Hash().Hex())
 					}
+					if hash := rawdb.ReadTxSenderLookupEntry(chain.db, address, tx.Nonce()); hash == nil || *hash != tx.Hash() {
+						t.Fatalf("Miss transaction sender indice, number %d hash %s", i, tx.Hash().Hex())
+					}
 				}
 			}
 			for i := uint64(0); i < *tail; i++ {
@@ -2573,6 +2576,9 @@ func TestTransactionIndices(t *testing.T) {
 					if index := rawdb.ReadTxLookupEntry(chain.db, tx.Hash()); index != nil {
 						t.Fatalf("Transaction indice should be deleted, number %d hash %s", i, tx.Hash().Hex())
 					}
+					if hash := rawdb.ReadTxSenderLookupEntry(chain.db, address, tx.Nonce()); hash != nil {
+						t.Fatalf("Transaction sender indice should be deleted, number %d hash %s", i, tx.Hash().Hex())
+					}
 				}
 			}
 		}
@@ -2623,6 +2629,66 @@ func TestTransactionIndices(t *testing.T) {
 	}
 }
 
+func TestTxIndexProgress(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{address: {Balance: big.NewInt(100000000000000000)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+		limit  = uint64(32)
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 128, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x00}, big.NewInt(1000), params.TxGas, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, &limit)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	progress, err := chain.TxIndexProgress()
+	if err != nil {
+		t.Fatalf("failed to retrieve indexing progress: %v", err)
+	}
+	if progress.Tail != nil || progress.Remaining != 1 || progress.Done() {
+		t.Fatalf("unexpected initial progress: %+v", progress)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Wait for the background indexer to settle on the final tail
+	for i := 0; ; i++ {
+		progress, err = chain.TxIndexProgress()
+		if err != nil {
+			t.Fatalf("failed to retrieve indexing progress: %v", err)
+		}
+		if !progress.Running && progress.Tail != nil && *progress.Tail == 128-limit+1 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("indexing not finished: %+v", progress)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if progress.Head != 128 || progress.Limit != limit || progress.Indexed != limit || !progress.Done() {
+		t.Fatalf("unexpected final progress: %+v", progress)
+	}
+	// Chains not maintaining the index must report an error
+	nolimit, _ := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer nolimit.Stop()
+	if _, err := nolimit.TxIndexProgress(); err == nil {
+		t.Fatal("progress reported for disabled indexer")
+	}
+}
+
 func TestSkipStaleTxIndicesInSnapSync(t *testing.T) {
 	// Configure and generate a sample block chain
 	var (
@@ -0,0 +1,91 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// LogIndexer implements a core.ChainIndexer, building up a persistent index
+// mapping the addresses and positional topics of the canonical chain's logs to
+// the positions of the logs, permitting exact log filtering.
+type LogIndexer struct {
+	size  uint64         // section size to generate the log index for
+	db    ethdb.Database // database instance to write index data and metadata into
+	batch ethdb.Batch    // batch accumulating the index entries of the current section
+}
+
+// NewLogIndexer returns a chain indexer that generates the log index for the
+// canonical chain.
+func NewLogIndexer(db ethdb.Database, size, confirms uint64) *ChainIndexer {
+	backend := &LogIndexer{
+		db:   db,
+		size: size,
+	}
+	table := rawdb.NewTable(db, string(rawdb.LogIndexPrefix))
+
+	return NewChainIndexer(db, table, backend, size, confirms, bloomThrottling, "logindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new log index section.
+// Any entries previously written for the section are removed, so that sections
+// invalidated by a reorg are repaired when reprocessed.
+func (l *LogIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	rawdb.DeleteLogIndex(l.db, section*l.size, (section+1)*l.size-1)
+	l.batch = l.db.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the logs of a new header
+// into the index.
+func (l *LogIndexer) Process(ctx context.Context, header *types.Header) error {
+	if header.Bloom == (types.Bloom{}) {
+		return nil
+	}
+	number := header.Number.Uint64()
+	receipts := rawdb.ReadRawReceipts(l.db, header.Hash(), number)
+	if receipts == nil {
+		return fmt.Errorf("missing receipts for block #%d [%x]", number, header.Hash())
+	}
+	rawdb.WriteLogIndex(l.batch, number, receipts)
+
+	// Flush large sections in chunks, a failure is repaired by the next Reset
+	if l.batch.ValueSize() >= ethdb.IdealBatchSize {
+		if err := l.batch.Write(); err != nil {
+			return err
+		}
+		l.batch.Reset()
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing out the remaining index
+// entries of the section into the database.
+func (l *LogIndexer) Commit() error {
+	return l.batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (l *LogIndexer) Prune(threshold uint64) error {
+	return nil
+}
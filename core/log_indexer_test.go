@@ -0,0 +1,88 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that the log indexer maps addresses and positional topics to the log
+// positions, and that reprocessing a section after a reorg drops stale entries.
+func TestLogIndexer(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		indexer = &LogIndexer{db: db, size: 4}
+		topic   = common.HexToHash("0x01")
+	)
+	// makeSection writes a section of headers and receipts, each block containing
+	// a log from the given address in its second receipt.
+	makeSection := func(address common.Address, extra []byte) []*types.Header {
+		headers := make([]*types.Header, 4)
+		for i := range headers {
+			receipts := types.Receipts{
+				{Logs: []*types.Log{{Address: common.Address{0xff}, Topics: []common.Hash{{}, topic}}}},
+				{Logs: []*types.Log{{Address: address, Topics: []common.Hash{topic}}}},
+			}
+			headers[i] = &types.Header{Number: big.NewInt(int64(i)), Bloom: types.CreateBloom(receipts), Extra: extra}
+			rawdb.WriteReceipts(db, headers[i].Hash(), uint64(i), receipts)
+		}
+		return headers
+	}
+	indexSection := func(headers []*types.Header) {
+		if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+			t.Fatalf("failed to reset section: %v", err)
+		}
+		for _, header := range headers {
+			if err := indexer.Process(context.Background(), header); err != nil {
+				t.Fatalf("failed to process header %d: %v", header.Number, err)
+			}
+		}
+		if err := indexer.Commit(); err != nil {
+			t.Fatalf("failed to commit section: %v", err)
+		}
+	}
+	indexSection(makeSection(common.Address{0x01}, nil))
+
+	want := []rawdb.LogPosition{{Number: 1, Index: 1}, {Number: 2, Index: 1}}
+	if have := rawdb.ReadAddressLogIndex(db, common.Address{0x01}, 1, 2); !reflect.DeepEqual(have, want) {
+		t.Errorf("address positions mismatch: have %v, want %v", have, want)
+	}
+	want = []rawdb.LogPosition{{Number: 3, Index: 1}}
+	if have := rawdb.ReadTopicLogIndex(db, 0, topic, 3, 10); !reflect.DeepEqual(have, want) {
+		t.Errorf("topic 0 positions mismatch: have %v, want %v", have, want)
+	}
+	want = []rawdb.LogPosition{{Number: 0, Index: 0}, {Number: 1, Index: 0}}
+	if have := rawdb.ReadTopicLogIndex(db, 1, topic, 0, 1); !reflect.DeepEqual(have, want) {
+		t.Errorf("topic 1 positions mismatch: have %v, want %v", have, want)
+	}
+	// Reindex the section with a reorged chain and ensure no stale entries remain
+	indexSection(makeSection(common.Address{0x02}, []byte("reorg")))
+
+	if have := rawdb.ReadAddressLogIndex(db, common.Address{0x01}, 0, 3); len(have) != 0 {
+		t.Errorf("stale positions remained after reorg: %v", have)
+	}
+	if have := rawdb.ReadAddressLogIndex(db, common.Address{0x02}, 0, 3); len(have) != 4 {
+		t.Errorf("reorged position count mismatch: have %d, want %d", len(have), 4)
+	}
+}
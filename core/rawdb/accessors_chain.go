@@ -294,6 +294,32 @@ func WriteFastTxLookupLimit(db ethdb.KeyValueWriter, number uint64) {
 	}
 }
 
+// ReadScrubProgress retrieves the number of the next block to be verified by an
+// interrupted database scrub.
+func ReadScrubProgress(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(scrubProgressKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteScrubProgress stores the number of the next block to be verified by a
+// database scrub.
+func WriteScrubProgress(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(scrubProgressKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the scrub progress", "err", err)
+	}
+}
+
+// DeleteScrubProgress removes the progress marker of a database scrub.
+func DeleteScrubProgress(db ethdb.KeyValueWriter) {
+	if err := db.Delete(scrubProgressKey); err != nil {
+		log.Crit("Failed to delete the scrub progress", "err", err)
+	}
+}
+
 // ReadHeaderRange returns the rlp-encoded headers, starting at 'number', and going
 // backwards towards genesis. This method assumes that the caller already has
 // placed a cap on count, to prevent DoS issues.
@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -71,11 +72,19 @@ func WriteTxLookupEntries(db ethdb.KeyValueWriter, number uint64, hashes []commo
 }
 
 // WriteTxLookupEntriesByBlock stores a positional metadata for every transaction from
-// a block, enabling hash based transaction and receipt lookups.
-func WriteTxLookupEntriesByBlock(db ethdb.KeyValueWriter, block *types.Block) {
+// a block, enabling hash based transaction and receipt lookups. The transactions
+// are also indexed by sender and nonce, recovered with the signer of the block.
+func WriteTxLookupEntriesByBlock(db ethdb.KeyValueWriter, block *types.Block, signer types.Signer) {
 	numberBytes := block.Number().Bytes()
 	for _, tx := range block.Transactions() {
 		writeTxLookupEntry(db, tx.Hash(), numberBytes)
+
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			log.Error("Failed to derive transaction sender", "number", block.NumberU64(), "hash", tx.Hash(), "err", err)
+			continue
+		}
+		WriteTxSenderLookupEntry(db, sender, tx.Nonce(), tx.Hash())
 	}
 }
 
@@ -93,6 +102,37 @@ func DeleteTxLookupEntries(db ethdb.KeyValueWriter, hashes []common.Hash) {
 	}
 }
 
+// ReadTxSenderLookupEntry retrieves the hash of the canonical transaction sent by
+// the given account with the given nonce. Entries left behind by transactions
+// reorged out of the canonical chain are ignored, as their hash based lookup is
+// deleted; they are overwritten once the nonce is used again.
+func ReadTxSenderLookupEntry(db ethdb.Reader, sender common.Address, nonce uint64) *common.Hash {
+	data, _ := db.Get(txSenderLookupKey(sender, nonce))
+	if len(data) != common.HashLength {
+		return nil
+	}
+	hash := common.BytesToHash(data)
+	if ReadTxLookupEntry(db, hash) == nil {
+		return nil
+	}
+	return &hash
+}
+
+// WriteTxSenderLookupEntry stores the hash of the transaction sent by the given
+// account with the given nonce, enabling sender based transaction lookups.
+func WriteTxSenderLookupEntry(db ethdb.KeyValueWriter, sender common.Address, nonce uint64, hash common.Hash) {
+	if err := db.Put(txSenderLookupKey(sender, nonce), hash.Bytes()); err != nil {
+		log.Crit("Failed to store transaction sender lookup entry", "err", err)
+	}
+}
+
+// DeleteTxSenderLookupEntry removes the sender based lookup of a transaction.
+func DeleteTxSenderLookupEntry(db ethdb.KeyValueWriter, sender common.Address, nonce uint64) {
+	if err := db.Delete(txSenderLookupKey(sender, nonce)); err != nil {
+		log.Crit("Failed to delete transaction sender lookup entry", "err", err)
+	}
+}
+
 // ReadTransaction retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func ReadTransaction(db ethdb.Reader, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
@@ -179,3 +219,146 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// Log index entry kinds, identifying which field of a log an indexed value
+// belongs to. Topics are indexed positionally, starting at logIndexTopicKind.
+const (
+	logIndexAddressKind = byte(0)
+	logIndexTopicKind   = byte(1)
+)
+
+// LogPosition identifies a log in the canonical chain by the number of its block
+// and its index within the block.
+type LogPosition struct {
+	Number uint64
+	Index  uint64
+}
+
+// logIndexEntries returns the log index entries of a block, mapping the kind
+// prefixed address and positional topics of every log to their indices in the
+// block. The returned entries are in order of first occurrence.
+func logIndexEntries(receipts types.Receipts) ([][]byte, map[string][]uint64) {
+	var (
+		entries   [][]byte
+		positions = make(map[string][]uint64)
+		index     uint64
+	)
+	add := func(kind byte, value []byte) {
+		entry := append([]byte{kind}, value...)
+		if _, ok := positions[string(entry)]; !ok {
+			entries = append(entries, entry)
+		}
+		positions[string(entry)] = append(positions[string(entry)], index)
+	}
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			add(logIndexAddressKind, log.Address.Bytes())
+			for i, topic := range log.Topics {
+				add(logIndexTopicKind+byte(i), topic.Bytes())
+			}
+			index++
+		}
+	}
+	return entries, positions
+}
+
+// WriteLogIndex stores the log index entries of a block, mapping the address and
+// positional topics of every contained log to the log positions.
+func WriteLogIndex(db ethdb.KeyValueWriter, number uint64, receipts types.Receipts) {
+	entries, positions := logIndexEntries(receipts)
+	if len(entries) == 0 {
+		return
+	}
+	for _, entry := range entries {
+		data, err := rlp.EncodeToBytes(positions[string(entry)])
+		if err != nil {
+			log.Crit("Failed to encode log index positions", "err", err)
+		}
+		if err := db.Put(logIndexKey(entry, number), data); err != nil {
+			log.Crit("Failed to store log index entry", "err", err)
+		}
+	}
+	// Track the entries of the block to allow removing them on reorgs
+	data, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		log.Crit("Failed to encode log index entries", "err", err)
+	}
+	if err := db.Put(logIndexBlockKey(number), data); err != nil {
+		log.Crit("Failed to store log index block entries", "err", err)
+	}
+}
+
+// DeleteLogIndex removes all log index entries belonging to the blocks in the
+// given range, inclusive.
+func DeleteLogIndex(db ethdb.KeyValueStore, from uint64, to uint64) {
+	it := db.NewIterator(logIndexBlockPrefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(logIndexBlockPrefix)+8 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(logIndexBlockPrefix):])
+		if number > to {
+			break
+		}
+		var entries [][]byte
+		if err := rlp.DecodeBytes(it.Value(), &entries); err != nil {
+			log.Crit("Invalid log index block entries", "number", number, "err", err)
+		}
+		for _, entry := range entries {
+			if err := db.Delete(logIndexKey(entry, number)); err != nil {
+				log.Crit("Failed to delete log index entry", "err", err)
+			}
+		}
+		if err := db.Delete(key); err != nil {
+			log.Crit("Failed to delete log index block entries", "err", err)
+		}
+	}
+	if it.Error() != nil {
+		log.Crit("Failed to iterate log index", "err", it.Error())
+	}
+}
+
+// ReadAddressLogIndex retrieves the positions of all logs emitted by the given
+// address in the given block range, inclusive.
+func ReadAddressLogIndex(db ethdb.Iteratee, address common.Address, from uint64, to uint64) []LogPosition {
+	return readLogIndex(db, append([]byte{logIndexAddressKind}, address.Bytes()...), from, to)
+}
+
+// ReadTopicLogIndex retrieves the positions of all logs having the given topic
+// at the given position in the given block range, inclusive.
+func ReadTopicLogIndex(db ethdb.Iteratee, position int, topic common.Hash, from uint64, to uint64) []LogPosition {
+	return readLogIndex(db, append([]byte{logIndexTopicKind + byte(position)}, topic.Bytes()...), from, to)
+}
+
+// readLogIndex retrieves the positions of all logs matching a log index entry
+// in the given block range, inclusive.
+func readLogIndex(db ethdb.Iteratee, entry []byte, from uint64, to uint64) []LogPosition {
+	prefix := append(append([]byte{}, logIndexPrefix...), entry...)
+
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	var positions []LogPosition
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+8 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			break
+		}
+		var indices []uint64
+		if err := rlp.DecodeBytes(it.Value(), &indices); err != nil {
+			log.Error("Invalid log index entry", "number", number, "err", err)
+			continue
+		}
+		for _, index := range indices {
+			positions = append(positions, LogPosition{Number: number, Index: index})
+		}
+	}
+	return positions
+}
@@ -64,7 +64,7 @@ func TestLookupStorage(t *testing.T) {
 		{
 			"DatabaseV6",
 			func(db ethdb.Writer, block *types.Block) {
-				WriteTxLookupEntriesByBlock(db, block)
+				WriteTxLookupEntriesByBlock(db, block, types.MakeSigner(params.TestChainConfig, block.Number(), block.Time()))
 			},
 		},
 		{
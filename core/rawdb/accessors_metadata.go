@@ -187,3 +187,36 @@ func WriteTransitionStatus(db ethdb.KeyValueWriter, data []byte) {
 		log.Crit("Failed to store the eth2 transition status", "err", err)
 	}
 }
+
+// FreezerLayout describes the storage location of the ancient store, if it was
+// moved away from the configured one or split into a hot and a cold tier.
+type FreezerLayout struct {
+	Directory     string // Root directory of the ancient store, superseding the configured one
+	ColdDirectory string // Root directory of the cold tier, empty if tiering is disabled
+	HotFiles      uint32 // Number of most recent data files per table kept in the hot tier
+}
+
+// ReadFreezerLayout retrieves the stored layout of the ancient store.
+func ReadFreezerLayout(db ethdb.KeyValueReader) *FreezerLayout {
+	data, _ := db.Get(freezerLayoutKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var layout FreezerLayout
+	if err := rlp.DecodeBytes(data, &layout); err != nil {
+		log.Error("Invalid freezer layout", "err", err)
+		return nil
+	}
+	return &layout
+}
+
+// WriteFreezerLayout stores the layout of the ancient store.
+func WriteFreezerLayout(db ethdb.KeyValueWriter, layout *FreezerLayout) {
+	data, err := rlp.EncodeToBytes(layout)
+	if err != nil {
+		log.Crit("Failed to encode freezer layout", "err", err)
+	}
+	if err := db.Put(freezerLayoutKey, data); err != nil {
+		log.Crit("Failed to store freezer layout", "err", err)
+	}
+}
@@ -17,6 +17,8 @@
 package rawdb
 
 import (
+	"encoding/binary"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
@@ -92,3 +94,222 @@ func DeleteCode(db ethdb.KeyValueWriter, hash common.Hash) {
 		log.Crit("Failed to delete contract code", "err", err)
 	}
 }
+
+// ReadStateID retrieves the state id with the provided state root.
+func ReadStateID(db ethdb.KeyValueReader, root common.Hash) *uint64 {
+	data, err := db.Get(stateIDKey(root))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteStateID writes the provided state lookup to database.
+func WriteStateID(db ethdb.KeyValueWriter, root common.Hash, id uint64) {
+	var buff [8]byte
+	binary.BigEndian.PutUint64(buff[:], id)
+	if err := db.Put(stateIDKey(root), buff[:]); err != nil {
+		log.Crit("Failed to store state ID", "err", err)
+	}
+}
+
+// DeleteStateID deletes the specified state lookup from the database.
+func DeleteStateID(db ethdb.KeyValueWriter, root common.Hash) {
+	if err := db.Delete(stateIDKey(root)); err != nil {
+		log.Crit("Failed to delete state ID", "err", err)
+	}
+}
+
+// ReadPersistentStateID retrieves the id of the persistent state from the database.
+func ReadPersistentStateID(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(persistentStateIDKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WritePersistentStateID stores the id of the persistent state into database.
+func WritePersistentStateID(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(persistentStateIDKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the persistent state ID", "err", err)
+	}
+}
+
+// ReadTrieJournal retrieves the serialized in-memory trie nodes of layers saved at
+// the last shutdown.
+func ReadTrieJournal(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(trieJournalKey)
+	return data
+}
+
+// WriteTrieJournal stores the serialized in-memory trie nodes of layers to save at
+// shutdown.
+func WriteTrieJournal(db ethdb.KeyValueWriter, journal []byte) {
+	if err := db.Put(trieJournalKey, journal); err != nil {
+		log.Crit("Failed to store tries journal", "err", err)
+	}
+}
+
+// DeleteTrieJournal deletes the serialized in-memory trie nodes of layers saved at
+// the last shutdown.
+func DeleteTrieJournal(db ethdb.KeyValueWriter) {
+	if err := db.Delete(trieJournalKey); err != nil {
+		log.Crit("Failed to remove tries journal", "err", err)
+	}
+}
+
+// ReadTrieHistory retrieves the trie reverse diff corresponding to the specified
+// state id. Nil is returned if the history is not found.
+func ReadTrieHistory(db ethdb.KeyValueReader, id uint64) []byte {
+	data, _ := db.Get(trieHistoryKey(id))
+	return data
+}
+
+// WriteTrieHistory writes the provided trie reverse diff to database.
+func WriteTrieHistory(db ethdb.KeyValueWriter, id uint64, blob []byte) {
+	if err := db.Put(trieHistoryKey(id), blob); err != nil {
+		log.Crit("Failed to store trie history", "err", err)
+	}
+}
+
+// DeleteTrieHistory deletes the specified trie reverse diff from the database.
+func DeleteTrieHistory(db ethdb.KeyValueWriter, id uint64) {
+	if err := db.Delete(trieHistoryKey(id)); err != nil {
+		log.Crit("Failed to delete trie history", "err", err)
+	}
+}
+
+// IterateTrieHistories returns an iterator over the trie reverse diffs, starting
+// at the given state id in ascending order.
+func IterateTrieHistories(db ethdb.Iteratee, from uint64) ethdb.Iterator {
+	return db.NewIterator(trieHistoryPrefix, encodeBlockNumber(from))
+}
+
+// ReadStateDiff retrieves the state reverse diff of the block with the given
+// number and hash from the key-value store. Nil is returned if it's not found.
+func ReadStateDiff(db ethdb.KeyValueReader, number uint64, hash common.Hash) []byte {
+	data, _ := db.Get(stateDiffKey(number, hash))
+	return data
+}
+
+// WriteStateDiff writes the state reverse diff of a block into the key-value store.
+func WriteStateDiff(db ethdb.KeyValueWriter, number uint64, hash common.Hash, blob []byte) {
+	if err := db.Put(stateDiffKey(number, hash), blob); err != nil {
+		log.Crit("Failed to store state diff", "err", err)
+	}
+}
+
+// DeleteStateDiffs removes the state reverse diffs of all blocks with the given
+// number, canonical or not, from the key-value store.
+func DeleteStateDiffs(db ethdb.KeyValueStore, number uint64) {
+	it := db.NewIterator(append(stateDiffPrefix, encodeBlockNumber(number)...), nil)
+	defer it.Release()
+
+	for it.Next() {
+		if len(it.Key()) != len(stateDiffPrefix)+8+common.HashLength {
+			continue
+		}
+		if err := db.Delete(it.Key()); err != nil {
+			log.Crit("Failed to delete state diff", "err", err)
+		}
+	}
+	if it.Error() != nil {
+		log.Crit("Failed to iterate state diffs", "err", it.Error())
+	}
+}
+
+// ReadStateDiffOffset retrieves the number of the block whose state reverse diff
+// is the first item of the state diff freezer.
+func ReadStateDiffOffset(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(stateDiffOffsetKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteStateDiffOffset stores the number of the block whose state reverse diff
+// is the first item of the state diff freezer.
+func WriteStateDiffOffset(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(stateDiffOffsetKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store state diff offset", "err", err)
+	}
+}
+
+// ReadFrozenStateDiff retrieves the state reverse diff with the given item id
+// from the state diff freezer. Nil is returned if it's not found.
+func ReadFrozenStateDiff(db ethdb.AncientReaderOp, id uint64) []byte {
+	blob, err := db.Ancient(StateDiffFreezerTable, id)
+	if err != nil {
+		return nil
+	}
+	return blob
+}
+
+// WriteFrozenStateDiff appends the state reverse diff with the given item id
+// into the state diff freezer.
+func WriteFrozenStateDiff(db ethdb.AncientWriter, id uint64, blob []byte) error {
+	_, err := db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		return op.AppendRaw(StateDiffFreezerTable, id, blob)
+	})
+	return err
+}
+
+// ReadStateDiffAccountIndex retrieves the number of the first block not before
+// the given one, whose frozen state reverse diff contains the given account.
+func ReadStateDiffAccountIndex(db ethdb.Iteratee, accountHash common.Hash, from uint64) (uint64, bool) {
+	return readStateDiffIndex(db, append(stateDiffAccountIndexPrefix, accountHash.Bytes()...), from)
+}
+
+// WriteStateDiffAccountIndex marks the account as contained in the frozen state
+// reverse diff of the given block.
+func WriteStateDiffAccountIndex(db ethdb.KeyValueWriter, accountHash common.Hash, number uint64) {
+	if err := db.Put(stateDiffAccountIndexKey(accountHash, number), nil); err != nil {
+		log.Crit("Failed to store state diff account index", "err", err)
+	}
+}
+
+// DeleteStateDiffAccountIndex removes the account index entry of the given block.
+func DeleteStateDiffAccountIndex(db ethdb.KeyValueWriter, accountHash common.Hash, number uint64) {
+	if err := db.Delete(stateDiffAccountIndexKey(accountHash, number)); err != nil {
+		log.Crit("Failed to delete state diff account index", "err", err)
+	}
+}
+
+// ReadStateDiffStorageIndex retrieves the number of the first block not before
+// the given one, whose frozen state reverse diff contains the given storage slot.
+func ReadStateDiffStorageIndex(db ethdb.Iteratee, accountHash, storageHash common.Hash, from uint64) (uint64, bool) {
+	prefix := append(append(stateDiffStorageIndexPrefix, accountHash.Bytes()...), storageHash.Bytes()...)
+	return readStateDiffIndex(db, prefix, from)
+}
+
+// WriteStateDiffStorageIndex marks the storage slot as contained in the frozen
+// state reverse diff of the given block.
+func WriteStateDiffStorageIndex(db ethdb.KeyValueWriter, accountHash, storageHash common.Hash, number uint64) {
+	if err := db.Put(stateDiffStorageIndexKey(accountHash, storageHash, number), nil); err != nil {
+		log.Crit("Failed to store state diff storage index", "err", err)
+	}
+}
+
+// DeleteStateDiffStorageIndex removes the storage index entry of the given block.
+func DeleteStateDiffStorageIndex(db ethdb.KeyValueWriter, accountHash, storageHash common.Hash, number uint64) {
+	if err := db.Delete(stateDiffStorageIndexKey(accountHash, storageHash, number)); err != nil {
+		log.Crit("Failed to delete state diff storage index", "err", err)
+	}
+}
+
+// readStateDiffIndex retrieves the first block number not before the given one
+// from the state diff index entries having the given prefix.
+func readStateDiffIndex(db ethdb.Iteratee, prefix []byte, from uint64) (uint64, bool) {
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	for it.Next() {
+		if key := it.Key(); len(key) == len(prefix)+8 {
+			return binary.BigEndian.Uint64(key[len(prefix):]), true
+		}
+	}
+	return 0, false
+}
@@ -44,9 +44,30 @@ var chainFreezerNoSnappy = map[string]bool{
 	ChainFreezerDifficultyTable: true,
 }
 
+// chainFreezerPrunable configures which ancient-tables may be tail-truncated by
+// the chain history pruning. Headers, hashes and difficulties are retained for
+// the chain to remain verifiable.
+var chainFreezerPrunable = map[string]bool{
+	ChainFreezerBodiesTable:  true,
+	ChainFreezerReceiptTable: true,
+}
+
+// The list of table names of state diff freezer.
+const (
+	// StateDiffFreezerTable indicates the name of the freezer state reverse diff table.
+	StateDiffFreezerTable = "diffs"
+)
+
+// stateDiffFreezerNoSnappy configures whether compression is disabled for the
+// state diff tables.
+var stateDiffFreezerNoSnappy = map[string]bool{
+	StateDiffFreezerTable: false,
+}
+
 // The list of identifiers of ancient stores.
 var (
-	chainFreezerName = "chain" // the folder name of chain segment ancient store.
+	chainFreezerName     = "chain"     // the folder name of chain segment ancient store.
+	stateDiffFreezerName = "statediff" // the folder name of state reverse diff ancient store.
 )
 
 // freezers the collections of all builtin freezers.
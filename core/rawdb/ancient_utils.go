@@ -17,10 +17,17 @@
 package rawdb
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 )
 
 type tableSize struct {
@@ -91,16 +98,23 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 
 // InspectFreezerTable dumps out the index of a specific freezer table. The passed
 // ancient indicates the path of root ancient directory where the chain freezer can
-// be opened. Start and end specify the range for dumping out indexes.
+// be opened, superseded by the layout stored in the key-value store if the ancient
+// store was relocated or tiered. Start and end specify the range for dumping out
+// indexes.
 // Note this function can only be used for debugging purposes.
-func InspectFreezerTable(ancient string, freezerName string, tableName string, start, end int64) error {
+func InspectFreezerTable(db ethdb.KeyValueReader, ancient string, freezerName string, tableName string, start, end int64) error {
 	var (
-		path   string
-		tables map[string]bool
+		path     string
+		coldPath string
+		tables   map[string]bool
 	)
 	switch freezerName {
 	case chainFreezerName:
-		path, tables = resolveChainFreezerDir(ancient), chainFreezerNoSnappy
+		root, tier := resolveFreezerLayout(db, ancient)
+		path, tables = resolveChainFreezerDir(root), chainFreezerNoSnappy
+		if tier != nil {
+			coldPath = tier.path
+		}
 	default:
 		return fmt.Errorf("unknown freezer, supported ones: %v", freezers)
 	}
@@ -112,10 +126,255 @@ func InspectFreezerTable(ancient string, freezerName string, tableName string, s
 		}
 		return fmt.Errorf("unknown table, supported ones: %v", names)
 	}
-	table, err := newFreezerTable(path, tableName, noSnappy, true)
+	table, err := newTieredTable(path, coldPath, tableName, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, noSnappy, true)
 	if err != nil {
 		return err
 	}
+	defer table.Close()
+
 	table.dumpIndexStdout(start, end)
 	return nil
 }
+
+// RecompressFreezerTable rewrites the items of a compressed chain freezer table
+// with the given codec. The passed ancient indicates the path of root ancient
+// directory, superseded by the layout stored in the key-value store if the
+// ancient store was relocated or tiered. The freezer must not be in use by a
+// running node.
+func RecompressFreezerTable(db ethdb.KeyValueReader, ancient string, tableName string, codec FreezerCodec) error {
+	noSnappy, exist := chainFreezerNoSnappy[tableName]
+	if !exist {
+		var names []string
+		for name := range chainFreezerNoSnappy {
+			names = append(names, name)
+		}
+		return fmt.Errorf("unknown table, supported ones: %v", names)
+	}
+	if noSnappy {
+		return fmt.Errorf("table %s is not compressed", tableName)
+	}
+	root, tier := resolveFreezerLayout(db, ancient)
+	path := resolveChainFreezerDir(root)
+
+	// Opening the freezer writable creates any missing table, refuse to do so
+	// instead of recompressing an empty one.
+	if !common.FileExist(filepath.Join(path, tableName+".cidx")) {
+		return fmt.Errorf("no chain freezer table %s in %s", tableName, path)
+	}
+	freezer, err := newFreezer(path, "", false, freezerTableSize, chainFreezerNoSnappy, chainFreezerPrunable, tier)
+	if err != nil {
+		return err
+	}
+	defer freezer.Close()
+
+	return freezer.RecompressTable(tableName, codec)
+}
+
+// FreezerIndexError reports an invalid index entry in a chain freezer table.
+type FreezerIndexError struct {
+	Table  string // Name of the freezer table
+	Number uint64 // Number of the first item with an invalid index entry
+	Err    error  // Reason of the index entry being invalid
+}
+
+func (e *FreezerIndexError) Error() string {
+	return fmt.Sprintf("table %s item %d: %v", e.Table, e.Number, e.Err)
+}
+
+// CheckFreezerIndex verifies that the index files of the chain freezer tables
+// are consistent with their data files, reporting the first invalid index entry
+// of every table. The tables are opened separately in read-only mode, so the
+// database should not be written to in the meantime. Tables failing to open or
+// to be read are reported as an error, as no invalid entry can be pinpointed.
+func CheckFreezerIndex(db ethdb.Database) ([]*FreezerIndexError, error) {
+	ancient, err := db.AncientDatadir()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		root, tier = resolveFreezerLayout(db, ancient)
+		path       = resolveChainFreezerDir(root)
+		coldPath   string
+		issues     []*FreezerIndexError
+	)
+	if tier != nil {
+		coldPath = tier.path
+	}
+	for name, noSnappy := range chainFreezerNoSnappy {
+		table, err := newTieredTable(path, coldPath, name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, noSnappy, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open table %s: %w", name, err)
+		}
+		number, reason, err := table.checkIndex()
+		table.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check table %s: %w", name, err)
+		}
+		if reason != nil {
+			issues = append(issues, &FreezerIndexError{Table: name, Number: number, Err: reason})
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Table < issues[j].Table })
+	return issues, nil
+}
+
+// openTieredChainFreezer opens the chain freezer in the given ancient directory
+// in read-only mode, reading the data files of the cold tier if any.
+func openTieredChainFreezer(ancient string, layout *FreezerLayout) (*Freezer, error) {
+	var tier *freezerTier
+	if layout != nil && layout.ColdDirectory != "" {
+		tier = &freezerTier{path: filepath.Join(layout.ColdDirectory, chainFreezerName), hotFiles: layout.HotFiles}
+	}
+	return newFreezer(resolveChainFreezerDir(ancient), "", true, freezerTableSize, chainFreezerNoSnappy, chainFreezerPrunable, tier)
+}
+
+// summarizeChainFreezer returns a description of the content of a chain freezer,
+// used to verify that a copy of it is complete.
+func summarizeChainFreezer(ancient string, layout *FreezerLayout) (string, error) {
+	freezer, err := openTieredChainFreezer(ancient, layout)
+	if err != nil {
+		return "", err
+	}
+	defer freezer.Close()
+
+	head, err := freezer.Ancients()
+	if err != nil {
+		return "", err
+	}
+	tail, err := freezer.Tail()
+	if err != nil {
+		return "", err
+	}
+	summary := fmt.Sprintf("items %d-%d", tail, head)
+	if head > tail {
+		hash, err := freezer.Ancient(ChainFreezerHashTable, head-1)
+		if err != nil {
+			return "", err
+		}
+		summary += fmt.Sprintf(", head %x", hash)
+	}
+	names := make([]string, 0, len(chainFreezerNoSnappy))
+	for name := range chainFreezerNoSnappy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		size, err := freezer.AncientSize(name)
+		if err != nil {
+			return "", err
+		}
+		summary += fmt.Sprintf(", %s %d", name, size)
+	}
+	return summary, nil
+}
+
+// MigrateAncientDirectory relocates the ancient store from one root directory
+// into another one, which must not hold any data yet. The copy is verified
+// before the new location is recorded in the key-value store, superseding the
+// configured ancient directory from then on, and the old one is deleted.
+//
+// The database must not be in use by a running node. If the relocation is
+// interrupted, the ancient store remains in its old location.
+func MigrateAncientDirectory(db ethdb.KeyValueStore, from, to string) error {
+	from, err := filepath.Abs(from)
+	if err != nil {
+		return err
+	}
+	if to, err = filepath.Abs(to); err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(from, to); err == nil && (rel == "." || !strings.HasPrefix(rel, "..")) {
+		return fmt.Errorf("target %s is inside the ancient directory %s", to, from)
+	}
+	if entries, err := os.ReadDir(to); err == nil && len(entries) > 0 {
+		return fmt.Errorf("target directory %s is not empty", to)
+	}
+	layout := ReadFreezerLayout(db)
+	want, err := summarizeChainFreezer(from, layout)
+	if err != nil {
+		return err
+	}
+	log.Info("Copying ancient store", "from", from, "to", to)
+	if err := copyDir(from, to); err != nil {
+		return err
+	}
+	have, err := summarizeChainFreezer(to, layout)
+	if err != nil {
+		return fmt.Errorf("failed to verify copy: %v", err)
+	}
+	if have != want {
+		return fmt.Errorf("copy mismatch: have %s, want %s", have, want)
+	}
+	if layout == nil {
+		layout = new(FreezerLayout)
+	}
+	layout.Directory = to
+	WriteFreezerLayout(db, layout)
+
+	log.Info("Removing old ancient store", "path", from)
+	return os.RemoveAll(from)
+}
+
+// ConfigureAncientTier splits the ancient store in the given root directory into
+// a hot and a cold tier. The given number of most recent data files of every
+// table stays in place, the older ones are moved into the cold directory by the
+// node while running and read from there transparently.
+//
+// If the cold directory is empty, tiering is disabled instead and the data files
+// are moved back out of the cold tier. The database must not be in use by a
+// running node.
+func ConfigureAncientTier(db ethdb.KeyValueStore, ancient string, cold string, hotFiles uint32) error {
+	layout := ReadFreezerLayout(db)
+	if layout == nil {
+		layout = new(FreezerLayout)
+	}
+	if cold != "" {
+		cold, err := filepath.Abs(cold)
+		if err != nil {
+			return err
+		}
+		if layout.ColdDirectory != "" && layout.ColdDirectory != cold {
+			return fmt.Errorf("ancient store already tiered into %s", layout.ColdDirectory)
+		}
+		if err := os.MkdirAll(filepath.Join(cold, chainFreezerName), 0755); err != nil {
+			return err
+		}
+		layout.ColdDirectory, layout.HotFiles = cold, hotFiles
+		WriteFreezerLayout(db, layout)
+		return nil
+	}
+	if layout.ColdDirectory == "" {
+		return errors.New("ancient store is not tiered")
+	}
+	var (
+		hot   = resolveChainFreezerDir(ancient)
+		path  = filepath.Join(layout.ColdDirectory, chainFreezerName)
+		moved int
+	)
+	files, err := os.ReadDir(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		src, dst := filepath.Join(path, file.Name()), filepath.Join(hot, file.Name())
+
+		// A file still present in the hot tier supersedes the cold copy
+		if !common.FileExist(dst) {
+			if err := copyFile(src, dst); err != nil {
+				return err
+			}
+			moved++
+		}
+		if err := os.Remove(src); err != nil {
+			return err
+		}
+	}
+	log.Info("Moved ancient data files out of cold tier", "files", moved, "path", path)
+
+	layout.ColdDirectory, layout.HotFiles = "", 0
+	WriteFreezerLayout(db, layout)
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// checkAncientBlocks verifies that the ancient store of the database holds the
+// headers of the given blocks.
+func checkAncientBlocks(t *testing.T, db ethdb.AncientReader, blocks []*types.Block) {
+	t.Helper()
+
+	for _, block := range blocks {
+		hash, err := db.Ancient(ChainFreezerHashTable, block.NumberU64())
+		if err != nil || common.BytesToHash(hash) != block.Hash() {
+			t.Fatalf("block %d: hash mismatch: have %x, want %x (%v)", block.NumberU64(), hash, block.Hash(), err)
+		}
+		blob, err := db.Ancient(ChainFreezerHeaderTable, block.NumberU64())
+		if err != nil || crypto.Keccak256Hash(blob) != block.Hash() {
+			t.Fatalf("block %d: header mismatch (%v)", block.NumberU64(), err)
+		}
+	}
+}
+
+func TestMigrateAncientDirectory(t *testing.T) {
+	var (
+		chaindata = t.TempDir()
+		from      = filepath.Join(chaindata, "ancient")
+		to        = filepath.Join(t.TempDir(), "moved")
+		blocks    = makeBackupChain(32, "a")
+	)
+	db, err := Open(OpenOptions{Directory: chaindata, AncientsDirectory: from})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	if _, err := WriteAncientBlocks(db, blocks, make([]types.Receipts, len(blocks)), big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	db.Close()
+
+	kvdb, err := Open(OpenOptions{Directory: chaindata})
+	if err != nil {
+		t.Fatalf("failed to open key-value store: %v", err)
+	}
+	if err := MigrateAncientDirectory(kvdb, from, filepath.Join(from, "nested")); err == nil {
+		t.Fatal("migrated ancient store into itself")
+	}
+	if err := MigrateAncientDirectory(kvdb, from, to); err != nil {
+		t.Fatalf("failed to migrate ancient store: %v", err)
+	}
+	kvdb.Close()
+
+	if common.FileExist(from) {
+		t.Fatal("old ancient directory retained")
+	}
+	// The recorded location must supersede the configured one
+	db, err = Open(OpenOptions{Directory: chaindata, AncientsDirectory: from, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("failed to open migrated database: %v", err)
+	}
+	defer db.Close()
+
+	if dir, _ := db.AncientDatadir(); dir != to {
+		t.Fatalf("ancient directory mismatch: have %s, want %s", dir, to)
+	}
+	checkAncientBlocks(t, db, blocks)
+}
+
+func TestAncientTier(t *testing.T) {
+	var (
+		ancient = t.TempDir()
+		cold    = t.TempDir()
+		blocks  = makeBackupChain(32, "a")
+		kvdb    = NewMemoryDatabase()
+	)
+	if err := ConfigureAncientTier(kvdb, ancient, cold, 2); err != nil {
+		t.Fatalf("failed to configure tiering: %v", err)
+	}
+	layout := ReadFreezerLayout(kvdb)
+	if layout == nil || layout.ColdDirectory != cold || layout.HotFiles != 2 {
+		t.Fatalf("layout mismatch: %+v", layout)
+	}
+	// Fill a chain freezer with tiny data files and move the old ones away
+	tier := &freezerTier{path: filepath.Join(cold, chainFreezerName), hotFiles: layout.HotFiles}
+	freezer, err := newFreezer(resolveChainFreezerDir(ancient), "", false, 256, chainFreezerNoSnappy, chainFreezerPrunable, tier)
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	if _, err := WriteAncientBlocks(freezer, blocks, make([]types.Receipts, len(blocks)), big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	if err := freezer.moveToColdTier(make(chan struct{})); err != nil {
+		t.Fatalf("failed to move files to cold tier: %v", err)
+	}
+	moved, _ := filepath.Glob(filepath.Join(tier.path, "*.rdat"))
+	if len(moved) == 0 {
+		t.Fatal("no files moved to cold tier")
+	}
+	checkAncientBlocks(t, freezer, blocks)
+	freezer.Close()
+
+	// Disable tiering and read everything back from the hot tier only
+	if err := ConfigureAncientTier(kvdb, ancient, "", 0); err != nil {
+		t.Fatalf("failed to disable tiering: %v", err)
+	}
+	if layout := ReadFreezerLayout(kvdb); layout.ColdDirectory != "" {
+		t.Fatalf("tiering not disabled: %+v", layout)
+	}
+	if files, _ := os.ReadDir(tier.path); len(files) != 0 {
+		t.Fatalf("%d files left in cold tier", len(files))
+	}
+	freezer, err = newFreezer(resolveChainFreezerDir(ancient), "", true, 256, chainFreezerNoSnappy, chainFreezerPrunable, nil)
+	if err != nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	defer freezer.Close()
+	checkAncientBlocks(t, freezer, blocks)
+}
+
+func TestFreezerToolsTiered(t *testing.T) {
+	var (
+		ancient = t.TempDir()
+		cold    = t.TempDir()
+		stale   = filepath.Join(t.TempDir(), "stale")
+		blocks  = makeBackupChain(32, "a")
+		kvdb    = NewMemoryDatabase()
+	)
+	// Relocate and tier the ancient store, configuring a stale directory
+	WriteFreezerLayout(kvdb, &FreezerLayout{Directory: ancient, ColdDirectory: cold, HotFiles: 2})
+
+	tier := &freezerTier{path: filepath.Join(cold, chainFreezerName), hotFiles: 2}
+	freezer, err := newFreezer(resolveChainFreezerDir(ancient), "", false, 256, chainFreezerNoSnappy, chainFreezerPrunable, tier)
+	if err != nil {
+		t.Fatalf("failed to create freezer: %v", err)
+	}
+	if _, err := WriteAncientBlocks(freezer, blocks, make([]types.Receipts, len(blocks)), big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	if err := freezer.moveToColdTier(make(chan struct{})); err != nil {
+		t.Fatalf("failed to move files to cold tier: %v", err)
+	}
+	freezer.Close()
+
+	if err := InspectFreezerTable(kvdb, stale, chainFreezerName, ChainFreezerHeaderTable, 0, 1); err != nil {
+		t.Fatalf("failed to inspect table: %v", err)
+	}
+	if err := RecompressFreezerTable(kvdb, stale, ChainFreezerHeaderTable, FreezerCodecZstd); err != nil {
+		t.Fatalf("failed to recompress table: %v", err)
+	}
+	if common.FileExist(stale) {
+		t.Fatal("freezer created in stale ancient directory")
+	}
+	// Without the layout, the stale directory must not be mistaken for an empty freezer
+	if err := RecompressFreezerTable(NewMemoryDatabase(), stale, ChainFreezerHeaderTable, FreezerCodecZstd); err == nil {
+		t.Fatal("recompressed table of missing freezer")
+	}
+	db, err := NewDatabaseWithFreezer(kvdb, stale, "", true)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	issues, err := CheckFreezerIndex(db)
+	if err != nil {
+		t.Fatalf("failed to check index: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("unexpected index issues: %v", issues)
+	}
+	checkAncientBlocks(t, db, blocks)
+}
@@ -0,0 +1,579 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	backupManifestName  = "BACKUP"    // Name of the manifest file, written last
+	backupChaindataName = "chaindata" // Folder of the key-value store copy
+	backupAncientName   = "ancient"   // Folder of the ancient store copy
+)
+
+// BackupManifest describes a point-in-time backup of a database, consisting of
+// a copy of the key-value store and of the chain freezer up to a consistent
+// number of items.
+type BackupManifest struct {
+	Frozen  uint64                  `json:"frozen"`  // Number of items in the backed up chain freezer
+	Tail    uint64                  `json:"tail"`    // Number of the first item in the backed up chain freezer
+	Hash    common.Hash             `json:"hash"`    // Hash of the last frozen block, zero if nothing frozen
+	Codecs  map[string]FreezerCodec `json:"codecs"`  // Codecs of the backed up freezer tables
+	Keys    uint64                  `json:"keys"`    // Number of entries in the backed up key-value store
+	Copied  uint64                  `json:"copied"`  // Number of freezer bytes copied by the backup run
+	Linked  uint64                  `json:"linked"`  // Number of freezer files hard-linked by the backup run
+	Created uint64                  `json:"created"` // Unix timestamp of the backup
+}
+
+// ReadBackupManifest retrieves the manifest of the backup in the given
+// directory. An error is returned if the directory holds no complete backup.
+func ReadBackupManifest(dir string) (*BackupManifest, error) {
+	blob, err := os.ReadFile(filepath.Join(dir, backupManifestName))
+	if err != nil {
+		return nil, err
+	}
+	manifest := new(BackupManifest)
+	if err := json.Unmarshal(blob, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// pendingCopy is a freezer data file whose content is copied after the freezer
+// is released. The source is opened while the freezer is locked, so that the
+// content is retained even if the file is deleted by a tail truncation.
+type pendingCopy struct {
+	src      *os.File
+	dest     string
+	from, to int64
+}
+
+// Backup creates a consistent point-in-time copy of the database in the given
+// directory while the database is in use.
+//
+// The key-value store is snapshotted and the item count of the chain freezer is
+// fixed while the freezer is locked for writes. The freezer index and metadata
+// files are copied under the lock, whereas the append-only data files are only
+// opened and copied afterwards. Sealed data files are hard-linked if the backup
+// resides on the same file system.
+//
+// If the directory already holds a backup of the same chain, the backup is
+// updated incrementally: only freezer data appended since is copied. Tables
+// rewritten with a different codec since are copied in full, as are tables of
+// backups not recording their codecs. The key-value store is always copied in
+// full.
+//
+// The state diff freezer is not backed up, a restored node restarts its state
+// diff history from scratch.
+func Backup(db ethdb.Database, dir string) (*BackupManifest, error) {
+	root, err := db.AncientDatadir()
+	if err != nil {
+		return nil, err
+	}
+	source := resolveChainFreezerDir(root)
+	if !common.FileExist(source) {
+		return nil, errors.New("chain freezer not found")
+	}
+	var cold string
+	if layout := ReadFreezerLayout(db); layout != nil && layout.ColdDirectory != "" {
+		cold = filepath.Join(layout.ColdDirectory, chainFreezerName)
+	}
+	prev, _ := ReadBackupManifest(dir)
+
+	// Remove the manifest first, so that an interrupted backup is never mistaken
+	// for a complete one
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(filepath.Join(dir, backupManifestName)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var (
+		manifest = &BackupManifest{Codecs: make(map[string]FreezerCodec), Created: uint64(time.Now().Unix())}
+		dest     = filepath.Join(dir, backupAncientName, chainFreezerName)
+		snap     ethdb.Snapshot
+		pending  []*pendingCopy
+	)
+	err = db.ReadAncients(func(op ethdb.AncientReaderOp) error {
+		// Take the key-value snapshot while no item can be frozen, the data of
+		// any block is either in the snapshot, in the freezer or in both.
+		var err error
+		if snap, err = db.NewSnapshot(); err != nil {
+			return err
+		}
+		if manifest.Frozen, err = op.Ancients(); err != nil {
+			return err
+		}
+		if manifest.Tail, err = op.Tail(); err != nil {
+			return err
+		}
+		if manifest.Frozen > 0 {
+			blob, err := op.Ancient(ChainFreezerHashTable, manifest.Frozen-1)
+			if err != nil {
+				return err
+			}
+			manifest.Hash = common.BytesToHash(blob)
+		}
+		// Discard the previous freezer copy if the chain was rewound since
+		if prev != nil && prev.Frozen > 0 {
+			var hash common.Hash
+			if prev.Frozen <= manifest.Frozen {
+				if blob, err := op.Ancient(ChainFreezerHashTable, prev.Frozen-1); err == nil {
+					hash = common.BytesToHash(blob)
+				}
+			}
+			if hash != prev.Hash {
+				log.Info("Discarding stale freezer backup", "frozen", prev.Frozen, "hash", prev.Hash)
+				if err := os.RemoveAll(dest); err != nil {
+					return err
+				}
+			}
+		}
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			return err
+		}
+		for name, noSnappy := range chainFreezerNoSnappy {
+			codec, err := readFreezerTableCodec(source, name)
+			if err != nil {
+				return err
+			}
+			manifest.Codecs[name] = codec
+
+			// A recompressed table is rewritten in place, so none of its files
+			// can be reused by an incremental backup
+			if prev != nil {
+				if recorded, ok := prev.Codecs[name]; !ok || recorded != codec {
+					log.Info("Recopying rewritten freezer table", "table", name, "codec", codec)
+					if err := removeFreezerTableFiles(dest, name); err != nil {
+						return err
+					}
+				}
+			}
+			files, err := backupFreezerTable(source, cold, dest, name, noSnappy, manifest.Frozen, manifest)
+			pending = append(pending, files...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	defer func() {
+		for _, file := range pending {
+			file.src.Close()
+		}
+		if snap != nil {
+			snap.Release()
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+	// Freezer released, copy the sealed data files and the key-value store
+	for _, file := range pending {
+		if err := copyFileRange(file.src, file.dest, file.from, file.to); err != nil {
+			return nil, err
+		}
+		manifest.Copied += uint64(file.to - file.from)
+	}
+	if manifest.Keys, err = backupKeyValueStore(snap, filepath.Join(dir, backupChaindataName)); err != nil {
+		return nil, err
+	}
+	// Mark the backup complete
+	blob, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, backupManifestName+".tmp"), blob, 0644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(filepath.Join(dir, backupManifestName+".tmp"), filepath.Join(dir, backupManifestName)); err != nil {
+		return nil, err
+	}
+	log.Info("Backed up database", "dir", dir, "frozen", manifest.Frozen, "keys", manifest.Keys, "copied", common.StorageSize(manifest.Copied), "linked", manifest.Linked)
+	return manifest, nil
+}
+
+// backupFreezerTable copies the index and metadata files of a freezer table
+// covering the given number of items, along with the data file holding the last
+// item. The sealed data files are hard-linked if possible, or returned to be
+// copied once the freezer is released. Data files missing from the source are
+// looked up in the cold tier, if any. The caller must hold the freezer lock.
+func backupFreezerTable(source, cold, dest, name string, noSnappy bool, items uint64, manifest *BackupManifest) ([]*pendingCopy, error) {
+	idxName, datExt := fmt.Sprintf("%s.cidx", name), "cdat"
+	if noSnappy {
+		idxName, datExt = fmt.Sprintf("%s.ridx", name), "rdat"
+	}
+	index, err := os.Open(filepath.Join(source, idxName))
+	if err != nil {
+		return nil, err
+	}
+	defer index.Close()
+
+	// Resolve the data range of the table from the index
+	buffer := make([]byte, indexEntrySize)
+	if _, err := index.ReadAt(buffer, 0); err != nil {
+		return nil, err
+	}
+	var tail, head indexEntry
+	tail.unmarshalBinary(buffer)
+	if items < uint64(tail.offset) {
+		return nil, fmt.Errorf("table %s: tail %d above item count %d", name, tail.offset, items)
+	}
+	length := items - uint64(tail.offset)
+	if length == 0 {
+		head = indexEntry{filenum: tail.filenum, offset: 0}
+	} else {
+		if _, err := index.ReadAt(buffer, int64(length*indexEntrySize)); err != nil {
+			return nil, err
+		}
+		head.unmarshalBinary(buffer)
+	}
+	// Copy the index incrementally, unless the tail of the table moved
+	idxPath := filepath.Join(dest, idxName)
+	size := int64(length+1) * indexEntrySize
+	from, _ := backupFileSize(idxPath)
+	if from > 0 {
+		existing := make([]byte, indexEntrySize)
+		if f, err := os.Open(idxPath); err != nil {
+			from = 0
+		} else {
+			if _, err := f.ReadAt(existing, 0); err != nil || string(existing) != string(tail.append(nil)) {
+				from = 0
+			}
+			f.Close()
+		}
+	}
+	if from > size {
+		from = 0
+	}
+	if err := copyFileRange(index, idxPath, from, size); err != nil {
+		return nil, err
+	}
+	manifest.Copied += uint64(size - from)
+
+	// Copy the metadata file holding the virtual tail
+	if err := copyFile(filepath.Join(source, fmt.Sprintf("%s.meta", name)), filepath.Join(dest, fmt.Sprintf("%s.meta", name))); err != nil {
+		return nil, err
+	}
+	// Drop any data file not covered by the index anymore
+	files, err := filepath.Glob(filepath.Join(dest, fmt.Sprintf("%s.*.%s", name, datExt)))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		num, err := strconv.ParseUint(strings.Split(filepath.Base(file), ".")[1], 10, 32)
+		if err == nil && (uint32(num) < tail.filenum || uint32(num) > head.filenum) {
+			os.Remove(file)
+		}
+	}
+	// Link, copy or schedule the copying of the data files
+	var pending []*pendingCopy
+	for num := tail.filenum; num <= head.filenum; num++ {
+		var (
+			datName = fmt.Sprintf("%s.%04d.%s", name, num, datExt)
+			src     = filepath.Join(source, datName)
+			dst     = filepath.Join(dest, datName)
+			size    = int64(head.offset)
+		)
+		if cold != "" && !common.FileExist(src) {
+			src = filepath.Join(cold, datName)
+		}
+		if num < head.filenum {
+			stat, err := os.Stat(src)
+			if err != nil {
+				return pending, err
+			}
+			size = stat.Size()
+		}
+		from, _ := backupFileSize(dst)
+		if from == size {
+			continue
+		}
+		if from > size {
+			os.Remove(dst)
+			from = 0
+		}
+		if from == 0 && num < head.filenum {
+			if err := os.Link(src, dst); err == nil {
+				manifest.Linked++
+				continue
+			}
+		}
+		f, err := os.Open(src)
+		if err != nil {
+			return pending, err
+		}
+		if num < head.filenum {
+			pending = append(pending, &pendingCopy{src: f, dest: dst, from: from, to: size})
+			continue
+		}
+		// The head file may still be appended to, copy it under the lock
+		err = copyFileRange(f, dst, from, size)
+		f.Close()
+		if err != nil {
+			return pending, err
+		}
+		manifest.Copied += uint64(size - from)
+	}
+	return pending, nil
+}
+
+// readFreezerTableCodec reads the codec of a freezer table from its metadata file.
+func readFreezerTableCodec(dir, name string) (FreezerCodec, error) {
+	f, err := os.Open(filepath.Join(dir, fmt.Sprintf("%s.meta", name)))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	meta, err := readMetadata(f)
+	if err != nil {
+		return 0, err
+	}
+	return FreezerCodec(meta.Codec), nil
+}
+
+// removeFreezerTableFiles deletes the index, metadata and data files of a freezer
+// table from the given directory.
+func removeFreezerTableFiles(dir, name string) error {
+	files, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf("%s.*", name)))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupKeyValueStore writes the content of a key-value store snapshot into a
+// fresh database at the given path, replacing any previous copy.
+func backupKeyValueStore(snap ethdb.Snapshot, path string) (uint64, error) {
+	tmp := path + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return 0, err
+	}
+	db, err := openKeyValueDatabase(OpenOptions{Directory: tmp, Cache: 16, Handles: 16})
+	if err != nil {
+		return 0, err
+	}
+	var (
+		it    = snap.NewIterator(nil, nil)
+		batch = db.NewBatch()
+		keys  uint64
+	)
+	defer it.Release()
+
+	for it.Next() {
+		// The restored database lives wherever it is restored to
+		if bytes.Equal(it.Key(), freezerLayoutKey) {
+			continue
+		}
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			db.Close()
+			return 0, err
+		}
+		keys++
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				db.Close()
+				return 0, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		db.Close()
+		return 0, err
+	}
+	if err := batch.Write(); err != nil {
+		db.Close()
+		return 0, err
+	}
+	if err := db.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return 0, err
+	}
+	return keys, os.Rename(tmp, path)
+}
+
+// Restore copies the backup in the given directory into the key-value store and
+// ancient store directories of a node, which must not hold a database yet. The
+// restored database is opened once to verify that it matches the backup.
+//
+// As the state diff freezer is not part of the backup, the state diff index and
+// offset contained in the key-value store are dropped.
+func Restore(dir string, chaindata string, ancient string) (*BackupManifest, error) {
+	manifest, err := ReadBackupManifest(dir)
+	if err != nil {
+		return nil, fmt.Errorf("no complete backup found: %w", err)
+	}
+	if hasPreexistingDb(chaindata) != "" {
+		return nil, fmt.Errorf("database already exists at %s", chaindata)
+	}
+	if common.FileExist(filepath.Join(ancient, chainFreezerName)) || common.FileExist(filepath.Join(ancient, stateDiffFreezerName)) {
+		return nil, fmt.Errorf("ancient store already exists at %s", ancient)
+	}
+	if err := copyDir(filepath.Join(dir, backupChaindataName), chaindata); err != nil {
+		return nil, err
+	}
+	if err := copyDir(filepath.Join(dir, backupAncientName), ancient); err != nil {
+		return nil, err
+	}
+	db, err := Open(OpenOptions{Directory: chaindata, AncientsDirectory: ancient, Cache: 16, Handles: 16})
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if frozen, err := db.Ancients(); err != nil {
+		return nil, err
+	} else if frozen != manifest.Frozen {
+		return nil, fmt.Errorf("restored ancient store mismatch: have %d items, want %d", frozen, manifest.Frozen)
+	}
+	if manifest.Frozen > 0 {
+		if hash := ReadCanonicalHash(db, manifest.Frozen-1); hash != manifest.Hash {
+			return nil, fmt.Errorf("restored ancient store mismatch: have hash %x, want %x", hash, manifest.Hash)
+		}
+		// Make sure the last item of every table can be decoded
+		for name := range chainFreezerNoSnappy {
+			if _, err := db.Ancient(name, manifest.Frozen-1); err != nil {
+				return nil, fmt.Errorf("restored ancient store mismatch: table %s: %w", name, err)
+			}
+		}
+	}
+	if err := deleteStateDiffIndex(db); err != nil {
+		return nil, err
+	}
+	log.Info("Restored database", "dir", dir, "frozen", manifest.Frozen, "keys", manifest.Keys)
+	return manifest, nil
+}
+
+// deleteStateDiffIndex removes the index and the offset of the state diff
+// freezer from the key-value store.
+func deleteStateDiffIndex(db ethdb.KeyValueStore) error {
+	batch := db.NewBatch()
+	for _, prefix := range [][]byte{stateDiffAccountIndexPrefix, stateDiffStorageIndexPrefix} {
+		it := db.NewIterator(prefix, nil)
+		for it.Next() {
+			if err := batch.Delete(it.Key()); err != nil {
+				it.Release()
+				return err
+			}
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	if err := batch.Delete(stateDiffOffsetKey); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// backupFileSize returns the size of the given file, or zero if it doesn't exist.
+func backupFileSize(path string) (int64, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return stat.Size(), nil
+}
+
+// copyFileRange copies the content of src in [from, to) into the file at dest,
+// truncating it to the start offset first.
+func copyFileRange(src *os.File, dest string, from, to int64) error {
+	f, err := os.OpenFile(dest, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(from); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(from, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := io.Copy(f, io.NewSectionReader(src, from, to-from)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// copyFile copies the entire content of src into dest.
+func copyFile(src, dest string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return copyFileRange(f, dest, 0, stat.Size())
+}
+
+// copyDir recursively copies the files of the src directory into dest.
+func copyDir(src, dest string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dest, rel), 0755)
+		}
+		return copyFile(path, filepath.Join(dest, rel))
+	})
+}
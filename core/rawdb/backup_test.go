@@ -0,0 +1,193 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+package rawdb
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// makeBackupChain creates a chain of empty blocks with the given extra data.
+func makeBackupChain(n int, extra string) []*types.Block {
+	blocks := make([]*types.Block, n)
+	for i := range blocks {
+		header := &types.Header{Number: big.NewInt(int64(i)), Extra: []byte(extra)}
+		if i > 0 {
+			header.ParentHash = blocks[i-1].Hash()
+		}
+		blocks[i] = types.NewBlockWithHeader(header)
+	}
+	return blocks
+}
+
+// checkRestore restores the backup into a fresh location and verifies that the
+// restored database holds the given blocks and key-value entries.
+func checkRestore(t *testing.T, dir string, blocks []*types.Block, entries map[string]string) {
+	t.Helper()
+
+	var (
+		datadir   = t.TempDir()
+		chaindata = filepath.Join(datadir, "chaindata")
+		ancient   = filepath.Join(chaindata, "ancient")
+	)
+	manifest, err := Restore(dir, chaindata, ancient)
+	if err != nil {
+		t.Fatalf("failed to restore backup: %v", err)
+	}
+	if manifest.Frozen != uint64(len(blocks)) {
+		t.Fatalf("restored item count mismatch: have %d, want %d", manifest.Frozen, len(blocks))
+	}
+	db, err := Open(OpenOptions{Directory: chaindata, AncientsDirectory: ancient, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("failed to open restored database: %v", err)
+	}
+	defer db.Close()
+
+	for _, block := range blocks {
+		if hash := ReadCanonicalHash(db, block.NumberU64()); hash != block.Hash() {
+			t.Fatalf("block %d: hash mismatch: have %x, want %x", block.NumberU64(), hash, block.Hash())
+		}
+		if header := ReadHeader(db, block.Hash(), block.NumberU64()); header == nil || !bytes.Equal(header.Extra, block.Extra()) {
+			t.Fatalf("block %d: header mismatch", block.NumberU64())
+		}
+	}
+	for key, val := range entries {
+		if have, _ := db.Get([]byte(key)); string(have) != val {
+			t.Fatalf("key %s: value mismatch: have %s, want %s", key, have, val)
+		}
+	}
+	// The state diff freezer is not backed up, neither must be its index
+	if offset := ReadStateDiffOffset(db); offset != 0 {
+		t.Fatalf("state diff offset retained: %d", offset)
+	}
+	if number, ok := ReadStateDiffAccountIndex(db, common.Hash{0x01}, 0); ok {
+		t.Fatalf("state diff account index retained: %d", number)
+	}
+	if number, ok := ReadStateDiffStorageIndex(db, common.Hash{0x01}, common.Hash{0x02}, 0); ok {
+		t.Fatalf("state diff storage index retained: %d", number)
+	}
+	if _, err := Restore(dir, chaindata, ancient); err == nil {
+		t.Fatalf("expected error restoring over an existing database")
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	var (
+		dir     = t.TempDir()
+		blocks  = makeBackupChain(64, "a")
+		entries = map[string]string{"key1": "val1", "key2": "val2"}
+	)
+	writeBackupData := func(db ethdb.Database, blocks []*types.Block, entries map[string]string) {
+		if _, err := WriteAncientBlocks(db, blocks, make([]types.Receipts, len(blocks)), big.NewInt(1)); err != nil {
+			t.Fatalf("failed to write ancient blocks: %v", err)
+		}
+		for key, val := range entries {
+			db.Put([]byte(key), []byte(val))
+		}
+	}
+	// Create a full backup
+	writeBackupData(db, blocks[:32], entries)
+	WriteStateDiffOffset(db, 10)
+	WriteStateDiffAccountIndex(db, common.Hash{0x01}, 12)
+	WriteStateDiffStorageIndex(db, common.Hash{0x01}, common.Hash{0x02}, 12)
+	full, err := Backup(db, dir)
+	if err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	if full.Frozen != 32 || full.Keys != 5 || full.Hash != blocks[31].Hash() {
+		t.Fatalf("backup manifest mismatch: %+v", full)
+	}
+	checkRestore(t, dir, blocks[:32], entries)
+
+	// Extend the database and create an incremental backup
+	entries["key3"] = "val3"
+	writeBackupData(db, blocks[32:], map[string]string{"key3": "val3"})
+	incr, err := Backup(db, dir)
+	if err != nil {
+		t.Fatalf("failed to create incremental backup: %v", err)
+	}
+	if incr.Frozen != 64 || incr.Keys != 6 {
+		t.Fatalf("backup manifest mismatch: %+v", incr)
+	}
+	if incr.Copied >= full.Copied*2 {
+		t.Fatalf("incremental backup copied too much: have %d, full %d", incr.Copied, full.Copied)
+	}
+	checkRestore(t, dir, blocks, entries)
+
+	// Rewind the ancient store onto a different chain, the stale backup must be
+	// replaced rather than extended
+	if err := db.TruncateHead(16); err != nil {
+		t.Fatalf("failed to truncate ancients: %v", err)
+	}
+	forked := append(append([]*types.Block{}, blocks[:16]...), makeBackupChain(80, "b")[16:]...)
+	writeBackupData(db, forked[16:], nil)
+	if _, err := Backup(db, dir); err != nil {
+		t.Fatalf("failed to create backup after rewind: %v", err)
+	}
+	checkRestore(t, dir, forked, entries)
+
+	// An interrupted backup must not be restorable
+	os.Remove(filepath.Join(dir, backupManifestName))
+	if _, err := Restore(dir, filepath.Join(t.TempDir(), "chaindata"), filepath.Join(t.TempDir(), "ancient")); err == nil {
+		t.Fatalf("expected error restoring incomplete backup")
+	}
+}
+
+func TestBackupRecompress(t *testing.T) {
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	var (
+		dir    = t.TempDir()
+		blocks = makeBackupChain(64, "a")
+	)
+	if _, err := WriteAncientBlocks(db, blocks, make([]types.Receipts, len(blocks)), big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	if _, err := Backup(db, dir); err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	// Rewrite the headers in place, the next backup must not reuse any of the
+	// previously backed up files of the table
+	freezer := db.(*freezerdb).AncientStore.(*chainFreezer).Freezer
+	if err := freezer.RecompressTable(ChainFreezerHeaderTable, FreezerCodecZstd); err != nil {
+		t.Fatalf("failed to recompress headers: %v", err)
+	}
+	manifest, err := Backup(db, dir)
+	if err != nil {
+		t.Fatalf("failed to create backup after recompression: %v", err)
+	}
+	if codec := manifest.Codecs[ChainFreezerHeaderTable]; codec != FreezerCodecZstd {
+		t.Fatalf("recorded codec mismatch: have %v, want %v", codec, FreezerCodecZstd)
+	}
+	checkRestore(t, dir, blocks, nil)
+}
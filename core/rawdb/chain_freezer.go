@@ -51,9 +51,10 @@ type chainFreezer struct {
 	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
 }
 
-// newChainFreezer initializes the freezer for ancient chain data.
-func newChainFreezer(datadir string, namespace string, readonly bool) (*chainFreezer, error) {
-	freezer, err := NewChainFreezer(datadir, namespace, readonly)
+// newChainFreezer initializes the freezer for ancient chain data, optionally
+// moving the older data files into a cold tier.
+func newChainFreezer(datadir string, namespace string, readonly bool, tier *freezerTier) (*chainFreezer, error) {
+	freezer, err := newFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy, chainFreezerPrunable, tier)
 	if err != nil {
 		return nil, err
 	}
@@ -98,6 +99,10 @@ func (f *chainFreezer) freeze(db ethdb.KeyValueStore) {
 			return
 		default:
 		}
+		// Relocate the older data files into the cold tier, if enabled
+		if err := f.moveToColdTier(f.quit); err != nil {
+			log.Error("Failed to move ancient data to cold tier", "err", err)
+		}
 		if backoff {
 			// If we were doing a manual trigger, notify it
 			if triggered != nil {
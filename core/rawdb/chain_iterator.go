@@ -17,6 +17,8 @@
 package rawdb
 
 import (
+	"errors"
+	"math/big"
 	"runtime"
 	"sync/atomic"
 	"time"
@@ -26,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -84,18 +87,27 @@ func InitDatabaseFromFreezer(db ethdb.Database) {
 }
 
 type blockTxHashes struct {
-	number uint64
-	hashes []common.Hash
+	number  uint64
+	hashes  []common.Hash
+	senders []txSenderNonce
+}
+
+// txSenderNonce identifies a transaction by its sender and nonce.
+type txSenderNonce struct {
+	sender common.Address
+	nonce  uint64
 }
 
 // iterateTransactions iterates over all transactions in the (canon) block
-// number(s) given, and yields the hashes on a channel. If there is a signal
-// received from interrupt channel, the iteration will be aborted and result
-// channel will be closed.
-func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool, interrupt chan struct{}) chan *blockTxHashes {
+// number(s) given, and yields the hashes on a channel, along with the senders
+// recovered with the signer of each block. If there is a signal received from
+// interrupt channel, the iteration will be aborted and result channel will be
+// closed.
+func iterateTransactions(db ethdb.Database, config *params.ChainConfig, from uint64, to uint64, reverse bool, interrupt chan struct{}) chan *blockTxHashes {
 	// One thread sequentially reads data from db
 	type numberRlp struct {
 		number uint64
+		time   uint64
 		rlp    rlp.RawValue
 	}
 	if to == from {
@@ -118,9 +130,15 @@ func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool
 		defer close(rlpCh)
 		for n != end {
 			data := ReadCanonicalBodyRLP(db, n)
+
+			// The timestamp is needed to pick the signer of the block
+			var time uint64
+			if header := ReadHeader(db, ReadCanonicalHash(db, n), n); header != nil {
+				time = header.Time
+			}
 			// Feed the block to the aggregator, or abort on interrupt
 			select {
-			case rlpCh <- &numberRlp{n, data}:
+			case rlpCh <- &numberRlp{n, time, data}:
 			case <-interrupt:
 				return
 			}
@@ -147,13 +165,24 @@ func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool
 				log.Warn("Failed to decode block body", "block", data.number, "error", err)
 				return
 			}
-			var hashes []common.Hash
+			var (
+				hashes  []common.Hash
+				senders []txSenderNonce
+				signer  = types.MakeSigner(config, new(big.Int).SetUint64(data.number), data.time)
+			)
 			for _, tx := range body.Transactions {
 				hashes = append(hashes, tx.Hash())
+
+				sender, err := types.Sender(signer, tx)
+				if err != nil {
+					log.Warn("Failed to derive transaction sender", "block", data.number, "hash", tx.Hash(), "error", err)
+				}
+				senders = append(senders, txSenderNonce{sender: sender, nonce: tx.Nonce()})
 			}
 			result := &blockTxHashes{
-				hashes: hashes,
-				number: data.number,
+				hashes:  hashes,
+				senders: senders,
+				number:  data.number,
 			}
 			// Feed the block to the aggregator, or abort on interrupt
 			select {
@@ -178,13 +207,16 @@ func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool
 //
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
-func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
+//
+// The index tail is only updated if requested, otherwise the range is expected
+// to be above the tail, with its indices being rewritten.
+func indexTransactions(db ethdb.Database, config *params.ChainConfig, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool, updateTail bool) {
 	// short circuit for invalid range
 	if from >= to {
 		return
 	}
 	var (
-		hashesCh = iterateTransactions(db, from, to, true, interrupt)
+		hashesCh = iterateTransactions(db, config, from, to, true, interrupt)
 		batch    = db.NewBatch()
 		start    = time.Now()
 		logged   = start.Add(-7 * time.Second)
@@ -214,11 +246,18 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 			delivery := queue.PopItem()
 			lastNum = delivery.number
 			WriteTxLookupEntries(batch, delivery.number, delivery.hashes)
+			for i, tx := range delivery.senders {
+				if tx.sender != (common.Address{}) {
+					WriteTxSenderLookupEntry(batch, tx.sender, tx.nonce, delivery.hashes[i])
+				}
+			}
 			blocks++
 			txs += len(delivery.hashes)
 			// If enough data was accumulated in memory or we're at the last block, dump to disk
 			if batch.ValueSize() > ethdb.IdealBatchSize {
-				WriteTxIndexTail(batch, lastNum) // Also write the tail here
+				if updateTail {
+					WriteTxIndexTail(batch, lastNum) // Also write the tail here
+				}
 				if err := batch.Write(); err != nil {
 					log.Crit("Failed writing batch to db", "error", err)
 					return
@@ -235,7 +274,9 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 	// Flush the new indexing tail and the last committed data. It can also happen
 	// that the last batch is empty because nothing to index, but the tail has to
 	// be flushed anyway.
-	WriteTxIndexTail(batch, lastNum)
+	if updateTail {
+		WriteTxIndexTail(batch, lastNum)
+	}
 	if err := batch.Write(); err != nil {
 		log.Crit("Failed writing batch to db", "error", err)
 		return
@@ -257,26 +298,51 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 //
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
-func IndexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}) {
-	indexTransactions(db, from, to, interrupt, nil)
+func IndexTransactions(db ethdb.Database, config *params.ChainConfig, from uint64, to uint64, interrupt chan struct{}) {
+	indexTransactions(db, config, from, to, interrupt, nil, true)
 }
 
 // indexTransactionsForTesting is the internal debug version with an additional hook.
-func indexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
-	indexTransactions(db, from, to, interrupt, hook)
+func indexTransactionsForTesting(db ethdb.Database, config *params.ChainConfig, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
+	indexTransactions(db, config, from, to, interrupt, hook, true)
+}
+
+// ReindexTransactions rewrites the txlookup indices of the specified block range,
+// including the sender lookups missing from the indices of older databases. The
+// from is included while to is excluded.
+//
+// If the range starts below the index tail, the indexed blocks are extended down
+// to it and the tail is moved, indexing any gap up to the old tail too, so that
+// no untracked indices are left behind for the unindexer.
+//
+// There is a passed channel, the whole procedure will be interrupted if any
+// signal received.
+func ReindexTransactions(db ethdb.Database, config *params.ChainConfig, from uint64, to uint64, interrupt chan struct{}) {
+	tail := ReadTxIndexTail(db)
+	if tail == nil || from >= *tail {
+		indexTransactions(db, config, from, to, interrupt, nil, false)
+		return
+	}
+	indexTransactions(db, config, *tail, to, interrupt, nil, false)
+	select {
+	case <-interrupt:
+		return
+	default:
+	}
+	indexTransactions(db, config, from, *tail, interrupt, nil, true)
 }
 
 // unindexTransactions removes txlookup indices of the specified block range.
 //
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
-func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
+func unindexTransactions(db ethdb.Database, config *params.ChainConfig, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
 	// short circuit for invalid range
 	if from >= to {
 		return
 	}
 	var (
-		hashesCh = iterateTransactions(db, from, to, false, interrupt)
+		hashesCh = iterateTransactions(db, config, from, to, false, interrupt)
 		batch    = db.NewBatch()
 		start    = time.Now()
 		logged   = start.Add(-7 * time.Second)
@@ -303,6 +369,11 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 			delivery := queue.PopItem()
 			nextNum = delivery.number + 1
 			DeleteTxLookupEntries(batch, delivery.hashes)
+			for _, tx := range delivery.senders {
+				if tx.sender != (common.Address{}) {
+					DeleteTxSenderLookupEntry(batch, tx.sender, tx.nonce)
+				}
+			}
 			txs += len(delivery.hashes)
 			blocks++
 
@@ -345,11 +416,52 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 //
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
-func UnindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}) {
-	unindexTransactions(db, from, to, interrupt, nil)
+func UnindexTransactions(db ethdb.Database, config *params.ChainConfig, from uint64, to uint64, interrupt chan struct{}) {
+	unindexTransactions(db, config, from, to, interrupt, nil)
 }
 
 // unindexTransactionsForTesting is the internal debug version with an additional hook.
-func unindexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
-	unindexTransactions(db, from, to, interrupt, hook)
+func unindexTransactionsForTesting(db ethdb.Database, config *params.ChainConfig, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
+	unindexTransactions(db, config, from, to, interrupt, hook)
+}
+
+// PruneChainHistory drops the frozen block bodies and receipts below the given
+// block, retaining the headers. The transactions of the dropped blocks are
+// unindexed beforehand. Blocks not yet moved into the freezer are never pruned,
+// the returned number is the first block whose history is retained.
+//
+// There is a passed channel, the whole procedure will be interrupted if any
+// signal received.
+func PruneChainHistory(db ethdb.Database, config *params.ChainConfig, before uint64, interrupt chan struct{}) (uint64, error) {
+	frozen, err := db.Ancients()
+	if err != nil {
+		return 0, err
+	}
+	if before > frozen {
+		before = frozen
+	}
+	tail, err := db.Tail()
+	if err != nil {
+		return 0, err
+	}
+	if before <= tail {
+		return tail, nil
+	}
+	// Unindex the transactions of the dropped bodies, their lookups would be
+	// dangling otherwise.
+	if indexed := ReadTxIndexTail(db); indexed != nil && *indexed < before {
+		from := *indexed
+		if from < tail {
+			from = tail
+		}
+		UnindexTransactions(db, config, from, before, interrupt)
+		if indexed = ReadTxIndexTail(db); indexed == nil || *indexed < before {
+			return tail, errors.New("transaction unindexing interrupted")
+		}
+	}
+	if err := db.TruncateTail(before); err != nil {
+		return tail, err
+	}
+	log.Debug("Pruned chain history", "tail", before)
+	return before, nil
 }
@@ -25,6 +25,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 func TestChainIterator(t *testing.T) {
@@ -80,7 +82,7 @@ func TestChainIterator(t *testing.T) {
 	}
 	for i, c := range cases {
 		var numbers []int
-		hashCh := iterateTransactions(chainDb, c.from, c.to, c.reverse, nil)
+		hashCh := iterateTransactions(chainDb, params.AllEthashProtocolChanges, c.from, c.to, c.reverse, nil)
 		if hashCh != nil {
 			for h := range hashCh {
 				numbers = append(numbers, int(h.number))
@@ -162,24 +164,24 @@ func TestIndexTransactions(t *testing.T) {
 			t.Fatalf("Transaction tail mismatch")
 		}
 	}
-	IndexTransactions(chainDb, 5, 11, nil)
+	IndexTransactions(chainDb, params.AllEthashProtocolChanges, 5, 11, nil)
 	verify(5, 11, true, 5)
 	verify(0, 5, false, 5)
 
-	IndexTransactions(chainDb, 0, 5, nil)
+	IndexTransactions(chainDb, params.AllEthashProtocolChanges, 0, 5, nil)
 	verify(0, 11, true, 0)
 
-	UnindexTransactions(chainDb, 0, 5, nil)
+	UnindexTransactions(chainDb, params.AllEthashProtocolChanges, 0, 5, nil)
 	verify(5, 11, true, 5)
 	verify(0, 5, false, 5)
 
-	UnindexTransactions(chainDb, 5, 11, nil)
+	UnindexTransactions(chainDb, params.AllEthashProtocolChanges, 5, 11, nil)
 	verify(0, 11, false, 11)
 
 	// Testing corner cases
 	signal := make(chan struct{})
 	var once sync.Once
-	indexTransactionsForTesting(chainDb, 5, 11, signal, func(n uint64) bool {
+	indexTransactionsForTesting(chainDb, params.AllEthashProtocolChanges, 5, 11, signal, func(n uint64) bool {
 		if n <= 8 {
 			once.Do(func() {
 				close(signal)
@@ -190,11 +192,11 @@ func TestIndexTransactions(t *testing.T) {
 	})
 	verify(9, 11, true, 9)
 	verify(0, 9, false, 9)
-	IndexTransactions(chainDb, 0, 9, nil)
+	IndexTransactions(chainDb, params.AllEthashProtocolChanges, 0, 9, nil)
 
 	signal = make(chan struct{})
 	var once2 sync.Once
-	unindexTransactionsForTesting(chainDb, 0, 11, signal, func(n uint64) bool {
+	unindexTransactionsForTesting(chainDb, params.AllEthashProtocolChanges, 0, 11, signal, func(n uint64) bool {
 		if n >= 8 {
 			once2.Do(func() {
 				close(signal)
@@ -206,3 +208,154 @@ func TestIndexTransactions(t *testing.T) {
 	verify(8, 11, true, 8)
 	verify(0, 8, false, 8)
 }
+
+func TestPruneChainHistory(t *testing.T) {
+	// Construct test chain db with all blocks in the ancient store
+	chainDb, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	defer chainDb.Close()
+
+	var (
+		to       = common.BytesToAddress([]byte{0x11})
+		blocks   = []*types.Block{types.NewBlock(&types.Header{Number: big.NewInt(0)}, nil, nil, nil, newHasher())}
+		receipts = []types.Receipts{nil}
+	)
+	for i := uint64(1); i <= 10; i++ {
+		tx := types.NewTx(&types.LegacyTx{
+			Nonce:    i,
+			GasPrice: big.NewInt(11111),
+			Gas:      1111,
+			To:       &to,
+			Value:    big.NewInt(111),
+		})
+		blocks = append(blocks, types.NewBlock(&types.Header{Number: big.NewInt(int64(i)), ParentHash: blocks[i-1].Hash()}, []*types.Transaction{tx}, nil, nil, newHasher()))
+		receipts = append(receipts, types.Receipts{{Status: types.ReceiptStatusSuccessful, TxHash: tx.Hash()}})
+	}
+	if _, err := WriteAncientBlocks(chainDb, blocks, receipts, big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	IndexTransactions(chainDb, params.AllEthashProtocolChanges, 0, 11, nil)
+
+	verify := func(tail uint64) {
+		for _, block := range blocks {
+			number, hash := block.NumberU64(), block.Hash()
+			if ReadHeader(chainDb, hash, number) == nil {
+				t.Fatalf("block %d: header missing", number)
+			}
+			pruned := number < tail
+			if have := ReadBodyRLP(chainDb, hash, number) == nil; have != pruned {
+				t.Fatalf("block %d: body pruned mismatch: have %v, want %v", number, have, pruned)
+			}
+			if have := ReadReceiptsRLP(chainDb, hash, number) == nil; have != pruned {
+				t.Fatalf("block %d: receipts pruned mismatch: have %v, want %v", number, have, pruned)
+			}
+			for _, tx := range block.Transactions() {
+				if have := ReadTxLookupEntry(chainDb, tx.Hash()) == nil; have != pruned {
+					t.Fatalf("block %d: tx lookup pruned mismatch: have %v, want %v", number, have, pruned)
+				}
+			}
+		}
+		if have, _ := chainDb.Tail(); have != tail {
+			t.Fatalf("ancient tail mismatch: have %d, want %d", have, tail)
+		}
+	}
+	if tail, err := PruneChainHistory(chainDb, params.AllEthashProtocolChanges, 6, nil); err != nil || tail != 6 {
+		t.Fatalf("failed to prune chain history: tail %d, err %v", tail, err)
+	}
+	verify(6)
+
+	// Pruning below the tail should be a noop, beyond the ancients capped
+	if tail, err := PruneChainHistory(chainDb, params.AllEthashProtocolChanges, 3, nil); err != nil || tail != 6 {
+		t.Fatalf("failed to prune chain history: tail %d, err %v", tail, err)
+	}
+	verify(6)
+
+	if tail, err := PruneChainHistory(chainDb, params.AllEthashProtocolChanges, 20, nil); err != nil || tail != 11 {
+		t.Fatalf("failed to prune chain history: tail %d, err %v", tail, err)
+	}
+	verify(11)
+}
+
+func TestIndexTransactionSenders(t *testing.T) {
+	var (
+		chainDb = NewMemoryDatabase()
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		signer  = types.LatestSignerForChainID(big.NewInt(1337))
+		to      = common.BytesToAddress([]byte{0x11})
+		txs     []*types.Transaction
+	)
+	block := types.NewBlock(&types.Header{Number: big.NewInt(0)}, nil, nil, nil, newHasher())
+	WriteBlock(chainDb, block)
+	WriteCanonicalHash(chainDb, block.Hash(), block.NumberU64())
+	for i := uint64(1); i <= 10; i++ {
+		var inner types.TxData = &types.LegacyTx{Nonce: i, GasPrice: big.NewInt(11111), Gas: 21000, To: &to}
+		if i%2 == 0 {
+			inner = &types.DynamicFeeTx{ChainID: big.NewInt(1337), Nonce: i, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(11111), Gas: 21000, To: &to}
+		}
+		tx := types.MustSignNewTx(key, signer, inner)
+		txs = append(txs, tx)
+
+		block = types.NewBlock(&types.Header{Number: big.NewInt(int64(i))}, []*types.Transaction{tx}, nil, nil, newHasher())
+		WriteBlock(chainDb, block)
+		WriteCanonicalHash(chainDb, block.Hash(), block.NumberU64())
+	}
+	// verify checks whether the sender lookups of the blocks in the range [from, to)
+	// are as expected.
+	verify := func(from, to int, exist bool) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			if i == 0 {
+				continue
+			}
+			hash := ReadTxSenderLookupEntry(chainDb, sender, uint64(i))
+			if exist && (hash == nil || *hash != txs[i-1].Hash()) {
+				t.Fatalf("sender lookup %d mismatch: have %v, want %x", i, hash, txs[i-1].Hash())
+			}
+			if !exist && hash != nil {
+				t.Fatalf("sender lookup %d is not deleted", i)
+			}
+		}
+	}
+	verifyTail := func(want uint64) {
+		t.Helper()
+		if tail := ReadTxIndexTail(chainDb); tail == nil || *tail != want {
+			t.Fatalf("tail mismatch: have %v, want %d", tail, want)
+		}
+	}
+	IndexTransactions(chainDb, params.AllEthashProtocolChanges, 0, 11, nil)
+	verify(0, 11, true)
+
+	UnindexTransactions(chainDb, params.AllEthashProtocolChanges, 0, 5, nil)
+	verify(0, 5, false)
+	verify(5, 11, true)
+	verifyTail(5)
+
+	// Reindexing a range above the tail must leave it untouched, backfilling the
+	// sender lookups missing from it
+	for i := 5; i <= 10; i++ {
+		DeleteTxSenderLookupEntry(chainDb, sender, uint64(i))
+	}
+	ReindexTransactions(chainDb, params.AllEthashProtocolChanges, 6, 11, nil)
+	verify(5, 6, false)
+	verify(6, 11, true)
+	ReindexTransactions(chainDb, params.AllEthashProtocolChanges, 5, 6, nil)
+	verifyTail(5)
+
+	// Reindexing a range below the tail must extend the indexed blocks down to it,
+	// leaving no gap untracked by the tail
+	ReindexTransactions(chainDb, params.AllEthashProtocolChanges, 1, 3, nil)
+	verify(1, 11, true)
+	verifyTail(1)
+
+	// Unindexing must remove all the reindexed lookups
+	UnindexTransactions(chainDb, params.AllEthashProtocolChanges, 1, 6, nil)
+	verify(1, 6, false)
+	verifyTail(6)
+
+	// Lookups of transactions dropped from the chain must be ignored
+	DeleteTxLookupEntry(chainDb, txs[9].Hash())
+	verify(10, 11, false)
+}
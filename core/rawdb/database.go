@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/leveldb"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/olekukonko/tablewriter"
 )
@@ -193,13 +194,38 @@ func resolveChainFreezerDir(ancient string) string {
 	return freezer
 }
 
+// resolveFreezerLayout applies the stored layout of the ancient store to the
+// configured root ancient directory, returning the directory the ancient store
+// actually resides in along with the cold tier of the chain freezer, if any.
+func resolveFreezerLayout(db ethdb.KeyValueReader, ancient string) (string, *freezerTier) {
+	layout := ReadFreezerLayout(db)
+	if layout == nil {
+		return ancient, nil
+	}
+	if layout.Directory != "" && layout.Directory != ancient {
+		log.Warn("Using relocated ancient directory", "configured", ancient, "relocated", layout.Directory)
+		ancient = layout.Directory
+	}
+	var tier *freezerTier
+	if layout.ColdDirectory != "" {
+		tier = &freezerTier{path: filepath.Join(layout.ColdDirectory, chainFreezerName), hotFiles: layout.HotFiles}
+	}
+	return ancient, tier
+}
+
 // NewDatabaseWithFreezer creates a high level database on top of a given key-
 // value data store with a freezer moving immutable chain segments into cold
 // storage. The passed ancient indicates the path of root ancient directory
 // where the chain freezer can be opened.
+//
+// If the ancient store was relocated, the recorded location supersedes the
+// passed one. If it was split into tiers, the older data files are moved into
+// and served from the cold tier.
 func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace string, readonly bool) (ethdb.Database, error) {
+	ancient, tier := resolveFreezerLayout(db, ancient)
+
 	// Create the idle freezer instance
-	frdb, err := newChainFreezer(resolveChainFreezerDir(ancient), namespace, readonly)
+	frdb, err := newChainFreezer(resolveChainFreezerDir(ancient), namespace, readonly, tier)
 	if err != nil {
 		printChainMetadata(db)
 		return nil, err
@@ -352,6 +378,10 @@ type OpenOptions struct {
 	Cache             int    // the capacity(in megabytes) of the data caching
 	Handles           int    // number of files to be open simultaneously
 	ReadOnly          bool
+
+	// Pebble contains the optional tuning parameters of a pebble database,
+	// nil selecting the defaults. It is ignored by leveldb.
+	Pebble *pebble.Config
 }
 
 // openKeyValueDatabase opens a disk-based key-value database, e.g. leveldb or pebble.
@@ -374,7 +404,7 @@ func openKeyValueDatabase(o OpenOptions) (ethdb.Database, error) {
 	if o.Type == dbPebble || existingDb == dbPebble {
 		if PebbleEnabled {
 			log.Info("Using pebble as the backing database")
-			return NewPebbleDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly)
+			return NewPebbleDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly, o.Pebble)
 		} else {
 			return nil, errors.New("db.engine 'pebble' not supported on this platform")
 		}
@@ -387,7 +417,7 @@ func openKeyValueDatabase(o OpenOptions) (ethdb.Database, error) {
 	// on supported platforms and LevelDB on anything else.
 	if PebbleEnabled {
 		log.Info("Defaulting to pebble as the backing database")
-		return NewPebbleDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly)
+		return NewPebbleDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly, o.Pebble)
 	} else {
 		log.Info("Defaulting to leveldb as the backing database")
 		return NewLevelDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly)
@@ -470,8 +500,12 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		storageSnaps    stat
 		preimages       stat
 		bloomBits       stat
+		logIndex        stat
 		beaconHeaders   stat
 		cliqueSnaps     stat
+		stateLookups    stat
+		trieHistories   stat
+		stateDiffs      stat
 
 		// Les statistic
 		chtTrieNodes   stat
@@ -506,10 +540,14 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			hashNumPairings.Add(size)
 		case len(key) == common.HashLength:
 			tries.Add(size)
+		case isPathTrieNode(key):
+			tries.Add(size)
 		case bytes.HasPrefix(key, CodePrefix) && len(key) == len(CodePrefix)+common.HashLength:
 			codes.Add(size)
 		case bytes.HasPrefix(key, txLookupPrefix) && len(key) == (len(txLookupPrefix)+common.HashLength):
 			txLookups.Add(size)
+		case bytes.HasPrefix(key, txSenderLookupPrefix) && len(key) == (len(txSenderLookupPrefix)+common.AddressLength+8):
+			txLookups.Add(size)
 		case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == (len(SnapshotAccountPrefix)+common.HashLength):
 			accountSnaps.Add(size)
 		case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == (len(SnapshotStoragePrefix)+2*common.HashLength):
@@ -524,8 +562,24 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			bloomBits.Add(size)
 		case bytes.HasPrefix(key, BloomBitsIndexPrefix):
 			bloomBits.Add(size)
+		case bytes.HasPrefix(key, logIndexPrefix) && (len(key) == len(logIndexPrefix)+1+common.AddressLength+8 || len(key) == len(logIndexPrefix)+1+common.HashLength+8):
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, logIndexBlockPrefix) && len(key) == len(logIndexBlockPrefix)+8:
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, LogIndexPrefix):
+			logIndex.Add(size)
 		case bytes.HasPrefix(key, skeletonHeaderPrefix) && len(key) == (len(skeletonHeaderPrefix)+8):
 			beaconHeaders.Add(size)
+		case bytes.HasPrefix(key, stateIDPrefix) && len(key) == len(stateIDPrefix)+common.HashLength:
+			stateLookups.Add(size)
+		case bytes.HasPrefix(key, trieHistoryPrefix) && len(key) == len(trieHistoryPrefix)+8:
+			trieHistories.Add(size)
+		case bytes.HasPrefix(key, stateDiffPrefix) && len(key) == len(stateDiffPrefix)+8+common.HashLength:
+			stateDiffs.Add(size)
+		case bytes.HasPrefix(key, stateDiffAccountIndexPrefix) && len(key) == len(stateDiffAccountIndexPrefix)+common.HashLength+8:
+			stateDiffs.Add(size)
+		case bytes.HasPrefix(key, stateDiffStorageIndexPrefix) && len(key) == len(stateDiffStorageIndexPrefix)+2*common.HashLength+8:
+			stateDiffs.Add(size)
 		case bytes.HasPrefix(key, CliqueSnapshotPrefix) && len(key) == 7+common.HashLength:
 			cliqueSnaps.Add(size)
 		case bytes.HasPrefix(key, ChtTablePrefix) ||
@@ -543,6 +597,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 				lastPivotKey, fastTrieProgressKey, snapshotDisabledKey, SnapshotRootKey, snapshotJournalKey,
 				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
 				uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
+				persistentStateIDKey, trieJournalKey, stateDiffOffsetKey, scrubProgressKey, freezerLayoutKey,
 			} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
@@ -570,6 +625,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
+		{"Key-Value store", "Log index", logIndex.Size(), logIndex.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
@@ -577,6 +633,9 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Storage snapshot", storageSnaps.Size(), storageSnaps.Count()},
 		{"Key-Value store", "Beacon sync headers", beaconHeaders.Size(), beaconHeaders.Count()},
 		{"Key-Value store", "Clique snapshots", cliqueSnaps.Size(), cliqueSnaps.Count()},
+		{"Key-Value store", "State lookups", stateLookups.Size(), stateLookups.Count()},
+		{"Key-Value store", "Trie histories", trieHistories.Size(), trieHistories.Count()},
+		{"Key-Value store", "State diffs", stateDiffs.Size(), stateDiffs.Count()},
 		{"Key-Value store", "Singleton metadata", metadata.Size(), metadata.Count()},
 		{"Light client", "CHT trie nodes", chtTrieNodes.Size(), chtTrieNodes.Count()},
 		{"Light client", "Bloom trie nodes", bloomTrieNodes.Size(), bloomTrieNodes.Count()},
@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/gofrs/flock"
 )
 
@@ -72,14 +73,29 @@ type Freezer struct {
 
 	readonly     bool
 	tables       map[string]*freezerTable // Data tables for storing everything
+	prunable     map[string]bool          // Tables affected by tail truncation, nil if all
+	tier         *freezerTier             // Cold tier of the older data files, nil if disabled
 	instanceLock *flock.Flock             // File-system lock to prevent double opens
 	closeOnce    sync.Once
 }
 
+// freezerTier configures moving the older data files of the freezer tables into
+// a secondary, typically slower and cheaper, storage location.
+type freezerTier struct {
+	path     string // Directory of the cold tier
+	hotFiles uint32 // Number of most recent sealed data files per table kept in the hot tier
+}
+
 // NewChainFreezer is a small utility method around NewFreezer that sets the
 // default parameters for the chain storage.
 func NewChainFreezer(datadir string, namespace string, readonly bool) (*Freezer, error) {
-	return NewFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy)
+	return newFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy, chainFreezerPrunable, nil)
+}
+
+// NewStateDiffFreezer initializes the freezer for the per-block state reverse
+// diffs, placed in the given ancient directory.
+func NewStateDiffFreezer(ancientDir string, readonly bool) (*Freezer, error) {
+	return NewFreezer(filepath.Join(ancientDir, stateDiffFreezerName), "eth/db/statediff", readonly, freezerTableSize, stateDiffFreezerNoSnappy)
 }
 
 // NewFreezer creates a freezer instance for maintaining immutable ordered
@@ -88,6 +104,14 @@ func NewChainFreezer(datadir string, namespace string, readonly bool) (*Freezer,
 // The 'tables' argument defines the data tables. If the value of a map
 // entry is true, snappy compression is disabled for the table.
 func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+	return newFreezer(datadir, namespace, readonly, maxTableSize, tables, nil, nil)
+}
+
+// newFreezer creates a freezer instance in which only the given tables are
+// affected by tail truncation, the others retaining all their items. If the
+// set of prunable tables is nil, all tables are tail-truncated. The older data
+// files of the tables are looked up in the cold tier too, if one is given.
+func newFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool, prunable map[string]bool, tier *freezerTier) (*Freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -117,12 +141,17 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 	freezer := &Freezer{
 		readonly:     readonly,
 		tables:       make(map[string]*freezerTable),
+		prunable:     prunable,
+		tier:         tier,
 		instanceLock: lock,
 	}
-
+	var coldPath string
+	if tier != nil {
+		coldPath = tier.path
+	}
 	// Create the tables.
 	for name, disableSnappy := range tables {
-		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, maxTableSize, disableSnappy, readonly)
+		table, err := newTieredTable(datadir, coldPath, name, readMeter, writeMeter, sizeGauge, maxTableSize, disableSnappy, readonly)
 		if err != nil {
 			for _, table := range freezer.tables {
 				table.Close()
@@ -178,6 +207,43 @@ func (f *Freezer) Close() error {
 	return nil
 }
 
+// moveToColdTier relocates the sealed data files of all tables, apart from the
+// most recent ones, into the cold tier. It is a noop if tiering is disabled.
+// The operation is aborted between two files if the abort channel is closed.
+func (f *Freezer) moveToColdTier(abort chan struct{}) error {
+	if f.tier == nil || f.readonly {
+		return nil
+	}
+	var (
+		start = time.Now()
+		moved int
+	)
+	for _, table := range f.tables {
+		table.lock.RLock()
+		tail, head := table.tailId, table.headId
+		table.lock.RUnlock()
+
+		for num := tail; num+f.tier.hotFiles < head; num++ {
+			select {
+			case <-abort:
+				return nil
+			default:
+			}
+			ok, err := table.moveToColdTier(num, &f.writeLock)
+			if err != nil {
+				return err
+			}
+			if ok {
+				moved++
+			}
+		}
+	}
+	if moved > 0 {
+		log.Info("Moved ancient data files to cold tier", "files", moved, "path", f.tier.path, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	return nil
+}
+
 // HasAncient returns an indicator whether the specified ancient data exists
 // in the freezer.
 func (f *Freezer) HasAncient(kind string, number uint64) (bool, error) {
@@ -212,7 +278,8 @@ func (f *Freezer) Ancients() (uint64, error) {
 	return f.frozen.Load(), nil
 }
 
-// Tail returns the number of first stored item in the freezer.
+// Tail returns the number of first stored item in the freezer. If only some of
+// the tables are prunable, it is the first item stored in those.
 func (f *Freezer) Tail() (uint64, error) {
 	return f.tail.Load(), nil
 }
@@ -304,7 +371,10 @@ func (f *Freezer) TruncateTail(tail uint64) error {
 	if f.tail.Load() >= tail {
 		return nil
 	}
-	for _, table := range f.tables {
+	for kind, table := range f.tables {
+		if !f.isPrunable(kind) {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return err
 		}
@@ -313,6 +383,11 @@ func (f *Freezer) TruncateTail(tail uint64) error {
 	return nil
 }
 
+// isPrunable reports whether the given table is affected by tail truncation.
+func (f *Freezer) isPrunable(kind string) bool {
+	return f.prunable == nil || f.prunable[kind]
+}
+
 // Sync flushes all data tables to disk.
 func (f *Freezer) Sync() error {
 	var errs []error
@@ -334,24 +409,27 @@ func (f *Freezer) validate() error {
 		return nil
 	}
 	var (
-		head uint64
-		tail uint64
-		name string
+		head     uint64
+		tail     uint64
+		name     string
+		tailName string
 	)
 	// Hack to get boundary of any table
 	for kind, table := range f.tables {
 		head = table.items.Load()
-		tail = table.itemHidden.Load()
 		name = kind
-		break
+		if f.isPrunable(kind) {
+			tail = table.itemHidden.Load()
+			tailName = kind
+		}
 	}
 	// Now check every table against those boundaries.
 	for kind, table := range f.tables {
 		if head != table.items.Load() {
 			return fmt.Errorf("freezer tables %s and %s have differing head: %d != %d", kind, name, table.items.Load(), head)
 		}
-		if tail != table.itemHidden.Load() {
-			return fmt.Errorf("freezer tables %s and %s have differing tail: %d != %d", kind, name, table.itemHidden.Load(), tail)
+		if f.isPrunable(kind) && tail != table.itemHidden.Load() {
+			return fmt.Errorf("freezer tables %s and %s have differing tail: %d != %d", kind, tailName, table.itemHidden.Load(), tail)
 		}
 	}
 	f.frozen.Store(head)
@@ -365,20 +443,26 @@ func (f *Freezer) repair() error {
 		head = uint64(math.MaxUint64)
 		tail = uint64(0)
 	)
-	for _, table := range f.tables {
+	for kind, table := range f.tables {
 		items := table.items.Load()
 		if head > items {
 			head = items
 		}
+		if !f.isPrunable(kind) {
+			continue
+		}
 		hidden := table.itemHidden.Load()
 		if hidden > tail {
 			tail = hidden
 		}
 	}
-	for _, table := range f.tables {
+	for kind, table := range f.tables {
 		if err := table.truncateHead(head); err != nil {
 			return err
 		}
+		if !f.isPrunable(kind) {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return err
 		}
@@ -405,6 +489,46 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	if !ok {
 		return errUnknownTable
 	}
+	// TODO(s1na): This is a sanity-check since as of now no process does tail-deletion. But the migration
+	// process assumes no deletion at tail and needs to be modified to account for that.
+	if table.itemOffset.Load() > 0 || table.itemHidden.Load() > 0 {
+		return errors.New("migration not supported for tail-deleted freezers")
+	}
+	return f.migrateTable(table, table.codec, convert)
+}
+
+// RecompressTable rewrites all the items of a compressed table with the given
+// codec, replacing the table in place. Items hidden by tail deletion are dropped.
+// The table is swapped out once rewritten, so the freezer must not be read by
+// anyone else in the meantime.
+func (f *Freezer) RecompressTable(kind string, codec FreezerCodec) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	if !codec.valid() {
+		return fmt.Errorf("unsupported freezer codec %d", codec)
+	}
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	table, ok := f.tables[kind]
+	if !ok {
+		return errUnknownTable
+	}
+	if table.noCompression {
+		return fmt.Errorf("table %s is not compressed", kind)
+	}
+	if table.codec == codec {
+		log.Info("Freezer table already uses codec", "table", kind, "codec", codec)
+		return nil
+	}
+	return f.migrateTable(table, codec, func(blob []byte) ([]byte, error) { return blob, nil })
+}
+
+// migrateTable rewrites the visible items of a table through the conversion
+// function into a new table using the given codec, replacing the files of the
+// original table and reopening it once done. The caller must hold the write lock.
+func (f *Freezer) migrateTable(table *freezerTable, codec FreezerCodec, convert convertLegacyFn) error {
 	// forEach iterates every entry in the table serially and in order, calling `fn`
 	// with the item as argument. If `fn` returns an error the iteration stops
 	// and that error will be returned.
@@ -431,28 +555,27 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 		}
 		return nil
 	}
-	// TODO(s1na): This is a sanity-check since as of now no process does tail-deletion. But the migration
-	// process assumes no deletion at tail and needs to be modified to account for that.
-	if table.itemOffset.Load() > 0 || table.itemHidden.Load() > 0 {
-		return errors.New("migration not supported for tail-deleted freezers")
-	}
-	ancientsPath := filepath.Dir(table.index.Name())
+	var (
+		kind         = table.name
+		ancientsPath = filepath.Dir(table.index.Name())
+		tail         = table.itemHidden.Load()
+	)
 	// Set up new dir for the migrated table, the content of which
 	// we'll at the end move over to the ancients dir.
 	migrationPath := filepath.Join(ancientsPath, "migration")
-	newTable, err := newFreezerTable(migrationPath, kind, table.noCompression, false)
+	migrated, err := openMigrationTable(migrationPath, kind, table.noCompression, tail, codec)
 	if err != nil {
 		return err
 	}
 	var (
-		batch  = newTable.newBatch()
+		batch  = migrated.newBatch()
 		out    []byte
 		start  = time.Now()
 		logged = time.Now()
-		offset = newTable.items.Load()
+		offset = migrated.items.Load()
 	)
-	if offset > 0 {
-		log.Info("found previous migration attempt", "migrated", offset)
+	if offset > tail {
+		log.Info("found previous migration attempt", "migrated", offset-tail)
 	}
 	// Iterate through entries and transform them
 	if err := forEach(table, offset, func(i uint64, blob []byte) error {
@@ -469,17 +592,40 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 		}
 		return nil
 	}); err != nil {
+		migrated.Close()
 		return err
 	}
 	if err := batch.commit(); err != nil {
+		migrated.Close()
 		return err
 	}
 	log.Info("Replacing old table files with migrated ones", "elapsed", common.PrettyDuration(time.Since(start)))
-	// Release and delete old table files. Note this won't
-	// delete the index file.
-	table.releaseFilesAfter(0, true)
 
-	if err := newTable.Close(); err != nil {
+	// Close the old table and delete its data files. Note this won't delete
+	// the index and metadata files, they are replaced by the migrated ones.
+	tailId, headId := table.tailId, table.headId
+	size, err := table.sizeNolock()
+	if err != nil {
+		migrated.Close()
+		return err
+	}
+	if err := table.Close(); err != nil {
+		migrated.Close()
+		return err
+	}
+	for num := tailId; num <= headId; num++ {
+		if err := os.Remove(table.dataFileName(num)); err != nil && !os.IsNotExist(err) {
+			migrated.Close()
+			return err
+		}
+		if table.coldPath != "" {
+			if err := os.Remove(table.coldFileName(num)); err != nil && !os.IsNotExist(err) {
+				migrated.Close()
+				return err
+			}
+		}
+	}
+	if err := migrated.Close(); err != nil {
 		return err
 	}
 	files, err := os.ReadDir(migrationPath)
@@ -497,5 +643,53 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	if err := os.Remove(migrationPath); err != nil {
 		return err
 	}
+	// Reopen the table on top of the migrated files
+	table.sizeGauge.Dec(int64(size))
+	reopened, err := newTieredTable(ancientsPath, table.coldPath, kind, table.readMeter, table.writeMeter, table.sizeGauge, table.maxFileSize, table.noCompression, false)
+	if err != nil {
+		return err
+	}
+	f.tables[kind] = reopened
+	f.writeBatch = newFreezerBatch(f)
 	return nil
 }
+
+// openMigrationTable opens the table in the migration directory, resuming any
+// previous migration to the same tail and codec. Otherwise the directory is
+// wiped and the table initialized with the given tail and codec.
+func openMigrationTable(path, name string, noCompression bool, tail uint64, codec FreezerCodec) (*freezerTable, error) {
+	table, err := newFreezerTable(path, name, noCompression, false)
+	if err != nil {
+		return nil, err
+	}
+	if table.itemOffset.Load() == tail && table.itemHidden.Load() == tail && table.codec == codec {
+		return table, nil
+	}
+	if table.items.Load() > table.itemOffset.Load() {
+		log.Info("Discarding previous migration attempt", "table", name, "migrated", table.items.Load()-table.itemOffset.Load())
+	}
+	idxPath := table.index.Name()
+	metaPath := table.meta.Name()
+	if err := table.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	// The first index entry records the number of deleted items
+	first := indexEntry{filenum: 0, offset: uint32(tail)}
+	if err := os.WriteFile(idxPath, first.append(nil), 0644); err != nil {
+		return nil, err
+	}
+	meta, err := rlp.EncodeToBytes(newMetadata(tail, codec))
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(metaPath, meta, 0644); err != nil {
+		return nil, err
+	}
+	return newFreezerTable(path, name, noCompression, false)
+}
@@ -84,7 +84,7 @@ func (batch *freezerBatch) commit() (item uint64, writeSize int64, err error) {
 type freezerTableBatch struct {
 	t *freezerTable
 
-	sb          *snappyBuffer
+	comp        *compressor
 	encBuffer   writeBuffer
 	dataBuffer  []byte
 	indexBuffer []byte
@@ -96,7 +96,7 @@ type freezerTableBatch struct {
 func (t *freezerTable) newBatch() *freezerTableBatch {
 	batch := &freezerTableBatch{t: t}
 	if !t.noCompression {
-		batch.sb = new(snappyBuffer)
+		batch.comp = &compressor{codec: t.codec}
 	}
 	batch.reset()
 	return batch
@@ -124,8 +124,8 @@ func (batch *freezerTableBatch) Append(item uint64, data interface{}) error {
 		return err
 	}
 	encItem := batch.encBuffer.data
-	if batch.sb != nil {
-		encItem = batch.sb.compress(encItem)
+	if batch.comp != nil {
+		encItem = batch.comp.compress(encItem)
 	}
 	return batch.appendItem(encItem)
 }
@@ -139,8 +139,8 @@ func (batch *freezerTableBatch) AppendRaw(item uint64, blob []byte) error {
 	}
 
 	encItem := blob
-	if batch.sb != nil {
-		encItem = batch.sb.compress(blob)
+	if batch.comp != nil {
+		encItem = batch.comp.compress(blob)
 	}
 	return batch.appendItem(encItem)
 }
@@ -0,0 +1,117 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"fmt"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// FreezerCodec identifies the compression algorithm applied to the items of a
+// compressed freezer table. The codec of a table is recorded in its metadata.
+type FreezerCodec uint8
+
+const (
+	// FreezerCodecSnappy is the snappy block format, the default codec of all
+	// the compressed freezer tables.
+	FreezerCodecSnappy FreezerCodec = iota
+
+	// FreezerCodecZstd is the zstd frame format, trading some speed for a much
+	// better compression ratio.
+	FreezerCodecZstd
+)
+
+var (
+	// zstdEncoder is the shared zstd compressor, EncodeAll is safe for
+	// concurrent use.
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
+
+	// zstdDecoder is the shared zstd decompressor, DecodeAll is safe for
+	// concurrent use.
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+)
+
+// ParseFreezerCodec converts the name of a codec into its identifier.
+func ParseFreezerCodec(name string) (FreezerCodec, error) {
+	switch name {
+	case "snappy":
+		return FreezerCodecSnappy, nil
+	case "zstd":
+		return FreezerCodecZstd, nil
+	default:
+		return 0, fmt.Errorf("unknown freezer codec %q", name)
+	}
+}
+
+// String implements fmt.Stringer, returning the name of the codec.
+func (c FreezerCodec) String() string {
+	switch c {
+	case FreezerCodecSnappy:
+		return "snappy"
+	case FreezerCodecZstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(c))
+	}
+}
+
+// valid returns whether the codec is supported.
+func (c FreezerCodec) valid() bool {
+	return c == FreezerCodecSnappy || c == FreezerCodecZstd
+}
+
+// decodedLen returns the length of the item once decompressed.
+func (c FreezerCodec) decodedLen(item []byte) (int, error) {
+	if c == FreezerCodecZstd {
+		var header zstd.Header
+		if err := header.Decode(item); err != nil {
+			return 0, err
+		}
+		if !header.HasFCS {
+			return len(item), nil
+		}
+		return int(header.FrameContentSize), nil
+	}
+	return snappy.DecodedLen(item)
+}
+
+// decode decompresses an item.
+func (c FreezerCodec) decode(item []byte) ([]byte, error) {
+	if c == FreezerCodecZstd {
+		return zstdDecoder.DecodeAll(item, nil)
+	}
+	return snappy.Decode(nil, item)
+}
+
+// compressor compresses the items of a table with its codec, reusing the output
+// buffer. The returned slice is only valid until the next call.
+type compressor struct {
+	codec FreezerCodec
+	sb    snappyBuffer
+	dst   []byte
+}
+
+// compress compresses the data.
+func (c *compressor) compress(data []byte) []byte {
+	if c.codec == FreezerCodecZstd {
+		c.dst = zstdEncoder.EncodeAll(data, c.dst[:0])
+		return c.dst
+	}
+	return c.sb.compress(data)
+}
@@ -36,13 +36,20 @@ type freezerTableMeta struct {
 	// plus the number of items hidden in the table, so it should never
 	// be lower than the "actual tail".
 	VirtualTail uint64
+
+	// Codec is the compression algorithm of the items in a compressed table.
+	// It is omitted for the default snappy codec, keeping the metadata of such
+	// tables readable by older versions.
+	Codec uint8 `rlp:"optional"`
 }
 
-// newMetadata initializes the metadata object with the given virtual tail.
-func newMetadata(tail uint64) *freezerTableMeta {
+// newMetadata initializes the metadata object with the given virtual tail
+// and item codec.
+func newMetadata(tail uint64, codec FreezerCodec) *freezerTableMeta {
 	return &freezerTableMeta{
 		Version:     freezerVersion,
 		VirtualTail: tail,
+		Codec:       uint8(codec),
 	}
 }
 
@@ -85,7 +92,7 @@ func loadMetadata(file *os.File, tail uint64) (*freezerTableMeta, error) {
 	// In both cases, write the meta into the file with the actual tail
 	// as the virtual tail.
 	if stat.Size() == 0 {
-		m := newMetadata(tail)
+		m := newMetadata(tail, FreezerCodecSnappy)
 		if err := writeMetadata(file, m); err != nil {
 			return nil, err
 		}
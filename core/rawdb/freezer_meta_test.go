@@ -17,8 +17,11 @@
 package rawdb
 
 import (
+	"bytes"
 	"os"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 func TestReadWriteFreezerTableMeta(t *testing.T) {
@@ -26,7 +29,7 @@ func TestReadWriteFreezerTableMeta(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create file %v", err)
 	}
-	err = writeMetadata(f, newMetadata(100))
+	err = writeMetadata(f, newMetadata(100, FreezerCodecSnappy))
 	if err != nil {
 		t.Fatalf("Failed to write metadata %v", err)
 	}
@@ -58,3 +61,26 @@ func TestInitializeFreezerTableMeta(t *testing.T) {
 		t.Fatalf("Unexpected virtual tail field")
 	}
 }
+
+func TestFreezerTableMetaCodec(t *testing.T) {
+	// The default codec should be omitted, keeping the legacy encoding
+	legacy, _ := rlp.EncodeToBytes([]interface{}{uint16(freezerVersion), uint64(100)})
+	if enc, _ := rlp.EncodeToBytes(newMetadata(100, FreezerCodecSnappy)); !bytes.Equal(enc, legacy) {
+		t.Fatalf("Unexpected snappy metadata encoding: have %x, want %x", enc, legacy)
+	}
+	f, err := os.CreateTemp(os.TempDir(), "*")
+	if err != nil {
+		t.Fatalf("Failed to create file %v", err)
+	}
+	err = writeMetadata(f, newMetadata(100, FreezerCodecZstd))
+	if err != nil {
+		t.Fatalf("Failed to write metadata %v", err)
+	}
+	meta, err := readMetadata(f)
+	if err != nil {
+		t.Fatalf("Failed to read metadata %v", err)
+	}
+	if FreezerCodec(meta.Codec) != FreezerCodecZstd {
+		t.Fatalf("Unexpected codec field")
+	}
+}
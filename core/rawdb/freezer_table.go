@@ -30,7 +30,6 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
-	"github.com/golang/snappy"
 )
 
 var (
@@ -98,11 +97,13 @@ type freezerTable struct {
 	// should never be lower than itemOffset.
 	itemHidden atomic.Uint64
 
-	noCompression bool // if true, disables snappy compression. Note: does not work retroactively
+	noCompression bool         // if true, disables snappy compression. Note: does not work retroactively
+	codec         FreezerCodec // Compression algorithm of the items, unless noCompression is set
 	readonly      bool
 	maxFileSize   uint32 // Max file size for data-files
 	name          string
 	path          string
+	coldPath      string // Directory of the cold tier holding older data files, empty if disabled
 
 	head   *os.File            // File descriptor for the data head of the table
 	index  *os.File            // File descriptor for the indexEntry file of the table
@@ -129,6 +130,12 @@ func newFreezerTable(path, name string, disableSnappy, readonly bool) (*freezerT
 // non-existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
 func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool) (*freezerTable, error) {
+	return newTieredTable(path, "", name, readMeter, writeMeter, sizeGauge, maxFilesize, noCompression, readonly)
+}
+
+// newTieredTable opens a freezer table whose older data files may reside in the
+// given cold tier directory. An empty cold path disables tiering.
+func newTieredTable(path string, coldPath string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool) (*freezerTable, error) {
 	// Ensure the containing directory exists and open the indexEntry file
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
@@ -174,6 +181,7 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 		sizeGauge:     sizeGauge,
 		name:          name,
 		path:          path,
+		coldPath:      coldPath,
 		logger:        log.New("database", path, "table", name),
 		noCompression: noCompression,
 		readonly:      readonly,
@@ -247,6 +255,10 @@ func (t *freezerTable) repair() error {
 	}
 	t.itemHidden.Store(meta.VirtualTail)
 
+	t.codec = FreezerCodec(meta.Codec)
+	if !t.codec.valid() {
+		return fmt.Errorf("unsupported freezer codec %d", meta.Codec)
+	}
 	// Read the last index, use the default value in case the freezer is empty
 	if offsetsSize == indexEntrySize {
 		lastIndex = indexEntry{filenum: t.tailId, offset: 0}
@@ -417,8 +429,18 @@ func (t *freezerTable) truncateHead(items uint64) error {
 	}
 	// We might need to truncate back to older files
 	if expected.filenum != t.headId {
-		// If already open for reading, force-reopen for writing
+		// If already open for reading, force-reopen for writing. The sealed file
+		// is replaced by a copy before being truncated, to leave any hard links
+		// to it (e.g. from database backups) intact.
+		// A file in the cold tier is brought back into the hot one.
 		t.releaseFile(expected.filenum)
+		src := t.locateDataFile(expected.filenum)
+		if err := copyFrom(src, t.dataFileName(expected.filenum), 0, nil); err != nil {
+			return err
+		}
+		if src != t.dataFileName(expected.filenum) {
+			os.Remove(src)
+		}
 		newHead, err := t.openFile(expected.filenum, openFreezerFileForAppend)
 		if err != nil {
 			return err
@@ -476,7 +498,7 @@ func (t *freezerTable) truncateTail(items uint64) error {
 	}
 	// Update the virtual tail marker and hidden these entries in table.
 	t.itemHidden.Store(items)
-	if err := writeMetadata(t.meta, newMetadata(items)); err != nil {
+	if err := writeMetadata(t.meta, newMetadata(items, t.codec)); err != nil {
 		return err
 	}
 	// Hidden items still fall in the current tail file, no data file
@@ -593,13 +615,7 @@ func (t *freezerTable) Close() error {
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
-		}
-		f, err = opener(filepath.Join(t.path, name))
+		f, err = opener(t.locateDataFile(num))
 		if err != nil {
 			return nil, err
 		}
@@ -608,6 +624,73 @@ func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error
 	return f, err
 }
 
+// dataFileName returns the path of the data file with the given number.
+func (t *freezerTable) dataFileName(num uint32) string {
+	if t.noCompression {
+		return filepath.Join(t.path, fmt.Sprintf("%s.%04d.rdat", t.name, num))
+	}
+	return filepath.Join(t.path, fmt.Sprintf("%s.%04d.cdat", t.name, num))
+}
+
+// coldFileName returns the path of the data file with the given number in the
+// cold tier.
+func (t *freezerTable) coldFileName(num uint32) string {
+	return filepath.Join(t.coldPath, filepath.Base(t.dataFileName(num)))
+}
+
+// locateDataFile returns the path of the data file with the given number in the
+// tier holding it. Files missing from both tiers resolve to the hot tier.
+func (t *freezerTable) locateDataFile(num uint32) string {
+	name := t.dataFileName(num)
+	if t.coldPath == "" || common.FileExist(name) {
+		return name
+	}
+	if cold := t.coldFileName(num); common.FileExist(cold) {
+		return cold
+	}
+	return name
+}
+
+// moveToColdTier relocates a sealed data file from the hot tier into the cold
+// one. The file is copied without holding any lock, and swapped in under the
+// given lock only if it was neither replaced nor deleted in the meantime. It
+// returns whether the file was moved.
+func (t *freezerTable) moveToColdTier(num uint32, lock sync.Locker) (bool, error) {
+	hot := t.dataFileName(num)
+	before, err := os.Stat(hot)
+	if os.IsNotExist(err) {
+		return false, nil // already moved or deleted
+	} else if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(t.coldPath, 0755); err != nil {
+		return false, err
+	}
+	cold := t.coldFileName(num)
+	if err := copyFile(hot, cold); err != nil {
+		os.Remove(cold)
+		return false, err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	after, err := os.Stat(hot)
+	if err != nil || !os.SameFile(before, after) || after.Size() != before.Size() || num < t.tailId || num >= t.headId {
+		os.Remove(cold)
+		return false, nil
+	}
+	f, err := openFreezerFileForReadOnly(cold)
+	if err != nil {
+		os.Remove(cold)
+		return false, err
+	}
+	t.releaseFile(num)
+	t.files[num] = f
+	return true, os.Remove(hot)
+}
+
 // releaseFile closes a file, and removes it from the open file cache.
 // Assumes that the caller holds the write lock
 func (t *freezerTable) releaseFile(num uint32) {
@@ -710,13 +793,13 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 		offset += diskSize
 		decompressedSize := diskSize
 		if !t.noCompression {
-			decompressedSize, _ = snappy.DecodedLen(item)
+			decompressedSize, _ = t.codec.decodedLen(item)
 		}
 		if i > 0 && uint64(outputSize+decompressedSize) > maxBytes {
 			break
 		}
 		if !t.noCompression {
-			data, err := snappy.Decode(nil, item)
+			data, err := t.codec.decode(item)
 			if err != nil {
 				return nil, err
 			}
@@ -904,6 +987,74 @@ func (t *freezerTable) Sync() error {
 	return err
 }
 
+// checkIndex verifies that the index entries of the table are ordered and point
+// into the existing data files. If an invalid entry is found, the number of the
+// item is returned along with the reason. Failures to read the index or the data
+// files are returned as errors instead, as they say nothing about the entries.
+func (t *freezerTable) checkIndex() (uint64, error, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	var (
+		offset = t.itemOffset.Load()
+		count  = t.items.Load() - offset
+		sizes  = make(map[uint32]int64)
+		prev   indexEntry
+		buffer = make([]byte, 1024*indexEntrySize)
+	)
+	if t.index == nil {
+		return 0, nil, errClosed
+	}
+	if _, err := t.index.ReadAt(buffer[:indexEntrySize], 0); err != nil {
+		return 0, nil, err
+	}
+	prev.unmarshalBinary(buffer)
+	prev.offset = 0 // The first item starts at the beginning of the tail file
+
+	for i := uint64(0); i < count; {
+		n := uint64(len(buffer) / indexEntrySize)
+		if count-i < n {
+			n = count - i
+		}
+		if _, err := t.index.ReadAt(buffer[:n*indexEntrySize], int64((i+1)*indexEntrySize)); err != nil {
+			return 0, nil, err
+		}
+		for j := uint64(0); j < n; j++ {
+			var entry indexEntry
+			entry.unmarshalBinary(buffer[j*indexEntrySize:])
+
+			number := offset + i + j
+			switch entry.filenum {
+			case prev.filenum:
+				if entry.offset < prev.offset {
+					return number, fmt.Errorf("index offset %d below previous offset %d", entry.offset, prev.offset), nil
+				}
+			case prev.filenum + 1:
+			default:
+				return number, fmt.Errorf("index file number %d does not follow %d", entry.filenum, prev.filenum), nil
+			}
+			size, ok := sizes[entry.filenum]
+			if !ok {
+				stat, err := os.Stat(t.locateDataFile(entry.filenum))
+				if os.IsNotExist(err) {
+					return number, fmt.Errorf("data file %d missing", entry.filenum), nil
+				}
+				if err != nil {
+					return 0, nil, err
+				}
+				size = stat.Size()
+				sizes[entry.filenum] = size
+			}
+			if int64(entry.offset) > size {
+				return number, fmt.Errorf("index offset %d beyond data file %d of size %d", entry.offset, entry.filenum, size), nil
+			}
+			prev = entry
+		}
+		i += n
+	}
+	return 0, nil, nil
+}
+
 func (t *freezerTable) dumpIndexStdout(start, stop int64) {
 	t.dumpIndex(os.Stdout, start, stop)
 }
@@ -24,10 +24,12 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
 	"testing/quick"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/stretchr/testify/require"
 )
@@ -1288,3 +1290,158 @@ func TestRandom(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// Tests that truncating the head of a freezer table into an older data file
+// leaves hard links to that file intact.
+func TestFreezerTruncateHeadLinked(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		fn   = "linked"
+		link = filepath.Join(t.TempDir(), "linked.0000.rdat")
+	)
+	f, err := newTable(dir, fn, metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge(), 50, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// Write 15 bytes 30 times, spanning 10 data files
+	writeChunks(t, f, 30, 15)
+
+	if err := os.Link(filepath.Join(dir, "linked.0000.rdat"), link); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+	want, _ := os.ReadFile(link)
+	if err := f.truncateHead(1); err != nil {
+		t.Fatal(err)
+	}
+	if have, _ := os.ReadFile(link); !bytes.Equal(have, want) {
+		t.Fatalf("linked file modified: have %x, want %x", have, want)
+	}
+	if stat, _ := os.Stat(filepath.Join(dir, "linked.0000.rdat")); stat.Size() != 15 {
+		t.Fatalf("truncated file size mismatch: have %d, want %d", stat.Size(), 15)
+	}
+	if _, err := f.Retrieve(0); err != nil {
+		t.Fatalf("failed to retrieve item: %v", err)
+	}
+}
+
+// TestFreezerCheckIndex tests that index entries pointing out of order or beyond
+// the data files are detected.
+func TestFreezerCheckIndex(t *testing.T) {
+	t.Parallel()
+	fname := fmt.Sprintf("checkindex-%d", rand.Uint64())
+
+	// Fill a table with 2 items per data file, then truncate its tail
+	f, err := newTable(os.TempDir(), fname, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 40, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeChunks(t, f, 20, 15)
+	if err := f.truncateTail(4); err != nil {
+		t.Fatal(err)
+	}
+	if number, reason, err := f.checkIndex(); reason != nil || err != nil {
+		t.Fatalf("valid index reported invalid at item %d: %v %v", number, reason, err)
+	}
+	f.Close()
+
+	// Move the end offset of item 11 below the one of item 10, both in data file 5
+	idx, err := os.OpenFile(filepath.Join(os.TempDir(), fmt.Sprintf("%s.ridx", fname)), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := indexEntry{filenum: 5, offset: 15}
+	if _, err := idx.WriteAt(entry.append(nil), int64(10-4+1)*indexEntrySize); err != nil {
+		t.Fatal(err)
+	}
+	entry = indexEntry{filenum: 5, offset: 10}
+	if _, err := idx.WriteAt(entry.append(nil), int64(11-4+1)*indexEntrySize); err != nil {
+		t.Fatal(err)
+	}
+	idx.Close()
+
+	f, err = newTable(os.TempDir(), fname, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 40, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	number, reason, err := f.checkIndex()
+	if err != nil {
+		t.Fatalf("failed to check index: %v", err)
+	}
+	if reason == nil {
+		t.Fatal("invalid index not detected")
+	}
+	if number != 11 {
+		t.Fatalf("invalid item mismatch: have %d, want %d", number, 11)
+	}
+}
+
+// TestFreezerColdTier tests that data files moved into the cold tier are read
+// transparently, also after reopening the table, and that truncating the head
+// into a cold file moves it back into the hot tier.
+func TestFreezerColdTier(t *testing.T) {
+	t.Parallel()
+	var (
+		hot, cold = t.TempDir(), t.TempDir()
+		lock      sync.Mutex
+	)
+	f, err := newTieredTable(hot, cold, "tiered", metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 50, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Write 15 bytes 30 times, spanning 10 data files
+	writeChunks(t, f, 30, 15)
+
+	for num := uint32(0); num < 5; num++ {
+		moved, err := f.moveToColdTier(num, &lock)
+		if err != nil || !moved {
+			t.Fatalf("failed to move file %d: moved %v, err %v", num, moved, err)
+		}
+	}
+	if moved, err := f.moveToColdTier(9, &lock); err != nil || moved {
+		t.Fatalf("head file moved: moved %v, err %v", moved, err)
+	}
+	for num := 0; num < 10; num++ {
+		name := fmt.Sprintf("tiered.%04d.rdat", num)
+		if common.FileExist(filepath.Join(hot, name)) != (num >= 5) {
+			t.Fatalf("file %d in wrong hot tier state", num)
+		}
+		if common.FileExist(filepath.Join(cold, name)) != (num < 5) {
+			t.Fatalf("file %d in wrong cold tier state", num)
+		}
+	}
+	for y := 0; y < 30; y++ {
+		if got, err := f.Retrieve(uint64(y)); err != nil || !bytes.Equal(got, getChunk(15, y)) {
+			t.Fatalf("item %d mismatch after move: %x, %v", y, got, err)
+		}
+	}
+	f.Close()
+
+	// Reopen the table and truncate the head into a cold file
+	f, err = newTieredTable(hot, cold, "tiered", metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 50, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := f.truncateHead(7); err != nil {
+		t.Fatal(err)
+	}
+	if !common.FileExist(filepath.Join(hot, "tiered.0002.rdat")) || common.FileExist(filepath.Join(cold, "tiered.0002.rdat")) {
+		t.Fatal("new head file not moved into hot tier")
+	}
+	for y := 0; y < 7; y++ {
+		if got, err := f.Retrieve(uint64(y)); err != nil || !bytes.Equal(got, getChunk(15, y)) {
+			t.Fatalf("item %d mismatch after truncation: %x, %v", y, got, err)
+		}
+	}
+	batch := f.newBatch()
+	require.NoError(t, batch.AppendRaw(7, getChunk(15, 0xaa)))
+	require.NoError(t, batch.commit())
+	if got, err := f.Retrieve(7); err != nil || !bytes.Equal(got, getChunk(15, 0xaa)) {
+		t.Fatalf("appended item mismatch: %x, %v", got, err)
+	}
+}
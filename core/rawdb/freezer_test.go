@@ -283,6 +283,145 @@ func TestFreezerReadonlyValidate(t *testing.T) {
 	}
 }
 
+// Tests that tail truncation only affects the prunable tables, also across
+// reopening the freezer.
+func TestFreezerPrunableTables(t *testing.T) {
+	var (
+		tables   = map[string]bool{"a": true, "b": true}
+		prunable = map[string]bool{"b": true}
+		dir      = t.TempDir()
+		item     = make([]byte, 1024)
+	)
+	f, err := newFreezer(dir, "", false, 2049, tables, prunable, nil)
+	if err != nil {
+		t.Fatal("can't open freezer", err)
+	}
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 10; i++ {
+			if err := op.AppendRaw("a", i, item); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("b", i, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, f.TruncateTail(5))
+
+	check := func(f *Freezer) {
+		t.Helper()
+
+		if tail, _ := f.Tail(); tail != 5 {
+			t.Fatalf("tail mismatch: have %d, want %d", tail, 5)
+		}
+		if _, err := f.Ancient("a", 0); err != nil {
+			t.Fatalf("retained item missing: %v", err)
+		}
+		if _, err := f.Ancient("b", 4); err == nil {
+			t.Fatalf("pruned item present")
+		}
+		if _, err := f.Ancient("b", 5); err != nil {
+			t.Fatalf("item above tail missing: %v", err)
+		}
+	}
+	check(f)
+	require.NoError(t, f.Close())
+
+	// Reopening, both in repair and in readonly mode, should keep the tables
+	// at their own tails.
+	f, err = newFreezer(dir, "", false, 2049, tables, prunable, nil)
+	require.NoError(t, err)
+	check(f)
+	require.NoError(t, f.Close())
+
+	f, err = newFreezer(dir, "", true, 2049, tables, prunable, nil)
+	require.NoError(t, err)
+	check(f)
+	require.NoError(t, f.Close())
+}
+
+func TestFreezerRecompressTable(t *testing.T) {
+	var (
+		tables = map[string]bool{"a": false, "b": true}
+		dir    = t.TempDir()
+	)
+	f, err := NewFreezer(dir, "", false, 2049, tables)
+	if err != nil {
+		t.Fatal("can't open freezer", err)
+	}
+	item := func(i uint64) []byte {
+		return bytes.Repeat([]byte{byte(i)}, 256+int(i)*16)
+	}
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 30; i++ {
+			if err := op.AppendRaw("a", i, item(i)); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("b", i, item(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, f.TruncateTail(5))
+
+	if err := f.RecompressTable("b", FreezerCodecZstd); err == nil {
+		t.Fatal("recompressed raw table")
+	}
+	require.NoError(t, f.RecompressTable("a", FreezerCodecZstd))
+
+	check := func(f *Freezer, codec FreezerCodec) {
+		t.Helper()
+
+		if have := f.tables["a"].codec; have != codec {
+			t.Fatalf("codec mismatch: have %v, want %v", have, codec)
+		}
+		checkAncientCount(t, f, "a", 30)
+		if _, err := f.Ancient("a", 4); err == nil {
+			t.Fatalf("pruned item present")
+		}
+		for i := uint64(5); i < 30; i++ {
+			blob, err := f.Ancient("a", i)
+			if err != nil {
+				t.Fatalf("item %d missing: %v", i, err)
+			}
+			if !bytes.Equal(blob, item(i)) {
+				t.Fatalf("item %d mismatch", i)
+			}
+		}
+	}
+	check(f, FreezerCodecZstd)
+
+	// Appending after the migration should use the new codec
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		if err := op.AppendRaw("a", 30, item(30)); err != nil {
+			return err
+		}
+		return op.AppendRaw("b", 30, item(30))
+	})
+	require.NoError(t, err)
+	require.NoError(t, f.TruncateHead(30))
+	require.NoError(t, f.Close())
+
+	// The codec should be persisted across restarts, and switching back
+	// should restore the items as well.
+	f, err = NewFreezer(dir, "", false, 2049, tables)
+	require.NoError(t, err)
+	check(f, FreezerCodecZstd)
+
+	require.NoError(t, f.RecompressTable("a", FreezerCodecSnappy))
+	check(f, FreezerCodecSnappy)
+	require.NoError(t, f.Close())
+
+	f, err = NewFreezer(dir, "", true, 2049, tables)
+	require.NoError(t, err)
+	check(f, FreezerCodecSnappy)
+	require.NoError(t, f.Close())
+}
+
 func newFreezerForTesting(t *testing.T, tables map[string]bool) (*Freezer, string) {
 	t.Helper()
 
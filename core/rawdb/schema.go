@@ -85,6 +85,23 @@ var (
 	// transitionStatusKey tracks the eth2 transition status.
 	transitionStatusKey = []byte("eth2-transition")
 
+	// persistentStateIDKey tracks the id of latest stored state(for path-based only).
+	persistentStateIDKey = []byte("LastStateID")
+
+	// trieJournalKey tracks the in-memory trie node layers across restarts.
+	trieJournalKey = []byte("TrieJournal")
+
+	// stateDiffOffsetKey tracks the block number of the first item in the state
+	// diff freezer.
+	stateDiffOffsetKey = []byte("StateDiffOffset")
+
+	// scrubProgressKey tracks the number of the next block to be verified by an
+	// interrupted database scrub.
+	scrubProgressKey = []byte("ScrubProgress")
+
+	// freezerLayoutKey tracks the location of a relocated or tiered ancient store.
+	freezerLayoutKey = []byte("FreezerLayout")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -95,7 +112,10 @@ var (
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
+	txSenderLookupPrefix  = []byte("N") // txSenderLookupPrefix + sender + nonce (uint64 big endian) -> transaction hash
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	logIndexPrefix        = []byte("G") // logIndexPrefix + kind (1 byte) + address/topic + num (uint64 big endian) -> log positions
+	logIndexBlockPrefix   = []byte("g") // logIndexBlockPrefix + num (uint64 big endian) -> log index entries of the block
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
@@ -104,6 +124,13 @@ var (
 	// Path-based storage scheme of merkle patricia trie.
 	trieNodeAccountPrefix = []byte("A") // trieNodeAccountPrefix + hexPath -> trie node
 	trieNodeStoragePrefix = []byte("O") // trieNodeStoragePrefix + accountHash + hexPath -> trie node
+	stateIDPrefix         = []byte("L") // stateIDPrefix + state root -> state id
+	trieHistoryPrefix     = []byte("R") // trieHistoryPrefix + state id (uint64 big endian) -> trie reverse diff
+
+	// State reverse diffs of recent blocks and the index of the frozen ones.
+	stateDiffPrefix             = []byte("D")  // stateDiffPrefix + num (uint64 big endian) + hash -> state reverse diff
+	stateDiffAccountIndexPrefix = []byte("dA") // stateDiffAccountIndexPrefix + account hash + num (uint64 big endian) -> nil
+	stateDiffStorageIndexPrefix = []byte("dS") // stateDiffStorageIndexPrefix + account hash + storage hash + num (uint64 big endian) -> nil
 
 	PreimagePrefix = []byte("secure-key-")       // PreimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-")  // config prefix for the db
@@ -112,6 +139,9 @@ var (
 	// BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
 	BloomBitsIndexPrefix = []byte("iB")
 
+	// LogIndexPrefix is the data table of a chain indexer to track its progress
+	LogIndexPrefix = []byte("iG")
+
 	ChtPrefix           = []byte("chtRootV2-") // ChtPrefix + chtNum (uint64 big endian) -> trie root hash
 	ChtTablePrefix      = []byte("cht-")
 	ChtIndexTablePrefix = []byte("chtIndexV2-")
@@ -181,6 +211,11 @@ func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
 }
 
+// txSenderLookupKey = txSenderLookupPrefix + sender + nonce (uint64 big endian)
+func txSenderLookupKey(sender common.Address, nonce uint64) []byte {
+	return append(append(txSenderLookupPrefix, sender.Bytes()...), encodeBlockNumber(nonce)...)
+}
+
 // accountSnapshotKey = SnapshotAccountPrefix + hash
 func accountSnapshotKey(hash common.Hash) []byte {
 	return append(SnapshotAccountPrefix, hash.Bytes()...)
@@ -206,6 +241,32 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// logIndexKey = logIndexPrefix + kind (1 byte) + address/topic + num (uint64 big endian)
+func logIndexKey(entry []byte, number uint64) []byte {
+	return append(append(append([]byte{}, logIndexPrefix...), entry...), encodeBlockNumber(number)...)
+}
+
+// logIndexBlockKey = logIndexBlockPrefix + num (uint64 big endian)
+func logIndexBlockKey(number uint64) []byte {
+	return append(logIndexBlockPrefix, encodeBlockNumber(number)...)
+}
+
+// stateDiffKey = stateDiffPrefix + num (uint64 big endian) + hash
+func stateDiffKey(number uint64, hash common.Hash) []byte {
+	return append(append(stateDiffPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// stateDiffAccountIndexKey = stateDiffAccountIndexPrefix + account hash + num (uint64 big endian)
+func stateDiffAccountIndexKey(accountHash common.Hash, number uint64) []byte {
+	return append(append(stateDiffAccountIndexPrefix, accountHash.Bytes()...), encodeBlockNumber(number)...)
+}
+
+// stateDiffStorageIndexKey = stateDiffStorageIndexPrefix + account hash + storage hash + num (uint64 big endian)
+func stateDiffStorageIndexKey(accountHash, storageHash common.Hash, number uint64) []byte {
+	key := append(append(stateDiffStorageIndexPrefix, accountHash.Bytes()...), storageHash.Bytes()...)
+	return append(key, encodeBlockNumber(number)...)
+}
+
 // skeletonHeaderKey = skeletonHeaderPrefix + num (uint64 big endian)
 func skeletonHeaderKey(number uint64) []byte {
 	return append(skeletonHeaderPrefix, encodeBlockNumber(number)...)
@@ -250,6 +311,16 @@ func storageTrieNodeKey(accountHash common.Hash, path []byte) []byte {
 	return append(append(trieNodeStoragePrefix, accountHash.Bytes()...), path...)
 }
 
+// stateIDKey = stateIDPrefix + root (32 bytes)
+func stateIDKey(root common.Hash) []byte {
+	return append(stateIDPrefix, root.Bytes()...)
+}
+
+// trieHistoryKey = trieHistoryPrefix + id (uint64 big endian)
+func trieHistoryKey(id uint64) []byte {
+	return append(trieHistoryPrefix, encodeBlockNumber(id)...)
+}
+
 // IsLegacyTrieNode reports whether a provided database entry is a legacy trie
 // node. The characteristics of legacy trie node are:
 // - the key length is 32 bytes
@@ -294,3 +365,13 @@ func IsStorageTrieNode(key []byte) (bool, common.Hash, []byte) {
 	accountHash := common.BytesToHash(key[len(trieNodeStoragePrefix) : len(trieNodeStoragePrefix)+common.HashLength])
 	return true, accountHash, key[len(trieNodeStoragePrefix)+common.HashLength:]
 }
+
+// isPathTrieNode reports whether the provided key is a path-based trie node
+// key, either belonging to the account trie or to one of the storage tries.
+func isPathTrieNode(key []byte) bool {
+	if ok, _ := IsAccountTrieNode(key); ok {
+		return true
+	}
+	ok, _, _ := IsStorageTrieNode(key)
+	return ok
+}
@@ -0,0 +1,361 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package scrub implements an offline integrity check of the chain data stored
+// in the key-value store and the freezer.
+package scrub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Kinds of the chain data verified by the scrubber.
+const (
+	KindHeader       = "header"        // Canonical header, its hash and its parent link
+	KindBody         = "body"          // Block body, checked against the header roots
+	KindReceipts     = "receipts"      // Block receipts, checked against the header root
+	KindTxLookup     = "txlookup"      // Transaction lookup entries of the block
+	KindFreezerIndex = "freezer-index" // Freezer index entries, checked against the data files
+)
+
+// Repair actions taken by the scrubber.
+const (
+	ActionDeleted   = "deleted"   // Corrupt entry deleted from the key-value store
+	ActionTruncated = "truncated" // Freezer truncated below the corrupt item
+	ActionReindexed = "reindexed" // Transaction lookup entry rewritten
+)
+
+// progressInterval is the number of blocks after which the progress of the
+// scrub is persisted.
+const progressInterval = 10000
+
+// Finding is a corruption detected by the scrubber.
+type Finding struct {
+	Kind    string      `json:"kind"`             // Type of the corrupt data
+	Number  uint64      `json:"number"`           // Number of the affected block (item number for freezer index findings)
+	Hash    common.Hash `json:"hash"`             // Canonical hash of the affected block, if known
+	Ancient bool        `json:"ancient"`          // Whether the data resides in the freezer
+	Table   string      `json:"table,omitempty"`  // Freezer table of freezer index findings
+	Error   string      `json:"error"`            // Description of the corruption
+	Action  string      `json:"action,omitempty"` // Repair action taken, if any
+}
+
+// Result summarizes a scrub run.
+type Result struct {
+	From     uint64  // Number of the first block verified by the run
+	Next     uint64  // Number of the first block not verified by the run
+	Done     bool    // Whether the run reached the chain head
+	Findings int     // Number of corruptions found
+	Rewound  *uint64 // Number of the first block removed from the chain by the repair, if any
+}
+
+// Scrubber verifies the consistency of the chain data in a database, reporting
+// the findings as JSON lines.
+type Scrubber struct {
+	db     ethdb.Database
+	config *params.ChainConfig
+	report *json.Encoder
+	repair bool
+
+	frozen uint64  // Number of items in the chain freezer
+	tail   uint64  // Number of the first block with bodies and receipts retained
+	rewind *uint64 // Number of the first block to remove from the chain on repair
+	result *Result
+}
+
+// New creates a scrubber over the given database, writing findings to the
+// report. If repair is set, the corrupt entries are deleted from the database
+// and the chain is rewound below them, so that they get synced again.
+func New(db ethdb.Database, report io.Writer, repair bool) (*Scrubber, error) {
+	genesis := rawdb.ReadCanonicalHash(db, 0)
+	if genesis == (common.Hash{}) {
+		return nil, errors.New("genesis block not found")
+	}
+	config := rawdb.ReadChainConfig(db, genesis)
+	if config == nil {
+		return nil, errors.New("chain config not found")
+	}
+	return &Scrubber{
+		db:     db,
+		config: config,
+		report: json.NewEncoder(report),
+		repair: repair,
+	}, nil
+}
+
+// Run verifies the chain from the progress of the last interrupted run, or from
+// genesis if the last run completed or restart is set. The run is interrupted,
+// persisting its progress, when the stop channel is closed.
+func (s *Scrubber) Run(restart bool, stop <-chan struct{}) (*Result, error) {
+	var start uint64
+	if progress := rawdb.ReadScrubProgress(s.db); progress != nil && !restart {
+		start = *progress
+	}
+	s.result = &Result{From: start, Next: start}
+	s.frozen, _ = s.db.Ancients()
+	s.tail, _ = s.db.Tail()
+
+	// Check the freezer index files first, they're cheap to verify
+	if start == 0 {
+		if err := s.checkFreezerIndex(); err != nil {
+			return nil, err
+		}
+	}
+	headHeader := rawdb.ReadHeadHeader(s.db)
+	if headHeader == nil {
+		return nil, errors.New("head header not found")
+	}
+	var (
+		head    = headHeader.Number.Uint64()
+		blocks  = s.headBlock()
+		logged  = time.Now()
+		started = time.Now()
+	)
+	log.Info("Scrubbing chain data", "from", start, "head", head, "blocks", blocks, "repair", s.repair)
+
+	for number := start; number <= head; number++ {
+		select {
+		case <-stop:
+			log.Info("Scrubbing interrupted", "next", number)
+			rawdb.WriteScrubProgress(s.db, number)
+			s.result.Next = number
+			return s.result, s.finalize()
+		default:
+		}
+		if err := s.checkBlock(number, number <= blocks); err != nil {
+			return nil, err
+		}
+		if number%progressInterval == 0 {
+			rawdb.WriteScrubProgress(s.db, number+1)
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Scrubbing chain data", "number", number, "head", head, "findings", s.result.Findings, "elapsed", common.PrettyDuration(time.Since(started)))
+			logged = time.Now()
+		}
+	}
+	rawdb.DeleteScrubProgress(s.db)
+	s.result.Next, s.result.Done = head+1, true
+	log.Info("Scrubbed chain data", "from", start, "head", head, "findings", s.result.Findings, "elapsed", common.PrettyDuration(time.Since(started)))
+	return s.result, s.finalize()
+}
+
+// headBlock returns the number of the last block whose body and receipts are
+// expected to be present.
+func (s *Scrubber) headBlock() uint64 {
+	var head uint64
+	for _, hash := range []common.Hash{rawdb.ReadHeadBlockHash(s.db), rawdb.ReadHeadFastBlockHash(s.db)} {
+		if number := rawdb.ReadHeaderNumber(s.db, hash); number != nil && *number > head {
+			head = *number
+		}
+	}
+	return head
+}
+
+// checkFreezerIndex verifies the index files of the chain freezer.
+func (s *Scrubber) checkFreezerIndex() error {
+	if _, err := s.db.AncientDatadir(); err != nil {
+		return nil // No chain freezer
+	}
+	issues, err := rawdb.CheckFreezerIndex(s.db)
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		finding := &Finding{
+			Kind:    KindFreezerIndex,
+			Number:  issue.Number,
+			Hash:    rawdb.ReadCanonicalHash(s.db, issue.Number),
+			Ancient: true,
+			Table:   issue.Table,
+			Error:   issue.Err.Error(),
+		}
+		if err := s.found(finding, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkBlock verifies the header of the canonical block with the given number,
+// and optionally its body, receipts and transaction lookup entries.
+func (s *Scrubber) checkBlock(number uint64, full bool) error {
+	var (
+		hash    = rawdb.ReadCanonicalHash(s.db, number)
+		ancient = number < s.frozen
+		header  *types.Header
+		fail    = func(kind string, format string, args ...interface{}) error {
+			finding := &Finding{Kind: kind, Number: number, Hash: hash, Ancient: ancient, Error: fmt.Sprintf(format, args...)}
+			return s.found(finding, true)
+		}
+	)
+	if hash == (common.Hash{}) {
+		return fail(KindHeader, "canonical hash missing")
+	}
+	if header = rawdb.ReadHeader(s.db, hash, number); header == nil {
+		return fail(KindHeader, "header missing or undecodable")
+	}
+	if have := header.Hash(); have != hash {
+		return fail(KindHeader, "header hash mismatch: have %x, want %x", have, hash)
+	}
+	if header.Number.Uint64() != number {
+		return fail(KindHeader, "header number mismatch: have %d, want %d", header.Number, number)
+	}
+	if n := rawdb.ReadHeaderNumber(s.db, hash); n == nil || *n != number {
+		return fail(KindHeader, "hash to number mapping missing or invalid")
+	}
+	if number > 0 {
+		if parent := rawdb.ReadCanonicalHash(s.db, number-1); header.ParentHash != parent {
+			return fail(KindHeader, "parent hash mismatch: have %x, want %x", header.ParentHash, parent)
+		}
+	}
+	if !full || number < s.tail {
+		return nil
+	}
+	// Verify the body against the header
+	body := rawdb.ReadBody(s.db, hash, number)
+	if body == nil {
+		return fail(KindBody, "body missing or undecodable")
+	}
+	hasher := trie.NewStackTrie(nil)
+	if have := types.DeriveSha(types.Transactions(body.Transactions), hasher); have != header.TxHash {
+		return fail(KindBody, "transaction root mismatch: have %x, want %x", have, header.TxHash)
+	}
+	if have := types.CalcUncleHash(body.Uncles); have != header.UncleHash {
+		return fail(KindBody, "uncle hash mismatch: have %x, want %x", have, header.UncleHash)
+	}
+	if header.WithdrawalsHash != nil {
+		if have := types.DeriveSha(types.Withdrawals(body.Withdrawals), hasher); have != *header.WithdrawalsHash {
+			return fail(KindBody, "withdrawals root mismatch: have %x, want %x", have, *header.WithdrawalsHash)
+		}
+	}
+	// Verify the receipts against the header
+	receipts := rawdb.ReadRawReceipts(s.db, hash, number)
+	if receipts == nil {
+		return fail(KindReceipts, "receipts missing or undecodable")
+	}
+	if err := receipts.DeriveFields(s.config, hash, number, header.Time, header.BaseFee, body.Transactions); err != nil {
+		return fail(KindReceipts, "receipts inconsistent with body: %v", err)
+	}
+	if have := types.DeriveSha(receipts, hasher); have != header.ReceiptHash {
+		return fail(KindReceipts, "receipt root mismatch: have %x, want %x", have, header.ReceiptHash)
+	}
+	// Verify the transaction lookup entries, if the block is indexed
+	indexTail := rawdb.ReadTxIndexTail(s.db)
+	for _, tx := range body.Transactions {
+		entry := rawdb.ReadTxLookupEntry(s.db, tx.Hash())
+		switch {
+		case entry == nil && indexTail != nil && number >= *indexTail:
+			err := s.found(&Finding{Kind: KindTxLookup, Number: number, Hash: hash, Error: fmt.Sprintf("lookup entry of transaction %x missing", tx.Hash())}, false)
+			if err != nil {
+				return err
+			}
+		case entry != nil && *entry != number:
+			err := s.found(&Finding{Kind: KindTxLookup, Number: number, Hash: hash, Error: fmt.Sprintf("lookup entry of transaction %x points to block %d", tx.Hash(), *entry)}, false)
+			if err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		if s.repair {
+			rawdb.WriteTxLookupEntries(s.db, number, []common.Hash{tx.Hash()})
+		}
+	}
+	return nil
+}
+
+// found reports a finding, deleting the corrupt data if repair is enabled. If
+// the chain is corrupt, it will be rewound below the affected block.
+func (s *Scrubber) found(finding *Finding, chain bool) error {
+	s.result.Findings++
+	if s.repair {
+		switch {
+		case !chain:
+			finding.Action = ActionReindexed
+		case finding.Ancient:
+			finding.Action = ActionTruncated
+		default:
+			finding.Action = ActionDeleted
+			switch finding.Kind {
+			case KindHeader:
+				if finding.Hash != (common.Hash{}) {
+					rawdb.DeleteHeader(s.db, finding.Hash, finding.Number)
+				}
+				rawdb.DeleteCanonicalHash(s.db, finding.Number)
+			case KindBody:
+				rawdb.DeleteBody(s.db, finding.Hash, finding.Number)
+			case KindReceipts:
+				rawdb.DeleteReceipts(s.db, finding.Hash, finding.Number)
+			}
+		}
+		if chain && (s.rewind == nil || finding.Number < *s.rewind) {
+			number := finding.Number
+			s.rewind = &number
+		}
+	}
+	log.Warn("Found corrupt chain data", "kind", finding.Kind, "number", finding.Number, "hash", finding.Hash, "err", finding.Error)
+	return s.report.Encode(finding)
+}
+
+// finalize rewinds the chain below the first corrupt block if repair is enabled,
+// truncating the freezer if needed.
+func (s *Scrubber) finalize() error {
+	if s.rewind == nil {
+		return nil
+	}
+	number := *s.rewind
+	if number == 0 {
+		return errors.New("genesis block corrupt, cannot rewind")
+	}
+	// Truncating the freezer to its tail would leave nothing verified behind
+	if number < s.frozen && s.tail > 0 && number <= s.tail {
+		return fmt.Errorf("freezer corrupt at block %d, at or below its tail %d, cannot rewind", number, s.tail)
+	}
+	if number < s.frozen {
+		if err := s.db.TruncateHead(number); err != nil {
+			return err
+		}
+		s.frozen = number
+	}
+	parent := rawdb.ReadCanonicalHash(s.db, number-1)
+	if n := rawdb.ReadHeaderNumber(s.db, rawdb.ReadHeadHeaderHash(s.db)); n == nil || *n >= number {
+		rawdb.WriteHeadHeaderHash(s.db, parent)
+	}
+	if n := rawdb.ReadHeaderNumber(s.db, rawdb.ReadHeadFastBlockHash(s.db)); n == nil || *n >= number {
+		rawdb.WriteHeadFastBlockHash(s.db, parent)
+	}
+	if n := rawdb.ReadHeaderNumber(s.db, rawdb.ReadHeadBlockHash(s.db)); n == nil || *n >= number {
+		rawdb.WriteHeadBlockHash(s.db, parent)
+	}
+	if n := rawdb.ReadHeaderNumber(s.db, rawdb.ReadFinalizedBlockHash(s.db)); n != nil && *n >= number {
+		rawdb.WriteFinalizedBlockHash(s.db, parent)
+	}
+	s.result.Rewound = &number
+	log.Warn("Rewound chain below corrupt data", "number", number, "parent", parent)
+	return nil
+}
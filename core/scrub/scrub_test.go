@@ -0,0 +1,270 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package scrub
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	testBlocks  = 64 // Number of blocks in the test chain
+	testAncient = 32 // Number of blocks in the freezer
+)
+
+// newTestDatabase creates a database holding a chain with a transaction in
+// every block, the first part of which is stored in the freezer.
+func newTestDatabase(t *testing.T) (ethdb.Database, []*types.Block) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSigner(genesis.Config)
+	)
+	_, blocks, receipts := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), testBlocks, func(i int, g *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), common.Address{0xaa}, big.NewInt(1), params.TxGas, g.BaseFee(), nil), signer, key)
+		g.AddTx(tx)
+	})
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	chain, err := core.NewBlockChain(db, nil, genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if _, err := chain.InsertHeaderChain(headers); err != nil {
+		t.Fatalf("failed to insert headers: %v", err)
+	}
+	if _, err := chain.InsertReceiptChain(blocks, receipts, testAncient); err != nil {
+		t.Fatalf("failed to insert receipts: %v", err)
+	}
+	if frozen, _ := db.Ancients(); frozen == 0 || frozen > testAncient+1 {
+		t.Fatalf("unexpected freezer size %d", frozen)
+	}
+	return db, blocks
+}
+
+// scrub runs a scrubber over the database, returning the findings.
+func scrub(t *testing.T, db ethdb.Database, repair bool, stop chan struct{}) (*Result, []*Finding) {
+	t.Helper()
+
+	var report bytes.Buffer
+	scrubber, err := New(db, &report, repair)
+	if err != nil {
+		t.Fatalf("failed to create scrubber: %v", err)
+	}
+	if stop == nil {
+		stop = make(chan struct{})
+	}
+	result, err := scrubber.Run(false, stop)
+	if err != nil {
+		t.Fatalf("failed to scrub database: %v", err)
+	}
+	var (
+		findings []*Finding
+		dec      = json.NewDecoder(&report)
+	)
+	for dec.More() {
+		finding := new(Finding)
+		if err := dec.Decode(finding); err != nil {
+			t.Fatalf("failed to decode report: %v", err)
+		}
+		findings = append(findings, finding)
+	}
+	if len(findings) != result.Findings {
+		t.Fatalf("finding count mismatch: have %d reported, %d counted", len(findings), result.Findings)
+	}
+	return result, findings
+}
+
+func TestScrubClean(t *testing.T) {
+	db, _ := newTestDatabase(t)
+
+	result, findings := scrub(t, db, false, nil)
+	if len(findings) != 0 {
+		t.Fatalf("unexpected findings: %v", findings[0])
+	}
+	if !result.Done || result.Next != testBlocks+1 {
+		t.Fatalf("scrub incomplete: next %d, done %v", result.Next, result.Done)
+	}
+}
+
+func TestScrubResume(t *testing.T) {
+	db, _ := newTestDatabase(t)
+
+	stop := make(chan struct{})
+	close(stop)
+	if result, _ := scrub(t, db, false, stop); result.Done {
+		t.Fatal("interrupted scrub completed")
+	}
+	rawdb.WriteScrubProgress(db, 40)
+
+	result, _ := scrub(t, db, false, nil)
+	if result.From != 40 || !result.Done {
+		t.Fatalf("scrub not resumed: from %d, done %v", result.From, result.Done)
+	}
+	if rawdb.ReadScrubProgress(db) != nil {
+		t.Fatal("progress retained after completion")
+	}
+}
+
+func TestScrubCorruptBody(t *testing.T) {
+	db, blocks := newTestDatabase(t)
+
+	// Swap the body of a block in the key-value store with its child's
+	var (
+		block = blocks[testBlocks-10]
+		child = blocks[testBlocks-9]
+	)
+	rawdb.WriteBody(db, block.Hash(), block.NumberU64(), child.Body())
+
+	_, findings := scrub(t, db, false, nil)
+	if len(findings) != 1 {
+		t.Fatalf("finding count mismatch: have %d, want 1", len(findings))
+	}
+	if f := findings[0]; f.Kind != KindBody || f.Number != block.NumberU64() || f.Ancient || f.Action != "" {
+		t.Fatalf("unexpected finding: %+v", f)
+	}
+	// Repair and check the chain is rewound below the corrupt block
+	result, findings := scrub(t, db, true, nil)
+	if len(findings) != 1 || findings[0].Action != ActionDeleted {
+		t.Fatalf("unexpected findings: %v", findings)
+	}
+	if result.Rewound == nil || *result.Rewound != block.NumberU64() {
+		t.Fatalf("rewind mismatch: have %v, want %d", result.Rewound, block.NumberU64())
+	}
+	if rawdb.ReadBody(db, block.Hash(), block.NumberU64()) != nil {
+		t.Fatal("corrupt body not deleted")
+	}
+	if head := rawdb.ReadHeadFastBlockHash(db); head != block.ParentHash() {
+		t.Fatalf("head block mismatch: have %x, want %x", head, block.ParentHash())
+	}
+	if _, findings := scrub(t, db, false, nil); len(findings) != 0 {
+		t.Fatalf("unexpected findings after repair: %v", findings[0])
+	}
+}
+
+func TestScrubCorruptAncientReceipts(t *testing.T) {
+	db, blocks := newTestDatabase(t)
+
+	// Drop the receipts of a frozen block by rewriting the freezer head
+	block := blocks[testAncient-5]
+	if err := db.TruncateHead(block.NumberU64()); err != nil {
+		t.Fatal(err)
+	}
+	_, err := db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		number := block.NumberU64()
+		if err := op.AppendRaw(rawdb.ChainFreezerHashTable, number, block.Hash().Bytes()); err != nil {
+			return err
+		}
+		if err := op.Append(rawdb.ChainFreezerHeaderTable, number, block.Header()); err != nil {
+			return err
+		}
+		if err := op.Append(rawdb.ChainFreezerBodiesTable, number, block.Body()); err != nil {
+			return err
+		}
+		if err := op.Append(rawdb.ChainFreezerReceiptTable, number, []*types.ReceiptForStorage{}); err != nil {
+			return err
+		}
+		return op.Append(rawdb.ChainFreezerDifficultyTable, number, block.Difficulty())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, findings := scrub(t, db, false, nil)
+	if len(findings) == 0 {
+		t.Fatal("no findings")
+	}
+	if f := findings[0]; f.Kind != KindReceipts || f.Number != block.NumberU64() || !f.Ancient {
+		t.Fatalf("unexpected finding: %+v", f)
+	}
+}
+
+func TestScrubTxLookup(t *testing.T) {
+	db, blocks := newTestDatabase(t)
+
+	block := blocks[testBlocks-5]
+	tx := block.Transactions()[0]
+	rawdb.WriteTxLookupEntries(db, 1, []common.Hash{tx.Hash()})
+
+	_, findings := scrub(t, db, true, nil)
+	if len(findings) != 1 {
+		t.Fatalf("finding count mismatch: have %d, want 1", len(findings))
+	}
+	if f := findings[0]; f.Kind != KindTxLookup || f.Number != block.NumberU64() || f.Action != ActionReindexed {
+		t.Fatalf("unexpected finding: %+v", f)
+	}
+	if number := rawdb.ReadTxLookupEntry(db, tx.Hash()); number == nil || *number != block.NumberU64() {
+		t.Fatalf("lookup entry not repaired: %v", number)
+	}
+}
+
+func TestScrubUnreadableFreezer(t *testing.T) {
+	db, _ := newTestDatabase(t)
+
+	// Cut the bodies data file short, the table can't be opened read-only anymore
+	ancient, err := db.AncientDatadir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(filepath.Join(ancient, "bodies.0000.cdat"), 10); err != nil {
+		t.Fatal(err)
+	}
+	frozen, _ := db.Ancients()
+
+	var report bytes.Buffer
+	scrubber, err := New(db, &report, true)
+	if err != nil {
+		t.Fatalf("failed to create scrubber: %v", err)
+	}
+	if _, err := scrubber.Run(false, make(chan struct{})); err == nil {
+		t.Fatal("expected error scrubbing unreadable freezer")
+	}
+	// The table is not known to be corrupt at any particular item
+	if report.Len() != 0 {
+		t.Fatalf("unexpected findings: %s", report.String())
+	}
+	if have, _ := db.Ancients(); have != frozen {
+		t.Fatalf("freezer truncated: have %d items, want %d", have, frozen)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+// errHistoricProof is returned when attempting to prove historic state, which
+// is served without the backing tries.
+var errHistoricProof = errors.New("proofs are not supported on historic state")
+
+// HistoryReader provides the values of accounts and storage slots at a historic
+// state, as recorded by the reverse diffs of the subsequent blocks.
+type HistoryReader interface {
+	// Account returns the slim RLP encoded account at the historic state. The
+	// flag reports whether the account was modified since, otherwise its value
+	// is unchanged and needs to be resolved from the current state instead.
+	Account(hash common.Hash) ([]byte, bool, error)
+
+	// Storage returns the RLP encoded storage slot at the historic state. The
+	// flag reports whether the slot was modified since, otherwise its value is
+	// unchanged and needs to be resolved from the current state instead.
+	Storage(accountHash, storageHash common.Hash) ([]byte, bool, error)
+}
+
+// historicDatabase is a state database serving a historic state by applying the
+// reverse diffs on top of the current state snapshot, without needing the tries
+// of the historic state.
+type historicDatabase struct {
+	Database // Underlying database for retrieving contract codes
+
+	root    common.Hash       // Root hash of the historic state
+	base    snapshot.Snapshot // Snapshot of the current state
+	history HistoryReader     // Reverse diffs between the historic and current state
+}
+
+// NewHistoricDatabase creates a state database for the historic state with the
+// given root, resolving accounts and storage slots through the reverse diffs,
+// falling back to the current state snapshot for unmodified entries.
+//
+// The state can be modified, but it can't be hashed or committed meaningfully.
+func NewHistoricDatabase(db Database, root common.Hash, base snapshot.Snapshot, history HistoryReader) Database {
+	return &historicDatabase{
+		Database: db,
+		root:     root,
+		base:     base,
+		history:  history,
+	}
+}
+
+// OpenTrie opens the account trie of the historic state.
+func (db *historicDatabase) OpenTrie(root common.Hash) (Trie, error) {
+	if root != db.root {
+		return nil, fmt.Errorf("historic state %x not available, have %x", root, db.root)
+	}
+	return &historicTrie{db: db, root: root, dirties: make(map[common.Hash][]byte)}, nil
+}
+
+// OpenStorageTrie opens the storage trie of an account in the historic state.
+func (db *historicDatabase) OpenStorageTrie(stateRoot common.Hash, addrHash, root common.Hash) (Trie, error) {
+	if stateRoot != db.root {
+		return nil, fmt.Errorf("historic state %x not available, have %x", stateRoot, db.root)
+	}
+	return &historicTrie{db: db, owner: addrHash, root: root, dirties: make(map[common.Hash][]byte)}, nil
+}
+
+// CopyTrie returns an independent copy of the given trie.
+func (db *historicDatabase) CopyTrie(t Trie) Trie {
+	if t, ok := t.(*historicTrie); ok {
+		return t.copy()
+	}
+	return db.Database.CopyTrie(t)
+}
+
+// historicTrie is an account or storage trie of a historic state. It has no
+// backing nodes, it resolves the values directly from the historic database and
+// keeps any modifications in memory.
+type historicTrie struct {
+	db      *historicDatabase
+	owner   common.Hash            // Account hash of storage tries, empty for the account trie
+	root    common.Hash            // Root hash of the trie in the historic state
+	dirties map[common.Hash][]byte // Local modifications, nil values denote deletions
+}
+
+// copy returns an independent copy of the trie.
+func (t *historicTrie) copy() *historicTrie {
+	cpy := &historicTrie{db: t.db, owner: t.owner, root: t.root, dirties: make(map[common.Hash][]byte, len(t.dirties))}
+	for hash, blob := range t.dirties {
+		cpy.dirties[hash] = blob
+	}
+	return cpy
+}
+
+// GetKey returns nil as preimages are not tracked.
+func (t *historicTrie) GetKey([]byte) []byte {
+	return nil
+}
+
+// GetStorage returns the value of the storage slot, stripped of its RLP encoding
+// as done by the state trie.
+func (t *historicTrie) GetStorage(addr common.Address, key []byte) ([]byte, error) {
+	hash := crypto.Keccak256Hash(key)
+
+	blob, ok := t.dirties[hash]
+	if !ok {
+		var err error
+		if blob, ok, err = t.db.history.Storage(t.owner, hash); err != nil {
+			return nil, err
+		}
+		if !ok {
+			if blob, err = t.db.base.Storage(t.owner, hash); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	_, content, _, err := rlp.Split(blob)
+	return content, err
+}
+
+// GetAccount returns the account with the given address.
+func (t *historicTrie) GetAccount(address common.Address) (*types.StateAccount, error) {
+	hash := crypto.Keccak256Hash(address.Bytes())
+
+	blob, ok := t.dirties[hash]
+	if !ok {
+		var err error
+		if blob, ok, err = t.db.history.Account(hash); err != nil {
+			return nil, err
+		}
+		if !ok {
+			if blob, err = t.db.base.AccountRLP(hash); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	return types.FullAccount(blob)
+}
+
+// UpdateStorage sets the value of the storage slot in memory.
+func (t *historicTrie) UpdateStorage(addr common.Address, key, value []byte) error {
+	blob, err := rlp.EncodeToBytes(value)
+	if err != nil {
+		return err
+	}
+	t.dirties[crypto.Keccak256Hash(key)] = blob
+	return nil
+}
+
+// UpdateAccount sets the account in memory.
+func (t *historicTrie) UpdateAccount(address common.Address, account *types.StateAccount) error {
+	t.dirties[crypto.Keccak256Hash(address.Bytes())] = types.SlimAccountRLP(*account)
+	return nil
+}
+
+// DeleteStorage removes the storage slot in memory.
+func (t *historicTrie) DeleteStorage(addr common.Address, key []byte) error {
+	t.dirties[crypto.Keccak256Hash(key)] = nil
+	return nil
+}
+
+// DeleteAccount removes the account in memory.
+func (t *historicTrie) DeleteAccount(address common.Address) error {
+	t.dirties[crypto.Keccak256Hash(address.Bytes())] = nil
+	return nil
+}
+
+// Hash returns the root hash of the trie in the historic state, regardless of
+// any modifications made since.
+func (t *historicTrie) Hash() common.Hash {
+	return t.root
+}
+
+// Commit returns the root hash of the trie in the historic state, there are no
+// nodes to be committed.
+func (t *historicTrie) Commit(collectLeaf bool) (common.Hash, *trienode.NodeSet) {
+	return t.root, nil
+}
+
+// NodeIterator returns an empty iterator, as the historic state has no nodes.
+func (t *historicTrie) NodeIterator(startKey []byte) trie.NodeIterator {
+	return trie.NewEmpty(nil).NodeIterator(startKey)
+}
+
+// Prove returns an error, as the historic state has no nodes to prove with.
+func (t *historicTrie) Prove(key []byte, fromLevel uint, proofDb ethdb.KeyValueWriter) error {
+	return errHistoricProof
+}
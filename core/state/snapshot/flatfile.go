@@ -0,0 +1,452 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// The flat file is a portable serialization of the state at a single root. It
+// starts with a magic marker, followed by a sequence of checksummed chunks:
+//
+//	chunk := length (uint32) || kind (byte) || rlp(payload) || crc32c(kind || rlp(payload))
+//
+// The first chunk is a header carrying the state root, the last one a footer
+// with the entry counts, and the ones in between a batch of entries each. The
+// entries are the accounts in hash order, every account followed by its code
+// (unless it was already exported) and its storage slots in hash order.
+const (
+	flatFileVersion = 1                       // Version of the flat file format
+	flatChunkSize   = 1024 * 1024             // Target size of the entries in a single chunk
+	flatChunkLimit  = 16 * flatChunkSize      // Maximum accepted size of a chunk on import
+	flatLogInterval = 8 * time.Second         // Time between two progress reports
+	flatHeaderChunk = byte(0)                 // Chunk kind of the file header
+	flatEntryChunk  = byte(1)                 // Chunk kind of a batch of entries
+	flatFooterChunk = byte(2)                 // Chunk kind of the file footer
+	flatAccount     = uint8(0)                // Entry kind of an account in slim format
+	flatStorage     = uint8(1)                // Entry kind of a storage slot of the last account
+	flatCode        = uint8(2)                // Entry kind of a contract code
+	flatMagic       = "\x00geth-snapshot\x00" // Marker at the start of a flat file
+)
+
+var (
+	// errFlatChecksum is returned if a chunk of the flat file is corrupted.
+	errFlatChecksum = errors.New("flat file checksum mismatch")
+
+	// crc32c is the checksum table of the flat file chunks.
+	crc32c = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// flatHeader is the first chunk of a flat file.
+type flatHeader struct {
+	Version uint64
+	Root    common.Hash
+}
+
+// flatEntry is a single account, storage slot or code of a flat file.
+type flatEntry struct {
+	Kind  uint8
+	Hash  common.Hash
+	Value []byte
+}
+
+// flatFooter is the last chunk of a flat file.
+type flatFooter struct {
+	Accounts uint64
+	Slots    uint64
+	Codes    uint64
+}
+
+// flatWriter batches the entries of a flat file into chunks.
+type flatWriter struct {
+	w       io.Writer
+	entries []flatEntry
+	size    int
+}
+
+// writeChunk encodes a payload into a checksummed chunk.
+func (fw *flatWriter) writeChunk(kind byte, payload interface{}) error {
+	blob, err := rlp.EncodeToBytes(payload)
+	if err != nil {
+		return err
+	}
+	chunk := make([]byte, 4+1+len(blob)+4)
+	binary.BigEndian.PutUint32(chunk, uint32(1+len(blob)))
+	chunk[4] = kind
+	copy(chunk[5:], blob)
+	binary.BigEndian.PutUint32(chunk[5+len(blob):], crc32.Checksum(chunk[4:5+len(blob)], crc32c))
+
+	_, err = fw.w.Write(chunk)
+	return err
+}
+
+// add queues an entry, writing out the chunk once it grows large enough.
+func (fw *flatWriter) add(kind uint8, hash common.Hash, value []byte) error {
+	fw.entries = append(fw.entries, flatEntry{Kind: kind, Hash: hash, Value: common.CopyBytes(value)})
+	fw.size += common.HashLength + len(value)
+	if fw.size < flatChunkSize {
+		return nil
+	}
+	return fw.flush()
+}
+
+// flush writes out the queued entries.
+func (fw *flatWriter) flush() error {
+	if len(fw.entries) == 0 {
+		return nil
+	}
+	if err := fw.writeChunk(flatEntryChunk, fw.entries); err != nil {
+		return err
+	}
+	fw.entries, fw.size = fw.entries[:0], 0
+	return nil
+}
+
+// readFlatChunk reads and verifies the next chunk of a flat file.
+func readFlatChunk(r io.Reader) (byte, []byte, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(prefix[:])
+	if size == 0 || size > flatChunkLimit {
+		return 0, nil, fmt.Errorf("invalid flat file chunk size %d", size)
+	}
+	chunk := make([]byte, size+4)
+	if _, err := io.ReadFull(r, chunk); err != nil {
+		return 0, nil, err
+	}
+	if crc32.Checksum(chunk[:size], crc32c) != binary.BigEndian.Uint32(chunk[size:]) {
+		return 0, nil, errFlatChecksum
+	}
+	return chunk[0], chunk[1:size], nil
+}
+
+// Export writes the state at the given root into a flat file, which can be
+// used to bootstrap the snapshot and the state trie of another node without
+// syncing. The contract codes are read from the supplied database.
+func (t *Tree) Export(root common.Hash, codedb ethdb.KeyValueReader, w io.Writer) error {
+	accIt, err := t.AccountIterator(root, common.Hash{})
+	if err != nil {
+		return err
+	}
+	defer accIt.Release()
+
+	fw := &flatWriter{w: w}
+	if _, err := io.WriteString(w, flatMagic); err != nil {
+		return err
+	}
+	if err := fw.writeChunk(flatHeaderChunk, &flatHeader{Version: flatFileVersion, Root: root}); err != nil {
+		return err
+	}
+	var (
+		footer flatFooter
+		codes  = make(map[common.Hash]struct{})
+		start  = time.Now()
+		logged = time.Now()
+	)
+	log.Info("Exporting snapshot", "root", root)
+	for accIt.Next() {
+		blob := accIt.Account()
+		account, err := types.FullAccount(blob)
+		if err != nil {
+			return err
+		}
+		if err := fw.add(flatAccount, accIt.Hash(), blob); err != nil {
+			return err
+		}
+		footer.Accounts++
+
+		// Export the contract code along with the first account using it
+		codeHash := common.BytesToHash(account.CodeHash)
+		if _, ok := codes[codeHash]; !ok && codeHash != types.EmptyCodeHash {
+			code := rawdb.ReadCode(codedb, codeHash)
+			if len(code) == 0 {
+				return fmt.Errorf("missing code %x of account %x", codeHash, accIt.Hash())
+			}
+			if err := fw.add(flatCode, codeHash, code); err != nil {
+				return err
+			}
+			codes[codeHash] = struct{}{}
+			footer.Codes++
+		}
+		if account.Root != types.EmptyRootHash {
+			stIt, err := t.StorageIterator(root, accIt.Hash(), common.Hash{})
+			if err != nil {
+				return err
+			}
+			for stIt.Next() {
+				if err := fw.add(flatStorage, stIt.Hash(), stIt.Slot()); err != nil {
+					stIt.Release()
+					return err
+				}
+				footer.Slots++
+			}
+			err = stIt.Error()
+			stIt.Release()
+			if err != nil {
+				return err
+			}
+		}
+		if time.Since(logged) > flatLogInterval {
+			log.Info("Exporting snapshot", "at", accIt.Hash(), "accounts", footer.Accounts, "slots", footer.Slots,
+				"codes", footer.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := accIt.Error(); err != nil {
+		return err
+	}
+	if err := fw.flush(); err != nil {
+		return err
+	}
+	if err := fw.writeChunk(flatFooterChunk, &footer); err != nil {
+		return err
+	}
+	log.Info("Exported snapshot", "root", root, "accounts", footer.Accounts, "slots", footer.Slots,
+		"codes", footer.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// flatImporter rebuilds the snapshot and the state trie from the entries of a
+// flat file.
+type flatImporter struct {
+	batch ethdb.Batch
+
+	accTrie *trie.StackTrie // Account trie, fed as the accounts are completed
+	stTrie  *trie.StackTrie // Storage trie of the current account, nil if no slots yet
+
+	account     *types.StateAccount // Current account, waiting for its storage slots
+	accountHash common.Hash         // Hash of the current account
+	slotHash    *common.Hash        // Hash of the last slot of the current account
+
+	codes  map[common.Hash]bool // Referenced code hashes, flagging whether they were imported
+	counts flatFooter           // Number of entries imported
+	size   common.StorageSize   // Size of the imported snapshot entries
+}
+
+// writeNode persists a trie node in hash-based scheme.
+func (im *flatImporter) writeNode(owner common.Hash, path []byte, hash common.Hash, blob []byte) {
+	rawdb.WriteTrieNode(im.batch, owner, path, hash, blob, rawdb.HashScheme)
+}
+
+// process imports a single entry of the flat file.
+func (im *flatImporter) process(entry *flatEntry) error {
+	switch entry.Kind {
+	case flatAccount:
+		if im.account != nil && bytes.Compare(entry.Hash[:], im.accountHash[:]) <= 0 {
+			return fmt.Errorf("account %x out of order", entry.Hash)
+		}
+		if err := im.finishAccount(); err != nil {
+			return err
+		}
+		account, err := types.FullAccount(entry.Value)
+		if err != nil {
+			return fmt.Errorf("invalid account %x: %v", entry.Hash, err)
+		}
+		if codeHash := common.BytesToHash(account.CodeHash); codeHash != types.EmptyCodeHash && !im.codes[codeHash] {
+			im.codes[codeHash] = false
+		}
+		im.account, im.accountHash, im.slotHash = account, entry.Hash, nil
+		rawdb.WriteAccountSnapshot(im.batch, entry.Hash, entry.Value)
+		im.counts.Accounts++
+		im.size += common.StorageSize(1 + common.HashLength + len(entry.Value))
+
+	case flatStorage:
+		if im.account == nil {
+			return fmt.Errorf("storage slot %x without account", entry.Hash)
+		}
+		if im.slotHash != nil && bytes.Compare(entry.Hash[:], im.slotHash[:]) <= 0 {
+			return fmt.Errorf("storage slot %x of account %x out of order", entry.Hash, im.accountHash)
+		}
+		if im.stTrie == nil {
+			im.stTrie = trie.NewStackTrieWithOwner(im.writeNode, im.accountHash)
+		}
+		if err := im.stTrie.Update(entry.Hash[:], entry.Value); err != nil {
+			return err
+		}
+		slot := entry.Hash
+		im.slotHash = &slot
+		rawdb.WriteStorageSnapshot(im.batch, im.accountHash, entry.Hash, entry.Value)
+		im.counts.Slots++
+		im.size += common.StorageSize(1 + 2*common.HashLength + len(entry.Value))
+
+	case flatCode:
+		if crypto.Keccak256Hash(entry.Value) != entry.Hash {
+			return fmt.Errorf("code hash mismatch for %x", entry.Hash)
+		}
+		rawdb.WriteCode(im.batch, entry.Hash, entry.Value)
+		im.codes[entry.Hash] = true
+		im.counts.Codes++
+
+	default:
+		return fmt.Errorf("unknown entry kind %d", entry.Kind)
+	}
+	if im.batch.ValueSize() >= ethdb.IdealBatchSize {
+		if err := im.batch.Write(); err != nil {
+			return err
+		}
+		im.batch.Reset()
+	}
+	return nil
+}
+
+// finishAccount verifies the storage root of the current account and inserts
+// it into the account trie.
+func (im *flatImporter) finishAccount() error {
+	if im.account == nil {
+		return nil
+	}
+	root := types.EmptyRootHash
+	if im.stTrie != nil {
+		var err error
+		if root, err = im.stTrie.Commit(); err != nil {
+			return err
+		}
+		im.stTrie = nil
+	}
+	if root != im.account.Root {
+		return fmt.Errorf("storage root mismatch for account %x: have %x, want %x", im.accountHash, root, im.account.Root)
+	}
+	blob, err := rlp.EncodeToBytes(im.account)
+	if err != nil {
+		return err
+	}
+	im.account = nil
+	return im.accTrie.Update(im.accountHash[:], blob)
+}
+
+// Import rebuilds the snapshot and the state trie from a flat file, returning
+// the root of the imported state once it is verified against the one recorded
+// in the file. The trie nodes are written in the hash-based scheme.
+//
+// The database must not contain a snapshot yet. If the import fails midway, the
+// partially imported data is left in place, but no snapshot root is recorded.
+func Import(db ethdb.KeyValueStore, r io.Reader) (common.Hash, error) {
+	if root := rawdb.ReadSnapshotRoot(db); root != (common.Hash{}) {
+		return common.Hash{}, fmt.Errorf("database already contains a snapshot at %x", root)
+	}
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(flatMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != flatMagic {
+		return common.Hash{}, errors.New("not a snapshot flat file")
+	}
+	kind, blob, err := readFlatChunk(br)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if kind != flatHeaderChunk {
+		return common.Hash{}, fmt.Errorf("unexpected chunk kind %d, want header", kind)
+	}
+	var header flatHeader
+	if err := rlp.DecodeBytes(blob, &header); err != nil {
+		return common.Hash{}, err
+	}
+	if header.Version != flatFileVersion {
+		return common.Hash{}, fmt.Errorf("unsupported flat file version %d", header.Version)
+	}
+	im := &flatImporter{
+		batch: db.NewBatch(),
+		codes: make(map[common.Hash]bool),
+	}
+	im.accTrie = trie.NewStackTrie(im.writeNode)
+
+	var (
+		footer *flatFooter
+		start  = time.Now()
+		logged = time.Now()
+	)
+	log.Info("Importing snapshot", "root", header.Root)
+	for footer == nil {
+		kind, blob, err := readFlatChunk(br)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return common.Hash{}, errors.New("truncated flat file")
+		} else if err != nil {
+			return common.Hash{}, err
+		}
+		switch kind {
+		case flatEntryChunk:
+			var entries []flatEntry
+			if err := rlp.DecodeBytes(blob, &entries); err != nil {
+				return common.Hash{}, err
+			}
+			for i := range entries {
+				if err := im.process(&entries[i]); err != nil {
+					return common.Hash{}, err
+				}
+			}
+		case flatFooterChunk:
+			footer = new(flatFooter)
+			if err := rlp.DecodeBytes(blob, footer); err != nil {
+				return common.Hash{}, err
+			}
+		default:
+			return common.Hash{}, fmt.Errorf("unexpected chunk kind %d", kind)
+		}
+		if time.Since(logged) > flatLogInterval {
+			log.Info("Importing snapshot", "at", im.accountHash, "accounts", im.counts.Accounts, "slots", im.counts.Slots,
+				"codes", im.counts.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if *footer != im.counts {
+		return common.Hash{}, fmt.Errorf("entry count mismatch: have %d/%d/%d, want %d/%d/%d accounts/slots/codes",
+			im.counts.Accounts, im.counts.Slots, im.counts.Codes, footer.Accounts, footer.Slots, footer.Codes)
+	}
+	for hash, ok := range im.codes {
+		if !ok {
+			return common.Hash{}, fmt.Errorf("missing code %x", hash)
+		}
+	}
+	if err := im.finishAccount(); err != nil {
+		return common.Hash{}, err
+	}
+	root, err := im.accTrie.Commit()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if root != header.Root {
+		return common.Hash{}, fmt.Errorf("state root mismatch: have %x, want %x", root, header.Root)
+	}
+	// State verified, mark the snapshot complete
+	journalProgress(im.batch, nil, &generatorStats{accounts: im.counts.Accounts, slots: im.counts.Slots, storage: im.size})
+	rawdb.DeleteSnapshotJournal(im.batch)
+	rawdb.WriteSnapshotRoot(im.batch, root)
+	if err := im.batch.Write(); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Imported snapshot", "root", root, "accounts", im.counts.Accounts, "slots", im.counts.Slots,
+		"codes", im.counts.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return root, nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// exportTestState creates a generated snapshot with a few accounts, storage
+// slots and contract codes, and exports it into a flat file.
+func exportTestState(t *testing.T) (common.Hash, []byte) {
+	var (
+		helper = newHelper()
+		code   = []byte{0x60, 0x00, 0x60, 0x00, 0xf3}
+		keys   = []string{"key-1", "key-2", "key-3"}
+		vals   = []string{"val-1", "val-2", "val-3"}
+	)
+	rawdb.WriteCode(helper.diskdb, crypto.Keccak256Hash(code), code)
+
+	stRoot := helper.makeStorageTrie(common.Hash{}, keys, vals, false)
+	helper.addTrieAccount("acc-1", &types.StateAccount{Balance: big.NewInt(1), Root: stRoot, CodeHash: crypto.Keccak256(code)})
+	helper.addTrieAccount("acc-2", &types.StateAccount{Balance: big.NewInt(2), Root: types.EmptyRootHash, CodeHash: types.EmptyCodeHash.Bytes()})
+	helper.addTrieAccount("acc-3", &types.StateAccount{Balance: big.NewInt(3), Root: stRoot, CodeHash: crypto.Keccak256(code)})
+	helper.makeStorageTrie(hashData([]byte("acc-1")), keys, vals, true)
+	helper.makeStorageTrie(hashData([]byte("acc-3")), keys, vals, true)
+
+	root, snap := helper.CommitAndGenerate()
+	select {
+	case <-snap.genPending:
+	case <-time.After(3 * time.Second):
+		t.Fatal("snapshot generation failed")
+	}
+	stop := make(chan *generatorStats)
+	snap.genAbort <- stop
+	<-stop
+
+	var (
+		tree = &Tree{layers: map[common.Hash]snapshot{root: snap}}
+		buf  bytes.Buffer
+	)
+	if err := tree.Export(root, helper.diskdb, &buf); err != nil {
+		t.Fatalf("failed to export snapshot: %v", err)
+	}
+	return root, buf.Bytes()
+}
+
+func TestFlatFileExportImport(t *testing.T) {
+	root, blob := exportTestState(t)
+
+	db := rawdb.NewMemoryDatabase()
+	imported, err := Import(db, bytes.NewReader(blob))
+	if err != nil {
+		t.Fatalf("failed to import snapshot: %v", err)
+	}
+	if imported != root {
+		t.Fatalf("root mismatch: have %x, want %x", imported, root)
+	}
+	// The whole state trie must be present and match the snapshot
+	snaps, err := New(Config{CacheSize: 16, NoBuild: true}, db, trie.NewDatabase(db), root)
+	if err != nil {
+		t.Fatalf("failed to load imported snapshot: %v", err)
+	}
+	checkSnapRoot(t, snaps.disklayer(), root)
+
+	tr, err := trie.New(trie.StateTrieID(root), trie.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := trie.NewIterator(tr.NodeIterator(nil))
+	for it.Next() {
+		account, err := types.FullAccount(it.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if account.Root != types.EmptyRootHash {
+			st, err := trie.New(trie.StorageTrieID(root, common.BytesToHash(it.Key), account.Root), trie.NewDatabase(db))
+			if err != nil {
+				t.Fatal(err)
+			}
+			stIt := trie.NewIterator(st.NodeIterator(nil))
+			for stIt.Next() {
+			}
+			if stIt.Err != nil {
+				t.Fatalf("storage trie of %x incomplete: %v", it.Key, stIt.Err)
+			}
+		}
+		if !bytes.Equal(account.CodeHash, types.EmptyCodeHash.Bytes()) && len(rawdb.ReadCode(db, common.BytesToHash(account.CodeHash))) == 0 {
+			t.Fatalf("missing code of %x", it.Key)
+		}
+	}
+	if it.Err != nil {
+		t.Fatalf("account trie incomplete: %v", it.Err)
+	}
+	// A second import into the same database must be rejected
+	if _, err := Import(db, bytes.NewReader(blob)); err == nil {
+		t.Fatal("imported snapshot over an existing one")
+	}
+}
+
+func TestFlatFileCorruption(t *testing.T) {
+	_, blob := exportTestState(t)
+
+	corrupt := common.CopyBytes(blob)
+	corrupt[len(corrupt)/2] ^= 0xff
+	if _, err := Import(rawdb.NewMemoryDatabase(), bytes.NewReader(corrupt)); err != errFlatChecksum {
+		t.Fatalf("corruption not detected: %v", err)
+	}
+	for _, size := range []int{len(flatMagic) / 2, len(blob) / 2, len(blob) - 1} {
+		db := rawdb.NewMemoryDatabase()
+		if _, err := Import(db, bytes.NewReader(blob[:size])); err == nil {
+			t.Fatalf("truncated file of %d bytes imported", size)
+		}
+		if rawdb.ReadSnapshotRoot(db) != (common.Hash{}) {
+			t.Fatalf("truncated file of %d bytes marked complete", size)
+		}
+	}
+}
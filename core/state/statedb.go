@@ -73,6 +73,14 @@ type StateDB struct {
 	snapAccounts map[common.Hash][]byte
 	snapStorage  map[common.Hash]map[common.Hash][]byte
 
+	// Reverse diff recording of the state transition, requires the snapshot
+	recordDiff     bool
+	reverseDiff    *StateDiff
+	reverseDiffErr error
+
+	// Logger notified of the state modifications, nil if not traced
+	logger StateLogger
+
 	// This map holds 'live' objects, which will get modified while processing a state transition.
 	stateObjects         map[common.Address]*stateObject
 	stateObjectsPending  map[common.Address]struct{} // State objects finalized but not yet written to the trie
@@ -195,6 +203,12 @@ func (s *StateDB) Error() error {
 	return s.dbErr
 }
 
+// SetLogger sets the logger to be notified of the subsequent state modifications.
+// The logger is not inherited by copies of the state.
+func (s *StateDB) SetLogger(l StateLogger) {
+	s.logger = l
+}
+
 func (s *StateDB) AddLog(log *types.Log) {
 	s.journal.append(addLogChange{txhash: s.thash})
 
@@ -203,6 +217,10 @@ func (s *StateDB) AddLog(log *types.Log) {
 	log.Index = s.logSize
 	s.logs[s.thash] = append(s.logs[s.thash], log)
 	s.logSize++
+
+	if s.logger != nil {
+		s.logger.OnLog(log)
+	}
 }
 
 // GetLogs returns the logs matching the specified transaction hash, and annotates
@@ -463,14 +481,21 @@ func (s *StateDB) Suicide(addr common.Address) bool {
 	if stateObject == nil {
 		return false
 	}
+	var (
+		prev = new(big.Int).Set(stateObject.Balance())
+		n    = new(big.Int)
+	)
 	s.journal.append(suicideChange{
 		account:     &addr,
 		prev:        stateObject.suicided,
-		prevbalance: new(big.Int).Set(stateObject.Balance()),
+		prevbalance: prev,
 	})
 	stateObject.markSuicided()
-	stateObject.data.Balance = new(big.Int)
+	stateObject.data.Balance = n
 
+	if s.logger != nil && prev.Sign() != 0 {
+		s.logger.OnBalanceChange(addr, prev, n)
+	}
 	return true
 }
 
@@ -1063,7 +1088,11 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
 		start := time.Now()
 		// Only update if there's a state transition (skip empty Clique blocks)
 		if parent := s.snap.Root(); parent != root {
-			if err := s.snaps.Update(root, parent, s.convertAccountSet(s.stateObjectsDestruct), s.snapAccounts, s.snapStorage); err != nil {
+			destructs := s.convertAccountSet(s.stateObjectsDestruct)
+			if s.recordDiff {
+				s.reverseDiff, s.reverseDiffErr = s.buildReverseDiff(root, destructs)
+			}
+			if err := s.snaps.Update(root, parent, destructs, s.snapAccounts, s.snapStorage); err != nil {
 				log.Warn("Failed to update snapshot tree", "from", parent, "to", root, "err", err)
 			}
 			// Keep 128 diff layers in the memory, persistent layer is 129th.
@@ -1073,6 +1102,8 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
 			if err := s.snaps.Cap(root, 128); err != nil {
 				log.Warn("Failed to cap snapshot tree", "root", root, "layers", 128, "err", err)
 			}
+		} else if s.recordDiff {
+			s.reverseDiff = newStateDiff(root)
 		}
 		if metrics.EnabledExpensive {
 			s.SnapshotCommits += time.Since(start)
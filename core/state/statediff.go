@@ -0,0 +1,188 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// StateDiff is the reverse diff of a block's state transition. It holds the
+// original values of all accounts and storage slots modified by the block, in
+// the snapshot data format and keyed by their hashes. Empty values denote
+// entries not existing before the transition.
+type StateDiff struct {
+	Root     common.Hash                            // State root after the transition
+	Accounts map[common.Hash][]byte                 // Original slim RLP encoded accounts
+	Storages map[common.Hash]map[common.Hash][]byte // Original RLP encoded storage slots
+}
+
+// stateDiffAccount is the encoding of an original account value.
+type stateDiffAccount struct {
+	Hash common.Hash
+	Blob []byte
+}
+
+// stateDiffStorage is the encoding of the original storage values of an account.
+type stateDiffStorage struct {
+	Hash  common.Hash
+	Slots []stateDiffAccount
+}
+
+// stateDiffRLP is the compact encoding of a state diff, with all the entries
+// sorted by hash.
+type stateDiffRLP struct {
+	Root     common.Hash
+	Accounts []stateDiffAccount
+	Storages []stateDiffStorage
+}
+
+// newStateDiff creates an empty reverse diff of the transition to root.
+func newStateDiff(root common.Hash) *StateDiff {
+	return &StateDiff{
+		Root:     root,
+		Accounts: make(map[common.Hash][]byte),
+		Storages: make(map[common.Hash]map[common.Hash][]byte),
+	}
+}
+
+// sortedHashes returns the keys of the given map in ascending order.
+func sortedHashes[V any](m map[common.Hash]V) []common.Hash {
+	hashes := make([]common.Hash, 0, len(m))
+	for hash := range m {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+	return hashes
+}
+
+// Encode serializes the state diff into its compact RLP form.
+func (d *StateDiff) Encode() ([]byte, error) {
+	enc := stateDiffRLP{Root: d.Root}
+	for _, hash := range sortedHashes(d.Accounts) {
+		enc.Accounts = append(enc.Accounts, stateDiffAccount{Hash: hash, Blob: d.Accounts[hash]})
+	}
+	for _, hash := range sortedHashes(d.Storages) {
+		storage := stateDiffStorage{Hash: hash}
+		for _, slot := range sortedHashes(d.Storages[hash]) {
+			storage.Slots = append(storage.Slots, stateDiffAccount{Hash: slot, Blob: d.Storages[hash][slot]})
+		}
+		enc.Storages = append(enc.Storages, storage)
+	}
+	return rlp.EncodeToBytes(&enc)
+}
+
+// DecodeStateDiff deserializes a state diff from its compact RLP form.
+func DecodeStateDiff(blob []byte) (*StateDiff, error) {
+	var dec stateDiffRLP
+	if err := rlp.DecodeBytes(blob, &dec); err != nil {
+		return nil, err
+	}
+	diff := newStateDiff(dec.Root)
+	for _, account := range dec.Accounts {
+		diff.Accounts[account.Hash] = account.Blob
+	}
+	for _, storage := range dec.Storages {
+		slots := make(map[common.Hash][]byte, len(storage.Slots))
+		for _, slot := range storage.Slots {
+			slots[slot.Hash] = slot.Blob
+		}
+		diff.Storages[storage.Hash] = slots
+	}
+	return diff, nil
+}
+
+// buildReverseDiff assembles the reverse diff of the state transition being
+// committed, resolving the original values from the parent snapshot layer. The
+// storage of destructed accounts is wiped by the transition, so all of their
+// original slots are recorded.
+func (s *StateDB) buildReverseDiff(root common.Hash, destructs map[common.Hash]struct{}) (*StateDiff, error) {
+	diff := newStateDiff(root)
+
+	record := func(hash common.Hash) error {
+		if _, ok := diff.Accounts[hash]; ok {
+			return nil
+		}
+		blob, err := s.snap.AccountRLP(hash)
+		if err != nil {
+			return err
+		}
+		diff.Accounts[hash] = blob
+		return nil
+	}
+	for hash := range destructs {
+		if err := record(hash); err != nil {
+			return nil, err
+		}
+		it, err := s.snaps.StorageIterator(s.snap.Root(), hash, common.Hash{})
+		if err != nil {
+			return nil, err
+		}
+		slots := make(map[common.Hash][]byte)
+		for it.Next() {
+			slots[it.Hash()] = common.CopyBytes(it.Slot())
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return nil, err
+		}
+		if len(slots) > 0 {
+			diff.Storages[hash] = slots
+		}
+	}
+	for hash := range s.snapAccounts {
+		if err := record(hash); err != nil {
+			return nil, err
+		}
+	}
+	for hash, storage := range s.snapStorage {
+		slots := diff.Storages[hash]
+		if slots == nil {
+			slots = make(map[common.Hash][]byte)
+		}
+		for slot := range storage {
+			if _, ok := slots[slot]; ok {
+				continue
+			}
+			blob, err := s.snap.Storage(hash, slot)
+			if err != nil {
+				return nil, err
+			}
+			slots[slot] = blob
+		}
+		if len(slots) > 0 {
+			diff.Storages[hash] = slots
+		}
+	}
+	return diff, nil
+}
+
+// EnableDiffRecording makes the subsequent commit record the reverse diff of the
+// state transition. Recording requires the state snapshot to be available.
+func (s *StateDB) EnableDiffRecording() {
+	s.recordDiff = true
+}
+
+// ReverseDiff returns the reverse diff recorded by the last commit, or nil if
+// recording was not enabled or not possible without a snapshot. An error is
+// returned if the diff could not be assembled from the snapshot.
+func (s *StateDB) ReverseDiff() (*StateDiff, error) {
+	return s.reverseDiff, s.reverseDiffErr
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that state diffs survive an encoding round trip, including the entries
+// marking non-existent accounts and slots, and that the encoding is canonical.
+func TestStateDiffEncoding(t *testing.T) {
+	diff := newStateDiff(common.Hash{0x01})
+	diff.Accounts[common.Hash{0x02}] = []byte{0xc4, 0x01, 0x80, 0x80, 0x80}
+	diff.Accounts[common.Hash{0x03}] = nil
+	diff.Storages[common.Hash{0x02}] = map[common.Hash][]byte{
+		{0x04}: {0x05},
+		{0x06}: nil,
+	}
+	blob, err := diff.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode diff: %v", err)
+	}
+	dec, err := DecodeStateDiff(blob)
+	if err != nil {
+		t.Fatalf("failed to decode diff: %v", err)
+	}
+	if dec.Root != diff.Root {
+		t.Errorf("root mismatch: have %x, want %x", dec.Root, diff.Root)
+	}
+	for hash, want := range diff.Accounts {
+		have, ok := dec.Accounts[hash]
+		if !ok || !bytes.Equal(have, want) {
+			t.Errorf("account %x mismatch: have %x (%v), want %x", hash, have, ok, want)
+		}
+	}
+	for hash, slots := range diff.Storages {
+		for slot, want := range slots {
+			have, ok := dec.Storages[hash][slot]
+			if !ok || !bytes.Equal(have, want) {
+				t.Errorf("slot %x of %x mismatch: have %x (%v), want %x", slot, hash, have, ok, want)
+			}
+		}
+	}
+	again, err := dec.Encode()
+	if err != nil {
+		t.Fatalf("failed to re-encode diff: %v", err)
+	}
+	if !bytes.Equal(blob, again) {
+		t.Errorf("encoding not canonical: have %x, want %x", again, blob)
+	}
+}
@@ -0,0 +1,380 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// stateDiffFreezeDelay is the number of blocks the state reverse diff of a block
+// is kept in the key-value store, before being moved into the freezer. Reorgs
+// deeper than this are not supported by the snapshot tree either.
+const stateDiffFreezeDelay = TriesInMemory
+
+var (
+	// ErrStateDiffsDisabled is returned if historic state is requested, but the
+	// state diff history is not maintained.
+	ErrStateDiffsDisabled = errors.New("state diff history disabled")
+
+	// errStateDiffsPruned is returned if historic state is requested, but the
+	// state diffs required to reconstruct it are not available (anymore).
+	errStateDiffsPruned = errors.New("historic state not covered by the state diff history")
+)
+
+// stateDiffHistory maintains the state reverse diffs of the canonical chain,
+// permitting access to historic state by applying them on top of the current
+// state snapshot.
+//
+// The diffs of the most recent blocks are kept in the key-value store keyed by
+// block hash, as they may still be reorged. Older ones are moved into the state
+// diff freezer and indexed by the accounts and storage slots they contain.
+type stateDiffHistory struct {
+	db      ethdb.Database // Database holding the recent diffs and the index
+	freezer *rawdb.Freezer // Freezer holding the canonical diffs of older blocks
+	limit   uint64         // Number of recent blocks to retain diffs for
+
+	lock sync.RWMutex // Lock protecting the freezer content
+}
+
+// newStateDiffHistory opens the state diff freezer belonging to the database.
+func newStateDiffHistory(db ethdb.Database, limit uint64) (*stateDiffHistory, error) {
+	ancient, err := db.AncientDatadir()
+	if err != nil {
+		return nil, err
+	}
+	freezer, err := rawdb.NewStateDiffFreezer(ancient, false)
+	if err != nil {
+		return nil, err
+	}
+	return &stateDiffHistory{db: db, freezer: freezer, limit: limit}, nil
+}
+
+// close releases the state diff freezer.
+func (h *stateDiffHistory) close() error {
+	return h.freezer.Close()
+}
+
+// frozen returns the range of block numbers [first, next) whose state diffs
+// are stored in the freezer, and the number of the block stored as item zero.
+func (h *stateDiffHistory) frozen() (uint64, uint64, uint64) {
+	offset := rawdb.ReadStateDiffOffset(h.db)
+	tail, _ := h.freezer.Tail()
+	items, _ := h.freezer.Ancients()
+	return offset + tail, offset + items, offset
+}
+
+// freeze moves the state diffs of the canonical blocks which are deep enough
+// below the given head into the freezer, pruning the ones falling out of the
+// retention window. If the chain was rewound, any frozen diff above the head
+// is dropped.
+func (h *stateDiffHistory) freeze(head uint64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	first, next, offset := h.frozen()
+	if next > head+1 {
+		if first > head+1 {
+			h.truncateHead(first)
+		} else {
+			h.truncateHead(head + 1)
+		}
+		first, next, offset = h.frozen()
+	}
+	if head < stateDiffFreezeDelay {
+		return
+	}
+	limit := head - stateDiffFreezeDelay
+
+	// Start the history at the current freeze point if empty, older blocks are
+	// not expected to have any diffs recorded.
+	if first == next && next < limit {
+		h.reset(limit)
+		first, next, offset = h.frozen()
+	}
+	for ; next <= limit; next++ {
+		hash := rawdb.ReadCanonicalHash(h.db, next)
+		blob := rawdb.ReadStateDiff(h.db, next, hash)
+
+		var diff *state.StateDiff
+		if blob != nil {
+			var err error
+			if diff, err = state.DecodeStateDiff(blob); err != nil {
+				log.Error("Invalid state diff", "number", next, "hash", hash, "err", err)
+			}
+		}
+		// The history can only be extended contiguously, restart it after any gap
+		if diff == nil {
+			if first != next {
+				log.Warn("State diff history interrupted, restarting", "number", next)
+			}
+			rawdb.DeleteStateDiffs(h.db, next)
+			h.reset(next + 1)
+			first, _, offset = h.frozen()
+			continue
+		}
+		if err := rawdb.WriteFrozenStateDiff(h.freezer, next-offset, blob); err != nil {
+			log.Error("Failed to freeze state diff", "number", next, "err", err)
+			return
+		}
+		batch := h.db.NewBatch()
+		for account := range diff.Accounts {
+			rawdb.WriteStateDiffAccountIndex(batch, account, next)
+		}
+		for account, slots := range diff.Storages {
+			for slot := range slots {
+				rawdb.WriteStateDiffStorageIndex(batch, account, slot, next)
+			}
+		}
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to write state diff index", "err", err)
+		}
+		rawdb.DeleteStateDiffs(h.db, next)
+	}
+	if h.limit > 0 && next-first > h.limit {
+		h.truncateTail(next - h.limit)
+	}
+}
+
+// reset drops all frozen state diffs, restarting the history at the given block.
+func (h *stateDiffHistory) reset(number uint64) {
+	first, _, _ := h.frozen()
+	h.truncateHead(first)
+
+	tail, _ := h.freezer.Tail()
+	rawdb.WriteStateDiffOffset(h.db, number-tail)
+}
+
+// truncateHead drops the frozen state diffs of the given block and above.
+func (h *stateDiffHistory) truncateHead(number uint64) {
+	_, next, offset := h.frozen()
+	for n := number; n < next; n++ {
+		h.unindex(n, n-offset)
+	}
+	if err := h.freezer.TruncateHead(number - offset); err != nil {
+		log.Error("Failed to truncate state diff history", "number", number, "err", err)
+	}
+}
+
+// truncateTail drops the frozen state diffs below the given block.
+func (h *stateDiffHistory) truncateTail(number uint64) {
+	first, _, offset := h.frozen()
+	for n := first; n < number; n++ {
+		h.unindex(n, n-offset)
+	}
+	if err := h.freezer.TruncateTail(number - offset); err != nil {
+		log.Error("Failed to prune state diff history", "number", number, "err", err)
+	}
+}
+
+// unindex removes the index entries of the frozen state diff of a block.
+func (h *stateDiffHistory) unindex(number uint64, id uint64) {
+	diff, err := state.DecodeStateDiff(rawdb.ReadFrozenStateDiff(h.freezer, id))
+	if err != nil {
+		log.Error("Failed to load state diff", "number", number, "err", err)
+		return
+	}
+	batch := h.db.NewBatch()
+	for account := range diff.Accounts {
+		rawdb.DeleteStateDiffAccountIndex(batch, account, number)
+	}
+	for account, slots := range diff.Storages {
+		for slot := range slots {
+			rawdb.DeleteStateDiffStorageIndex(batch, account, slot, number)
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete state diff index", "err", err)
+	}
+}
+
+// reader creates a history reader for the state of the given canonical block,
+// reverting the state of the given canonical head.
+func (h *stateDiffHistory) reader(number uint64, head uint64) (*stateDiffReader, error) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	first, next, offset := h.frozen()
+	if number+1 < first {
+		return nil, errStateDiffsPruned
+	}
+	r := &stateDiffReader{
+		h:      h,
+		number: number,
+		next:   next,
+		offset: offset,
+		frozen: make(map[uint64]*state.StateDiff),
+	}
+	start := number + 1
+	if start < next {
+		start = next
+	}
+	for n := start; n <= head; n++ {
+		blob := rawdb.ReadStateDiff(h.db, n, rawdb.ReadCanonicalHash(h.db, n))
+		if blob == nil {
+			return nil, fmt.Errorf("%w: missing state diff #%d", errStateDiffsPruned, n)
+		}
+		diff, err := state.DecodeStateDiff(blob)
+		if err != nil {
+			return nil, err
+		}
+		r.recent = append(r.recent, diff)
+	}
+	return r, nil
+}
+
+// stateDiffReader implements state.HistoryReader, resolving the values of a
+// historic state from the state diffs of the subsequent blocks. The index is
+// used to find the first frozen diff modifying an entry, after which the recent
+// diffs are searched in ascending order.
+type stateDiffReader struct {
+	h      *stateDiffHistory
+	number uint64                      // Number of the block the historic state belongs to
+	next   uint64                      // Number of the first block without a frozen diff
+	offset uint64                      // Number of the block stored as the freezer item zero
+	recent []*state.StateDiff          // Diffs of the unfrozen blocks after the historic state
+	frozen map[uint64]*state.StateDiff // Cache of the decoded frozen diffs
+	lock   sync.Mutex                  // Lock protecting the frozen diff cache
+}
+
+// indexedDiff retrieves the frozen state diff of the first block after the
+// historic state modifying an entry, located with the given index lookup. The
+// history lock is held meanwhile, so the index and the freezer can't be pruned
+// or restarted concurrently; if they were since the reader was created, the
+// historic state is reported as unavailable.
+func (r *stateDiffReader) indexedDiff(lookup func() (uint64, bool)) (*state.StateDiff, uint64, bool, error) {
+	r.h.lock.RLock()
+	defer r.h.lock.RUnlock()
+
+	if first, _, offset := r.h.frozen(); offset != r.offset || r.number+1 < first {
+		return nil, 0, false, errStateDiffsPruned
+	}
+	number, ok := lookup()
+	if !ok || number >= r.next {
+		return nil, 0, false, nil
+	}
+	diff, err := r.frozenDiff(number)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return diff, number, true, nil
+}
+
+// frozenDiff retrieves the frozen state diff of the given block. The caller must
+// hold the history lock.
+func (r *stateDiffReader) frozenDiff(number uint64) (*state.StateDiff, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if diff, ok := r.frozen[number]; ok {
+		return diff, nil
+	}
+	blob := rawdb.ReadFrozenStateDiff(r.h.freezer, number-r.offset)
+	if blob == nil {
+		return nil, fmt.Errorf("%w: missing frozen state diff #%d", errStateDiffsPruned, number)
+	}
+	diff, err := state.DecodeStateDiff(blob)
+	if err != nil {
+		return nil, err
+	}
+	r.frozen[number] = diff
+	return diff, nil
+}
+
+// Account implements state.HistoryReader, returning the slim RLP encoded account
+// at the historic state if it was modified since.
+func (r *stateDiffReader) Account(hash common.Hash) ([]byte, bool, error) {
+	diff, number, ok, err := r.indexedDiff(func() (uint64, bool) {
+		return rawdb.ReadStateDiffAccountIndex(r.h.db, hash, r.number+1)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		blob, ok := diff.Accounts[hash]
+		if !ok {
+			return nil, false, fmt.Errorf("account %x missing from indexed state diff #%d", hash, number)
+		}
+		return blob, true, nil
+	}
+	for _, diff := range r.recent {
+		if blob, ok := diff.Accounts[hash]; ok {
+			return blob, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// Storage implements state.HistoryReader, returning the RLP encoded storage slot
+// at the historic state if it was modified since.
+func (r *stateDiffReader) Storage(accountHash, storageHash common.Hash) ([]byte, bool, error) {
+	diff, number, ok, err := r.indexedDiff(func() (uint64, bool) {
+		return rawdb.ReadStateDiffStorageIndex(r.h.db, accountHash, storageHash, r.number+1)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		blob, ok := diff.Storages[accountHash][storageHash]
+		if !ok {
+			return nil, false, fmt.Errorf("slot %x of %x missing from indexed state diff #%d", storageHash, accountHash, number)
+		}
+		return blob, true, nil
+	}
+	for _, diff := range r.recent {
+		if blob, ok := diff.Storages[accountHash][storageHash]; ok {
+			return blob, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// HistoricState returns the state of a canonical block, reconstructed from the
+// state diff history by reverting the current state snapshot, without needing
+// the historic tries. The returned state can't be hashed or committed.
+func (bc *BlockChain) HistoricState(header *types.Header) (*state.StateDB, error) {
+	if bc.stateDiffs == nil {
+		return nil, ErrStateDiffsDisabled
+	}
+	var (
+		number = header.Number.Uint64()
+		head   = bc.CurrentBlock()
+	)
+	if number >= head.Number.Uint64() {
+		return nil, fmt.Errorf("block #%d is not historic, head #%d", number, head.Number)
+	}
+	if rawdb.ReadCanonicalHash(bc.db, number) != header.Hash() {
+		return nil, fmt.Errorf("block #%d [%x] is not canonical", number, header.Hash())
+	}
+	base := bc.snaps.Snapshot(head.Root)
+	if base == nil {
+		return nil, fmt.Errorf("snapshot of head state %x not available", head.Root)
+	}
+	reader, err := bc.stateDiffs.reader(number, head.Number.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	return state.New(header.Root, state.NewHistoricDatabase(bc.stateCache, header.Root, base, reader), nil)
+}
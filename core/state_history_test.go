@@ -0,0 +1,244 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	stateDiffKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	stateDiffSender  = crypto.PubkeyToAddress(stateDiffKey.PublicKey)
+	stateDiffStorage = common.HexToAddress("0xc0de")
+)
+
+// newStateDiffGenesis creates a genesis with a funded sender and a contract which
+// stores the block number both in slot zero and in the slot of the block number.
+func newStateDiffGenesis() *Genesis {
+	return &Genesis{
+		Config: params.TestChainConfig,
+		Alloc: GenesisAlloc{
+			stateDiffSender:  {Balance: big.NewInt(params.Ether)},
+			stateDiffStorage: {Balance: common.Big0, Code: common.FromHex("0x4360005543435500")}, // NUMBER PUSH1 0 SSTORE NUMBER NUMBER SSTORE STOP
+		},
+	}
+}
+
+// generateStateDiffBlocks generates a chain in which every block transfers funds
+// to a new account and calls the storage contract.
+func generateStateDiffBlocks(gspec *Genesis, blocks int, coinbase common.Address) []*types.Block {
+	signer := types.LatestSigner(gspec.Config)
+	_, chain, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), blocks, func(i int, b *BlockGen) {
+		b.SetCoinbase(coinbase)
+
+		recipient := common.BigToAddress(big.NewInt(int64(0x1000 + i)))
+		tx, _ := types.SignTx(types.NewTransaction(uint64(2*i), recipient, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, stateDiffKey)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(uint64(2*i+1), stateDiffStorage, common.Big0, 100000, b.BaseFee(), nil), signer, stateDiffKey)
+		b.AddTx(tx)
+	})
+	return chain
+}
+
+// newStateDiffChain creates a chain maintaining state diffs, with the given number
+// of blocks generated by generateStateDiffBlocks.
+func newStateDiffChain(t *testing.T, blocks int, history uint64) *BlockChain {
+	var (
+		gspec  = newStateDiffGenesis()
+		chain  = generateStateDiffBlocks(gspec, blocks, common.Address{0x02})
+		engine = ethash.NewFaker()
+	)
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	config := &CacheConfig{
+		TrieCleanLimit:   256,
+		TrieDirtyLimit:   256,
+		TrieTimeLimit:    5 * time.Minute,
+		SnapshotLimit:    256,
+		SnapshotWait:     true,
+		StateDiffs:       true,
+		StateDiffHistory: history,
+	}
+	bc, err := NewBlockChain(db, config, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	if _, err := bc.InsertChain(chain); err != nil {
+		t.Fatalf("Failed to insert chain: %v", err)
+	}
+	return bc
+}
+
+// Tests that historic state is reconstructed from the state diffs, both from the
+// frozen and the recent ones.
+func TestHistoricState(t *testing.T) {
+	var (
+		blocks = 2*TriesInMemory + 16
+		bc     = newStateDiffChain(t, blocks, 0)
+	)
+	defer bc.Stop()
+
+	if first, next, _ := bc.stateDiffs.frozen(); next != uint64(blocks-TriesInMemory+1) || first > 1 {
+		t.Fatalf("frozen range mismatch: have [%d, %d), want [1, %d)", first, next, blocks-TriesInMemory+1)
+	}
+	for _, number := range []uint64{0, 1, 10, TriesInMemory, uint64(blocks) - TriesInMemory, uint64(blocks) - 10, uint64(blocks) - 1} {
+		header := bc.GetHeaderByNumber(number)
+		statedb, err := bc.HistoricState(header)
+		if err != nil {
+			t.Fatalf("block %d: failed to retrieve historic state: %v", number, err)
+		}
+		if have := statedb.GetState(stateDiffStorage, common.Hash{}); have != common.BigToHash(new(big.Int).SetUint64(number)) {
+			t.Errorf("block %d: slot zero mismatch: have %x, want %d", number, have, number)
+		}
+		if have := statedb.GetState(stateDiffStorage, common.BigToHash(new(big.Int).SetUint64(number+1))); have != (common.Hash{}) {
+			t.Errorf("block %d: future slot mismatch: have %x, want empty", number, have)
+		}
+		if number > 1 {
+			if have := statedb.GetState(stateDiffStorage, common.BigToHash(new(big.Int).SetUint64(number-1))); have != common.BigToHash(new(big.Int).SetUint64(number-1)) {
+				t.Errorf("block %d: past slot mismatch: have %x, want %d", number, have, number-1)
+			}
+		}
+		if number > 0 {
+			recipient := common.BigToAddress(big.NewInt(int64(0x1000 + number - 1)))
+			if have := statedb.GetBalance(recipient); have.Cmp(big.NewInt(1000)) != 0 {
+				t.Errorf("block %d: recipient balance mismatch: have %v, want %v", number, have, 1000)
+			}
+		}
+		if statedb.Exist(common.BigToAddress(big.NewInt(int64(0x1000 + number)))) {
+			t.Errorf("block %d: future recipient exists", number)
+		}
+		if have := statedb.GetNonce(stateDiffSender); have != 2*number {
+			t.Errorf("block %d: sender nonce mismatch: have %d, want %d", number, have, 2*number)
+		}
+	}
+	if _, err := bc.HistoricState(bc.CurrentBlock()); err == nil {
+		t.Errorf("expected error retrieving the head state")
+	}
+}
+
+// Tests that state diffs falling out of the retention window are pruned, and
+// the historic state covered by them is not served anymore.
+func TestHistoricStatePruning(t *testing.T) {
+	var (
+		blocks = 2*TriesInMemory + 16
+		bc     = newStateDiffChain(t, blocks, 32)
+	)
+	defer bc.Stop()
+
+	first, next, _ := bc.stateDiffs.frozen()
+	if next-first != 32 {
+		t.Fatalf("frozen diff count mismatch: have %d, want %d", next-first, 32)
+	}
+	if _, err := bc.HistoricState(bc.GetHeaderByNumber(first - 2)); !errors.Is(err, errStateDiffsPruned) {
+		t.Errorf("pruned state error mismatch: have %v, want %v", err, errStateDiffsPruned)
+	}
+	statedb, err := bc.HistoricState(bc.GetHeaderByNumber(first - 1))
+	if err != nil {
+		t.Fatalf("failed to retrieve oldest historic state: %v", err)
+	}
+	if have := statedb.GetNonce(stateDiffSender); have != 2*(first-1) {
+		t.Errorf("sender nonce mismatch: have %d, want %d", have, 2*(first-1))
+	}
+	// Pruned diffs should not be indexed anymore
+	if number, ok := rawdb.ReadStateDiffAccountIndex(bc.db, crypto.Keccak256Hash(stateDiffSender.Bytes()), 0); !ok || number != first {
+		t.Errorf("first indexed diff mismatch: have %d (%v), want %d", number, ok, first)
+	}
+}
+
+// Tests that state diffs are only kept for canonical blocks, and that side chain
+// blocks don't leave theirs behind.
+func TestStateDiffSideChain(t *testing.T) {
+	bc := newStateDiffChain(t, 10, 0)
+	defer bc.Stop()
+
+	side := generateStateDiffBlocks(newStateDiffGenesis(), 5, common.Address{0x03})
+	if _, err := bc.InsertChain(side); err != nil {
+		t.Fatalf("Failed to insert side chain: %v", err)
+	}
+	for _, block := range side {
+		if bc.GetCanonicalHash(block.NumberU64()) == block.Hash() {
+			t.Fatalf("block %d: side chain block became canonical", block.NumberU64())
+		}
+		if rawdb.ReadStateDiff(bc.db, block.NumberU64(), block.Hash()) != nil {
+			t.Errorf("block %d: state diff stored for side chain block", block.NumberU64())
+		}
+		if rawdb.ReadStateDiff(bc.db, block.NumberU64(), bc.GetCanonicalHash(block.NumberU64())) == nil {
+			t.Errorf("block %d: state diff missing for canonical block", block.NumberU64())
+		}
+	}
+}
+
+// Tests that historic state readers created before the state diff history was
+// pruned or restarted report the state as unavailable, instead of resolving
+// it from the diffs of other blocks.
+func TestHistoricStateReaderInvalidated(t *testing.T) {
+	var (
+		blocks = 2*TriesInMemory + 16
+		bc     = newStateDiffChain(t, blocks, 0)
+		head   = bc.CurrentBlock().Number.Uint64()
+		sender = crypto.Keccak256Hash(stateDiffSender.Bytes())
+	)
+	defer bc.Stop()
+
+	pruned, err := bc.stateDiffs.reader(10, head)
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	restarted, err := bc.stateDiffs.reader(uint64(blocks)-TriesInMemory-10, head)
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	if _, _, err := pruned.Account(sender); err != nil {
+		t.Fatalf("Failed to read historic account: %v", err)
+	}
+	// Prune the diffs covering the first reader
+	bc.stateDiffs.lock.Lock()
+	bc.stateDiffs.truncateTail(20)
+	bc.stateDiffs.lock.Unlock()
+
+	if _, _, err := pruned.Account(sender); !errors.Is(err, errStateDiffsPruned) {
+		t.Errorf("pruned reader error mismatch: have %v, want %v", err, errStateDiffsPruned)
+	}
+	if _, _, err := restarted.Account(sender); err != nil {
+		t.Fatalf("Failed to read historic account: %v", err)
+	}
+	// Restart the history, moving the frozen diffs to other freezer items
+	bc.stateDiffs.lock.Lock()
+	_, next, _ := bc.stateDiffs.frozen()
+	bc.stateDiffs.reset(next + 5)
+	bc.stateDiffs.lock.Unlock()
+
+	if _, _, err := restarted.Account(sender); !errors.Is(err, errStateDiffsPruned) {
+		t.Errorf("restarted reader error mismatch: have %v, want %v", err, errStateDiffsPruned)
+	}
+	if _, _, err := restarted.Storage(crypto.Keccak256Hash(stateDiffStorage.Bytes()), common.Hash{}); !errors.Is(err, errStateDiffsPruned) {
+		t.Errorf("restarted reader error mismatch: have %v, want %v", err, errStateDiffsPruned)
+	}
+}
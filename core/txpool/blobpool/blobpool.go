@@ -0,0 +1,943 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package blobpool implements the EIP-4844 blob transaction pool.
+package blobpool
+
+import (
+	"container/heap"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// txMaxSize is the maximum size a single transaction can have, outside
+	// the included blobs. Since blob transactions are pulled instead of pushed,
+	// and only a small metadata is kept in ram, the rest is on disk, there is
+	// no critical limit that should be enforced. Still, capping it to some sane
+	// limit can never hurt.
+	txMaxSize = 1024 * 1024
+
+	// maxTxsPerAccount is the maximum number of blob transactions admitted from
+	// a single account. The limit is enforced to minimize the DoS potential of
+	// a private tx cancelling publicly propagated blobs.
+	maxTxsPerAccount = 16
+
+	// pendingTransactionStore is the subfolder containing the currently queued
+	// blob transactions.
+	pendingTransactionStore = "queue"
+)
+
+// blobTx is the on-disk representation of a pooled blob transaction, bundling
+// the canonical transaction with its sidecar (blobs, commitments and proofs)
+// which is needed for propagation and block building, but is not part of the
+// chain itself.
+type blobTx struct {
+	Tx      *types.Transaction
+	Blobs   []kzg4844.Blob
+	Commits []kzg4844.Commitment
+	Proofs  []kzg4844.Proof
+}
+
+// blobTxMeta is the minimal subset of types.BlobTx necessary to validate and
+// schedule the blob transactions into the following blocks. Only ever add the
+// bare minimum needed fields to keep the size down (and thus number of entries
+// larger with the same memory consumption).
+type blobTxMeta struct {
+	hash common.Hash // Transaction hash to maintain the lookup table
+	size uint64      // Byte size in the pool's persistent store
+	time time.Time   // Time when the transaction was first seen by the pool
+
+	nonce      uint64   // Needed to prioritize inclusion order within an account
+	costCap    *big.Int // Needed to validate cumulative balance sufficiency
+	execTipCap *big.Int // Needed to prioritize inclusion order across accounts and validate replacement price bump
+	execFeeCap *big.Int // Needed to validate replacement price bump
+	blobFeeCap *big.Int // Needed to validate replacement price bump
+
+	basefeeJumps float64 // Absolute number of 1559 fee adjustments needed to reach the tx's fee cap
+	blobfeeJumps float64 // Absolute number of 4844 fee adjustments needed to reach the tx's blob fee cap
+
+	evictionExecTip      *big.Int // Worst gas tip across all previous nonces
+	evictionExecFeeJumps float64  // Worst base fee (converted to fee jumps) across all previous nonces
+	evictionBlobFeeJumps float64  // Worse blob fee (converted to fee jumps) across all previous nonces
+}
+
+// newBlobTxMeta retrieves the indexed metadata fields from a blob transaction
+// and assembles a helper struct to track in memory.
+func newBlobTxMeta(size uint64, tx *types.Transaction) *blobTxMeta {
+	meta := &blobTxMeta{
+		hash:       tx.Hash(),
+		size:       size,
+		time:       time.Now(),
+		nonce:      tx.Nonce(),
+		costCap:    tx.Cost(),
+		execTipCap: tx.GasTipCap(),
+		execFeeCap: tx.GasFeeCap(),
+		blobFeeCap: tx.BlobGasFeeCap(),
+	}
+	meta.basefeeJumps = dynamicFeeJumps(meta.execFeeCap)
+	meta.blobfeeJumps = dynamicFeeJumps(meta.blobFeeCap)
+
+	return meta
+}
+
+// BlockChain defines the minimal set of methods needed to back a blob pool with
+// a chain. Exists to allow mocking the live chain out of tests.
+type BlockChain interface {
+	// Config retrieves the chain's fork configuration.
+	Config() *params.ChainConfig
+
+	// CurrentBlock returns the current head of the chain.
+	CurrentBlock() *types.Header
+
+	// StateAt returns a state database for a given root hash (generally the head).
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// BlobPool is the transaction pool dedicated to EIP-4844 blob transactions.
+//
+// Blob transactions are special snowflakes that are designed for a very specific
+// purpose (rollups) and are expected to adhere to that specific use case. These
+// behavioural expectations allow us to design a transaction pool that is more
+// robust (i.e. resending issues) and more resilient to DoS attacks (e.g. replace-
+// flush attacks) than the generic tx pool. These improvements are:
+//
+//   - Blob transactions are large. The pool keeps only a small metadata set for
+//     each transaction in memory, everything else (the transaction itself and
+//     its blobs, commitments and proofs) is kept in a persistent on-disk store
+//     that survives node restarts. The total size of the store is capped, and
+//     on overflow the pool evicts from the account with the cheapest bottleneck.
+//
+//   - Blob transactions are priced by two dynamic fees (execution and blob),
+//     which can both fluctuate wildly. Eviction priority is computed from the
+//     number of fee jumps a transaction is away from the current base and blob
+//     fees, taking the worst across all preceding nonces of the same account.
+//     Transactions whose fee caps are below the current fees are kept, but not
+//     offered for inclusion until the fees come down.
+//
+//   - Blob transactions must form a contiguous nonce sequence on top of the
+//     account's state nonce. No gaps are permitted and no more than a limited
+//     number of transactions may be pooled per account.
+//
+//   - Replacing a blob transaction is expensive for the network, thus all three
+//     fee components (tip, fee cap and blob fee cap) need to be bumped by the
+//     configured percentage (100% by default) for a replacement to be accepted.
+//
+//   - Blob transactions included in blocks that get reorged out are not resurrected,
+//     as the chain does not retain the blob sidecars needed to re-propagate them.
+type BlobPool struct {
+	config  Config                 // Pool configuration
+	reserve txpool.AddressReserver // Address reserver to ensure exclusivity across subpools
+
+	store  ethdb.KeyValueStore // Persistent data store for the transactions and blobs
+	stored uint64              // Useful data size of all transactions on disk
+
+	signer types.Signer // Transaction signer to use for sender recovery
+	chain  BlockChain   // Chain object to access the state through
+
+	head    *types.Header  // Current head of the chain
+	state   *state.StateDB // Current state at the head of the chain
+	gasTip  *big.Int       // Currently accepted minimum gas tip
+	basefee *big.Int       // Base fee of the next block to be produced
+	blobfee *big.Int       // Blob fee of the next block to be produced
+
+	lookup map[common.Hash]common.Address   // Lookup table mapping hashes to tx senders
+	index  map[common.Address][]*blobTxMeta // Blob transactions grouped by accounts, sorted by nonce
+	spent  map[common.Address]*big.Int      // Expenditure tracking for individual accounts
+	evict  *evictHeap                       // Heap of cheapest accounts for eviction when full
+
+	eventFeed  event.Feed              // Event feed to send out new tx events on pool inclusion
+	eventScope event.SubscriptionScope // Event scope to track and mass unsubscribe on termination
+
+	lock sync.RWMutex // Mutex protecting the pool during reorg handling
+}
+
+// New creates a new blob transaction pool to gather, sort and filter inbound
+// blob transactions from the network.
+func New(config Config, chain BlockChain) *BlobPool {
+	// Sanitize the input to ensure no vulnerable gas prices are set
+	config = (&config).sanitize()
+
+	// Create the transaction pool with its initial settings
+	return &BlobPool{
+		config: config,
+		signer: types.LatestSigner(chain.Config()),
+		chain:  chain,
+		lookup: make(map[common.Hash]common.Address),
+		index:  make(map[common.Address][]*blobTxMeta),
+		spent:  make(map[common.Address]*big.Int),
+	}
+}
+
+// Filter returns whether the given transaction can be consumed by the blob pool.
+func (p *BlobPool) Filter(tx *types.Transaction) bool {
+	return tx.Type() == types.BlobTxType
+}
+
+// Init sets the gas price needed to keep a transaction in the pool and the chain
+// head to allow balance / nonce checks. The transactions persisted to disk will
+// be loaded and filtered based on the provided starting settings.
+func (p *BlobPool) Init(gasTip *big.Int, head *types.Header, reserve txpool.AddressReserver) error {
+	p.reserve = reserve
+
+	// Open the persistent store holding the pooled transactions, or an ephemeral
+	// one if no data directory was configured (mostly for tests).
+	if p.config.Datadir == "" {
+		p.store = memorydb.New()
+	} else {
+		store, err := leveldb.New(filepath.Join(p.config.Datadir, pendingTransactionStore), 16, 16, "eth/blobpool/", false)
+		if err != nil {
+			return err
+		}
+		p.store = store
+	}
+	// Initialize the state with head block, or fallback to empty one in
+	// case the head state is not available (might occur when node is not
+	// fully synced).
+	state, err := p.chain.StateAt(head.Root)
+	if err != nil {
+		state, err = p.chain.StateAt(types.EmptyRootHash)
+	}
+	if err != nil {
+		return err
+	}
+	p.head, p.state = head, state
+	p.gasTip = new(big.Int).Set(gasTip)
+	p.basefee, p.blobfee = p.pendingFees()
+
+	// Index all transactions on disk and delete anything unprocessable
+	var fails [][]byte
+	it := p.store.NewIterator(nil, nil)
+	for it.Next() {
+		if err := p.parseTransaction(it.Value()); err != nil {
+			fails = append(fails, common.CopyBytes(it.Key()))
+		}
+	}
+	it.Release()
+
+	if len(fails) > 0 {
+		log.Warn("Dropping invalidated blob transactions", "ids", len(fails))
+		dropStaleMeter.Mark(int64(len(fails)))
+
+		for _, key := range fails {
+			if err := p.store.Delete(key); err != nil {
+				p.Close()
+				return err
+			}
+		}
+	}
+	// Sort the indexed transactions by nonce and delete anything gapped, create
+	// the eviction heap of anyone still remaining
+	for addr := range p.index {
+		p.recheck(addr)
+	}
+	p.evict = newPriceHeap(p.basefee, p.blobfee, p.index)
+
+	// Publish the initial storage metrics of the pool
+	datacapGauge.Update(int64(p.config.Datacap))
+	p.updateStorageMetrics()
+
+	log.Info("Blob transaction pool initialized", "txs", len(p.lookup), "size", common.StorageSize(p.stored))
+	return nil
+}
+
+// Close closes down the underlying persistent store.
+func (p *BlobPool) Close() error {
+	p.eventScope.Close()
+	return p.store.Close()
+}
+
+// parseTransaction is a callback method on pool creation that gets called for
+// each transaction on disk to create the in-memory metadata index.
+func (p *BlobPool) parseTransaction(blob []byte) error {
+	item := new(blobTx)
+	if err := rlp.DecodeBytes(blob, item); err != nil {
+		// This path is impossible unless the disk data representation changes
+		// across restarts. For that ever unprobable case, recover gracefully
+		// by ignoring this data entry.
+		log.Error("Failed to decode blob pool entry", "err", err)
+		return err
+	}
+	meta := newBlobTxMeta(uint64(len(blob)), item.Tx)
+
+	sender, err := p.signer.Sender(item.Tx)
+	if err != nil {
+		// This path is impossible unless the signature validity changes across
+		// restarts. For that ever unprobable case, recover gracefully by ignoring
+		// this data entry.
+		log.Error("Failed to recover blob tx sender", "hash", meta.hash, "err", err)
+		return err
+	}
+	if _, ok := p.index[sender]; !ok {
+		if err := p.reserve(sender, true); err != nil {
+			return err
+		}
+		p.index[sender] = []*blobTxMeta{}
+		p.spent[sender] = new(big.Int)
+	}
+	p.index[sender] = append(p.index[sender], meta)
+	p.spent[sender] = new(big.Int).Add(p.spent[sender], meta.costCap)
+
+	p.lookup[meta.hash] = sender
+	p.stored += meta.size
+
+	return nil
+}
+
+// pendingFees calculates the base fee and blob fee of the block following the
+// current head, used to decide which transactions are includable.
+func (p *BlobPool) pendingFees() (*big.Int, *big.Int) {
+	basefee := new(big.Int)
+	if p.chain.Config().IsLondon(new(big.Int).Add(p.head.Number, common.Big1)) {
+		basefee = misc.CalcBaseFee(p.chain.Config(), p.head)
+	}
+	var excessDataGas uint64
+	if p.head.ExcessDataGas != nil && p.head.DataGasUsed != nil {
+		excessDataGas = misc.CalcExcessDataGas(*p.head.ExcessDataGas, *p.head.DataGasUsed)
+	}
+	return basefee, misc.CalcBlobFee(excessDataGas)
+}
+
+// recheck verifies the pool's content for a specific account and drops anything
+// that does not fit anymore (dangling or filled nonce, overdraft).
+//
+// Note, the eviction heap is not updated, the caller needs to reinitialize it.
+func (p *BlobPool) recheck(addr common.Address) {
+	// Sort the transactions belonging to the account so the checks can be simple
+	txs := p.index[addr]
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].nonce < txs[j].nonce
+	})
+	// Drop everything that was already included (nonce below the state's), and
+	// if there is a gap between the chain state and the pool, drop all as they
+	// are non-executable.
+	next := p.state.GetNonce(addr)
+
+	var stale int
+	for stale < len(txs) && txs[stale].nonce < next {
+		stale++
+	}
+	if stale > 0 {
+		log.Trace("Dropping included blob transactions", "from", addr, "count", stale)
+		p.remove(addr, txs[:stale])
+		txs = txs[stale:]
+	}
+	if len(txs) > 0 && txs[0].nonce > next {
+		log.Trace("Dropping gapped blob transactions", "from", addr, "missing", next, "drop", txs[0].nonce, "count", len(txs))
+		p.remove(addr, txs)
+		txs = nil
+	}
+	// Iterate over the transactions to initialize their eviction thresholds
+	// and to detect any nonce gaps
+	for i := 0; i < len(txs); i++ {
+		// The first transaction will always use itself
+		if i == 0 {
+			txs[0].evictionExecTip = txs[0].execTipCap
+			txs[0].evictionExecFeeJumps = txs[0].basefeeJumps
+			txs[0].evictionBlobFeeJumps = txs[0].blobfeeJumps
+			continue
+		}
+		// If there's no nonce gap, initialize the eviction thresholds as the
+		// minimum between the cumulative thresholds and the current tx fees
+		if txs[i].nonce == txs[i-1].nonce+1 {
+			p.updateEviction(txs[i-1], txs[i])
+			continue
+		}
+		// Sanity check that there's no double nonce. This case would be a coding
+		// error, but better know about it
+		if txs[i].nonce == txs[i-1].nonce {
+			log.Error("Duplicate nonce blob transaction", "from", addr, "nonce", txs[i].nonce)
+		}
+		// Otherwise if there's a nonce gap evict all later transactions
+		log.Trace("Dropping gapped blob transactions", "from", addr, "missing", txs[i-1].nonce+1, "drop", txs[i].nonce, "count", len(txs)-i)
+		p.remove(addr, txs[i:])
+		txs = txs[:i]
+		break
+	}
+	// Ensure that there's no over-draft, this is expected to happen when some
+	// transactions get included without publishing on the network
+	if len(txs) > 0 {
+		balance := p.state.GetBalance(addr)
+		if p.spent[addr].Cmp(balance) > 0 {
+			// Evict the highest nonce transactions until the pending set falls under
+			// the account's available balance
+			var drop int
+			for p.spent[addr].Cmp(balance) > 0 {
+				drop++
+				p.remove(addr, txs[len(txs)-1:])
+				txs = txs[:len(txs)-1]
+			}
+			log.Trace("Dropping overdrafted blob transactions", "from", addr, "balance", balance, "count", drop)
+		}
+	}
+	// Sanity check that no account can have more queued transactions than the
+	// DoS protection threshold.
+	if len(txs) > maxTxsPerAccount {
+		log.Trace("Dropping overcapped blob transactions", "from", addr, "limit", maxTxsPerAccount, "count", len(txs)-maxTxsPerAccount)
+		p.remove(addr, txs[maxTxsPerAccount:])
+		txs = txs[:maxTxsPerAccount]
+	}
+	p.finalize(addr, txs)
+}
+
+// updateEviction calculates the cumulative eviction thresholds of a transaction
+// based on the thresholds of its preceding nonce.
+func (p *BlobPool) updateEviction(prev *blobTxMeta, tx *blobTxMeta) {
+	tx.evictionExecTip = tx.execTipCap
+	if prev.evictionExecTip.Cmp(tx.execTipCap) < 0 {
+		tx.evictionExecTip = prev.evictionExecTip
+	}
+	tx.evictionExecFeeJumps = tx.basefeeJumps
+	if prev.evictionExecFeeJumps < tx.basefeeJumps {
+		tx.evictionExecFeeJumps = prev.evictionExecFeeJumps
+	}
+	tx.evictionBlobFeeJumps = tx.blobfeeJumps
+	if prev.evictionBlobFeeJumps < tx.blobfeeJumps {
+		tx.evictionBlobFeeJumps = prev.evictionBlobFeeJumps
+	}
+}
+
+// remove deletes a batch of transactions belonging to the same account from the
+// persistent store and the lookup tables. The account's transaction list itself
+// is not updated, that is up to the caller via finalize.
+func (p *BlobPool) remove(addr common.Address, txs []*blobTxMeta) {
+	for _, tx := range txs {
+		if err := p.store.Delete(tx.hash[:]); err != nil {
+			log.Error("Failed to delete blob transaction", "hash", tx.hash, "err", err)
+		}
+		delete(p.lookup, tx.hash)
+		p.stored -= tx.size
+		p.spent[addr] = new(big.Int).Sub(p.spent[addr], tx.costCap)
+	}
+	dropStaleMeter.Mark(int64(len(txs)))
+}
+
+// finalize updates the transaction list of an account after some have been
+// removed, releasing the address reservation if nothing remained.
+func (p *BlobPool) finalize(addr common.Address, txs []*blobTxMeta) {
+	if len(txs) > 0 {
+		p.index[addr] = txs
+		return
+	}
+	delete(p.index, addr)
+	delete(p.spent, addr)
+	if err := p.reserve(addr, false); err != nil {
+		log.Error("Failed to release blob pool reservation", "address", addr, "err", err)
+	}
+}
+
+// Reset implements txpool.SubPool, allowing the blob pool's internal state to be
+// kept in sync with the main transaction pool's internal state.
+func (p *BlobPool) Reset(oldHead, newHead *types.Header) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	statedb, err := p.chain.StateAt(newHead.Root)
+	if err != nil {
+		log.Error("Failed to reset blobpool state", "err", err)
+		return
+	}
+	p.head = newHead
+	p.state = statedb
+
+	// Drop all the transactions that were included or invalidated by the new
+	// head and recompute the eviction order based on the new fees
+	for addr := range p.index {
+		p.recheck(addr)
+	}
+	p.basefee, p.blobfee = p.pendingFees()
+	p.evict = newPriceHeap(p.basefee, p.blobfee, p.index)
+
+	p.updateStorageMetrics()
+}
+
+// SetGasTip implements txpool.SubPool, allowing the blob pool's gas requirements
+// to be kept in sync with the main transaction pool's gas requirements.
+func (p *BlobPool) SetGasTip(tip *big.Int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// Store the new minimum gas tip
+	old := p.gasTip
+	p.gasTip = new(big.Int).Set(tip)
+
+	// If the min miner fee increased, remove transactions below the new threshold
+	if old == nil || p.gasTip.Cmp(old) > 0 {
+		for addr, txs := range p.index {
+			for i, tx := range txs {
+				if tx.execTipCap.Cmp(p.gasTip) < 0 {
+					// Drop the offending transaction and all subsequent ones
+					// to avoid creating a nonce gap
+					log.Trace("Dropping underpriced blob transactions", "from", addr, "tip", tx.execTipCap, "needed", p.gasTip, "count", len(txs)-i)
+					dropUnderpricedMeter.Mark(int64(len(txs) - i))
+
+					p.remove(addr, txs[i:])
+					p.finalize(addr, txs[:i])
+					break
+				}
+			}
+		}
+		p.evict = newPriceHeap(p.basefee, p.blobfee, p.index)
+		p.updateStorageMetrics()
+	}
+	log.Debug("Blobpool tip threshold updated", "tip", tip)
+}
+
+// validateTx checks whether a transaction is valid according to the consensus
+// rules and adheres to some heuristic limits of the local node (price and size).
+func (p *BlobPool) validateTx(tx *types.Transaction, blobs []kzg4844.Blob, commits []kzg4844.Commitment, proofs []kzg4844.Proof) error {
+	// Ensure the transaction adheres to basic pool filters (type, size, tip) and
+	// consensus rules
+	baseOpts := &txpool.ValidationOptions{
+		Config:  p.chain.Config(),
+		Accept:  1 << types.BlobTxType,
+		MaxSize: txMaxSize,
+		MinTip:  p.gasTip,
+	}
+	if err := txpool.ValidateTransaction(tx, blobs, commits, proofs, p.head, p.signer, baseOpts); err != nil {
+		return err
+	}
+	// Ensure the blob fee cap satisfies the minimum blob gas price
+	if tx.BlobGasFeeCapIntCmp(big.NewInt(params.BlobTxMinDataGasprice)) < 0 {
+		return fmt.Errorf("%w: blob fee cap %v, minimum needed %v", txpool.ErrUnderpriced, tx.BlobGasFeeCap(), params.BlobTxMinDataGasprice)
+	}
+	// Ensure the transaction adheres to the stateful pool filters (nonce, balance)
+	stateOpts := &txpool.ValidationOptionsWithState{
+		State: p.state,
+
+		FirstNonceGap: func(addr common.Address) uint64 {
+			// Nonce gaps are not permitted in the blob pool, the first gap will
+			// be the next nonce shifted by however many transactions we already
+			// have pooled.
+			return p.state.GetNonce(addr) + uint64(len(p.index[addr]))
+		},
+		ExistingExpenditure: func(addr common.Address) *big.Int {
+			if spent := p.spent[addr]; spent != nil {
+				return new(big.Int).Set(spent)
+			}
+			return new(big.Int)
+		},
+		ExistingCost: func(addr common.Address, nonce uint64) *big.Int {
+			next := p.state.GetNonce(addr)
+			if uint64(len(p.index[addr])) > nonce-next {
+				return new(big.Int).Set(p.index[addr][int(nonce-next)].costCap)
+			}
+			return nil
+		},
+	}
+	if err := txpool.ValidateTransactionWithState(tx, p.signer, stateOpts); err != nil {
+		return err
+	}
+	// If the transaction replaces an existing one, ensure that price bumps are
+	// adhered to.
+	var (
+		from, _ = types.Sender(p.signer, tx) // already validated above
+		next    = p.state.GetNonce(from)
+	)
+	if uint64(len(p.index[from])) > tx.Nonce()-next {
+		prev := p.index[from][int(tx.Nonce()-next)]
+
+		// Account can support the replacement, but the price bump must also be met
+		switch {
+		case tx.GasFeeCapIntCmp(prev.execFeeCap) <= 0:
+			return fmt.Errorf("%w: new tx gas fee cap %v <= %v queued", txpool.ErrReplaceUnderpriced, tx.GasFeeCap(), prev.execFeeCap)
+		case tx.GasTipCapIntCmp(prev.execTipCap) <= 0:
+			return fmt.Errorf("%w: new tx gas tip cap %v <= %v queued", txpool.ErrReplaceUnderpriced, tx.GasTipCap(), prev.execTipCap)
+		case tx.BlobGasFeeCapIntCmp(prev.blobFeeCap) <= 0:
+			return fmt.Errorf("%w: new tx blob gas fee cap %v <= %v queued", txpool.ErrReplaceUnderpriced, tx.BlobGasFeeCap(), prev.blobFeeCap)
+		}
+		var (
+			multiplier = big.NewInt(100 + int64(p.config.PriceBump))
+			onehundred = big.NewInt(100)
+
+			minGasFeeCap     = new(big.Int).Div(new(big.Int).Mul(multiplier, prev.execFeeCap), onehundred)
+			minGasTipCap     = new(big.Int).Div(new(big.Int).Mul(multiplier, prev.execTipCap), onehundred)
+			minBlobGasFeeCap = new(big.Int).Div(new(big.Int).Mul(multiplier, prev.blobFeeCap), onehundred)
+		)
+		switch {
+		case tx.GasFeeCapIntCmp(minGasFeeCap) < 0:
+			return fmt.Errorf("%w: new tx gas fee cap %v < %v queued + %d%% replacement penalty", txpool.ErrReplaceUnderpriced, tx.GasFeeCap(), prev.execFeeCap, p.config.PriceBump)
+		case tx.GasTipCapIntCmp(minGasTipCap) < 0:
+			return fmt.Errorf("%w: new tx gas tip cap %v < %v queued + %d%% replacement penalty", txpool.ErrReplaceUnderpriced, tx.GasTipCap(), prev.execTipCap, p.config.PriceBump)
+		case tx.BlobGasFeeCapIntCmp(minBlobGasFeeCap) < 0:
+			return fmt.Errorf("%w: new tx blob gas fee cap %v < %v queued + %d%% replacement penalty", txpool.ErrReplaceUnderpriced, tx.BlobGasFeeCap(), prev.blobFeeCap, p.config.PriceBump)
+		}
+		return nil
+	}
+	// Transaction is a new nonce, ensure the account limit is not exceeded
+	if len(p.index[from]) >= maxTxsPerAccount {
+		return fmt.Errorf("%w: %d pooled, %d permitted", txpool.ErrAccountLimitExceeded, len(p.index[from]), maxTxsPerAccount)
+	}
+	return nil
+}
+
+// Has returns an indicator whether subpool has a transaction cached with the
+// given hash.
+func (p *BlobPool) Has(hash common.Hash) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	_, ok := p.lookup[hash]
+	return ok
+}
+
+// Get returns a transaction if it is contained in the pool, or nil otherwise.
+func (p *BlobPool) Get(hash common.Hash) *txpool.Transaction {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	item := p.get(hash)
+	if item == nil {
+		return nil
+	}
+	return &txpool.Transaction{
+		Tx:            item.Tx,
+		BlobTxBlobs:   item.Blobs,
+		BlobTxCommits: item.Commits,
+		BlobTxProofs:  item.Proofs,
+	}
+}
+
+// get retrieves a tracked transaction along with its sidecar from the persistent
+// store. The pool lock must be held.
+func (p *BlobPool) get(hash common.Hash) *blobTx {
+	if _, ok := p.lookup[hash]; !ok {
+		return nil
+	}
+	data, err := p.store.Get(hash[:])
+	if err != nil {
+		log.Error("Tracked blob transaction missing from store", "hash", hash, "err", err)
+		return nil
+	}
+	item := new(blobTx)
+	if err = rlp.DecodeBytes(data, item); err != nil {
+		log.Error("Blobs corrupted for traced transaction", "hash", hash, "err", err)
+		return nil
+	}
+	return item
+}
+
+// Add inserts a set of blob transactions into the pool if they pass validation
+// (both consensus validity and pool restrictions).
+//
+// Note, the blob pool does not distinguish local transactions, all pooled ones
+// are persisted and subject to the same eviction rules.
+func (p *BlobPool) Add(txs []*txpool.Transaction, local bool, sync bool) []error {
+	var (
+		adds = make([]*types.Transaction, 0, len(txs))
+		errs = make([]error, len(txs))
+	)
+	for i, tx := range txs {
+		errs[i] = p.add(tx)
+		if errs[i] == nil {
+			adds = append(adds, tx.Tx)
+		}
+	}
+	if len(adds) > 0 {
+		p.eventFeed.Send(core.NewTxsEvent{Txs: adds})
+	}
+	return errs
+}
+
+// add inserts a new blob transaction into the pool if it passes validation (both
+// consensus validity and pool restrictions).
+func (p *BlobPool) add(tx *txpool.Transaction) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// Ensure the transaction is not already tracked by the pool
+	hash := tx.Tx.Hash()
+	if _, ok := p.lookup[hash]; ok {
+		return txpool.ErrAlreadyKnown
+	}
+	// Ensure the transaction is valid from all perspectives
+	if err := p.validateTx(tx.Tx, tx.BlobTxBlobs, tx.BlobTxCommits, tx.BlobTxProofs); err != nil {
+		log.Trace("Transaction validation failed", "hash", hash, "err", err)
+		addInvalidMeter.Mark(1)
+		return err
+	}
+	// If the address is not yet known, request exclusivity to track the account
+	// only by this subpool until all transactions are evicted
+	from, _ := types.Sender(p.signer, tx.Tx) // already validated above
+
+	_, known := p.index[from]
+	if !known {
+		if err := p.reserve(from, true); err != nil {
+			return err
+		}
+	}
+	// Transaction permitted into the pool from a nonce and cost perspective,
+	// insert it into the database and update the indices
+	blob, err := rlp.EncodeToBytes(&blobTx{
+		Tx:      tx.Tx,
+		Blobs:   tx.BlobTxBlobs,
+		Commits: tx.BlobTxCommits,
+		Proofs:  tx.BlobTxProofs,
+	})
+	if err == nil {
+		err = p.store.Put(hash[:], blob)
+	}
+	if err != nil {
+		log.Error("Failed to store blob transaction", "hash", hash, "err", err)
+		if !known {
+			p.reserve(from, false)
+		}
+		return err
+	}
+	meta := newBlobTxMeta(uint64(len(blob)), tx.Tx)
+
+	var (
+		next   = p.state.GetNonce(from)
+		offset = int(tx.Tx.Nonce() - next)
+	)
+	if len(p.index[from]) > offset {
+		// Transaction replaces a previously queued one
+		prev := p.index[from][offset]
+		if err := p.store.Delete(prev.hash[:]); err != nil {
+			// Bad situation, but try to recover gracefully instead of going boom
+			log.Error("Failed to delete replaced transaction", "hash", prev.hash, "err", err)
+		}
+		delete(p.lookup, prev.hash)
+		p.stored -= prev.size
+
+		p.index[from][offset] = meta
+		p.spent[from] = new(big.Int).Sub(p.spent[from], prev.costCap)
+		p.spent[from].Add(p.spent[from], meta.costCap)
+	} else {
+		// Transaction extends previously scheduled ones
+		p.index[from] = append(p.index[from], meta)
+		if _, ok := p.spent[from]; !ok {
+			p.spent[from] = new(big.Int)
+		}
+		p.spent[from] = new(big.Int).Add(p.spent[from], meta.costCap)
+	}
+	p.lookup[meta.hash] = from
+	p.stored += meta.size
+
+	// Recompute the rolling eviction fields. In case of a replacement, this will
+	// recompute all subsequent fields. In case of an append, this will only do
+	// the fresh calculation.
+	txs := p.index[from]
+	for i := offset; i < len(txs); i++ {
+		// The first transaction will always use itself
+		if i == 0 {
+			txs[0].evictionExecTip = txs[0].execTipCap
+			txs[0].evictionExecFeeJumps = txs[0].basefeeJumps
+			txs[0].evictionBlobFeeJumps = txs[0].blobfeeJumps
+			continue
+		}
+		// Subsequent transactions will use a rolling calculation
+		p.updateEviction(txs[i-1], txs[i])
+	}
+	// Update the eviction heap with the new information:
+	//   - If the transaction is from a new account, add it to the heap
+	//   - Otherwise the account's bottleneck may have changed, fix the heap
+	if !known {
+		heap.Push(p.evict, from)
+	} else {
+		heap.Fix(p.evict, p.evict.index[from])
+	}
+	// If the pool went over the allowed data limit, evict transactions until
+	// we're again below the threshold
+	for p.stored > p.config.Datacap {
+		p.drop()
+	}
+	p.updateStorageMetrics()
+
+	// If the freshly added transaction was the cheapest one, it might have been
+	// evicted immediately, report it as underpriced in that case
+	if _, ok := p.lookup[hash]; !ok {
+		return fmt.Errorf("%w: pool full, evicted as cheapest", txpool.ErrUnderpriced)
+	}
+	addValidMeter.Mark(1)
+	return nil
+}
+
+// drop removes the worst transaction from the pool. It is primarily used when a
+// freshly added transaction overflows the pool and needs to evict something.
+func (p *BlobPool) drop() {
+	// Peek at the account with the worse transaction set to evict from (Go's heap
+	// stores the minimum at index zero of the heap slice) and retrieve it's last
+	// transaction.
+	var (
+		from = p.evict.addrs[0] // cannot call drop on empty pool
+		txs  = p.index[from]
+		drop = txs[len(txs)-1]
+		last = len(txs) == 1
+	)
+	log.Debug("Evicting overflown blob transaction", "from", from, "evicted", drop.nonce, "hash", drop.hash)
+	dropOverflownMeter.Mark(1)
+
+	// Remove the transaction from the pool's index and the data store
+	if err := p.store.Delete(drop.hash[:]); err != nil {
+		log.Error("Failed to drop evicted transaction", "hash", drop.hash, "err", err)
+	}
+	delete(p.lookup, drop.hash)
+	p.stored -= drop.size
+	p.spent[from] = new(big.Int).Sub(p.spent[from], drop.costCap)
+
+	// Remove the transaction from the pool's eviction heap:
+	//   - If the entire account was dropped, pop off the address
+	//   - Otherwise, if the new tail has better eviction caps, fix the heap
+	if last {
+		heap.Pop(p.evict)
+		p.finalize(from, nil)
+	} else {
+		txs[len(txs)-1] = nil
+		p.finalize(from, txs[:len(txs)-1])
+		heap.Fix(p.evict, 0)
+	}
+}
+
+// Pending retrieves all currently processable transactions, grouped by origin
+// account and sorted by nonce.
+func (p *BlobPool) Pending(enforceTips bool) map[common.Address][]*txpool.LazyTransaction {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	pending := make(map[common.Address][]*txpool.LazyTransaction)
+	for addr, txs := range p.index {
+		var lazies []*txpool.LazyTransaction
+		for _, tx := range txs {
+			// Stop at the first transaction that cannot be included in the next
+			// block, all subsequent ones are blocked by it
+			if tx.execFeeCap.Cmp(p.basefee) < 0 || tx.blobFeeCap.Cmp(p.blobfee) < 0 {
+				break
+			}
+			if enforceTips && tx.execTipCap.Cmp(p.gasTip) < 0 {
+				break
+			}
+			lazies = append(lazies, &txpool.LazyTransaction{
+				Pool:      p,
+				Hash:      tx.hash,
+				Time:      tx.time,
+				GasFeeCap: new(big.Int).Set(tx.execFeeCap),
+				GasTipCap: new(big.Int).Set(tx.execTipCap),
+			})
+		}
+		if len(lazies) > 0 {
+			pending[addr] = lazies
+		}
+	}
+	return pending
+}
+
+// updateStorageMetrics retrieves a bunch of stats from the data store and pushes
+// them out as metrics.
+func (p *BlobPool) updateStorageMetrics() {
+	datausedGauge.Update(int64(p.stored))
+	txsGauge.Update(int64(len(p.lookup)))
+}
+
+// SubscribeTransactions registers a subscription of NewTxsEvent and
+// starts sending event to the given channel.
+func (p *BlobPool) SubscribeTransactions(ch chan<- core.NewTxsEvent) event.Subscription {
+	return p.eventScope.Track(p.eventFeed.Subscribe(ch))
+}
+
+// Nonce returns the next nonce of an account, with all transactions executable
+// by the pool already applied on top.
+func (p *BlobPool) Nonce(addr common.Address) uint64 {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if txs, ok := p.index[addr]; ok {
+		return txs[len(txs)-1].nonce + 1
+	}
+	return p.state.GetNonce(addr)
+}
+
+// Stats retrieves the current pool stats, namely the number of pending and the
+// number of queued (non-executable) transactions.
+func (p *BlobPool) Stats() (int, int) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return len(p.lookup), 0
+}
+
+// Content retrieves the data content of the transaction pool, returning all the
+// pending as well as queued transactions, grouped by account and sorted by nonce.
+//
+// Note, the blob pool does not track queued transactions, and the pending ones
+// need to be loaded from disk, so this method is quite expensive.
+func (p *BlobPool) Content() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	pending := make(map[common.Address]types.Transactions, len(p.index))
+	for addr := range p.index {
+		if txs := p.content(addr); len(txs) > 0 {
+			pending[addr] = txs
+		}
+	}
+	return pending, make(map[common.Address]types.Transactions)
+}
+
+// ContentFrom retrieves the data content of the transaction pool, returning the
+// pending as well as queued transactions of this address, grouped by nonce.
+func (p *BlobPool) ContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.content(addr), types.Transactions{}
+}
+
+// content loads all the transactions of an account from the persistent store.
+// The pool lock must be held.
+func (p *BlobPool) content(addr common.Address) types.Transactions {
+	txs := make(types.Transactions, 0, len(p.index[addr]))
+	for _, meta := range p.index[addr] {
+		if item := p.get(meta.hash); item != nil {
+			txs = append(txs, item.Tx)
+		}
+	}
+	return txs
+}
+
+// Locals retrieves the accounts currently considered local by the pool. The blob
+// pool does not distinguish local accounts, so it always returns an empty set.
+func (p *BlobPool) Locals() []common.Address {
+	return []common.Address{}
+}
+
+// Status returns the known status (unknown/pending/queued) of a transaction
+// identified by their hashes.
+func (p *BlobPool) Status(hash common.Hash) txpool.TxStatus {
+	if p.Has(hash) {
+		return txpool.TxStatusPending
+	}
+	return txpool.TxStatusUnknown
+}
@@ -0,0 +1,328 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blobpool
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+var (
+	emptyBlob       kzg4844.Blob
+	emptyBlobCommit kzg4844.Commitment
+	emptyBlobProof  kzg4844.Proof
+	emptyBlobVHash  common.Hash
+
+	emptyBlobOnce sync.Once
+)
+
+// initEmptyBlob lazily computes the KZG commitment and proof of the all-zero
+// blob, since the cryptography is slow enough to not want it in package init.
+func initEmptyBlob(t *testing.T) {
+	emptyBlobOnce.Do(func() {
+		var err error
+		if emptyBlobCommit, err = kzg4844.BlobToCommitment(emptyBlob); err != nil {
+			panic(err)
+		}
+		if emptyBlobProof, err = kzg4844.ComputeBlobProof(emptyBlob, emptyBlobCommit); err != nil {
+			panic(err)
+		}
+		hash := sha256.Sum256(emptyBlobCommit[:])
+		emptyBlobVHash[0] = params.BlobTxHashVersion
+		copy(emptyBlobVHash[1:], hash[1:])
+	})
+}
+
+// testChainConfig is a chain config with Cancun enabled from genesis.
+var testChainConfig *params.ChainConfig
+
+func init() {
+	testChainConfig = new(params.ChainConfig)
+	*testChainConfig = *params.AllEthashProtocolChanges
+
+	testChainConfig.ShanghaiTime = new(uint64)
+	testChainConfig.CancunTime = new(uint64)
+}
+
+// testBlockChain is a mock of the live chain for testing the pool.
+type testBlockChain struct {
+	config  *params.ChainConfig
+	basefee *big.Int
+	statedb *state.StateDB
+}
+
+func (bc *testBlockChain) Config() *params.ChainConfig {
+	return bc.config
+}
+
+func (bc *testBlockChain) CurrentBlock() *types.Header {
+	var (
+		excessDataGas uint64
+		dataGasUsed   uint64
+	)
+	return &types.Header{
+		Number:        big.NewInt(1),
+		GasLimit:      30_000_000,
+		BaseFee:       bc.basefee,
+		ExcessDataGas: &excessDataGas,
+		DataGasUsed:   &dataGasUsed,
+	}
+}
+
+func (bc *testBlockChain) StateAt(common.Hash) (*state.StateDB, error) {
+	return bc.statedb, nil
+}
+
+// newTestChain creates a mock chain with the given accounts funded.
+func newTestChain(funded ...common.Address) *testBlockChain {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	for _, addr := range funded {
+		statedb.AddBalance(addr, big.NewInt(params.Ether))
+	}
+	return &testBlockChain{
+		config:  testChainConfig,
+		basefee: big.NewInt(params.InitialBaseFee),
+		statedb: statedb,
+	}
+}
+
+// makeAddressReserver is a utility method to sanity check that accounts are
+// properly reserved by the blobpool (no duplicate reserves or unreserves).
+func makeAddressReserver() txpool.AddressReserver {
+	var (
+		reserved = make(map[common.Address]struct{})
+		lock     sync.Mutex
+	)
+	return func(addr common.Address, reserve bool) error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		_, exists := reserved[addr]
+		if reserve {
+			if exists {
+				panic("already reserved")
+			}
+			reserved[addr] = struct{}{}
+			return nil
+		}
+		if !exists {
+			panic("not reserved")
+		}
+		delete(reserved, addr)
+		return nil
+	}
+}
+
+// makeTx creates a signed blob transaction (along with its sidecar) carrying a
+// single empty blob.
+func makeTx(t *testing.T, nonce uint64, tip, fee, blobFee uint64, key *ecdsa.PrivateKey) *txpool.Transaction {
+	initEmptyBlob(t)
+
+	tx := types.MustSignNewTx(key, types.LatestSigner(testChainConfig), &types.BlobTx{
+		ChainID:    uint256.MustFromBig(testChainConfig.ChainID),
+		Nonce:      nonce,
+		GasTipCap:  uint256.NewInt(tip),
+		GasFeeCap:  uint256.NewInt(fee),
+		Gas:        21000,
+		BlobFeeCap: uint256.NewInt(blobFee),
+		BlobHashes: []common.Hash{emptyBlobVHash},
+		Value:      uint256.NewInt(100),
+	})
+	return &txpool.Transaction{
+		Tx:            tx,
+		BlobTxBlobs:   []kzg4844.Blob{emptyBlob},
+		BlobTxCommits: []kzg4844.Commitment{emptyBlobCommit},
+		BlobTxProofs:  []kzg4844.Proof{emptyBlobProof},
+	}
+}
+
+// newTestPool creates and initializes a blob pool on top of the given chain.
+func newTestPool(t *testing.T, config Config, chain *testBlockChain) *BlobPool {
+	pool := New(config, chain)
+	if err := pool.Init(big.NewInt(1), chain.CurrentBlock(), makeAddressReserver()); err != nil {
+		t.Fatalf("failed to create blob pool: %v", err)
+	}
+	return pool
+}
+
+// Tests that blob transactions are accepted, retrievable and that the nonce and
+// replacement rules of the pool are enforced.
+func TestAdd(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		chain  = newTestChain(addr)
+		pool   = newTestPool(t, Config{Datacap: DefaultConfig.Datacap, PriceBump: 100}, chain)
+	)
+	defer pool.Close()
+
+	// Add a gapless sequence of transactions and ensure they are accepted
+	txs := []*txpool.Transaction{
+		makeTx(t, 0, 10, params.GWei, 10, key),
+		makeTx(t, 1, 10, params.GWei, 10, key),
+	}
+	for i, err := range pool.Add(txs, false, true) {
+		if err != nil {
+			t.Fatalf("tx %d: failed to add: %v", i, err)
+		}
+	}
+	if err := pool.Add(txs[:1], false, true)[0]; !errors.Is(err, txpool.ErrAlreadyKnown) {
+		t.Errorf("duplicate error mismatch: have %v, want %v", err, txpool.ErrAlreadyKnown)
+	}
+	for i, tx := range txs {
+		if !pool.Has(tx.Tx.Hash()) {
+			t.Errorf("tx %d: not found in pool", i)
+		}
+		got := pool.Get(tx.Tx.Hash())
+		if got == nil || got.Tx.Hash() != tx.Tx.Hash() || len(got.BlobTxBlobs) != 1 || got.BlobTxCommits[0] != emptyBlobCommit {
+			t.Errorf("tx %d: retrieved transaction mismatch", i)
+		}
+	}
+	if nonce := pool.Nonce(addr); nonce != 2 {
+		t.Errorf("pool nonce mismatch: have %d, want %d", nonce, 2)
+	}
+	// Nonce gaps are not permitted in the blob pool
+	if err := pool.Add([]*txpool.Transaction{makeTx(t, 3, 10, params.GWei, 10, key)}, false, true)[0]; !errors.Is(err, core.ErrNonceTooHigh) {
+		t.Errorf("gapped error mismatch: have %v, want %v", err, core.ErrNonceTooHigh)
+	}
+	// Replacements need to bump all the fees by the configured percentage
+	if err := pool.Add([]*txpool.Transaction{makeTx(t, 1, 15, 2*params.GWei, 20, key)}, false, true)[0]; !errors.Is(err, txpool.ErrReplaceUnderpriced) {
+		t.Errorf("underpriced replacement error mismatch: have %v, want %v", err, txpool.ErrReplaceUnderpriced)
+	}
+	replacement := makeTx(t, 1, 20, 2*params.GWei, 20, key)
+	if err := pool.Add([]*txpool.Transaction{replacement}, false, true)[0]; err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	if pool.Has(txs[1].Tx.Hash()) || !pool.Has(replacement.Tx.Hash()) {
+		t.Errorf("replacement not tracked correctly")
+	}
+	// Ensure the pending set is returned in nonce order
+	pending := pool.Pending(true)[addr]
+	if len(pending) != 2 || pending[0].Hash != txs[0].Tx.Hash() || pending[1].Hash != replacement.Tx.Hash() {
+		t.Errorf("pending transactions mismatch: %v", pending)
+	}
+	if tx := pending[1].Resolve(); tx == nil || tx.Tx.Hash() != replacement.Tx.Hash() {
+		t.Errorf("failed to resolve lazy transaction")
+	}
+	// Transactions whose fee cap is below the base fee are pooled but not pending
+	if err := pool.Add([]*txpool.Transaction{makeTx(t, 2, 1, 1, 10, key)}, false, true)[0]; err != nil {
+		t.Fatalf("failed to add underpriced transaction: %v", err)
+	}
+	if pending := pool.Pending(true)[addr]; len(pending) != 2 {
+		t.Errorf("pending transaction count mismatch: have %d, want %d", len(pending), 2)
+	}
+}
+
+// Tests that pooled transactions are persisted to disk and reloaded on restart,
+// dropping anything that became stale in the meantime.
+func TestPersistence(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		chain   = newTestChain(addr)
+		datadir = t.TempDir()
+		config  = Config{Datadir: datadir, Datacap: DefaultConfig.Datacap, PriceBump: 100}
+		pool    = newTestPool(t, config, chain)
+	)
+	txs := []*txpool.Transaction{
+		makeTx(t, 0, 10, params.GWei, 10, key),
+		makeTx(t, 1, 10, params.GWei, 10, key),
+		makeTx(t, 2, 10, params.GWei, 10, key),
+	}
+	for i, err := range pool.Add(txs, false, true) {
+		if err != nil {
+			t.Fatalf("tx %d: failed to add: %v", i, err)
+		}
+	}
+	pool.Close()
+
+	// Mark the first transaction included and reopen the pool
+	chain.statedb.SetNonce(addr, 1)
+
+	pool = newTestPool(t, config, chain)
+	defer pool.Close()
+
+	if pool.Has(txs[0].Tx.Hash()) {
+		t.Errorf("included transaction not dropped")
+	}
+	for i, tx := range txs[1:] {
+		if got := pool.Get(tx.Tx.Hash()); got == nil || got.Tx.Hash() != tx.Tx.Hash() {
+			t.Errorf("tx %d: not reloaded from disk", i+1)
+		}
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Errorf("pending count mismatch: have %d, want %d", pending, 2)
+	}
+}
+
+// Tests that the pool evicts the cheapest account's transactions when the data
+// cap is exceeded.
+func TestEviction(t *testing.T) {
+	var (
+		keys  = make([]*ecdsa.PrivateKey, 3)
+		addrs = make([]common.Address, 3)
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	var (
+		chain = newTestChain(addrs...)
+		pool  = newTestPool(t, Config{Datacap: 5 * params.BlobTxDataGasPerBlob / 2, PriceBump: 100}, chain)
+	)
+	defer pool.Close()
+
+	cheap := makeTx(t, 0, 1, params.GWei, 10, keys[0])
+	if err := pool.Add([]*txpool.Transaction{cheap}, false, true)[0]; err != nil {
+		t.Fatalf("failed to add cheap transaction: %v", err)
+	}
+	if err := pool.Add([]*txpool.Transaction{makeTx(t, 0, 10, params.GWei, 10, keys[1])}, false, true)[0]; err != nil {
+		t.Fatalf("failed to add first pricey transaction: %v", err)
+	}
+	// Pool overflows with the third transaction, the cheapest one must go
+	if err := pool.Add([]*txpool.Transaction{makeTx(t, 0, 10, params.GWei, 10, keys[2])}, false, true)[0]; err != nil {
+		t.Fatalf("failed to add second pricey transaction: %v", err)
+	}
+	if pool.Has(cheap.Tx.Hash()) {
+		t.Errorf("cheapest transaction not evicted")
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Errorf("pending count mismatch: have %d, want %d", pending, 2)
+	}
+	// Adding an even cheaper transaction should get immediately rejected
+	if err := pool.Add([]*txpool.Transaction{makeTx(t, 0, 1, params.GWei, 10, keys[0])}, false, true)[0]; !errors.Is(err, txpool.ErrUnderpriced) {
+		t.Errorf("overflow error mismatch: have %v, want %v", err, txpool.ErrUnderpriced)
+	}
+	if pool.stored > pool.config.Datacap {
+		t.Errorf("pool over capacity: stored %d, cap %d", pool.stored, pool.config.Datacap)
+	}
+}
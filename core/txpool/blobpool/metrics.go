@@ -0,0 +1,39 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blobpool
+
+import "github.com/ethereum/go-ethereum/metrics"
+
+var (
+	// datacapGauge tracks the user's configured capacity for the blob pool. It
+	// is mostly a way to expose/debug issues.
+	datacapGauge = metrics.NewRegisteredGauge("blobpool/datacap", nil)
+
+	// The below metrics track the storage usage and the number of transactions
+	// tracked by the blob pool.
+	datausedGauge = metrics.NewRegisteredGauge("blobpool/dataused", nil)
+	txsGauge      = metrics.NewRegisteredGauge("blobpool/transactions", nil)
+
+	// Additions track the outcome of transaction insertions into the pool.
+	addInvalidMeter = metrics.NewRegisteredMeter("blobpool/add/invalid", nil)
+	addValidMeter   = metrics.NewRegisteredMeter("blobpool/add/valid", nil)
+
+	// Removals track the reasons transactions were dropped from the pool.
+	dropStaleMeter       = metrics.NewRegisteredMeter("blobpool/drop/stale", nil)       // Included, gapped or overdrafted
+	dropUnderpricedMeter = metrics.NewRegisteredMeter("blobpool/drop/underpriced", nil) // Gas tip raised above the tx's
+	dropOverflownMeter   = metrics.NewRegisteredMeter("blobpool/drop/overflown", nil)   // Evicted due to the storage cap
+)
@@ -23,8 +23,10 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -32,7 +34,8 @@ import (
 // AdminAPI is the collection of Ethereum full node related APIs for node
 // administration.
 type AdminAPI struct {
-	eth *Ethereum
+	eth    *Ethereum
+	backup sync.Mutex // Lock preventing concurrent database backups
 }
 
 // NewAdminAPI creates a new instance of AdminAPI.
@@ -137,3 +140,15 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	}
 	return true, nil
 }
+
+// Backup creates a consistent copy of the chain database in the given directory
+// while the node keeps running. If the directory already holds a backup of the
+// same chain, only the ancient data appended since is copied.
+func (api *AdminAPI) Backup(dir string) (*rawdb.BackupManifest, error) {
+	if !api.backup.TryLock() {
+		return nil, errors.New("backup already in progress")
+	}
+	defer api.backup.Unlock()
+
+	return rawdb.Backup(api.eth.ChainDb(), dir)
+}
@@ -418,3 +418,35 @@ func (api *DebugAPI) SetTrieFlushInterval(interval string) error {
 func (api *DebugAPI) GetTrieFlushInterval() string {
 	return api.eth.blockchain.GetTrieFlushInterval().String()
 }
+
+// TxIndexProgress is the progress of the background transaction indexing.
+type TxIndexProgress struct {
+	Head      hexutil.Uint64  `json:"head"`
+	Tail      *hexutil.Uint64 `json:"tail"`
+	Limit     hexutil.Uint64  `json:"limit"`
+	Indexed   hexutil.Uint64  `json:"indexed"`
+	Remaining hexutil.Uint64  `json:"remaining"`
+	Running   bool            `json:"running"`
+}
+
+// TxIndexProgress returns the progress of the background transaction indexing,
+// which (un)indexes the transactions whenever the head or the txlookup limit
+// changes.
+func (api *DebugAPI) TxIndexProgress() (*TxIndexProgress, error) {
+	progress, err := api.eth.blockchain.TxIndexProgress()
+	if err != nil {
+		return nil, err
+	}
+	result := &TxIndexProgress{
+		Head:      hexutil.Uint64(progress.Head),
+		Limit:     hexutil.Uint64(progress.Limit),
+		Indexed:   hexutil.Uint64(progress.Indexed),
+		Remaining: hexutil.Uint64(progress.Remaining),
+		Running:   progress.Running,
+	}
+	if progress.Tail != nil {
+		tail := hexutil.Uint64(*progress.Tail)
+		result.Tail = &tail
+	}
+	return result, nil
+}
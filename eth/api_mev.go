@@ -0,0 +1,67 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/miner"
+)
+
+// MevAPI provides an API to submit transaction bundles to the block builder.
+type MevAPI struct {
+	e *Ethereum
+}
+
+// NewMevAPI creates a new MevAPI instance.
+func NewMevAPI(e *Ethereum) *MevAPI {
+	return &MevAPI{e}
+}
+
+// SendBundleArgs represents the arguments for submitting a transaction bundle.
+type SendBundleArgs struct {
+	Txs               []hexutil.Bytes `json:"txs"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	RevertingTxHashes []common.Hash   `json:"revertingTxHashes"`
+}
+
+// SendBundle submits a bundle of signed transactions to be included atomically
+// at the front of the given block, which must be one of the next 25 blocks. The
+// bundle is only included if all of its transactions succeed, apart from the ones
+// explicitly allowed to revert, and it increases the profit of the block's
+// coinbase. The hash of the bundle is returned on successful submission.
+func (api *MevAPI) SendBundle(args SendBundleArgs) (common.Hash, error) {
+	bundle := &miner.Bundle{
+		Txs:               make(types.Transactions, 0, len(args.Txs)),
+		BlockNumber:       uint64(args.BlockNumber),
+		RevertingTxHashes: args.RevertingTxHashes,
+	}
+	for i, encoded := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(encoded); err != nil {
+			return common.Hash{}, fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+		bundle.Txs = append(bundle.Txs, tx)
+	}
+	if err := api.e.Miner().AddBundle(bundle); err != nil {
+		return common.Hash{}, err
+	}
+	return bundle.Hash(), nil
+}
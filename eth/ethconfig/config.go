@@ -27,7 +27,8 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
-	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
+	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -69,11 +70,15 @@ var Defaults = Config{
 	TrieDirtyCache:          256,
 	TrieTimeout:             60 * time.Minute,
 	SnapshotCache:           102,
+	StateHistory:            params.FullImmutabilityThreshold,
+	StateDiffHistory:        params.FullImmutabilityThreshold,
 	FilterLogCacheSize:      32,
 	Miner:                   miner.DefaultConfig,
-	TxPool:                  txpool.DefaultConfig,
+	TxPool:                  legacypool.DefaultConfig,
+	BlobPool:                blobpool.DefaultConfig,
 	RPCGasCap:               50000000,
 	RPCEVMTimeout:           5 * time.Second,
+	RPCTraceFilterRange:     100,
 	GPO:                     FullNodeGPO,
 	RPCTxFeeCap:             1, // 1 ether
 }
@@ -99,6 +104,8 @@ type Config struct {
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	LogIndex      bool   `toml:",omitempty"` // Whether to maintain a persistent address/topic index of the chain's logs
+	ChainHistory  uint64 `toml:",omitempty"` // The maximum number of blocks from head whose bodies and receipts are reserved.
 
 	// RequiredBlocks is a set of block number -> hash mappings which must be in the
 	// canonical chain of all remote peers. Setting the option makes geth verify the
@@ -132,6 +139,14 @@ type Config struct {
 	SnapshotCache           int
 	Preimages               bool
 
+	// State scheme and history options.
+	StateHistory uint64 `toml:",omitempty"` // The maximum number of blocks from head whose state histories are reserved.
+	StateScheme  string `toml:",omitempty"` // State scheme used to store ethereum state and merkle trie nodes on top
+
+	// State diff options, serving historic state without an archive node.
+	StateDiffs       bool   `toml:",omitempty"` // Whether to maintain the state reverse diffs for historic state access
+	StateDiffHistory uint64 `toml:",omitempty"` // The maximum number of blocks from head whose state reverse diffs are reserved.
+
 	// This is the number of blocks for which logs will be cached in the filter system.
 	FilterLogCacheSize int
 
@@ -139,7 +154,8 @@ type Config struct {
 	Miner miner.Config
 
 	// Transaction pool options
-	TxPool txpool.Config
+	TxPool   legacypool.Config
+	BlobPool blobpool.Config
 
 	// Gas Price Oracle options
 	GPO gasprice.Config
@@ -147,6 +163,10 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Enables the live tracer of the given name, with the given JSON config
+	VMTrace       string
+	VMTraceConfig string
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 
@@ -156,6 +176,10 @@ type Config struct {
 	// RPCEVMTimeout is the global timeout for eth-call.
 	RPCEVMTimeout time.Duration
 
+	// RPCTraceFilterRange is the maximum number of blocks trace_filter may
+	// cover in a single request.
+	RPCTraceFilterRange uint64
+
 	// RPCTxFeeCap is the global transaction fee(price * gaslimit) cap for
 	// send-transaction variants. The unit is ether.
 	RPCTxFeeCap float64
@@ -20,10 +20,13 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -198,6 +201,20 @@ func (f *Filter) rangeLogsAsync(ctx context.Context) (chan *types.Log, chan erro
 			size, sections = f.sys.backend.BloomStatus()
 			err            error
 		)
+		// Prefer the exact log index if available, falling back to the bloom bits
+		// for anything not covered by it
+		if f.logIndexable() {
+			if size, sections := f.sys.backend.LogIndexStatus(); sections*size > uint64(f.begin) {
+				indexed := sections * size
+				if indexed > end {
+					indexed = end + 1
+				}
+				if err = f.logIndexedLogs(ctx, size, indexed-1, logChan); err != nil {
+					errChan <- err
+					return
+				}
+			}
+		}
 		if indexed := sections * size; indexed > uint64(f.begin) {
 			if indexed > end {
 				indexed = end + 1
@@ -265,6 +282,101 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64, logChan chan *type
 	}
 }
 
+// logIndexable reports whether the filter criteria can be resolved through the
+// log index, that is whether any address or topic restriction is present.
+func (f *Filter) logIndexable() bool {
+	if len(f.addresses) > 0 {
+		return true
+	}
+	for _, sub := range f.topics {
+		if len(sub) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// logIndexedLogs returns the logs matching the filter criteria based on the
+// persistent log index. Contrary to the bloom bits the index is exact, so only
+// the receipts of blocks really containing matching logs are retrieved.
+func (f *Filter) logIndexedLogs(ctx context.Context, size uint64, end uint64, logChan chan *types.Log) error {
+	db := f.sys.backend.ChainDb()
+	for f.begin <= int64(end) {
+		// Resolve the matches section by section to bound the memory use
+		last := (uint64(f.begin)/size+1)*size - 1
+		if last > end {
+			last = end
+		}
+		for _, number := range f.logIndexMatches(db, uint64(f.begin), last) {
+			header, err := f.sys.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if header == nil || err != nil {
+				return err
+			}
+			found, err := f.checkMatches(ctx, header)
+			if err != nil {
+				return err
+			}
+			for _, log := range found {
+				select {
+				case logChan <- log:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+		f.begin = int64(last) + 1
+	}
+	return nil
+}
+
+// logIndexMatches returns the sorted numbers of the blocks in the given range
+// that contain a log satisfying all address and positional topic criteria of
+// the filter, according to the log index.
+func (f *Filter) logIndexMatches(db ethdb.Iteratee, from uint64, to uint64) []uint64 {
+	// Intersect the log positions matching each individual criterion
+	var matches map[rawdb.LogPosition]struct{}
+	intersect := func(positions []rawdb.LogPosition) {
+		set := make(map[rawdb.LogPosition]struct{})
+		for _, pos := range positions {
+			if _, ok := matches[pos]; ok || matches == nil {
+				set[pos] = struct{}{}
+			}
+		}
+		matches = set
+	}
+	if len(f.addresses) > 0 {
+		var positions []rawdb.LogPosition
+		for _, address := range f.addresses {
+			positions = append(positions, rawdb.ReadAddressLogIndex(db, address, from, to)...)
+		}
+		intersect(positions)
+	}
+	for i, sub := range f.topics {
+		if len(sub) == 0 {
+			continue
+		}
+		if matches != nil && len(matches) == 0 {
+			return nil
+		}
+		var positions []rawdb.LogPosition
+		for _, topic := range sub {
+			positions = append(positions, rawdb.ReadTopicLogIndex(db, i, topic, from, to)...)
+		}
+		intersect(positions)
+	}
+	// Deduplicate and sort the blocks containing the matching logs
+	blocks := make(map[uint64]struct{})
+	for pos := range matches {
+		blocks[pos.Number] = struct{}{}
+	}
+	numbers := make([]uint64, 0, len(blocks))
+	for number := range blocks {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
 // unindexedLogs returns the logs matching the filter criteria based on raw block
 // iteration and bloom matching.
 func (f *Filter) unindexedLogs(ctx context.Context, end uint64, logChan chan *types.Log) error {
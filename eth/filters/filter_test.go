@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"math/big"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -106,6 +107,9 @@ func BenchmarkFilters(b *testing.B) {
 	}
 }
 
+// testLogIndexSize is the section size of the log index served by the test backend.
+const testLogIndexSize = 100
+
 func TestFilters(t *testing.T) {
 	var (
 		db     = rawdb.NewMemoryDatabase()
@@ -356,6 +360,46 @@ func TestFilters(t *testing.T) {
 		}
 	}
 
+	t.Run("logindex", func(t *testing.T) {
+		// Index all but the last block, and cross check the results against the
+		// bloom based filtering
+		for _, block := range chain[:len(chain)-1] {
+			receipts := rawdb.ReadRawReceipts(db, block.Hash(), block.NumberU64())
+			rawdb.WriteLogIndex(db, block.NumberU64(), receipts)
+		}
+		backend := sys.backend.(*testBackend)
+		defer func() { backend.logSections = 0 }()
+
+		for i, tc := range []struct {
+			begin, end int64
+			addresses  []common.Address
+			topics     [][]common.Hash
+		}{
+			{0, int64(rpc.LatestBlockNumber), []common.Address{contract}, [][]common.Hash{{hash1, hash2, hash3, hash4}}},
+			{0, int64(rpc.LatestBlockNumber), []common.Address{contract, contract2}, nil},
+			{1, 10, []common.Address{contract}, [][]common.Hash{{hash2}, {hash1}}},
+			{1, 10, nil, [][]common.Hash{{hash1, hash2}}},
+			{1, 10, nil, [][]common.Hash{nil, {hash1}}},
+			{2, 2, []common.Address{contract2}, [][]common.Hash{{hash2}}},
+			{0, int64(rpc.LatestBlockNumber), nil, [][]common.Hash{{common.BytesToHash([]byte("fail"))}, {hash1}}},
+			{0, int64(rpc.LatestBlockNumber), nil, [][]common.Hash{nil, nil, nil, nil, {hash1}}},
+		} {
+			backend.logSections = 0
+			want, err := sys.NewRangeFilter(tc.begin, tc.end, tc.addresses, tc.topics).Logs(context.Background())
+			if err != nil {
+				t.Fatalf("test %d: bloom filtering failed: %v", i, err)
+			}
+			backend.logSections = uint64(len(chain)) / testLogIndexSize
+			have, err := sys.NewRangeFilter(tc.begin, tc.end, tc.addresses, tc.topics).Logs(context.Background())
+			if err != nil {
+				t.Fatalf("test %d: log index filtering failed: %v", i, err)
+			}
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("test %d: logs mismatch: have %d logs, want %d", i, len(have), len(want))
+			}
+		}
+	})
+
 	t.Run("timeout", func(t *testing.T) {
 		f := sys.NewRangeFilter(0, -1, nil, nil)
 		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Hour))
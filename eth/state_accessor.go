@@ -76,6 +76,11 @@ func (eth *Ethereum) StateAtBlock(ctx context.Context, block *types.Block, reexe
 				statedb.Database().TrieDB().Dereference(block.Root())
 			}, nil
 		}
+		// The state is pruned from the live database, try to serve it from
+		// the state reverse diffs instead of regenerating it.
+		if statedb, err = eth.blockchain.HistoricState(block.Header()); err == nil {
+			return statedb, noopReleaser, nil
+		}
 	}
 	// The state is both for reading and writing, or it's unavailable in disk,
 	// try to construct/recover the state over an ephemeral trie.Database for
@@ -83,6 +83,7 @@ type Backend interface {
 	BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error)
 	GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error)
 	RPCGasCap() uint64
+	RPCTraceFilterRange() uint64
 	ChainConfig() *params.ChainConfig
 	Engine() consensus.Engine
 	ChainDb() ethdb.Database
@@ -172,6 +173,10 @@ type StdTraceConfig struct {
 	logger.Config
 	Reexec *uint64
 	TxHash common.Hash
+
+	// StateDiff switches the dump from per-transaction opcode traces to a single
+	// block-level state diff in JSONL format, one line per modified account.
+	StateDiff bool
 }
 
 // txTraceResult is the result of a single transaction trace.
@@ -705,6 +710,10 @@ txloop:
 // and traces either a full block or an individual transaction. The return value will
 // be one filename per transaction traced.
 func (api *API) standardTraceBlockToFile(ctx context.Context, block *types.Block, config *StdTraceConfig) ([]string, error) {
+	// If the block-level state diff was requested, dump that instead
+	if config != nil && config.StateDiff {
+		return api.standardStateDiffToFile(ctx, block, config)
+	}
 	// If we're tracing a single transaction, make sure it's present
 	if config != nil && config.TxHash != (common.Hash{}) {
 		if !containsTx(block, config.TxHash) {
@@ -975,6 +984,9 @@ func APIs(backend Backend) []rpc.API {
 		{
 			Namespace: "debug",
 			Service:   NewAPI(backend),
+		}, {
+			Namespace: "trace",
+			Service:   NewTraceAPI(backend),
 		},
 	}
 }
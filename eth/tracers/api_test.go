@@ -65,6 +65,12 @@ type testBackend struct {
 // testBackend creates a new test backend. OBS: After test is done, teardown must be
 // invoked in order to release associated resources.
 func newTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i int, b *core.BlockGen)) *testBackend {
+	return newTestBackendWithPreimages(t, n, gspec, generator, true)
+}
+
+// newTestBackendWithPreimages creates a new test backend, recording the preimages
+// of the trie keys only if requested.
+func newTestBackendWithPreimages(t *testing.T, n int, gspec *core.Genesis, generator func(i int, b *core.BlockGen), preimages bool) *testBackend {
 	backend := &testBackend{
 		chainConfig: gspec.Config,
 		engine:      ethash.NewFaker(),
@@ -80,6 +86,7 @@ func newTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i i
 		TrieTimeLimit:     5 * time.Minute,
 		SnapshotLimit:     0,
 		TrieDirtyDisabled: true, // Archive mode
+		Preimages:         preimages,
 	}
 	chain, err := core.NewBlockChain(backend.chaindb, cacheConfig, gspec, nil, backend.engine, vm.Config{}, nil, nil)
 	if err != nil {
@@ -123,6 +130,10 @@ func (b *testBackend) RPCGasCap() uint64 {
 	return 25000000
 }
 
+func (b *testBackend) RPCTraceFilterRange() uint64 {
+	return 4
+}
+
 func (b *testBackend) ChainConfig() *params.ChainConfig {
 	return b.chainConfig
 }
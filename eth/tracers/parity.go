@@ -0,0 +1,515 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// parityTracer is the name of the tracer producing Parity style flat call frames,
+// and parityDiffTracer the one identifying the state modified by a transaction,
+// along with their configurations.
+var (
+	parityTracer       = "flatCallTracer"
+	parityTracerConfig = json.RawMessage(`{"convertParityErrors":true}`)
+
+	parityDiffTracer       = "prestateTracer"
+	parityDiffTracerConfig = json.RawMessage(`{"diffMode":true}`)
+)
+
+// TraceAPI is the collection of Parity compatible tracing APIs, exposed over the
+// trace namespace. Reward traces are not produced.
+type TraceAPI struct {
+	api *API
+}
+
+// NewTraceAPI creates a new API definition for the Parity compatible tracing
+// methods of the Ethereum service.
+func NewTraceAPI(backend Backend) *TraceAPI {
+	return &TraceAPI{api: NewAPI(backend)}
+}
+
+// TraceFilterArgs represents the arguments of a trace_filter request.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"`
+	Count       *uint64          `json:"count"`
+}
+
+// TraceResults is the result of replaying a single transaction with the trace
+// types requested by the caller.
+type TraceResults struct {
+	Output          hexutil.Bytes     `json:"output"`
+	StateDiff       ParityStateDiff   `json:"stateDiff"`
+	Trace           []json.RawMessage `json:"trace"`
+	VmTrace         interface{}       `json:"vmTrace"`
+	TransactionHash common.Hash       `json:"transactionHash"`
+}
+
+// Block returns the flat call traces of all the transactions in a block.
+func (api *TraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]json.RawMessage, error) {
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return api.traceBlock(ctx, block)
+}
+
+// Transaction returns the flat call traces of a single transaction.
+func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]json.RawMessage, error) {
+	res, err := api.api.TraceTransaction(ctx, hash, &TraceConfig{Tracer: &parityTracer, TracerConfig: parityTracerConfig})
+	if err != nil {
+		return nil, err
+	}
+	return decodeParityTraces(res)
+}
+
+// Filter returns the flat call traces of the given block range, which match the
+// requested sender and recipient addresses. The range is capped by the backend's
+// trace filter limit, and tracing stops as soon as count traces are collected.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]json.RawMessage, error) {
+	from, err := api.resolveBlockNumber(ctx, args.FromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.resolveBlockNumber(ctx, args.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range: %d > %d", from, to)
+	}
+	if limit := api.api.backend.RPCTraceFilterRange(); limit != 0 && to-from >= limit {
+		return nil, fmt.Errorf("block range too large: %d blocks, limit is %d", to-from+1, limit)
+	}
+	results := []json.RawMessage{}
+	if args.Count != nil && *args.Count == 0 {
+		return results, nil
+	}
+	var (
+		fromAddrs = addressSet(args.FromAddress)
+		toAddrs   = addressSet(args.ToAddress)
+		skipped   uint64
+		done      bool
+	)
+	collect := func(result *TraceResults) bool {
+		for _, trace := range result.Trace {
+			if !matchParityTrace(trace, fromAddrs, toAddrs) {
+				continue
+			}
+			if args.After != nil && skipped < *args.After {
+				skipped++
+				continue
+			}
+			results = append(results, trace)
+			if args.Count != nil && uint64(len(results)) >= *args.Count {
+				done = true
+				return false
+			}
+		}
+		return true
+	}
+	for number := from; number <= to && !done; number++ {
+		// Genesis has no transactions to trace
+		if number == 0 {
+			continue
+		}
+		block, err := api.api.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if err := api.replayBlock(ctx, block, true, false, collect); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// ReplayBlockTransactions replays all the transactions in a block, returning
+// the requested trace types for each. The supported types are "trace" and
+// "stateDiff".
+func (api *TraceAPI) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, traceTypes []string) ([]*TraceResults, error) {
+	var withTrace, withDiff bool
+	for _, typ := range traceTypes {
+		switch typ {
+		case "trace":
+			withTrace = true
+		case "stateDiff":
+			withDiff = true
+		default:
+			return nil, fmt.Errorf("unsupported trace type: %s", typ)
+		}
+	}
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	results := make([]*TraceResults, 0, len(block.Transactions()))
+	err = api.replayBlock(ctx, block, withTrace, withDiff, func(result *TraceResults) bool {
+		results = append(results, result)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// replayBlock executes the transactions of a block one by one on top of its parent
+// state, passing the requested trace types of each to fn until it returns false.
+// Both trace types are collected in a single run through the mux tracer.
+func (api *TraceAPI) replayBlock(ctx context.Context, block *types.Block, withTrace, withDiff bool, fn func(*TraceResults) bool) error {
+	parent, err := api.api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+	if err != nil {
+		return err
+	}
+	statedb, release, err := api.api.backend.StateAtBlock(ctx, parent, defaultTraceReexec, nil, true, false)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	config := make(map[string]json.RawMessage)
+	if withTrace {
+		config[parityTracer] = parityTracerConfig
+	}
+	if withDiff {
+		config[parityDiffTracer] = parityDiffTracerConfig
+	}
+	muxConfig, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	var (
+		chainConfig = api.api.backend.ChainConfig()
+		signer      = types.MakeSigner(chainConfig, block.Number(), block.Time())
+		vmctx       = core.NewEVMBlockContext(block.Header(), api.api.chainContext(ctx), nil)
+		is158       = chainConfig.IsEIP158(block.Number())
+	)
+	for i, tx := range block.Transactions() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var (
+			msg, _   = core.TransactionToMessage(tx, signer, block.BaseFee())
+			tracer   Tracer
+			prestate *state.StateDB
+			vmConfig vm.Config
+		)
+		if len(config) > 0 {
+			txctx := &Context{BlockHash: block.Hash(), BlockNumber: block.Number(), TxIndex: i, TxHash: tx.Hash()}
+			if tracer, err = DefaultDirectory.New("muxTracer", txctx, muxConfig); err != nil {
+				return err
+			}
+			vmConfig.Tracer = tracer
+		}
+		if withDiff {
+			prestate = statedb.Copy()
+		}
+		vmenv := vm.NewEVM(vmctx, core.NewEVMTxContext(msg), statedb, chainConfig, vmConfig)
+		statedb.SetTxContext(tx.Hash(), i)
+		res, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit))
+		if err != nil {
+			return fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		statedb.Finalise(is158)
+
+		result := &TraceResults{
+			Output:          res.ReturnData,
+			Trace:           []json.RawMessage{},
+			TransactionHash: tx.Hash(),
+		}
+		if tracer != nil {
+			raw, err := tracer.GetResult()
+			if err != nil {
+				return err
+			}
+			var traces map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &traces); err != nil {
+				return err
+			}
+			if withTrace {
+				if result.Trace, err = decodeParityTraces(traces[parityTracer]); err != nil {
+					return err
+				}
+			}
+			if withDiff {
+				if result.StateDiff, err = decodeParityStateDiff(traces[parityDiffTracer], prestate, statedb); err != nil {
+					return err
+				}
+			}
+		}
+		if !fn(result) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// traceBlock runs the flat call tracer on all the transactions of a block and
+// concatenates the produced frames.
+func (api *TraceAPI) traceBlock(ctx context.Context, block *types.Block) ([]json.RawMessage, error) {
+	results, err := api.api.traceBlock(ctx, block, &TraceConfig{Tracer: &parityTracer, TracerConfig: parityTracerConfig})
+	if err != nil {
+		return nil, err
+	}
+	traces := []json.RawMessage{}
+	for _, result := range results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("tracing transaction %#x failed: %s", result.TxHash, result.Error)
+		}
+		frames, err := decodeParityTraces(result.Result)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, frames...)
+	}
+	return traces, nil
+}
+
+// resolveBlockNumber converts an optional block number or tag into an absolute
+// block number, defaulting to the latest block.
+func (api *TraceAPI) resolveBlockNumber(ctx context.Context, number *rpc.BlockNumber) (uint64, error) {
+	if number != nil && *number >= 0 {
+		return uint64(*number), nil
+	}
+	tag := rpc.LatestBlockNumber
+	if number != nil {
+		tag = *number
+	}
+	header, err := api.api.backend.HeaderByNumber(ctx, tag)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("block %v not found", tag)
+	}
+	return header.Number.Uint64(), nil
+}
+
+// decodeParityTraces splits the result of a flat call tracer into its frames.
+func decodeParityTraces(res interface{}) ([]json.RawMessage, error) {
+	raw, ok := res.(json.RawMessage)
+	if !ok {
+		return nil, fmt.Errorf("unexpected trace result type %T", res)
+	}
+	var traces []json.RawMessage
+	if err := json.Unmarshal(raw, &traces); err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// parityTraceAddresses is the subset of a flat call frame needed to filter it by
+// sender and recipient.
+type parityTraceAddresses struct {
+	Action struct {
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Address       *common.Address `json:"address"`
+		RefundAddress *common.Address `json:"refundAddress"`
+	} `json:"action"`
+	Result *struct {
+		Address *common.Address `json:"address"`
+	} `json:"result"`
+	Type string `json:"type"`
+}
+
+// matchParityTrace reports whether the sender of a flat call frame is in the from
+// set and its recipient is in the to set. Empty sets match any address.
+func matchParityTrace(trace json.RawMessage, fromAddrs, toAddrs map[common.Address]struct{}) bool {
+	if len(fromAddrs) == 0 && len(toAddrs) == 0 {
+		return true
+	}
+	var frame parityTraceAddresses
+	if err := json.Unmarshal(trace, &frame); err != nil {
+		return false
+	}
+	var from, to *common.Address
+	switch frame.Type {
+	case "create":
+		from = frame.Action.From
+		if frame.Result != nil {
+			to = frame.Result.Address
+		}
+	case "suicide":
+		from, to = frame.Action.Address, frame.Action.RefundAddress
+	default:
+		from, to = frame.Action.From, frame.Action.To
+	}
+	return matchAddress(fromAddrs, from) && matchAddress(toAddrs, to)
+}
+
+// matchAddress reports whether addr is in the set, or the set is empty.
+func matchAddress(set map[common.Address]struct{}, addr *common.Address) bool {
+	if len(set) == 0 {
+		return true
+	}
+	if addr == nil {
+		return false
+	}
+	_, ok := set[*addr]
+	return ok
+}
+
+// addressSet converts a list of addresses into a set for quick lookups.
+func addressSet(addrs []common.Address) map[common.Address]struct{} {
+	set := make(map[common.Address]struct{}, len(addrs))
+	for _, addr := range addrs {
+		set[addr] = struct{}{}
+	}
+	return set
+}
+
+// ParityStateDiff is the state changes caused by a transaction in the Parity
+// stateDiff format.
+type ParityStateDiff map[common.Address]*ParityAccountDiff
+
+// ParityAccountDiff is the change of a single account in the Parity stateDiff
+// format. Only the modified storage slots are included.
+type ParityAccountDiff struct {
+	Balance parityDelta                 `json:"balance"`
+	Nonce   parityDelta                 `json:"nonce"`
+	Code    parityDelta                 `json:"code"`
+	Storage map[common.Hash]parityDelta `json:"storage"`
+}
+
+// parityDelta is a single value change in the Parity stateDiff format, encoded
+// as "=" if unchanged, {"+": to} if created, {"-": from} if deleted and as
+// {"*": {"from": from, "to": to}} if modified.
+type parityDelta struct {
+	from interface{}
+	to   interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d parityDelta) MarshalJSON() ([]byte, error) {
+	switch {
+	case d.from == nil && d.to == nil:
+		return []byte(`"="`), nil
+	case d.from == nil:
+		return json.Marshal(map[string]interface{}{"+": d.to})
+	case d.to == nil:
+		return json.Marshal(map[string]interface{}{"-": d.from})
+	default:
+		return json.Marshal(map[string]interface{}{"*": map[string]interface{}{"from": d.from, "to": d.to}})
+	}
+}
+
+// decodeParityStateDiff converts the diff mode output of the prestate tracer into
+// the Parity stateDiff format. The tracer output only identifies the modified
+// accounts and storage slots, their values are read from the states before and
+// after the transaction.
+func decodeParityStateDiff(raw json.RawMessage, prestate, poststate *state.StateDB) (ParityStateDiff, error) {
+	type accounts map[common.Address]struct {
+		Storage map[common.Hash]common.Hash `json:"storage"`
+	}
+	var diff struct {
+		Pre  accounts `json:"pre"`
+		Post accounts `json:"post"`
+	}
+	if err := json.Unmarshal(raw, &diff); err != nil {
+		return nil, err
+	}
+	touched := make(map[common.Address]map[common.Hash]struct{})
+	for _, accounts := range []accounts{diff.Pre, diff.Post} {
+		for addr, account := range accounts {
+			if _, ok := touched[addr]; !ok {
+				touched[addr] = make(map[common.Hash]struct{})
+			}
+			for slot := range account.Storage {
+				touched[addr][slot] = struct{}{}
+			}
+		}
+	}
+	result := make(ParityStateDiff)
+	for addr, slots := range touched {
+		if account := parityDiffAccount(prestate, poststate, addr, slots); account != nil {
+			result[addr] = account
+		}
+	}
+	return result, nil
+}
+
+// parityDiffAccount compares an account between two states in the Parity format,
+// returning nil if the account and the given storage slots are unchanged.
+func parityDiffAccount(prestate, poststate *state.StateDB, addr common.Address, slots map[common.Hash]struct{}) *ParityAccountDiff {
+	var (
+		preExist  = prestate.Exist(addr)
+		postExist = poststate.Exist(addr)
+	)
+	if !preExist && !postExist {
+		return nil
+	}
+	var (
+		preBal, postBal     = prestate.GetBalance(addr), poststate.GetBalance(addr)
+		preNonce, postNonce = prestate.GetNonce(addr), poststate.GetNonce(addr)
+		preCode, postCode   = prestate.GetCode(addr), poststate.GetCode(addr)
+
+		diff    = &ParityAccountDiff{Storage: make(map[common.Hash]parityDelta)}
+		changed = preExist != postExist
+	)
+	// delta creates the change of a single value based on the account existence
+	delta := func(from, to interface{}, equal bool) parityDelta {
+		switch {
+		case !preExist:
+			return parityDelta{to: to}
+		case !postExist:
+			return parityDelta{from: from}
+		case equal:
+			return parityDelta{}
+		default:
+			changed = true
+			return parityDelta{from: from, to: to}
+		}
+	}
+	diff.Balance = delta((*hexutil.Big)(new(big.Int).Set(preBal)), (*hexutil.Big)(new(big.Int).Set(postBal)), preBal.Cmp(postBal) == 0)
+	diff.Nonce = delta(hexutil.Uint64(preNonce), hexutil.Uint64(postNonce), preNonce == postNonce)
+	diff.Code = delta(hexutil.Bytes(preCode), hexutil.Bytes(postCode), bytes.Equal(preCode, postCode))
+
+	for slot := range slots {
+		preVal, postVal := prestate.GetState(addr, slot), poststate.GetState(addr, slot)
+		if preVal == postVal {
+			continue
+		}
+		diff.Storage[slot] = delta(preVal, postVal, false)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return diff
+}
@@ -0,0 +1,232 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	// Force-load the native tracers to trigger registration
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+)
+
+var (
+	parityKey, _   = crypto.GenerateKey()
+	parityAddr     = crypto.PubkeyToAddress(parityKey.PublicKey)
+	parityReceiver = common.HexToAddress("0xbeef")
+)
+
+// parityFrame is the subset of a Parity trace frame checked by the tests.
+type parityFrame struct {
+	Action struct {
+		From *common.Address `json:"from"`
+		To   *common.Address `json:"to"`
+	} `json:"action"`
+	Result *struct {
+		Address *common.Address `json:"address"`
+	} `json:"result"`
+	BlockNumber     uint64       `json:"blockNumber"`
+	TransactionHash *common.Hash `json:"transactionHash"`
+	Type            string       `json:"type"`
+}
+
+// newParityTestAPI creates a chain in which every block contains a value transfer
+// and a contract creation, and returns the trace API on top of it.
+func newParityTestAPI(t *testing.T, blocks int) (*tracers.TraceAPI, tracers.Backend, func()) {
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{parityAddr: {Balance: big.NewInt(params.Ether)}},
+	}
+	signer := types.HomesteadSigner{}
+	backend, teardown := tracers.NewTestBackend(t, blocks, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(2*i), parityReceiver, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, parityKey)
+		b.AddTx(tx)
+		// Deploy a contract storing 1 into slot 0 of its storage
+		tx, _ = types.SignTx(types.NewContractCreation(uint64(2*i+1), common.Big0, 100000, b.BaseFee(), common.FromHex("0x600160005500")), signer, parityKey)
+		b.AddTx(tx)
+	})
+	return tracers.NewTraceAPI(backend), backend, teardown
+}
+
+func decodeFrames(t *testing.T, traces []json.RawMessage) []parityFrame {
+	t.Helper()
+
+	frames := make([]parityFrame, len(traces))
+	for i, trace := range traces {
+		if err := json.Unmarshal(trace, &frames[i]); err != nil {
+			t.Fatalf("failed to decode frame %d: %v", i, err)
+		}
+	}
+	return frames
+}
+
+func TestTraceBlockParity(t *testing.T) {
+	t.Parallel()
+
+	api, _, teardown := newParityTestAPI(t, 2)
+	defer teardown()
+
+	traces, err := api.Block(context.Background(), rpc.BlockNumber(1))
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	frames := decodeFrames(t, traces)
+	if len(frames) != 2 {
+		t.Fatalf("frame count mismatch: have %d, want %d", len(frames), 2)
+	}
+	if frames[0].Type != "call" || *frames[0].Action.To != parityReceiver || frames[0].BlockNumber != 1 {
+		t.Errorf("unexpected transfer frame: %+v", frames[0])
+	}
+	if frames[1].Type != "create" || frames[1].Result == nil || frames[1].Result.Address == nil {
+		t.Errorf("unexpected creation frame: %+v", frames[1])
+	}
+	// Tracing the same transaction individually should produce the same frame
+	single, err := api.Transaction(context.Background(), *frames[1].TransactionHash)
+	if err != nil {
+		t.Fatalf("failed to trace transaction: %v", err)
+	}
+	if len(single) != 1 || string(single[0]) != string(traces[1]) {
+		t.Errorf("transaction trace mismatch: have %s, want %s", single, traces[1])
+	}
+}
+
+func TestTraceFilterParity(t *testing.T) {
+	t.Parallel()
+
+	api, _, teardown := newParityTestAPI(t, 3)
+	defer teardown()
+
+	var (
+		from  = rpc.BlockNumber(1)
+		to    = rpc.BlockNumber(4) // beyond the head, only reachable without early stop
+		after = uint64(1)
+		count = uint64(1)
+		none  = uint64(0)
+	)
+	tests := []struct {
+		args   tracers.TraceFilterArgs
+		blocks []uint64
+	}{
+		// All traces of the chain
+		{tracers.TraceFilterArgs{FromBlock: &from}, []uint64{1, 1, 2, 2, 3, 3}},
+		// Filtering by sender only
+		{tracers.TraceFilterArgs{FromAddress: []common.Address{parityAddr}}, []uint64{3, 3}},
+		// Filtering by recipient
+		{tracers.TraceFilterArgs{FromBlock: &from, ToAddress: []common.Address{parityReceiver}}, []uint64{1, 2, 3}},
+		// Paginating the filtered results
+		{tracers.TraceFilterArgs{FromBlock: &from, ToAddress: []common.Address{parityReceiver}, After: &after, Count: &count}, []uint64{2}},
+		// Filtering by unknown sender
+		{tracers.TraceFilterArgs{FromBlock: &from, FromAddress: []common.Address{parityReceiver}}, nil},
+		// Tracing stops once enough traces are collected
+		{tracers.TraceFilterArgs{FromBlock: &from, ToBlock: &to, Count: &count}, []uint64{1}},
+		{tracers.TraceFilterArgs{FromBlock: &from, ToBlock: &to, Count: &none}, nil},
+	}
+	for i, tt := range tests {
+		traces, err := api.Filter(context.Background(), tt.args)
+		if err != nil {
+			t.Fatalf("test %d: failed to filter traces: %v", i, err)
+		}
+		frames := decodeFrames(t, traces)
+		if len(frames) != len(tt.blocks) {
+			t.Errorf("test %d: frame count mismatch: have %d, want %d", i, len(frames), len(tt.blocks))
+			continue
+		}
+		for j, frame := range frames {
+			if frame.BlockNumber != tt.blocks[j] {
+				t.Errorf("test %d, frame %d: block mismatch: have %d, want %d", i, j, frame.BlockNumber, tt.blocks[j])
+			}
+		}
+	}
+}
+
+func TestTraceFilterRangeParity(t *testing.T) {
+	t.Parallel()
+
+	api, _, teardown := newParityTestAPI(t, 3)
+	defer teardown()
+
+	// The test backend allows ranges of up to 4 blocks
+	from, to := rpc.BlockNumber(0), rpc.BlockNumber(3)
+	if _, err := api.Filter(context.Background(), tracers.TraceFilterArgs{FromBlock: &from, ToBlock: &to}); err != nil {
+		t.Fatalf("failed to filter allowed range: %v", err)
+	}
+	to = rpc.BlockNumber(4)
+	if _, err := api.Filter(context.Background(), tracers.TraceFilterArgs{FromBlock: &from, ToBlock: &to}); err == nil {
+		t.Fatalf("expected error for range above the limit")
+	}
+}
+
+func TestTraceReplayBlockTransactionsParity(t *testing.T) {
+	t.Parallel()
+
+	api, backend, teardown := newParityTestAPI(t, 1)
+	defer teardown()
+
+	if _, err := api.ReplayBlockTransactions(context.Background(), rpc.BlockNumber(1), []string{"vmTrace"}); err == nil {
+		t.Errorf("expected error for unsupported trace type")
+	}
+	results, err := api.ReplayBlockTransactions(context.Background(), rpc.BlockNumber(1), []string{"trace", "stateDiff"})
+	if err != nil {
+		t.Fatalf("failed to replay block: %v", err)
+	}
+	block, _ := backend.BlockByNumber(context.Background(), 1)
+	if len(results) != len(block.Transactions()) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(results), len(block.Transactions()))
+	}
+	for i, result := range results {
+		if result.TransactionHash != block.Transactions()[i].Hash() {
+			t.Errorf("result %d: hash mismatch", i)
+		}
+		if len(result.Trace) != 1 {
+			t.Errorf("result %d: trace count mismatch: have %d, want %d", i, len(result.Trace), 1)
+		}
+	}
+	// Check the state diff encoding of the transfer and the contract creation
+	blob, _ := json.Marshal(results[0].StateDiff[parityReceiver])
+	if want := `{"balance":{"+":"0x3e8"},"nonce":{"+":"0x0"},"code":{"+":"0x"},"storage":{}}`; string(blob) != want {
+		t.Errorf("transfer diff mismatch: have %s, want %s", blob, want)
+	}
+	blob, _ = json.Marshal(results[0].StateDiff[parityAddr])
+	var sender map[string]json.RawMessage
+	json.Unmarshal(blob, &sender)
+	if string(sender["code"]) != `"="` {
+		t.Errorf("sender code diff mismatch: have %s, want %s", sender["code"], `"="`)
+	}
+	contract := crypto.CreateAddress(parityAddr, 1)
+	blob, _ = json.Marshal(results[1].StateDiff[contract].Storage)
+	if want := `{"0x0000000000000000000000000000000000000000000000000000000000000000":{"+":"0x0000000000000000000000000000000000000000000000000000000000000001"}}`; string(blob) != want {
+		t.Errorf("contract storage diff mismatch: have %s, want %s", blob, want)
+	}
+	// Without any trace types, only the outputs should be returned
+	results, err = api.ReplayBlockTransactions(context.Background(), rpc.BlockNumber(1), nil)
+	if err != nil {
+		t.Fatalf("failed to replay block: %v", err)
+	}
+	if results[0].StateDiff != nil || len(results[0].Trace) != 0 {
+		t.Errorf("unexpected traces without trace types: %+v", results[0])
+	}
+}
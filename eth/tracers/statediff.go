@@ -0,0 +1,499 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// StateDiffConfig holds extra parameters to the block state diff functions.
+type StateDiffConfig struct {
+	Reexec *uint64
+}
+
+// StateDiffAccount holds the fields of an account modified by a block. Fields
+// left untouched by the block are omitted. Storage slots wiped by a self-destruct
+// or account recreation whose key is unknown, as its preimage wasn't recorded,
+// are reported in HashedStorage by the hash of their key.
+type StateDiffAccount struct {
+	Balance       *hexutil.Big                `json:"balance,omitempty"`
+	Nonce         *hexutil.Uint64             `json:"nonce,omitempty"`
+	Code          hexutil.Bytes               `json:"code,omitempty"`
+	Storage       map[common.Hash]common.Hash `json:"storage,omitempty"`
+	HashedStorage map[common.Hash]common.Hash `json:"hashedStorage,omitempty"`
+}
+
+// BlockStateDiff is the set of state changes caused by executing an entire block,
+// including the block rewards and withdrawals. Accounts missing from the pre
+// section were created by the block, accounts missing from the post section were
+// deleted by it.
+type BlockStateDiff struct {
+	Number hexutil.Uint64                       `json:"number"`
+	Hash   common.Hash                          `json:"hash"`
+	Pre    map[common.Address]*StateDiffAccount `json:"pre"`
+	Post   map[common.Address]*StateDiffAccount `json:"post"`
+}
+
+// stateDiffEntry is a single line of a state diff dump, holding the changes of
+// one account.
+type stateDiffEntry struct {
+	Address common.Address    `json:"address"`
+	Pre     *StateDiffAccount `json:"pre,omitempty"`
+	Post    *StateDiffAccount `json:"post,omitempty"`
+}
+
+// TraceBlockStateDiff executes the requested block and returns every account
+// balance, nonce, code and storage change caused by it.
+func (api *API) TraceBlockStateDiff(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *StateDiffConfig) (*BlockStateDiff, error) {
+	var (
+		block *types.Block
+		err   error
+	)
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		block, err = api.blockByHash(ctx, hash)
+	} else if number, ok := blockNrOrHash.Number(); ok {
+		block, err = api.blockByNumber(ctx, number)
+	} else {
+		return nil, errors.New("invalid arguments; neither block nor hash specified")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var reexec *uint64
+	if config != nil {
+		reexec = config.Reexec
+	}
+	return api.traceBlockStateDiff(ctx, block, reexec)
+}
+
+// traceBlockStateDiff executes all the transactions of a block together with the
+// consensus engine finalization, collecting all the accounts and storage slots
+// touched along the way, and diffs them between the parent and resulting state.
+//
+// The storage wiped by self-destructs and account recreations is found through the
+// storage tries of the parent state. Slots whose key can't be resolved, neither
+// from the recorded preimages nor from the slots written by the block, are
+// reported by the hash of their key.
+func (api *API) traceBlockStateDiff(ctx context.Context, block *types.Block, reexecOverride *uint64) (*BlockStateDiff, error) {
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+	if err != nil {
+		return nil, err
+	}
+	reexec := defaultTraceReexec
+	if reexecOverride != nil {
+		reexec = *reexecOverride
+	}
+	statedb, release, err := api.backend.StateAtBlock(ctx, parent, reexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		prestate    = statedb.Copy()
+		tracker     = newStateTouchTracker()
+		chainConfig = api.backend.ChainConfig()
+		signer      = types.MakeSigner(chainConfig, block.Number(), block.Time())
+		vmctx       = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+		is158       = chainConfig.IsEIP158(block.Number())
+	)
+	for i, tx := range block.Transactions() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var (
+			msg, _    = core.TransactionToMessage(tx, signer, block.BaseFee())
+			txContext = core.NewEVMTxContext(msg)
+			vmenv     = vm.NewEVM(vmctx, txContext, statedb, chainConfig, vm.Config{Tracer: tracker})
+		)
+		statedb.SetTxContext(tx.Hash(), i)
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit)); err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		// Finalize the state so any modifications are written to the trie
+		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+		statedb.Finalise(is158)
+	}
+	// Apply the block rewards and withdrawals, tracking all credited accounts
+	api.backend.Engine().Finalize(&chainHeaderReader{ctx: ctx, backend: api.backend}, block.Header(), statedb, block.Transactions(), block.Uncles(), block.Withdrawals())
+	statedb.Finalise(is158)
+
+	tracker.touchAccount(block.Coinbase())
+	for _, uncle := range block.Uncles() {
+		tracker.touchAccount(uncle.Coinbase)
+	}
+	for _, withdrawal := range block.Withdrawals() {
+		tracker.touchAccount(withdrawal.Address)
+	}
+	// Self-destructed and recreated accounts lost all their storage, not only the
+	// slots written during execution
+	for addr := range tracker.cleared {
+		if err := tracker.touchStorage(prestate, addr); err != nil {
+			return nil, err
+		}
+	}
+	diff := &BlockStateDiff{
+		Number: hexutil.Uint64(block.NumberU64()),
+		Hash:   block.Hash(),
+		Pre:    make(map[common.Address]*StateDiffAccount),
+		Post:   make(map[common.Address]*StateDiffAccount),
+	}
+	for addr, slots := range tracker.touched {
+		pre, post, err := diffAccount(prestate, statedb, addr, slots, tracker.hashed[addr])
+		if err != nil {
+			return nil, err
+		}
+		if pre != nil {
+			diff.Pre[addr] = pre
+		}
+		if post != nil {
+			diff.Post[addr] = post
+		}
+	}
+	return diff, nil
+}
+
+// diffAccount compares an account between two states, returning the fields and
+// requested storage slots that differ. The slots only known by the hash of their
+// key are given along with their values in the prestate. A nil result is returned
+// for the side in which the account does not exist, or for both if the account
+// is unchanged.
+func diffAccount(prestate, poststate *state.StateDB, addr common.Address, slots map[common.Hash]struct{}, hashed map[common.Hash]common.Hash) (*StateDiffAccount, *StateDiffAccount, error) {
+	var (
+		preExist  = prestate.Exist(addr)
+		postExist = poststate.Exist(addr)
+	)
+	if !preExist && !postExist {
+		return nil, nil, nil
+	}
+	var (
+		pre     = new(StateDiffAccount)
+		post    = new(StateDiffAccount)
+		changed = preExist != postExist
+	)
+	if preBal, postBal := prestate.GetBalance(addr), poststate.GetBalance(addr); preBal.Cmp(postBal) != 0 {
+		pre.Balance, post.Balance = (*hexutil.Big)(new(big.Int).Set(preBal)), (*hexutil.Big)(new(big.Int).Set(postBal))
+		changed = true
+	}
+	if preNonce, postNonce := prestate.GetNonce(addr), poststate.GetNonce(addr); preNonce != postNonce {
+		pre.Nonce, post.Nonce = (*hexutil.Uint64)(&preNonce), (*hexutil.Uint64)(&postNonce)
+		changed = true
+	}
+	if preCode, postCode := prestate.GetCode(addr), poststate.GetCode(addr); !bytes.Equal(preCode, postCode) {
+		pre.Code, post.Code = preCode, postCode
+		changed = true
+	}
+	for slot := range slots {
+		preVal, postVal := prestate.GetState(addr, slot), poststate.GetState(addr, slot)
+		if preVal == postVal {
+			continue
+		}
+		if pre.Storage == nil {
+			pre.Storage, post.Storage = make(map[common.Hash]common.Hash), make(map[common.Hash]common.Hash)
+		}
+		pre.Storage[slot], post.Storage[slot] = preVal, postVal
+		changed = true
+	}
+	if len(hashed) > 0 {
+		postVals, err := storageByHash(poststate, addr, hashed)
+		if err != nil {
+			return nil, nil, err
+		}
+		for hash, preVal := range hashed {
+			postVal := postVals[hash]
+			if preVal == postVal {
+				continue
+			}
+			if pre.HashedStorage == nil {
+				pre.HashedStorage, post.HashedStorage = make(map[common.Hash]common.Hash), make(map[common.Hash]common.Hash)
+			}
+			pre.HashedStorage[hash], post.HashedStorage[hash] = preVal, postVal
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, nil, nil
+	}
+	if !preExist {
+		pre = nil
+	}
+	if !postExist {
+		post = nil
+	}
+	return pre, post, nil
+}
+
+// storageByHash retrieves the values of the storage slots of an account with the
+// given key hashes. Slots missing from the state are omitted.
+func storageByHash(statedb *state.StateDB, addr common.Address, hashes map[common.Hash]common.Hash) (map[common.Hash]common.Hash, error) {
+	values := make(map[common.Hash]common.Hash)
+
+	tr, err := statedb.StorageTrie(addr)
+	if err != nil || tr == nil {
+		return values, err
+	}
+	for hash := range hashes {
+		it := trie.NewIterator(tr.NodeIterator(hash[:]))
+		if !it.Next() {
+			if it.Err != nil {
+				return nil, it.Err
+			}
+			continue
+		}
+		if bytes.Equal(it.Key, hash[:]) {
+			val, err := decodeStorageValue(it.Value)
+			if err != nil {
+				return nil, err
+			}
+			values[hash] = val
+		}
+	}
+	return values, nil
+}
+
+// decodeStorageValue decodes a storage slot value as stored in the storage trie.
+func decodeStorageValue(blob []byte) (common.Hash, error) {
+	_, content, _, err := rlp.Split(blob)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(content), nil
+}
+
+// standardStateDiffToFile computes the state diff of a block and dumps it into
+// the local file system as JSONL, one line per modified account ordered by the
+// account address. The name of the created file is returned.
+func (api *API) standardStateDiffToFile(ctx context.Context, block *types.Block, config *StdTraceConfig) ([]string, error) {
+	diff, err := api.traceBlockStateDiff(ctx, block, config.Reexec)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]common.Address, 0, len(diff.Pre)+len(diff.Post))
+	for addr := range diff.Pre {
+		addrs = append(addrs, addr)
+	}
+	for addr := range diff.Post {
+		if _, ok := diff.Pre[addr]; !ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	prefix := fmt.Sprintf("statediff_%d-%#x-", block.NumberU64(), block.Hash().Bytes()[:4])
+	dump, err := os.CreateTemp(os.TempDir(), prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer dump.Close()
+
+	var (
+		writer  = bufio.NewWriter(dump)
+		encoder = json.NewEncoder(writer)
+	)
+	for _, addr := range addrs {
+		if err := encoder.Encode(&stateDiffEntry{Address: addr, Pre: diff.Pre[addr], Post: diff.Post[addr]}); err != nil {
+			return []string{dump.Name()}, err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return []string{dump.Name()}, err
+	}
+	log.Info("Wrote state diff", "file", dump.Name(), "accounts", len(addrs))
+	return []string{dump.Name()}, nil
+}
+
+// stateTouchTracker is an EVM logger which collects all the accounts and storage
+// slots which might have been modified during execution.
+type stateTouchTracker struct {
+	touched map[common.Address]map[common.Hash]struct{}
+	cleared map[common.Address]struct{}                    // Accounts self-destructed or (re)created
+	hashed  map[common.Address]map[common.Hash]common.Hash // Cleared slots with unknown keys, by key hash
+}
+
+// newStateTouchTracker creates an empty tracker, reusable across transactions.
+func newStateTouchTracker() *stateTouchTracker {
+	return &stateTouchTracker{
+		touched: make(map[common.Address]map[common.Hash]struct{}),
+		cleared: make(map[common.Address]struct{}),
+		hashed:  make(map[common.Address]map[common.Hash]common.Hash),
+	}
+}
+
+// touchAccount marks an account as potentially modified.
+func (t *stateTouchTracker) touchAccount(addr common.Address) {
+	if _, ok := t.touched[addr]; !ok {
+		t.touched[addr] = make(map[common.Hash]struct{})
+	}
+}
+
+// touchSlot marks a storage slot of an account as potentially modified.
+func (t *stateTouchTracker) touchSlot(addr common.Address, slot common.Hash) {
+	t.touchAccount(addr)
+	t.touched[addr][slot] = struct{}{}
+}
+
+// clearAccount marks an account as potentially having lost all its storage.
+func (t *stateTouchTracker) clearAccount(addr common.Address) {
+	t.touchAccount(addr)
+	t.cleared[addr] = struct{}{}
+}
+
+// touchStorage marks all the storage slots of an account in the given state as
+// potentially modified. The keys of the slots are resolved from the preimages of
+// the storage trie keys, or from the slots already touched. The slots left with
+// unknown keys are tracked by the hash of their key, along with their value.
+func (t *stateTouchTracker) touchStorage(statedb *state.StateDB, addr common.Address) error {
+	tr, err := statedb.StorageTrie(addr)
+	if err != nil || tr == nil {
+		return err
+	}
+	touched := make(map[common.Hash]struct{})
+	for slot := range t.touched[addr] {
+		touched[crypto.Keccak256Hash(slot[:])] = struct{}{}
+	}
+	it := trie.NewIterator(tr.NodeIterator(nil))
+	for it.Next() {
+		if key := tr.GetKey(it.Key); key != nil {
+			t.touchSlot(addr, common.BytesToHash(key))
+			continue
+		}
+		hash := common.BytesToHash(it.Key)
+		if _, ok := touched[hash]; ok {
+			continue
+		}
+		val, err := decodeStorageValue(it.Value)
+		if err != nil {
+			return err
+		}
+		if _, ok := t.hashed[addr]; !ok {
+			t.hashed[addr] = make(map[common.Hash]common.Hash)
+		}
+		t.hashed[addr][hash] = val
+	}
+	return it.Err
+}
+
+func (t *stateTouchTracker) CaptureTxStart(gasLimit uint64) {}
+
+func (t *stateTouchTracker) CaptureTxEnd(restGas uint64) {}
+
+// CaptureStart tracks the sender, the recipient and the fee recipient of the
+// transaction.
+func (t *stateTouchTracker) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.touchAccount(from)
+	t.touchAccount(to)
+	t.touchAccount(env.Context.Coinbase)
+	if create {
+		t.clearAccount(to)
+	}
+}
+
+func (t *stateTouchTracker) CaptureEnd(output []byte, gasUsed uint64, err error) {}
+
+// CaptureEnter tracks the target of all inner calls, contract creations and the
+// beneficiaries of self-destructs. Created and self-destructed accounts are also
+// tracked as having their storage cleared.
+func (t *stateTouchTracker) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.touchAccount(from)
+	t.touchAccount(to)
+
+	switch typ {
+	case vm.CREATE, vm.CREATE2:
+		t.clearAccount(to)
+	case vm.SELFDESTRUCT:
+		t.clearAccount(from)
+	}
+}
+
+func (t *stateTouchTracker) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+// CaptureState tracks the storage slots written by the executing contract.
+func (t *stateTouchTracker) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil || op != vm.SSTORE {
+		return
+	}
+	if stack := scope.Stack.Data(); len(stack) >= 1 {
+		t.touchSlot(scope.Contract.Address(), common.Hash(stack[len(stack)-1].Bytes32()))
+	}
+}
+
+func (t *stateTouchTracker) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// chainHeaderReader implements consensus.ChainHeaderReader on top of the tracing
+// backend, enough to run the consensus engine's block finalization.
+type chainHeaderReader struct {
+	ctx     context.Context
+	backend Backend
+}
+
+func (r *chainHeaderReader) Config() *params.ChainConfig {
+	return r.backend.ChainConfig()
+}
+
+func (r *chainHeaderReader) CurrentHeader() *types.Header {
+	header, _ := r.backend.HeaderByNumber(r.ctx, rpc.LatestBlockNumber)
+	return header
+}
+
+func (r *chainHeaderReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header, _ := r.backend.HeaderByHash(r.ctx, hash)
+	if header == nil || header.Number.Uint64() != number {
+		return nil
+	}
+	return header
+}
+
+func (r *chainHeaderReader) GetHeaderByNumber(number uint64) *types.Header {
+	header, _ := r.backend.HeaderByNumber(r.ctx, rpc.BlockNumber(number))
+	return header
+}
+
+func (r *chainHeaderReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	header, _ := r.backend.HeaderByHash(r.ctx, hash)
+	return header
+}
+
+// GetTd is not available through the tracing backend. None of the consensus
+// engines need it for block finalization.
+func (r *chainHeaderReader) GetTd(hash common.Hash, number uint64) *big.Int {
+	return nil
+}
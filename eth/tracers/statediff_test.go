@@ -0,0 +1,246 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newStateDiffBackend creates a chain in which every block transfers funds to a
+// new account, and calls a contract storing the block number in its storage.
+func newStateDiffBackend(t *testing.T, blocks int) *testBackend {
+	var (
+		accounts = newAccounts(1)
+		contract = common.HexToAddress("0xc0de")
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+				contract: {
+					Balance: common.Big0,
+					Code:    common.FromHex("0x4360005500"), // NUMBER PUSH1 0 SSTORE STOP
+				},
+			},
+		}
+		signer = types.HomesteadSigner{}
+	)
+	return newTestBackend(t, blocks, genesis, func(i int, b *core.BlockGen) {
+		recipient := common.BigToAddress(big.NewInt(int64(0x1000 + i)))
+		tx, _ := types.SignTx(types.NewTransaction(uint64(2*i), recipient, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(uint64(2*i+1), contract, common.Big0, 50000, b.BaseFee(), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+	})
+}
+
+// Tests that applying the block state diff onto the parent state reproduces the
+// state root of the block.
+func TestTraceBlockStateDiff(t *testing.T) {
+	t.Parallel()
+
+	backend := newStateDiffBackend(t, 4)
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	if _, err := api.TraceBlockStateDiff(context.Background(), rpc.BlockNumberOrHashWithNumber(0), nil); err == nil {
+		t.Fatalf("expected error tracing genesis")
+	}
+	for number := uint64(1); number <= 4; number++ {
+		block := backend.chain.GetBlockByNumber(number)
+		diff, err := api.TraceBlockStateDiff(context.Background(), rpc.BlockNumberOrHashWithHash(block.Hash(), false), nil)
+		if err != nil {
+			t.Fatalf("block %d: failed to trace state diff: %v", number, err)
+		}
+		// The sender, the coinbase, the contract and the new recipient must change
+		if len(diff.Post) != 4 {
+			t.Errorf("block %d: modified account count mismatch: have %d, want %d", number, len(diff.Post), 4)
+		}
+		recipient := common.BigToAddress(big.NewInt(int64(0x1000 + number - 1)))
+		if _, ok := diff.Pre[recipient]; ok {
+			t.Errorf("block %d: new account present in prestate", number)
+		}
+		if slot := diff.Post[common.HexToAddress("0xc0de")].Storage[common.Hash{}]; slot != common.BigToHash(block.Number()) {
+			t.Errorf("block %d: storage mismatch: have %x, want %x", number, slot, common.BigToHash(block.Number()))
+		}
+		// Replay the diff on top of the parent state and cross check the root
+		statedb, err := backend.chain.StateAt(backend.chain.GetBlockByNumber(number - 1).Root())
+		if err != nil {
+			t.Fatalf("block %d: failed to retrieve parent state: %v", number, err)
+		}
+		applyStateDiff(statedb, diff)
+		if root := statedb.IntermediateRoot(true); root != block.Root() {
+			t.Errorf("block %d: replayed root mismatch: have %x, want %x", number, root, block.Root())
+		}
+	}
+}
+
+// Tests that the whole storage of a self-destructed account is reported as cleared,
+// not only the slots written by the block, by the hash of their key if their
+// preimages are not recorded.
+func TestTraceBlockStateDiffSelfDestruct(t *testing.T) {
+	t.Parallel()
+
+	t.Run("preimages", func(t *testing.T) { testTraceBlockStateDiffSelfDestruct(t, true) })
+	t.Run("hashed", func(t *testing.T) { testTraceBlockStateDiffSelfDestruct(t, false) })
+}
+
+func testTraceBlockStateDiffSelfDestruct(t *testing.T, preimages bool) {
+	var (
+		accounts = newAccounts(1)
+		contract = common.HexToAddress("0xc0de")
+		storage  = map[common.Hash]common.Hash{
+			common.HexToHash("0x01"): common.HexToHash("0x11"),
+			common.HexToHash("0x02"): common.HexToHash("0x22"),
+		}
+		genesis = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+				contract: {
+					Balance: common.Big0,
+					Code:    common.FromHex("0x33ff"), // CALLER SELFDESTRUCT
+					Storage: storage,
+				},
+			},
+		}
+	)
+	backend := newTestBackendWithPreimages(t, 1, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(0, contract, common.Big0, 50000, b.BaseFee(), nil), types.HomesteadSigner{}, accounts[0].key)
+		b.AddTx(tx)
+	}, preimages)
+	defer backend.chain.Stop()
+
+	block := backend.chain.GetBlockByNumber(1)
+	diff, err := NewAPI(backend).TraceBlockStateDiff(context.Background(), rpc.BlockNumberOrHashWithNumber(1), nil)
+	if err != nil {
+		t.Fatalf("failed to trace state diff: %v", err)
+	}
+	if _, ok := diff.Post[contract]; ok {
+		t.Errorf("self-destructed account present in poststate")
+	}
+	pre := diff.Pre[contract]
+	if pre == nil {
+		t.Fatalf("self-destructed account missing from prestate")
+	}
+	cleared := pre.Storage
+	if !preimages {
+		if len(pre.Storage) != 0 {
+			t.Errorf("slots reported without preimages: %v", pre.Storage)
+		}
+		cleared = make(map[common.Hash]common.Hash)
+		for key := range storage {
+			cleared[key] = pre.HashedStorage[crypto.Keccak256Hash(key[:])]
+		}
+	}
+	if len(pre.Storage)+len(pre.HashedStorage) != len(storage) {
+		t.Fatalf("cleared storage not reported: %+v", pre)
+	}
+	for key, val := range storage {
+		if cleared[key] != val {
+			t.Errorf("slot %x mismatch: have %x, want %x", key, cleared[key], val)
+		}
+	}
+	statedb, err := backend.chain.StateAt(backend.chain.Genesis().Root())
+	if err != nil {
+		t.Fatalf("failed to retrieve parent state: %v", err)
+	}
+	applyStateDiff(statedb, diff)
+	if root := statedb.IntermediateRoot(true); root != block.Root() {
+		t.Errorf("replayed root mismatch: have %x, want %x", root, block.Root())
+	}
+}
+
+// Tests that the state diff can be dumped through the standard trace to file
+// machinery as JSONL.
+func TestStandardTraceBlockStateDiffToFile(t *testing.T) {
+	t.Parallel()
+
+	backend := newStateDiffBackend(t, 1)
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	block := backend.chain.GetBlockByNumber(1)
+	files, err := api.StandardTraceBlockToFile(context.Background(), block.Hash(), &StdTraceConfig{StateDiff: true})
+	if err != nil {
+		t.Fatalf("failed to dump state diff: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("dump file count mismatch: have %d, want %d", len(files), 1)
+	}
+	defer os.Remove(files[0])
+
+	dump, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("failed to open dump: %v", err)
+	}
+	defer dump.Close()
+
+	var (
+		scanner = bufio.NewScanner(dump)
+		entries []*stateDiffEntry
+	)
+	for scanner.Scan() {
+		entry := new(stateDiffEntry)
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			t.Fatalf("failed to decode line %d: %v", len(entries), err)
+		}
+		if n := len(entries); n > 0 && bytes.Compare(entries[n-1].Address[:], entry.Address[:]) >= 0 {
+			t.Errorf("line %d: accounts not in order", n)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 4 {
+		t.Errorf("dumped account count mismatch: have %d, want %d", len(entries), 4)
+	}
+}
+
+// applyStateDiff writes the post values of a state diff into a state database.
+func applyStateDiff(statedb *state.StateDB, diff *BlockStateDiff) {
+	for addr := range diff.Pre {
+		if _, ok := diff.Post[addr]; !ok {
+			statedb.Suicide(addr)
+		}
+	}
+	for addr, account := range diff.Post {
+		if account.Balance != nil {
+			statedb.SetBalance(addr, account.Balance.ToInt())
+		}
+		if account.Nonce != nil {
+			statedb.SetNonce(addr, uint64(*account.Nonce))
+		}
+		if account.Code != nil {
+			statedb.SetCode(addr, account.Code)
+		}
+		for key, val := range account.Storage {
+			statedb.SetState(addr, key, val)
+		}
+	}
+}
@@ -231,6 +231,8 @@ func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
 	if _, err := ethservice.BlockChain().InsertChain(blocks[1:]); err != nil {
 		t.Fatalf("can't import test blocks: %v", err)
 	}
+	// Wait for the transaction pool to catch up with the imported chain
+	ethservice.TxPool().Sync()
 	return n, blocks
 }
 
@@ -287,6 +289,9 @@ func TestEthClient(t *testing.T) {
 		"TransactionSender": {
 			func(t *testing.T) { testTransactionSender(t, client) },
 		},
+		"BlockReceipts": {
+			func(t *testing.T) { testBlockReceipts(t, chain, client) },
+		},
 	}
 
 	t.Parallel()
@@ -463,6 +468,37 @@ func testGetBlock(t *testing.T, client *rpc.Client) {
 	}
 }
 
+func testBlockReceipts(t *testing.T, chain []*types.Block, client *rpc.Client) {
+	ec := NewClient(client)
+
+	// Get the receipts of the block containing the test transactions
+	block := chain[2]
+	for _, id := range []rpc.BlockNumberOrHash{
+		rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(block.NumberU64())),
+		rpc.BlockNumberOrHashWithHash(block.Hash(), false),
+	} {
+		receipts, err := ec.BlockReceipts(context.Background(), id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(receipts) != len(block.Transactions()) {
+			t.Fatalf("wrong number of receipts: want %d got %d", len(block.Transactions()), len(receipts))
+		}
+		for i, receipt := range receipts {
+			if receipt.TxHash != block.Transactions()[i].Hash() {
+				t.Fatalf("receipt %d has wrong tx hash: want %v got %v", i, block.Transactions()[i].Hash(), receipt.TxHash)
+			}
+			if receipt.BlockHash != block.Hash() {
+				t.Fatalf("receipt %d has wrong block hash: want %v got %v", i, block.Hash(), receipt.BlockHash)
+			}
+		}
+	}
+	// Get the receipts of a non-existent block
+	if _, err := ec.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(100)); err != ethereum.NotFound {
+		t.Fatalf("error should be ethereum.NotFound, got %v", err)
+	}
+}
+
 func testStatusFunctions(t *testing.T, client *rpc.Client) {
 	ec := NewClient(client)
 
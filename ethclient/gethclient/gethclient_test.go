@@ -73,6 +73,8 @@ func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
 	if _, err := ethservice.BlockChain().InsertChain(blocks[1:]); err != nil {
 		t.Fatalf("can't import test blocks: %v", err)
 	}
+	// Wait for the transaction pool to catch up with the imported chain
+	ethservice.TxPool().Sync()
 	return n, blocks
 }
 
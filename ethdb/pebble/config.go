@@ -0,0 +1,33 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pebble
+
+// Config contains the tuning knobs of a pebble database. Any field left at its
+// zero value falls back to the default picked by geth.
+//
+// The type is available on all platforms, so that it can be carried around in
+// the node configuration even where pebble itself is not supported.
+type Config struct {
+	MemTableCount            int    `toml:",omitempty"` // Number of memory tables, including the frozen ones (default = 2)
+	MemTableSize             int    `toml:",omitempty"` // Size of a single memory table in bytes (default = half the cache, split among the tables)
+	LBaseMaxBytes            int64  `toml:",omitempty"` // Maximum size of the base level, further levels grow 10x (default = 64 MiB)
+	TargetFileSize           int64  `toml:",omitempty"` // Target size of the sstables in every level (default = 2 MiB)
+	MaxConcurrentCompactions int    `toml:",omitempty"` // Number of compactions run in parallel (default = number of CPUs)
+	BloomBitsPerKey          int    `toml:",omitempty"` // Bits per key of the sstable bloom filters (default = 10)
+	WALDir                   string `toml:",omitempty"` // Directory of the write-ahead log (default = inside the database)
+	WALBytesPerSync          int    `toml:",omitempty"` // Bytes of the write-ahead log written between background syncs (default = 0, disabled)
+}
@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"fmt"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -47,6 +48,9 @@ const (
 	// metricsGatheringInterval specifies the interval to retrieve pebble database
 	// compaction, io and pause stats to report to the user.
 	metricsGatheringInterval = 3 * time.Second
+
+	// numLevels is the number of levels of the pebble LSM tree.
+	numLevels = 7
 )
 
 // Database is a persistent key-value store based on the pebble storage engine.
@@ -69,6 +73,17 @@ type Database struct {
 	nonlevel0CompGauge  metrics.Gauge // Gauge for tracking the number of table compaction in non0 level
 	seekCompGauge       metrics.Gauge // Gauge for tracking the number of table compaction caused by read opt
 	manualMemAllocGauge metrics.Gauge // Gauge for tracking amount of non-managed memory currently allocated
+	readAmpGauge        metrics.Gauge // Gauge for tracking the read amplification of the whole database
+	compDebtGauge       metrics.Gauge // Gauge for tracking the estimated bytes to compact to reach a stable state
+	compProgressGauge   metrics.Gauge // Gauge for tracking the bytes of the compactions in progress
+	blockHitMeter       metrics.Meter // Meter for measuring the block cache hits
+	blockMissMeter      metrics.Meter // Meter for measuring the block cache misses
+	blockHitRateGauge   metrics.Gauge // Gauge for tracking the block cache hit rate (in percent) since the last report
+	blockSizeGauge      metrics.Gauge // Gauge for tracking the size of the block cache
+
+	levelReadAmpGauges [numLevels]metrics.Gauge // Gauges for tracking the read amplification of each level
+	levelSizeGauges    [numLevels]metrics.Gauge // Gauges for tracking the size of each level
+	levelFilesGauges   [numLevels]metrics.Gauge // Gauges for tracking the number of files in each level
 
 	quitLock sync.RWMutex    // Mutex protecting the quit channel and the closed flag
 	quitChan chan chan error // Quit channel to stop the metrics collection before closing the database
@@ -110,6 +125,7 @@ func (d *Database) onCompactionEnd(info pebble.CompactionInfo) {
 
 func (d *Database) onWriteStallBegin(b pebble.WriteStallBeginInfo) {
 	d.writeDelayStartTime = time.Now()
+	d.writeDelayCount.Add(1)
 }
 
 func (d *Database) onWriteStallEnd() {
@@ -117,8 +133,9 @@ func (d *Database) onWriteStallEnd() {
 }
 
 // New returns a wrapped pebble DB object. The namespace is the prefix that the
-// metrics reporting should use for surfacing internal stats.
-func New(file string, cache int, handles int, namespace string, readonly bool) (*Database, error) {
+// metrics reporting should use for surfacing internal stats. The config carries
+// the optional tuning parameters, nil selecting the defaults for all of them.
+func New(file string, cache int, handles int, namespace string, readonly bool, config *Config) (*Database, error) {
 	// Ensure we have some minimal caching and file guarantees
 	if cache < minCache {
 		cache = minCache
@@ -126,25 +143,100 @@ func New(file string, cache int, handles int, namespace string, readonly bool) (
 	if handles < minHandles {
 		handles = minHandles
 	}
+	if config == nil {
+		config = new(Config)
+	}
 	logger := log.New("database", file)
 	logger.Info("Allocated cache and file handles", "cache", common.StorageSize(cache*1024*1024), "handles", handles)
 
+	db := &Database{
+		fn:       file,
+		log:      logger,
+		quitChan: make(chan chan error),
+	}
+	opt := makeOptions(cache, handles, config)
+	opt.ReadOnly = readonly
+	opt.EventListener = &pebble.EventListener{
+		CompactionBegin: db.onCompactionBegin,
+		CompactionEnd:   db.onCompactionEnd,
+		WriteStallBegin: db.onWriteStallBegin,
+		WriteStallEnd:   db.onWriteStallEnd,
+	}
+
+	// Open the db and recover any potential corruptions
+	innerDB, err := pebble.Open(file, opt)
+	if err != nil {
+		return nil, err
+	}
+	db.db = innerDB
+
+	db.compTimeMeter = metrics.NewRegisteredMeter(namespace+"compact/time", nil)
+	db.compReadMeter = metrics.NewRegisteredMeter(namespace+"compact/input", nil)
+	db.compWriteMeter = metrics.NewRegisteredMeter(namespace+"compact/output", nil)
+	db.diskSizeGauge = metrics.NewRegisteredGauge(namespace+"disk/size", nil)
+	db.diskReadMeter = metrics.NewRegisteredMeter(namespace+"disk/read", nil)
+	db.diskWriteMeter = metrics.NewRegisteredMeter(namespace+"disk/write", nil)
+	db.writeDelayMeter = metrics.NewRegisteredMeter(namespace+"compact/writedelay/duration", nil)
+	db.writeDelayNMeter = metrics.NewRegisteredMeter(namespace+"compact/writedelay/counter", nil)
+	db.memCompGauge = metrics.NewRegisteredGauge(namespace+"compact/memory", nil)
+	db.level0CompGauge = metrics.NewRegisteredGauge(namespace+"compact/level0", nil)
+	db.nonlevel0CompGauge = metrics.NewRegisteredGauge(namespace+"compact/nonlevel0", nil)
+	db.seekCompGauge = metrics.NewRegisteredGauge(namespace+"compact/seek", nil)
+	db.manualMemAllocGauge = metrics.NewRegisteredGauge(namespace+"memory/manualalloc", nil)
+	db.readAmpGauge = metrics.NewRegisteredGauge(namespace+"readamp", nil)
+	db.compDebtGauge = metrics.NewRegisteredGauge(namespace+"compact/debt", nil)
+	db.compProgressGauge = metrics.NewRegisteredGauge(namespace+"compact/inprogress", nil)
+	db.blockHitMeter = metrics.NewRegisteredMeter(namespace+"cache/block/hit", nil)
+	db.blockMissMeter = metrics.NewRegisteredMeter(namespace+"cache/block/miss", nil)
+	db.blockHitRateGauge = metrics.NewRegisteredGauge(namespace+"cache/block/hitrate", nil)
+	db.blockSizeGauge = metrics.NewRegisteredGauge(namespace+"cache/block/size", nil)
+	for i := 0; i < numLevels; i++ {
+		db.levelReadAmpGauges[i] = metrics.NewRegisteredGauge(fmt.Sprintf("%slevel/%d/readamp", namespace, i), nil)
+		db.levelSizeGauges[i] = metrics.NewRegisteredGauge(fmt.Sprintf("%slevel/%d/size", namespace, i), nil)
+		db.levelFilesGauges[i] = metrics.NewRegisteredGauge(fmt.Sprintf("%slevel/%d/files", namespace, i), nil)
+	}
+
+	// Start up the metrics gathering and return
+	go db.meter(metricsGatheringInterval)
+	return db, nil
+}
+
+// makeOptions assembles the pebble options from the cache and file handle
+// allowances and the user supplied tuning parameters.
+func makeOptions(cache int, handles int, config *Config) *pebble.Options {
 	// The max memtable size is limited by the uint32 offsets stored in
 	// internal/arenaskl.node, DeferredBatchOp, and flushableBatchEntry.
 	// Taken from https://github.com/cockroachdb/pebble/blob/master/open.go#L38
 	maxMemTableSize := 4<<30 - 1 // Capped by 4 GB
 
-	// Two memory tables is configured which is identical to leveldb,
+	// Two memory tables is configured by default which is identical to leveldb,
 	// including a frozen memory table and another live one.
 	memTableLimit := 2
+	if config.MemTableCount > 0 {
+		memTableLimit = config.MemTableCount
+	}
 	memTableSize := cache * 1024 * 1024 / 2 / memTableLimit
+	if config.MemTableSize > 0 {
+		memTableSize = config.MemTableSize
+	}
 	if memTableSize > maxMemTableSize {
 		memTableSize = maxMemTableSize
 	}
-	db := &Database{
-		fn:       file,
-		log:      logger,
-		quitChan: make(chan chan error),
+	// The default compaction concurrency(1 thread),
+	// Here use all available CPUs for faster compaction.
+	compactions := runtime.NumCPU()
+	if config.MaxConcurrentCompactions > 0 {
+		compactions = config.MaxConcurrentCompactions
+	}
+	var (
+		fileSize  = int64(2 * 1024 * 1024)
+		bloomBits = 10
+	)
+	if config.TargetFileSize > 0 {
+		fileSize = config.TargetFileSize
+	}
+	if config.BloomBitsPerKey > 0 {
+		bloomBits = config.BloomBitsPerKey
 	}
 	opt := &pebble.Options{
 		// Pebble has a single combined cache area and the write
@@ -164,57 +256,24 @@ func New(file string, cache int, handles int, namespace string, readonly bool) (
 		// and to https://github.com/cockroachdb/pebble/blob/master/db.go#L1892-L1903.
 		MemTableStopWritesThreshold: memTableLimit,
 
-		// The default compaction concurrency(1 thread),
-		// Here use all available CPUs for faster compaction.
-		MaxConcurrentCompactions: func() int { return runtime.NumCPU() },
-
-		// Per-level options. Options for at least one level must be specified. The
-		// options for the last level are used for all subsequent levels.
-		Levels: []pebble.LevelOptions{
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-		},
-		ReadOnly: readonly,
-		EventListener: &pebble.EventListener{
-			CompactionBegin: db.onCompactionBegin,
-			CompactionEnd:   db.onCompactionEnd,
-			WriteStallBegin: db.onWriteStallBegin,
-			WriteStallEnd:   db.onWriteStallEnd,
-		},
+		MaxConcurrentCompactions: func() int { return compactions },
+
+		// Zero values are replaced by the pebble defaults.
+		LBaseMaxBytes:   config.LBaseMaxBytes,
+		WALDir:          config.WALDir,
+		WALBytesPerSync: config.WALBytesPerSync,
+	}
+	// Per-level options. Options for at least one level must be specified. The
+	// options for the last level are used for all subsequent levels.
+	opt.Levels = make([]pebble.LevelOptions, numLevels)
+	for i := range opt.Levels {
+		opt.Levels[i] = pebble.LevelOptions{TargetFileSize: fileSize, FilterPolicy: bloom.FilterPolicy(bloomBits)}
 	}
 	// Disable seek compaction explicitly. Check https://github.com/ethereum/go-ethereum/pull/20130
 	// for more details.
 	opt.Experimental.ReadSamplingMultiplier = -1
 
-	// Open the db and recover any potential corruptions
-	innerDB, err := pebble.Open(file, opt)
-	if err != nil {
-		return nil, err
-	}
-	db.db = innerDB
-
-	db.compTimeMeter = metrics.NewRegisteredMeter(namespace+"compact/time", nil)
-	db.compReadMeter = metrics.NewRegisteredMeter(namespace+"compact/input", nil)
-	db.compWriteMeter = metrics.NewRegisteredMeter(namespace+"compact/output", nil)
-	db.diskSizeGauge = metrics.NewRegisteredGauge(namespace+"disk/size", nil)
-	db.diskReadMeter = metrics.NewRegisteredMeter(namespace+"disk/read", nil)
-	db.diskWriteMeter = metrics.NewRegisteredMeter(namespace+"disk/write", nil)
-	db.writeDelayMeter = metrics.NewRegisteredMeter(namespace+"compact/writedelay/duration", nil)
-	db.writeDelayNMeter = metrics.NewRegisteredMeter(namespace+"compact/writedelay/counter", nil)
-	db.memCompGauge = metrics.NewRegisteredGauge(namespace+"compact/memory", nil)
-	db.level0CompGauge = metrics.NewRegisteredGauge(namespace+"compact/level0", nil)
-	db.nonlevel0CompGauge = metrics.NewRegisteredGauge(namespace+"compact/nonlevel0", nil)
-	db.seekCompGauge = metrics.NewRegisteredGauge(namespace+"compact/seek", nil)
-	db.manualMemAllocGauge = metrics.NewRegisteredGauge(namespace+"memory/manualalloc", nil)
-
-	// Start up the metrics gathering and return
-	go db.meter(metricsGatheringInterval)
-	return db, nil
+	return opt
 }
 
 // Close stops the metrics collection, flushes any pending data to disk and closes
@@ -355,6 +414,18 @@ func (snap *snapshot) Get(key []byte) ([]byte, error) {
 	return ret, nil
 }
 
+// NewIterator creates a binary-alphabetical iterator over a subset of the
+// snapshot content with a particular key prefix, starting at a particular
+// initial key (or after, if it does not exist).
+func (snap *snapshot) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	iter := snap.db.NewIter(&pebble.IterOptions{
+		LowerBound: append(prefix, start...),
+		UpperBound: upperBound(prefix),
+	})
+	iter.First()
+	return &pebbleIterator{iter: iter, moved: true}
+}
+
 // Release releases associated resources. Release should always succeed and can
 // be called multiple times without causing error.
 func (snap *snapshot) Release() {
@@ -376,9 +447,64 @@ func upperBound(prefix []byte) (limit []byte) {
 	return limit
 }
 
-// Stat returns a particular internal stat of the database.
+// Stat returns a particular internal stat of the database. The supported
+// properties are:
+//
+//   - pebble.metrics:    the full metrics table of pebble (alias leveldb.stats)
+//   - pebble.readamp:    the read amplification of the database and its levels
+//   - pebble.compaction: the compaction debt and write stall statistics
+//   - pebble.cache:      the block and table cache statistics
+//   - pebble.iostats:    the data read by compactions and the total data written
+//     (alias leveldb.iostats)
 func (d *Database) Stat(property string) (string, error) {
-	return "", nil
+	d.quitLock.RLock()
+	defer d.quitLock.RUnlock()
+	if d.closed {
+		return "", pebble.ErrClosed
+	}
+	metrics := d.db.Metrics()
+
+	var buf strings.Builder
+	switch property {
+	case "pebble.metrics", "leveldb.stats":
+		return metrics.String(), nil
+
+	case "pebble.readamp":
+		fmt.Fprintf(&buf, "total: %d\n", metrics.ReadAmp())
+		for level, levelMetrics := range metrics.Levels {
+			fmt.Fprintf(&buf, "L%d: %d (files: %d, size: %v)\n", level, levelMetrics.Sublevels, levelMetrics.NumFiles, common.StorageSize(levelMetrics.Size))
+		}
+	case "pebble.compaction":
+		fmt.Fprintf(&buf, "debt: %v\n", common.StorageSize(metrics.Compact.EstimatedDebt))
+		fmt.Fprintf(&buf, "in progress: %d (%v)\n", metrics.Compact.NumInProgress, common.StorageSize(metrics.Compact.InProgressBytes))
+		fmt.Fprintf(&buf, "write stalls: %d (%v)\n", d.writeDelayCount.Load(), time.Duration(d.writeDelayTime.Load()))
+
+	case "pebble.cache":
+		for _, cache := range []struct {
+			name    string
+			metrics pebble.CacheMetrics
+		}{{"block", metrics.BlockCache}, {"table", metrics.TableCache}} {
+			var rate float64
+			if total := cache.metrics.Hits + cache.metrics.Misses; total > 0 {
+				rate = 100 * float64(cache.metrics.Hits) / float64(total)
+			}
+			fmt.Fprintf(&buf, "%s: hits %d, misses %d, hit rate %.1f%%, entries %d, size %v\n", cache.name,
+				cache.metrics.Hits, cache.metrics.Misses, rate, cache.metrics.Count, common.StorageSize(cache.metrics.Size))
+		}
+	case "pebble.iostats", "leveldb.iostats":
+		// Same accounting as the disk meters, pebble doesn't track non-compaction reads
+		var read, write uint64
+		for _, levelMetrics := range metrics.Levels {
+			read += levelMetrics.BytesRead
+			write += levelMetrics.BytesCompacted + levelMetrics.BytesFlushed
+		}
+		write += metrics.WAL.BytesWritten
+		fmt.Fprintf(&buf, "Read(MB):%.5f Write(MB):%.5f", float64(read)/1048576, float64(write)/1048576)
+
+	default:
+		return "", fmt.Errorf("unknown property: %s", property)
+	}
+	return buf.String(), nil
 }
 
 // Compact flattens the underlying data store for the given key range. In essence,
@@ -422,6 +548,8 @@ func (d *Database) meter(refresh time.Duration) {
 		writeDelayCounts [2]int64
 		compWrites       [2]int64
 		compReads        [2]int64
+		blockHits        [2]int64
+		blockMisses      [2]int64
 
 		nWrites [2]int64
 	)
@@ -489,6 +617,29 @@ func (d *Database) meter(refresh time.Duration) {
 		d.level0CompGauge.Update(level0CompCount)
 		d.seekCompGauge.Update(metrics.Compact.ReadCount)
 
+		// Report the shape of the LSM tree and the pending compaction work
+		d.readAmpGauge.Update(int64(metrics.ReadAmp()))
+		d.compDebtGauge.Update(int64(metrics.Compact.EstimatedDebt))
+		d.compProgressGauge.Update(metrics.Compact.InProgressBytes)
+		for level, levelMetrics := range metrics.Levels {
+			d.levelReadAmpGauges[level].Update(int64(levelMetrics.Sublevels))
+			d.levelSizeGauges[level].Update(levelMetrics.Size)
+			d.levelFilesGauges[level].Update(levelMetrics.NumFiles)
+		}
+		// Report the block cache efficiency since the last round
+		blockHits[i%2] = metrics.BlockCache.Hits
+		blockMisses[i%2] = metrics.BlockCache.Misses
+
+		if d.blockHitMeter != nil {
+			hits, misses := blockHits[i%2]-blockHits[(i-1)%2], blockMisses[i%2]-blockMisses[(i-1)%2]
+			d.blockHitMeter.Mark(hits)
+			d.blockMissMeter.Mark(misses)
+			if hits+misses > 0 {
+				d.blockHitRateGauge.Update(100 * hits / (hits + misses))
+			}
+			d.blockSizeGauge.Update(metrics.BlockCache.Size)
+		}
+
 		// Sleep a bit, then repeat the stats collection
 		select {
 		case errc = <-d.quitChan:
@@ -19,9 +19,12 @@
 package pebble
 
 import (
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/bloom"
 	"github.com/cockroachdb/pebble/vfs"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/dbtest"
@@ -43,6 +46,78 @@ func TestPebbleDB(t *testing.T) {
 	})
 }
 
+func TestPebbleOptions(t *testing.T) {
+	// Defaults should be derived from the cache allowance
+	opt := makeOptions(64, 16, new(Config))
+	if opt.MemTableStopWritesThreshold != 2 || opt.MemTableSize != 16*1024*1024 {
+		t.Fatalf("default memtables mismatch: have %d x %d", opt.MemTableStopWritesThreshold, opt.MemTableSize)
+	}
+	if n := opt.MaxConcurrentCompactions(); n != runtime.NumCPU() {
+		t.Fatalf("default compactions mismatch: have %d, want %d", n, runtime.NumCPU())
+	}
+	if len(opt.Levels) != numLevels || opt.Levels[numLevels-1].TargetFileSize != 2*1024*1024 {
+		t.Fatalf("default levels mismatch: %+v", opt.Levels)
+	}
+	// Explicit settings should override the defaults
+	opt = makeOptions(64, 16, &Config{
+		MemTableCount:            4,
+		MemTableSize:             1024 * 1024,
+		LBaseMaxBytes:            128 * 1024 * 1024,
+		TargetFileSize:           4 * 1024 * 1024,
+		MaxConcurrentCompactions: 3,
+		BloomBitsPerKey:          16,
+		WALDir:                   "wal",
+		WALBytesPerSync:          512 * 1024,
+	})
+	if opt.MemTableStopWritesThreshold != 4 || opt.MemTableSize != 1024*1024 {
+		t.Fatalf("memtables mismatch: have %d x %d", opt.MemTableStopWritesThreshold, opt.MemTableSize)
+	}
+	if opt.LBaseMaxBytes != 128*1024*1024 || opt.Levels[0].TargetFileSize != 4*1024*1024 {
+		t.Fatalf("level sizes mismatch: lbase %d, file %d", opt.LBaseMaxBytes, opt.Levels[0].TargetFileSize)
+	}
+	if n := opt.MaxConcurrentCompactions(); n != 3 {
+		t.Fatalf("compactions mismatch: have %d, want 3", n)
+	}
+	if policy := opt.Levels[0].FilterPolicy; policy != bloom.FilterPolicy(16) {
+		t.Fatalf("filter policy mismatch: have %v, want %v", policy, bloom.FilterPolicy(16))
+	}
+	if opt.WALDir != "wal" || opt.WALBytesPerSync != 512*1024 {
+		t.Fatalf("wal settings mismatch: dir %q, sync %d", opt.WALDir, opt.WALBytesPerSync)
+	}
+}
+
+func TestPebbleStat(t *testing.T) {
+	inner, err := pebble.Open("", &pebble.Options{
+		FS: vfs.NewMem(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := &Database{db: inner}
+	defer db.Close()
+
+	for property, want := range map[string]string{
+		"pebble.metrics":    "level",
+		"leveldb.stats":     "level",
+		"pebble.readamp":    "total: 0",
+		"pebble.compaction": "write stalls: 0",
+		"pebble.cache":      "block: hits",
+		"pebble.iostats":    "Read(MB):",
+		"leveldb.iostats":   "Write(MB):",
+	} {
+		stat, err := db.Stat(property)
+		if err != nil {
+			t.Fatalf("failed to retrieve %s: %v", property, err)
+		}
+		if !strings.Contains(stat, want) {
+			t.Fatalf("%s mismatch: have %q, want %q", property, stat, want)
+		}
+	}
+	if _, err := db.Stat("pebble.unknown"); err == nil {
+		t.Fatal("retrieved unknown property")
+	}
+}
+
 func BenchmarkPebbleDB(b *testing.B) {
 	dbtest.BenchDatabaseSuite(b, func() ethdb.KeyValueStore {
 		db, err := pebble.Open("", &pebble.Options{
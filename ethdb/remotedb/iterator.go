@@ -0,0 +1,115 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotedb
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// iterator is a binary-alphabetical iterator over the content of a remote
+// database, retrieving the entries page by page. The pages are read from the
+// live database, so the iteration is not guaranteed to be consistent.
+type iterator struct {
+	remote *rpc.Client
+	prefix []byte
+	next   []byte // Start position of the next page, nil if exhausted
+
+	keys   [][]byte
+	values [][]byte
+	index  int
+	err    error
+}
+
+// newIterator creates an iterator over the entries with the given prefix,
+// starting at the given position.
+func newIterator(remote *rpc.Client, prefix []byte, start []byte) *iterator {
+	return &iterator{
+		remote: remote,
+		prefix: common.CopyBytes(prefix),
+		next:   append([]byte{}, start...),
+		index:  -1,
+	}
+}
+
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted.
+func (it *iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.index+1 < len(it.keys) {
+		it.index++
+		return true
+	}
+	// Current page exhausted, retrieve the next one if any
+	for it.next != nil {
+		var page IteratorPage
+		if err := it.remote.Call(&page, Namespace+"_iterate", hexutil.Bytes(it.prefix), hexutil.Bytes(it.next), maxBatchItems); err != nil {
+			it.err = err
+			break
+		}
+		if len(page.Keys) != len(page.Values) {
+			it.err = errors.New("invalid iterator page")
+			break
+		}
+		it.keys, it.values, it.index, it.next = it.keys[:0], it.values[:0], 0, nil
+		for i := range page.Keys {
+			it.keys = append(it.keys, page.Keys[i])
+			it.values = append(it.values, page.Values[i])
+		}
+		if page.Next != nil {
+			it.next = *page.Next
+		}
+		if len(it.keys) > 0 {
+			return true
+		}
+	}
+	it.keys, it.values, it.index = nil, nil, -1
+	return false
+}
+
+// Error returns any accumulated error. Exhausting all the key/value pairs
+// is not considered to be an error.
+func (it *iterator) Error() error {
+	return it.err
+}
+
+// Key returns the key of the current key/value pair, or nil if done.
+func (it *iterator) Key() []byte {
+	if it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	return it.keys[it.index]
+}
+
+// Value returns the value of the current key/value pair, or nil if done.
+func (it *iterator) Value() []byte {
+	if it.index < 0 || it.index >= len(it.values) {
+		return nil
+	}
+	return it.values[it.index]
+}
+
+// Release releases associated resources. Release should always succeed and can
+// be called multiple times without causing error.
+func (it *iterator) Release() {
+	it.keys, it.values, it.next, it.index = nil, nil, nil, -1
+}
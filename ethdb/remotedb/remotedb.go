@@ -15,37 +15,70 @@
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
 // Package remotedb implements the key-value database layer based on a remote geth
-// node. Under the hood, it utilises the `debug_dbGet` method to implement a
-// read-only database.
+// node. Under the hood, it utilises the read-only `remotedb` RPC service of the
+// node, which serves batched lookups, paginated iteration and ancient range reads.
 // There really are no guarantees in this database, since the local geth does not
-// exclusive access, but it can be used for basic diagnostics of a remote node.
+// exclusive access, but it can be used for diagnostics of a running node.
 package remotedb
 
 import (
+	"errors"
+
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
-// Database is a key-value lookup for a remote database via debug_dbGet.
+var (
+	// errNotFound is returned if a key is requested that is not found in the
+	// remote database.
+	errNotFound = errors.New("not found")
+
+	// errNotSupported is returned for the operations a remote database can't
+	// provide.
+	errNotSupported = errors.New("not supported by remote database")
+)
+
+// Database is a read-only key-value store and ancient store backed by the
+// remotedb RPC service of a node.
 type Database struct {
 	remote *rpc.Client
 }
 
 func (db *Database) Has(key []byte) (bool, error) {
-	if _, err := db.Get(key); err != nil {
-		return false, nil
+	values, err := db.get(key)
+	if err != nil {
+		return false, err
 	}
-	return true, nil
+	return values[0] != nil, nil
 }
 
 func (db *Database) Get(key []byte) ([]byte, error) {
-	var resp hexutil.Bytes
-	err := db.remote.Call(&resp, "debug_dbGet", hexutil.Bytes(key))
+	values, err := db.get(key)
 	if err != nil {
 		return nil, err
 	}
-	return resp, nil
+	if values[0] == nil {
+		return nil, errNotFound
+	}
+	return *values[0], nil
+}
+
+// get retrieves the values of the given keys from the remote database, nil
+// standing in for the missing ones.
+func (db *Database) get(keys ...[]byte) ([]*hexutil.Bytes, error) {
+	args := make([]hexutil.Bytes, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	var values []*hexutil.Bytes
+	if err := db.remote.Call(&values, Namespace+"_get", args); err != nil {
+		return nil, err
+	}
+	if len(values) != len(keys) {
+		return nil, errors.New("invalid response length")
+	}
+	return values, nil
 }
 
 func (db *Database) HasAncient(kind string, number uint64) (bool, error) {
@@ -56,30 +89,44 @@ func (db *Database) HasAncient(kind string, number uint64) (bool, error) {
 }
 
 func (db *Database) Ancient(kind string, number uint64) ([]byte, error) {
-	var resp hexutil.Bytes
-	err := db.remote.Call(&resp, "debug_dbAncient", kind, number)
+	items, err := db.AncientRange(kind, number, 1, 0)
 	if err != nil {
 		return nil, err
 	}
-	return resp, nil
+	return items[0], nil
 }
 
 func (db *Database) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
-	panic("not supported")
+	var resp []hexutil.Bytes
+	if err := db.remote.Call(&resp, Namespace+"_ancientRange", kind, start, count, maxBytes); err != nil {
+		return nil, err
+	}
+	if len(resp) == 0 {
+		return nil, errors.New("empty ancient range")
+	}
+	items := make([][]byte, len(resp))
+	for i, item := range resp {
+		items[i] = item
+	}
+	return items, nil
 }
 
 func (db *Database) Ancients() (uint64, error) {
 	var resp uint64
-	err := db.remote.Call(&resp, "debug_dbAncients")
+	err := db.remote.Call(&resp, Namespace+"_ancients")
 	return resp, err
 }
 
 func (db *Database) Tail() (uint64, error) {
-	panic("not supported")
+	var resp uint64
+	err := db.remote.Call(&resp, Namespace+"_tail")
+	return resp, err
 }
 
 func (db *Database) AncientSize(kind string) (uint64, error) {
-	panic("not supported")
+	var resp uint64
+	err := db.remote.Call(&resp, Namespace+"_ancientSize", kind)
+	return resp, err
 }
 
 func (db *Database) ReadAncients(fn func(op ethdb.AncientReaderOp) error) (err error) {
@@ -123,15 +170,17 @@ func (db *Database) NewBatchWithSize(size int) ethdb.Batch {
 }
 
 func (db *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
-	panic("not supported")
+	return newIterator(db.remote, prefix, start)
 }
 
 func (db *Database) Stat(property string) (string, error) {
-	panic("not supported")
+	var resp string
+	err := db.remote.Call(&resp, Namespace+"_stat", property)
+	return resp, err
 }
 
 func (db *Database) AncientDatadir() (string, error) {
-	panic("not supported")
+	return "", errNotSupported
 }
 
 func (db *Database) Compact(start []byte, limit []byte) error {
@@ -139,7 +188,7 @@ func (db *Database) Compact(start []byte, limit []byte) error {
 }
 
 func (db *Database) NewSnapshot() (ethdb.Snapshot, error) {
-	panic("not supported")
+	return nil, errNotSupported
 }
 
 func (db *Database) Close() error {
@@ -0,0 +1,158 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotedb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newTestDatabase creates a database with a populated key-value store and
+// chain freezer, and mounts it through an in-process remotedb service.
+func newTestDatabase(t *testing.T) (ethdb.Database, ethdb.Database) {
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for i := 0; i < 3*maxBatchItems+10; i++ {
+		key := binary.BigEndian.AppendUint32([]byte("a"), uint32(i))
+		if err := db.Put(key, bytes.Repeat([]byte{byte(i)}, i%7)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Put([]byte("b"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 10; i++ {
+			for _, kind := range []string{rawdb.ChainFreezerHeaderTable, rawdb.ChainFreezerHashTable, rawdb.ChainFreezerBodiesTable, rawdb.ChainFreezerReceiptTable, rawdb.ChainFreezerDifficultyTable} {
+				if err := op.AppendRaw(kind, i, []byte{byte(i)}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName(Namespace, NewService(db)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+
+	remote := New(rpc.DialInProc(server))
+	t.Cleanup(func() { remote.Close() })
+	return db, remote
+}
+
+func TestRemoteDatabaseGet(t *testing.T) {
+	_, remote := newTestDatabase(t)
+
+	if value, err := remote.Get([]byte("b")); err != nil || string(value) != "value" {
+		t.Fatalf("value mismatch: have %q (%v), want %q", value, err, "value")
+	}
+	// Empty values must not be mistaken for missing ones
+	if has, err := remote.Has(binary.BigEndian.AppendUint32([]byte("a"), 0)); err != nil || !has {
+		t.Fatalf("empty value missing: %v", err)
+	}
+	if has, err := remote.Has([]byte("c")); err != nil || has {
+		t.Fatalf("missing key present: %v", err)
+	}
+	if _, err := remote.Get([]byte("c")); err == nil {
+		t.Fatal("retrieved missing key")
+	}
+}
+
+func TestRemoteDatabaseIterator(t *testing.T) {
+	db, remote := newTestDatabase(t)
+
+	for _, test := range []struct {
+		prefix []byte
+		start  []byte
+	}{
+		{nil, nil},
+		{[]byte("a"), nil},
+		{[]byte("a"), []byte{0, 0, 4, 1}},
+		{[]byte("a"), []byte{0xff}},
+		{[]byte("b"), nil},
+		{[]byte("c"), nil},
+	} {
+		var (
+			local = db.NewIterator(test.prefix, test.start)
+			it    = remote.NewIterator(test.prefix, test.start)
+			count int
+		)
+		for local.Next() {
+			if !it.Next() {
+				t.Fatalf("prefix %x start %x: iterator exhausted early at %d: %v", test.prefix, test.start, count, it.Error())
+			}
+			if !bytes.Equal(it.Key(), local.Key()) || !bytes.Equal(it.Value(), local.Value()) {
+				t.Fatalf("prefix %x start %x: entry %d mismatch: have %x=%x, want %x=%x", test.prefix, test.start, count, it.Key(), it.Value(), local.Key(), local.Value())
+			}
+			count++
+		}
+		if it.Next() {
+			t.Fatalf("prefix %x start %x: iterator not exhausted after %d entries", test.prefix, test.start, count)
+		}
+		if err := it.Error(); err != nil {
+			t.Fatalf("prefix %x start %x: iterator failed: %v", test.prefix, test.start, err)
+		}
+		local.Release()
+		it.Release()
+	}
+}
+
+func TestRemoteDatabaseAncients(t *testing.T) {
+	_, remote := newTestDatabase(t)
+
+	if frozen, err := remote.Ancients(); err != nil || frozen != 10 {
+		t.Fatalf("ancients mismatch: have %d (%v), want %d", frozen, err, 10)
+	}
+	if tail, err := remote.Tail(); err != nil || tail != 0 {
+		t.Fatalf("tail mismatch: have %d (%v), want %d", tail, err, 0)
+	}
+	if size, err := remote.AncientSize(rawdb.ChainFreezerHashTable); err != nil || size == 0 {
+		t.Fatalf("ancient size mismatch: have %d (%v)", size, err)
+	}
+	if blob, err := remote.Ancient(rawdb.ChainFreezerHashTable, 3); err != nil || !bytes.Equal(blob, []byte{3}) {
+		t.Fatalf("ancient mismatch: have %x (%v), want %x", blob, err, []byte{3})
+	}
+	if ok, _ := remote.HasAncient(rawdb.ChainFreezerHashTable, 10); ok {
+		t.Fatal("non-existent ancient present")
+	}
+	items, err := remote.AncientRange(rawdb.ChainFreezerHashTable, 2, 5, 0)
+	if err != nil {
+		t.Fatalf("failed to retrieve ancient range: %v", err)
+	}
+	if len(items) != 5 {
+		t.Fatalf("ancient range length mismatch: have %d, want %d", len(items), 5)
+	}
+	for i, item := range items {
+		if !bytes.Equal(item, []byte{byte(i + 2)}) {
+			t.Fatalf("ancient %d mismatch: have %x, want %x", i+2, item, []byte{byte(i + 2)})
+		}
+	}
+}
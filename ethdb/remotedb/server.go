@@ -0,0 +1,152 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotedb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const (
+	// Namespace is the RPC namespace the database service is registered under.
+	Namespace = "remotedb"
+
+	maxBatchItems   = 1024            // Maximum number of keys, entries or ancient items in a single call
+	maxResponseSize = 4 * 1024 * 1024 // Soft limit of the data returned in a single call
+)
+
+var errTooManyKeys = fmt.Errorf("too many keys requested, limit %d", maxBatchItems)
+
+// Service exposes read-only access to a database over RPC, serving the batched
+// lookups, paginated iteration and ancient range reads needed by a remote client
+// to mount the database.
+type Service struct {
+	db ethdb.Database
+}
+
+// NewService creates the read-only RPC service of a database.
+func NewService(db ethdb.Database) *Service {
+	return &Service{db: db}
+}
+
+// Get retrieves the values of a batch of keys, with null standing in for the
+// missing ones.
+func (s *Service) Get(keys []hexutil.Bytes) ([]*hexutil.Bytes, error) {
+	if len(keys) > maxBatchItems {
+		return nil, errTooManyKeys
+	}
+	values := make([]*hexutil.Bytes, len(keys))
+	for i, key := range keys {
+		if ok, err := s.db.Has(key); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		blob, err := s.db.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		value := hexutil.Bytes(blob)
+		values[i] = &value
+	}
+	return values, nil
+}
+
+// IteratorPage is a batch of consecutive database entries.
+type IteratorPage struct {
+	Keys   []hexutil.Bytes `json:"keys"`
+	Values []hexutil.Bytes `json:"values"`
+
+	// Next is the start position, relative to the prefix, of the next page
+	// or null if the iteration is exhausted.
+	Next *hexutil.Bytes `json:"next"`
+}
+
+// Iterate retrieves a page of the entries with the given key prefix, starting
+// at the given position. At most limit entries are returned, but the page is
+// cut early if it would grow too large.
+func (s *Service) Iterate(prefix hexutil.Bytes, start hexutil.Bytes, limit int) (*IteratorPage, error) {
+	if limit <= 0 || limit > maxBatchItems {
+		limit = maxBatchItems
+	}
+	it := s.db.NewIterator(prefix, start)
+	defer it.Release()
+
+	var (
+		page = &IteratorPage{Keys: []hexutil.Bytes{}, Values: []hexutil.Bytes{}}
+		size int
+	)
+	for it.Next() {
+		if len(page.Keys) >= limit || size >= maxResponseSize {
+			next := hexutil.Bytes(common.CopyBytes(it.Key()[len(prefix):]))
+			page.Next = &next
+			break
+		}
+		page.Keys = append(page.Keys, common.CopyBytes(it.Key()))
+		page.Values = append(page.Values, common.CopyBytes(it.Value()))
+		size += len(it.Key()) + len(it.Value())
+	}
+	return page, it.Error()
+}
+
+// Stat returns a particular internal stat of the database.
+func (s *Service) Stat(property string) (string, error) {
+	return s.db.Stat(property)
+}
+
+// Ancients returns the number of items in the ancient store.
+func (s *Service) Ancients() (uint64, error) {
+	return s.db.Ancients()
+}
+
+// Tail returns the number of the first item in the ancient store.
+func (s *Service) Tail() (uint64, error) {
+	return s.db.Tail()
+}
+
+// AncientSize returns the size of the given ancient table.
+func (s *Service) AncientSize(kind string) (uint64, error) {
+	return s.db.AncientSize(kind)
+}
+
+// AncientRange retrieves consecutive items of an ancient table, starting at the
+// given number. At least one item is returned, otherwise the number and the total
+// size of the items are limited.
+func (s *Service) AncientRange(kind string, start, count, maxBytes uint64) ([]hexutil.Bytes, error) {
+	if count == 0 {
+		return nil, errors.New("zero item count")
+	}
+	if count > maxBatchItems {
+		count = maxBatchItems
+	}
+	if maxBytes == 0 || maxBytes > maxResponseSize {
+		maxBytes = maxResponseSize
+	}
+	items, err := s.db.AncientRange(kind, start, count, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	blobs := make([]hexutil.Bytes, len(items))
+	for i, item := range items {
+		blobs[i] = item
+	}
+	return blobs, nil
+}
@@ -875,6 +875,26 @@ func (b *Block) Raw(ctx context.Context) (hexutil.Bytes, error) {
 	return rlp.EncodeToBytes(block)
 }
 
+func (b *Block) RawReceipts(ctx context.Context) ([]hexutil.Bytes, error) {
+	// Ensure the block hash is resolved before fetching the receipts.
+	if _, err := b.resolveHeader(ctx); err != nil {
+		return nil, err
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]hexutil.Bytes, 0, len(receipts))
+	for _, receipt := range receipts {
+		blob, err := receipt.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, blob)
+	}
+	return ret, nil
+}
+
 // BlockNumberArgs encapsulates arguments to accessors that specify a block number.
 type BlockNumberArgs struct {
 	// TODO: Ideally we could use input unions to allow the query to specify the
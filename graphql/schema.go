@@ -260,6 +260,9 @@ const schema string = `
         rawHeader: Bytes!
         # Raw is the RLP encoding of the block.
         raw: Bytes!
+        # RawReceipts is the list of canonical encodings of the receipts of all
+        # transactions in this block, in the order of the transactions.
+        rawReceipts: [Bytes!]!
         # WithdrawalsRoot is the withdrawals trie root in this block.
         # If withdrawals are unavailable for this block, this field will be null.
         withdrawalsRoot: Bytes32
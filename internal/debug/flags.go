@@ -17,6 +17,7 @@
 package debug
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -24,11 +25,14 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/internal/telemetry/exporter"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/metrics/exp"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/fjl/memsize/memsizeui"
 	"github.com/mattn/go-colorable"
 	"github.com/mattn/go-isatty"
@@ -151,6 +155,22 @@ var (
 		Usage:    "Write execution trace to the given file",
 		Category: flags.LoggingCategory,
 	}
+	tracingEndpointFlag = &cli.StringFlag{
+		Name:     "tracing.endpoint",
+		Usage:    "OTLP/HTTP collector URL receiving the spans of RPC calls and block imports (e.g. http://localhost:4318)",
+		Category: flags.LoggingCategory,
+	}
+	tracingFileFlag = &cli.StringFlag{
+		Name:     "tracing.file",
+		Usage:    "Append the spans of RPC calls and block imports to the given file as JSON lines",
+		Category: flags.LoggingCategory,
+	}
+	tracingSampleFlag = &cli.Float64Flag{
+		Name:     "tracing.sample",
+		Usage:    "Fraction of the traces started by geth which are recorded (0 = none, 1 = all)",
+		Value:    1,
+		Category: flags.LoggingCategory,
+	}
 )
 
 // Flags holds all command-line flags required for debugging.
@@ -175,11 +195,15 @@ var Flags = []cli.Flag{
 	blockprofilerateFlag,
 	cpuprofileFlag,
 	traceFlag,
+	tracingEndpointFlag,
+	tracingFileFlag,
+	tracingSampleFlag,
 }
 
 var (
 	glogger         *log.GlogHandler
 	logOutputStream log.Handler
+	stopTracing     func(context.Context) error
 )
 
 func init() {
@@ -302,6 +326,18 @@ func Setup(ctx *cli.Context) error {
 		}
 	}
 
+	// OpenTelemetry span exporters
+	stop, err := exporter.Setup(exporter.Config{
+		Endpoint:       ctx.String(tracingEndpointFlag.Name),
+		File:           ctx.String(tracingFileFlag.Name),
+		SampleRatio:    ctx.Float64(tracingSampleFlag.Name),
+		ServiceVersion: params.VersionWithMeta,
+	})
+	if err != nil {
+		return err
+	}
+	stopTracing = stop
+
 	// pprof server
 	if ctx.Bool(pprofFlag.Name) {
 		listenHost := ctx.String(pprofAddrFlag.Name)
@@ -339,6 +375,13 @@ func StartPProf(address string, withMetrics bool) {
 func Exit() {
 	Handler.StopCPUProfile()
 	Handler.StopGoTrace()
+	if stopTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := stopTracing(ctx); err != nil {
+			log.Warn("Failed to flush trace spans", "err", err)
+		}
+		cancel()
+	}
 	if closer, ok := logOutputStream.(io.Closer); ok {
 		closer.Close()
 	}
@@ -0,0 +1,94 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// accumulatorDepth is the depth of the merkle tree of the accumulator, large
+// enough to hold the header records of a full Era1 file.
+const accumulatorDepth = 13
+
+// zeroHashes are the roots of the empty subtrees at each depth.
+var zeroHashes = func() [accumulatorDepth + 1][32]byte {
+	var hashes [accumulatorDepth + 1][32]byte
+	for i := 0; i < accumulatorDepth; i++ {
+		hashes[i+1] = sha256.Sum256(append(hashes[i][:], hashes[i][:]...))
+	}
+	return hashes
+}()
+
+// ComputeAccumulator calculates the SSZ hash tree root of the Era1 accumulator,
+// a List[HeaderRecord, 8192] in which each record holds the hash and the total
+// difficulty of a block.
+func ComputeAccumulator(hashes []common.Hash, tds []*big.Int) (common.Hash, error) {
+	if len(hashes) != len(tds) {
+		return common.Hash{}, fmt.Errorf("must have equal number hashes as td values: %d != %d", len(hashes), len(tds))
+	}
+	if len(hashes) > MaxEra1Size {
+		return common.Hash{}, fmt.Errorf("too many records: have %d, max %d", len(hashes), MaxEra1Size)
+	}
+	layer := make([][32]byte, len(hashes))
+	for i := range hashes {
+		if tds[i].Sign() < 0 || tds[i].BitLen() > 256 {
+			return common.Hash{}, fmt.Errorf("invalid total difficulty %v", tds[i])
+		}
+		td := bigToBytes32(tds[i])
+		layer[i] = sha256.Sum256(append(hashes[i].Bytes(), td[:]...))
+	}
+	// Merkleize the records, padding the tree with empty subtrees
+	for depth := 0; depth < accumulatorDepth; depth++ {
+		next := make([][32]byte, (len(layer)+1)/2)
+		for i := range next {
+			right := zeroHashes[depth]
+			if 2*i+1 < len(layer) {
+				right = layer[2*i+1]
+			}
+			next[i] = sha256.Sum256(append(layer[2*i][:], right[:]...))
+		}
+		layer = next
+	}
+	root := zeroHashes[accumulatorDepth]
+	if len(layer) > 0 {
+		root = layer[0]
+	}
+	// Mix in the length of the list
+	var length [32]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(hashes)))
+	return sha256.Sum256(append(root[:], length[:]...)), nil
+}
+
+// bigToBytes32 converts a big.Int into a little-endian 32-byte array.
+func bigToBytes32(n *big.Int) (b [32]byte) {
+	n.FillBytes(b[:])
+	reverseOrder(b[:])
+	return
+}
+
+// reverseOrder reverses the byte order of a slice.
+func reverseOrder(b []byte) []byte {
+	for i := 0; i < 16 && i < len(b)/2; i++ {
+		b[i], b[len(b)-1-i] = b[len(b)-1-i], b[i]
+	}
+	return b
+}
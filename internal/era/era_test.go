@@ -0,0 +1,219 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+package era
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+)
+
+// makeChain generates a chain with a value transfer in each block, returning
+// the blocks along with their receipts and total difficulties.
+func makeChain(n int) ([]*types.Block, []types.Receipts, []*big.Int) {
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{testAddress: {Balance: big.NewInt(params.Ether)}},
+	}
+	signer := types.LatestSigner(gspec.Config)
+	_, blocks, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), n, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), common.Address{0xaa}, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, testKey)
+		b.AddTx(tx)
+	})
+	var (
+		tds = make([]*big.Int, n)
+		td  = new(big.Int).Set(gspec.ToBlock().Difficulty())
+	)
+	for i, block := range blocks {
+		td = new(big.Int).Add(td, block.Difficulty())
+		tds[i] = td
+	}
+	return blocks, receipts, tds
+}
+
+// buildEra packs the given blocks into an in-memory Era1 archive.
+func buildEra(t *testing.T, blocks []*types.Block, receipts []types.Receipts, tds []*big.Int) ([]byte, common.Hash) {
+	var (
+		buf = bytes.NewBuffer(nil)
+		b   = NewBuilder(buf)
+	)
+	for i, block := range blocks {
+		if err := b.Add(block, receipts[i], tds[i]); err != nil {
+			t.Fatalf("error adding block %d: %v", block.NumberU64(), err)
+		}
+	}
+	root, err := b.Finalize()
+	if err != nil {
+		t.Fatalf("error finalizing era1: %v", err)
+	}
+	return buf.Bytes(), root
+}
+
+type testReader struct {
+	*bytes.Reader
+}
+
+func (r testReader) Close() error { return nil }
+
+func TestEra1Builder(t *testing.T) {
+	blocks, receipts, tds := makeChain(128)
+	data, root := buildEra(t, blocks, receipts, tds)
+
+	e, err := From(testReader{bytes.NewReader(data)})
+	if err != nil {
+		t.Fatalf("failed to open era: %v", err)
+	}
+	if e.Start() != 1 || e.Count() != 128 {
+		t.Fatalf("range mismatch: have [%d, +%d], want [%d, +%d]", e.Start(), e.Count(), 1, 128)
+	}
+	if have, err := e.Accumulator(); err != nil || have != root {
+		t.Fatalf("accumulator mismatch: have %x (%v), want %x", have, err, root)
+	}
+	if want, _ := ComputeAccumulator(hashes(blocks), tds); want != root {
+		t.Fatalf("accumulator root mismatch: have %x, want %x", root, want)
+	}
+	if td, err := e.InitialTD(); err != nil || td.Cmp(new(big.Int).Sub(tds[0], blocks[0].Difficulty())) != 0 {
+		t.Fatalf("initial td mismatch: have %v (%v)", td, err)
+	}
+	if err := e.Verify(); err != nil {
+		t.Fatalf("failed to verify era: %v", err)
+	}
+	// Check random access and iteration against the original chain
+	for _, i := range []int{0, 63, 127} {
+		block, err := e.GetBlockByNumber(blocks[i].NumberU64())
+		if err != nil {
+			t.Fatalf("failed to retrieve block %d: %v", i, err)
+		}
+		if block.Hash() != blocks[i].Hash() {
+			t.Fatalf("block %d hash mismatch: have %x, want %x", i, block.Hash(), blocks[i].Hash())
+		}
+	}
+	if _, err := e.GetBlockByNumber(129); err == nil {
+		t.Fatalf("expected error retrieving out-of-bounds block")
+	}
+	it, err := NewIterator(e)
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+	for i := 0; it.Next(); i++ {
+		if err := it.Error(); err != nil {
+			t.Fatalf("iterator error at %d: %v", i, err)
+		}
+		block, rs, err := it.BlockAndReceipts()
+		if err != nil {
+			t.Fatalf("failed to decode entry %d: %v", i, err)
+		}
+		if block.Hash() != blocks[i].Hash() {
+			t.Fatalf("block %d hash mismatch: have %x, want %x", i, block.Hash(), blocks[i].Hash())
+		}
+		if have, want := types.DeriveSha(rs, trie.NewStackTrie(nil)), blocks[i].ReceiptHash(); have != want {
+			t.Fatalf("receipts %d root mismatch: have %x, want %x", i, have, want)
+		}
+		if td, err := it.TotalDifficulty(); err != nil || td.Cmp(tds[i]) != 0 {
+			t.Fatalf("td %d mismatch: have %v (%v), want %v", i, td, err, tds[i])
+		}
+	}
+}
+
+// Tests that archives not matching their accumulator are rejected.
+func TestEra1VerifyTampered(t *testing.T) {
+	blocks, receipts, tds := makeChain(16)
+
+	// Drop the receipts of a block
+	dropped := receipts[3]
+	receipts[3] = nil
+	data, _ := buildEra(t, blocks, receipts, tds)
+	e, err := From(testReader{bytes.NewReader(data)})
+	if err != nil {
+		t.Fatalf("failed to open era: %v", err)
+	}
+	if err := e.Verify(); err == nil {
+		t.Fatalf("expected receipt root mismatch")
+	}
+	receipts[3] = dropped
+
+	// Replace the accumulator by the one of a different history
+	data, _ = buildEra(t, blocks, receipts, tds)
+	other, _ := ComputeAccumulator(hashes(blocks[1:]), tds[1:])
+	copy(data[bytes.Index(data, mustAccumulator(t, data).Bytes()):], other.Bytes())
+
+	e, err = From(testReader{bytes.NewReader(data)})
+	if err != nil {
+		t.Fatalf("failed to open era: %v", err)
+	}
+	if err := e.Verify(); err == nil {
+		t.Fatalf("expected accumulator mismatch")
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		Filename("mainnet", 1, common.Hash{0x02}),
+		Filename("mainnet", 0, common.Hash{0x01}),
+		Filename("sepolia", 0, common.Hash{0x03}),
+		"checksums.txt",
+	} {
+		if err := os.WriteFile(dir+"/"+name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := ReadDir(dir, "mainnet")
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(files) != 2 || files[0] != "mainnet-00000-01000000.era1" || files[1] != "mainnet-00001-02000000.era1" {
+		t.Fatalf("file list mismatch: have %v", files)
+	}
+	os.Remove(dir + "/" + files[0])
+	if _, err := ReadDir(dir, "mainnet"); err == nil {
+		t.Fatalf("expected error on missing epoch")
+	}
+}
+
+func hashes(blocks []*types.Block) []common.Hash {
+	hashes := make([]common.Hash, len(blocks))
+	for i, block := range blocks {
+		hashes[i] = block.Hash()
+	}
+	return hashes
+}
+
+func mustAccumulator(t *testing.T, data []byte) common.Hash {
+	e, err := From(testReader{bytes.NewReader(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := e.Accumulator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
@@ -325,7 +325,7 @@ func (b testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOr
 }
 func (b testBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) { panic("implement me") }
 func (b testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
-	panic("implement me")
+	return b.chain.GetReceiptsByHash(hash), nil
 }
 func (b testBackend) GetTd(ctx context.Context, hash common.Hash) *big.Int {
 	if b.pending != nil && hash == b.pending.Hash() {
@@ -360,6 +360,9 @@ func (b testBackend) SendTx(ctx context.Context, signedTx *types.Transaction) er
 func (b testBackend) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
 	panic("implement me")
 }
+func (b testBackend) GetTransactionBySender(ctx context.Context, sender common.Address, nonce uint64) (*types.Transaction, common.Hash, uint64, uint64, error) {
+	panic("implement me")
+}
 func (b testBackend) GetPoolTransactions() (types.Transactions, error)         { panic("implement me") }
 func (b testBackend) GetPoolTransaction(txHash common.Hash) *types.Transaction { panic("implement me") }
 func (b testBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
@@ -389,7 +392,8 @@ func (b testBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscripti
 func (b testBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	panic("implement me")
 }
-func (b testBackend) BloomStatus() (uint64, uint64) { panic("implement me") }
+func (b testBackend) BloomStatus() (uint64, uint64)    { panic("implement me") }
+func (b testBackend) LogIndexStatus() (uint64, uint64) { panic("implement me") }
 func (b testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	panic("implement me")
 }
@@ -1023,3 +1027,63 @@ func TestRPCGetBlockOrHeader(t *testing.T) {
 		require.JSONEqf(t, want, have, "test %d: json not match, want: %s, have: %s", i, want, have)
 	}
 }
+
+// prunedBackend is a test backend whose chain history below cutoff was pruned.
+type prunedBackend struct {
+	*testBackend
+	cutoff uint64
+}
+
+func (b prunedBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	if blockNr, ok := blockNrOrHash.Number(); ok && blockNr >= 0 && uint64(blockNr) < b.cutoff {
+		return nil, &PrunedHistoryError{}
+	}
+	return b.testBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+}
+
+func TestRPCGetBlockReceiptsPruned(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _  = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.HomesteadSigner{}
+	)
+	backend := newTestBackend(t, 4, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: uint64(i), To: &common.Address{1}, Value: big.NewInt(1000), Gas: params.TxGas, GasPrice: b.BaseFee()}), signer, key)
+		b.AddTx(tx)
+	})
+	api := NewBlockChainAPI(prunedBackend{testBackend: backend, cutoff: 2})
+	ctx := context.Background()
+
+	// Blocks below the cutoff must report the pruned history
+	for _, number := range []rpc.BlockNumber{0, 1} {
+		receipts, err := api.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(number))
+		var perr *PrunedHistoryError
+		if !errors.As(err, &perr) {
+			t.Errorf("block %d: want pruned history error, have %v", number, err)
+		}
+		if receipts != nil {
+			t.Errorf("block %d: want no receipts, have %d", number, len(receipts))
+		}
+	}
+	// Blocks from the cutoff must still be served
+	for _, number := range []rpc.BlockNumber{2, 3, 4} {
+		receipts, err := api.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(number))
+		if err != nil {
+			t.Errorf("block %d: want no error, have %v", number, err)
+		}
+		if len(receipts) != 1 {
+			t.Errorf("block %d: want 1 receipt, have %d", number, len(receipts))
+		}
+	}
+	// Blocks beyond the head are not found, not pruned
+	receipts, err := api.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(5))
+	if err != nil || receipts != nil {
+		t.Errorf("missing block: want null and no error, have %v, %v", receipts, err)
+	}
+}
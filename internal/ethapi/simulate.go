@@ -0,0 +1,410 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks that can be simulated
+	// in a single request.
+	maxSimulateBlocks = 256
+
+	// timestampIncrement is the default increment between block timestamps.
+	timestampIncrement = 12
+
+	// errCodeVMError is the JSON error code of a simulated call which failed
+	// for any reason other than an explicit revert.
+	errCodeVMError = -32015
+)
+
+var (
+	// transferAddress is the address used as the emitter of the synthetic
+	// logs recording ether transfers, as defined by ERC-7528.
+	transferAddress = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+
+	// transferTopic is the topic of the synthetic logs recording ether
+	// transfers, it equals keccak256('Transfer(address,address,uint256)').
+	transferTopic = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+)
+
+// simBlock is a batch of calls to be simulated sequentially on top of the
+// state left by the previous block, with optional overrides applied first.
+type simBlock struct {
+	BlockOverrides *BlockOverrides
+	StateOverrides *StateOverride
+	Calls          []TransactionArgs
+}
+
+// simOpts are the inputs to eth_simulate.
+type simOpts struct {
+	BlockStateCalls []simBlock
+	TraceTransfers  bool
+}
+
+// simCallError is the error of a simulated call which was executed but failed.
+type simCallError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+	Data    string `json:"data,omitempty"`
+}
+
+// simCallResult is the result of a simulated call.
+type simCallResult struct {
+	ReturnValue hexutil.Bytes  `json:"returnData"`
+	Logs        []*types.Log   `json:"logs"`
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	Status      hexutil.Uint64 `json:"status"`
+	Error       *simCallError  `json:"error,omitempty"`
+}
+
+// simBlockResult is the result of a simulated block.
+type simBlockResult struct {
+	Number       hexutil.Uint64  `json:"number"`
+	Hash         common.Hash     `json:"hash"`
+	ParentHash   common.Hash     `json:"parentHash"`
+	Timestamp    hexutil.Uint64  `json:"timestamp"`
+	GasLimit     hexutil.Uint64  `json:"gasLimit"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	FeeRecipient common.Address  `json:"miner"`
+	BaseFee      *hexutil.Big    `json:"baseFeePerGas,omitempty"`
+	Calls        []simCallResult `json:"calls"`
+}
+
+// simulator is a stateful object that simulates a series of blocks on top
+// of a base block, carrying the state forward between calls and blocks.
+type simulator struct {
+	b              Backend
+	state          *state.StateDB
+	base           *types.Header
+	headers        []*types.Header // Headers of the blocks simulated so far
+	gasRemaining   uint64          // Gas allowance left for all the remaining calls
+	traceTransfers bool
+}
+
+// Simulate executes a series of blocks, each containing a list of calls, on top
+// of the state of the given block. The state changes of each call are visible to
+// all the subsequent calls, both within the same block and in the following ones.
+// Every block can have its own block and state overrides, which are applied prior
+// to executing the calls of that block.
+//
+// Note, this function doesn't make any changes in the state/blockchain and is
+// useful to preview the outcome of a sequence of transactions.
+func (s *BlockChainAPI) Simulate(ctx context.Context, opts simOpts, blockNrOrHash *rpc.BlockNumberOrHash) ([]*simBlockResult, error) {
+	if len(opts.BlockStateCalls) == 0 {
+		return nil, errors.New("empty input")
+	}
+	if len(opts.BlockStateCalls) > maxSimulateBlocks {
+		return nil, fmt.Errorf("too many blocks, maximum %d", maxSimulateBlocks)
+	}
+	if blockNrOrHash == nil {
+		n := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &n
+	}
+	state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	gasCap := s.b.RPCGasCap()
+	if gasCap == 0 {
+		gasCap = math.MaxUint64 / 2
+	}
+	sim := &simulator{
+		b:              s.b,
+		state:          state,
+		base:           header,
+		gasRemaining:   gasCap,
+		traceTransfers: opts.TraceTransfers,
+	}
+	// Setup context so it may be cancelled when the simulation has completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	var cancel context.CancelFunc
+	if timeout := s.b.RPCEVMTimeout(); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	return sim.execute(ctx, opts.BlockStateCalls)
+}
+
+// execute runs the given blocks one by one and returns their results.
+func (sim *simulator) execute(ctx context.Context, blocks []simBlock) ([]*simBlockResult, error) {
+	defer func(start time.Time) { log.Debug("Simulating blocks finished", "runtime", time.Since(start)) }(time.Now())
+
+	results := make([]*simBlockResult, 0, len(blocks))
+	for i, block := range blocks {
+		result, err := sim.processBlock(ctx, &block)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// makeHeader assembles the header of the next simulated block from the parent
+// and the provided overrides.
+func (sim *simulator) makeHeader(overrides *BlockOverrides) (*types.Header, error) {
+	parent := sim.base
+	if len(sim.headers) > 0 {
+		parent = sim.headers[len(sim.headers)-1]
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   parent.Coinbase,
+		Difficulty: new(big.Int).Set(parent.Difficulty),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + timestampIncrement,
+		MixDigest:  parent.MixDigest,
+	}
+	if overrides != nil {
+		if overrides.Number != nil {
+			if overrides.Number.ToInt().Cmp(parent.Number) <= 0 {
+				return nil, fmt.Errorf("block number %v is not greater than parent %v", overrides.Number, parent.Number)
+			}
+			header.Number = new(big.Int).Set(overrides.Number.ToInt())
+		}
+		if overrides.Time != nil {
+			if uint64(*overrides.Time) <= parent.Time {
+				return nil, fmt.Errorf("block timestamp %d is not greater than parent %d", uint64(*overrides.Time), parent.Time)
+			}
+			header.Time = uint64(*overrides.Time)
+		}
+		if overrides.GasLimit != nil {
+			header.GasLimit = uint64(*overrides.GasLimit)
+		}
+		if overrides.Coinbase != nil {
+			header.Coinbase = *overrides.Coinbase
+		}
+		if overrides.Difficulty != nil {
+			header.Difficulty = new(big.Int).Set(overrides.Difficulty.ToInt())
+		}
+		if overrides.Random != nil {
+			header.MixDigest = *overrides.Random
+		}
+	}
+	switch {
+	case overrides != nil && overrides.BaseFee != nil:
+		header.BaseFee = new(big.Int).Set(overrides.BaseFee.ToInt())
+	case sim.b.ChainConfig().IsLondon(header.Number):
+		header.BaseFee = misc.CalcBaseFee(sim.b.ChainConfig(), parent)
+	}
+	return header, nil
+}
+
+// getHashFn returns a GetHashFunc which resolves the hashes of the simulated
+// blocks first and falls back to the canonical chain for the older ones.
+func (sim *simulator) getHashFn(ctx context.Context) vm.GetHashFunc {
+	canonical := core.GetHashFn(sim.base, NewChainContext(ctx, sim.b))
+	return func(n uint64) common.Hash {
+		base := sim.base.Number.Uint64()
+		switch {
+		case n < base:
+			return canonical(n)
+		case n == base:
+			return sim.base.Hash()
+		}
+		for _, header := range sim.headers {
+			if header.Number.Uint64() == n {
+				return header.Hash()
+			}
+		}
+		return common.Hash{}
+	}
+}
+
+// processBlock applies the overrides and executes all the calls of the given
+// simulated block on top of the current state.
+func (sim *simulator) processBlock(ctx context.Context, block *simBlock) (*simBlockResult, error) {
+	header, err := sim.makeHeader(block.BlockOverrides)
+	if err != nil {
+		return nil, err
+	}
+	if err := block.StateOverrides.Apply(sim.state); err != nil {
+		return nil, err
+	}
+	blockCtx := core.NewEVMBlockContext(header, NewChainContext(ctx, sim.b), &header.Coinbase)
+	blockCtx.GetHash = sim.getHashFn(ctx)
+	if sim.traceTransfers {
+		blockCtx.Transfer = transferWithLog
+	}
+	var (
+		gp      = new(core.GasPool).AddGas(header.GasLimit)
+		calls   = make([]simCallResult, 0, len(block.Calls))
+		hashes  = make([]common.Hash, 0, len(block.Calls))
+		gasUsed uint64
+	)
+	for i, args := range block.Calls {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", sim.b.RPCEVMTimeout())
+		}
+		result, hash, err := sim.processCall(ctx, header, blockCtx, args, i, gp)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		gasUsed += uint64(result.GasUsed)
+		calls = append(calls, *result)
+		hashes = append(hashes, hash)
+	}
+	header.GasUsed = gasUsed
+	sim.headers = append(sim.headers, header)
+
+	// Now that the header is complete, patch the block fields of the logs.
+	blockHash := header.Hash()
+	for i := range calls {
+		calls[i].Logs = sim.state.GetLogs(hashes[i], header.Number.Uint64(), blockHash)
+		if calls[i].Logs == nil {
+			calls[i].Logs = []*types.Log{}
+		}
+	}
+	result := &simBlockResult{
+		Number:       hexutil.Uint64(header.Number.Uint64()),
+		Hash:         blockHash,
+		ParentHash:   header.ParentHash,
+		Timestamp:    hexutil.Uint64(header.Time),
+		GasLimit:     hexutil.Uint64(header.GasLimit),
+		GasUsed:      hexutil.Uint64(header.GasUsed),
+		FeeRecipient: header.Coinbase,
+		Calls:        calls,
+	}
+	if header.BaseFee != nil {
+		result.BaseFee = (*hexutil.Big)(header.BaseFee)
+	}
+	return result, nil
+}
+
+// processCall executes a single call on top of the current state. The returned
+// error is only non-nil if the call couldn't be executed at all, failures during
+// the execution are reported in the call result instead.
+func (sim *simulator) processCall(ctx context.Context, header *types.Header, blockCtx vm.BlockContext, args TransactionArgs, index int, gp *core.GasPool) (*simCallResult, common.Hash, error) {
+	if sim.gasRemaining == 0 {
+		return nil, common.Hash{}, errors.New("gas cap exhausted")
+	}
+	// Fill in the nonce and gas limit the call would have as a transaction.
+	if args.Nonce == nil {
+		nonce := hexutil.Uint64(sim.state.GetNonce(args.from()))
+		args.Nonce = &nonce
+	}
+	if args.Gas == nil {
+		gas := hexutil.Uint64(gp.Gas())
+		if uint64(gas) > sim.gasRemaining {
+			gas = hexutil.Uint64(sim.gasRemaining)
+		}
+		args.Gas = &gas
+	}
+	if args.From == nil {
+		args.From = new(common.Address)
+	}
+	msg, err := args.ToMessage(sim.gasRemaining, header.BaseFee)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	hash := simCallHash(header.Number, index)
+	sim.state.SetTxContext(hash, index)
+
+	evm, vmError := sim.b.GetEVM(ctx, msg, sim.state, header, &vm.Config{NoBaseFee: true}, &blockCtx)
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+	result, err := core.ApplyMessage(evm, msg, gp)
+	if err := vmError(); err != nil {
+		return nil, common.Hash{}, err
+	}
+	if evm.Cancelled() {
+		return nil, common.Hash{}, fmt.Errorf("execution aborted (timeout = %v)", sim.b.RPCEVMTimeout())
+	}
+	if err != nil {
+		return nil, common.Hash{}, fmt.Errorf("err: %w (supplied gas %d)", err, msg.GasLimit)
+	}
+	sim.gasRemaining -= result.UsedGas
+
+	// Finalize the state so that the next call sees the changes
+	// in the same way as a transaction would in a block.
+	sim.state.Finalise(true)
+
+	call := &simCallResult{
+		ReturnValue: result.Return(),
+		GasUsed:     hexutil.Uint64(result.UsedGas),
+		Status:      hexutil.Uint64(types.ReceiptStatusSuccessful),
+	}
+	if result.Failed() {
+		call.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+		if errors.Is(result.Err, vm.ErrExecutionReverted) {
+			revertErr := newRevertError(result)
+			call.ReturnValue = result.Revert()
+			call.Error = &simCallError{Message: revertErr.Error(), Code: revertErr.ErrorCode(), Data: revertErr.reason}
+		} else {
+			call.Error = &simCallError{Message: result.Err.Error(), Code: errCodeVMError}
+		}
+	}
+	return call, hash, nil
+}
+
+// simCallHash returns the hash standing in for the transaction hash of the call
+// at the given index of a simulated block, under which the logs of the call are
+// collected. The hash of the call as an unsigned transaction would not do, as it
+// neither covers the sender nor differs between identical calls.
+func simCallHash(number *big.Int, index int) common.Hash {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], number.Uint64())
+	binary.BigEndian.PutUint64(buf[8:], uint64(index))
+	return crypto.Keccak256Hash(buf[:])
+}
+
+// transferWithLog is a vm.TransferFunc which, on top of moving the ether, emits
+// a synthetic log recording the transfer. The logs are reverted along with the
+// rest of the state changes if the enclosing call frame fails.
+//
+// Note, the ether moved by SELFDESTRUCT is not captured as it doesn't go through
+// the transfer function.
+func transferWithLog(db vm.StateDB, sender, recipient common.Address, amount *big.Int) {
+	core.Transfer(db, sender, recipient, amount)
+	if amount.Sign() == 0 {
+		return
+	}
+	db.AddLog(&types.Log{
+		Address: transferAddress,
+		Topics: []common.Hash{
+			transferTopic,
+			common.BytesToHash(sender.Bytes()),
+			common.BytesToHash(recipient.Bytes()),
+		},
+		Data: common.BigToHash(amount).Bytes(),
+	})
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestSimulate(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts = newAccounts(3)
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		genBlocks = 4
+		api       = NewBlockChainAPI(newTestBackend(t, genBlocks, genesis, func(i int, b *core.BlockGen) {}))
+
+		// numberCode returns the current block number.
+		numberCode = hexutil.Bytes{byte(vm.NUMBER), byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN)}
+		// revertCode reverts unconditionally.
+		revertCode = hexutil.Bytes{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT)}
+		// logCode emits an empty log.
+		logCode = hexutil.Bytes{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.LOG0), byte(vm.STOP)}
+
+		contract = common.Address{0xc0}
+		value    = (*hexutil.Big)(big.NewInt(1000))
+	)
+	number := func(n uint64) *hexutil.Big { return (*hexutil.Big)(new(big.Int).SetUint64(n)) }
+
+	// Ether moved in the first block must be spendable in the second one,
+	// the transfers must be recorded and block overrides must be honoured.
+	results, err := api.Simulate(context.Background(), simOpts{
+		TraceTransfers: true,
+		BlockStateCalls: []simBlock{
+			{
+				Calls: []TransactionArgs{
+					{From: &accounts[0].addr, To: &accounts[1].addr, Value: value},
+				},
+			},
+			{
+				BlockOverrides: &BlockOverrides{Number: number(uint64(genBlocks) + 10)},
+				StateOverrides: &StateOverride{contract: OverrideAccount{Code: &numberCode}},
+				Calls: []TransactionArgs{
+					{From: &accounts[1].addr, To: &accounts[2].addr, Value: value},
+					{From: &accounts[2].addr, To: &contract},
+				},
+			},
+		}}, nil)
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("wrong number of blocks: have %d, want 2", len(results))
+	}
+	if have, want := uint64(results[0].Number), uint64(genBlocks+1); have != want {
+		t.Errorf("wrong number of first block: have %d, want %d", have, want)
+	}
+	if have, want := uint64(results[1].Number), uint64(genBlocks+10); have != want {
+		t.Errorf("wrong number of second block: have %d, want %d", have, want)
+	}
+	if results[1].ParentHash != results[0].Hash {
+		t.Errorf("blocks not linked: parent %x, want %x", results[1].ParentHash, results[0].Hash)
+	}
+	for i, block := range results {
+		var gasUsed uint64
+		for j, call := range block.Calls {
+			if call.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) {
+				t.Errorf("block %d call %d: unexpected failure: %v", i, j, call.Error)
+			}
+			gasUsed += uint64(call.GasUsed)
+		}
+		if uint64(block.GasUsed) != gasUsed {
+			t.Errorf("block %d: wrong gas used: have %d, want %d", i, block.GasUsed, gasUsed)
+		}
+	}
+	// Check the synthetic transfer logs.
+	for i, call := range []simCallResult{results[0].Calls[0], results[1].Calls[0]} {
+		if len(call.Logs) != 1 {
+			t.Fatalf("transfer %d: wrong number of logs: have %d, want 1", i, len(call.Logs))
+		}
+		l := call.Logs[0]
+		if l.Address != transferAddress || l.Topics[0] != transferTopic {
+			t.Errorf("transfer %d: unexpected log %v", i, l)
+		}
+		if l.Topics[2] != common.BytesToHash(accounts[i+1].addr.Bytes()) {
+			t.Errorf("transfer %d: wrong recipient %x", i, l.Topics[2])
+		}
+		if new(big.Int).SetBytes(l.Data).Cmp(value.ToInt()) != 0 {
+			t.Errorf("transfer %d: wrong value %x", i, l.Data)
+		}
+		if l.BlockHash != results[i].Hash {
+			t.Errorf("transfer %d: wrong block hash %x, want %x", i, l.BlockHash, results[i].Hash)
+		}
+	}
+	// Check the block number seen by the contract.
+	if have := new(big.Int).SetBytes(results[1].Calls[1].ReturnValue); have.Uint64() != uint64(genBlocks+10) {
+		t.Errorf("wrong block number in call: have %d, want %d", have, genBlocks+10)
+	}
+
+	// Reverted calls are reported in the call result.
+	results, err = api.Simulate(context.Background(), simOpts{
+		BlockStateCalls: []simBlock{{
+			StateOverrides: &StateOverride{contract: OverrideAccount{Code: &revertCode}},
+			Calls:          []TransactionArgs{{From: &accounts[0].addr, To: &contract}},
+		}}}, nil)
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	call := results[0].Calls[0]
+	if call.Status != hexutil.Uint64(types.ReceiptStatusFailed) || call.Error == nil || call.Error.Code != 3 {
+		t.Errorf("unexpected result of reverted call: status %d, error %v", call.Status, call.Error)
+	}
+
+	// Identical calls from different senders have the same unsigned transaction
+	// hash, yet each of them must only report its own log.
+	gas := hexutil.Uint64(100000)
+	results, err = api.Simulate(context.Background(), simOpts{
+		BlockStateCalls: []simBlock{{
+			StateOverrides: &StateOverride{contract: OverrideAccount{Code: &logCode}},
+			Calls: []TransactionArgs{
+				{From: &accounts[1].addr, To: &contract, Gas: &gas},
+				{From: &accounts[2].addr, To: &contract, Gas: &gas},
+			},
+		}}}, nil)
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	for i, call := range results[0].Calls {
+		if len(call.Logs) != 1 {
+			t.Fatalf("identical call %d: wrong number of logs: have %d, want 1", i, len(call.Logs))
+		}
+		if call.Logs[0].TxIndex != uint(i) {
+			t.Errorf("identical call %d: wrong log tx index %d", i, call.Logs[0].TxIndex)
+		}
+	}
+	if results[0].Calls[0].Logs[0].TxHash == results[0].Calls[1].Logs[0].TxHash {
+		t.Errorf("identical calls share the log tx hash %x", results[0].Calls[0].Logs[0].TxHash)
+	}
+
+	// Invalid inputs are rejected.
+	for i, opts := range []simOpts{
+		{},
+		{BlockStateCalls: []simBlock{
+			{BlockOverrides: &BlockOverrides{Number: number(uint64(genBlocks) + 5)}},
+			{BlockOverrides: &BlockOverrides{Number: number(uint64(genBlocks) + 5)}},
+		}},
+		{BlockStateCalls: []simBlock{{
+			Calls: []TransactionArgs{{From: &accounts[1].addr, To: &accounts[2].addr, Value: value}},
+		}}},
+	} {
+		if _, err := api.Simulate(context.Background(), opts, nil); err == nil {
+			t.Errorf("test %d: expected error", i)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package exporter installs the OpenTelemetry span exporters, sending the spans
+// created through package telemetry to a collector or a local file.
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+)
+
+// Config contains the settings of the span exporters.
+type Config struct {
+	// Endpoint is the URL of an OTLP/HTTP collector, e.g. "http://localhost:4318".
+	// The /v1/traces path is used unless the URL has a path of its own.
+	Endpoint string
+
+	// File is the path of a file the spans are appended to as JSON objects, one
+	// per line, for inspecting traces without a collector.
+	File string
+
+	// SampleRatio is the fraction of the traces started by geth which are recorded,
+	// from 0 (none) to 1 (all). Traces continued from the trace context of an
+	// incoming request follow the sampling decision of the caller.
+	SampleRatio float64
+
+	// ServiceVersion is reported along with the spans.
+	ServiceVersion string
+}
+
+// Setup installs the exporters configured in cfg as the global tracer provider, and
+// the W3C trace context as the global propagator. The returned function flushes the
+// pending spans and shuts down the exporters. If neither an endpoint nor a file is
+// configured, Setup does nothing.
+func Setup(cfg Config) (func(context.Context) error, error) {
+	if cfg.Endpoint == "" && cfg.File == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("invalid trace sample ratio %v", cfg.SampleRatio)
+	}
+	var exporters []sdktrace.SpanExporter
+	if cfg.Endpoint != "" {
+		exp, err := newOTLPExporter(cfg.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exp)
+	}
+	if cfg.File != "" {
+		exp, err := newFileExporter(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exp)
+	}
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceNameKey.String("geth"),
+			semconv.ServiceVersionKey.String(cfg.ServiceVersion),
+		)),
+	}
+	for _, exp := range exporters {
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+	provider := sdktrace.NewTracerProvider(opts...)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider.Shutdown, nil
+}
+
+// newOTLPExporter creates an exporter sending the spans to the OTLP/HTTP collector
+// at the given URL.
+func newOTLPExporter(endpoint string) (sdktrace.SpanExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace endpoint %q: %v", endpoint, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid trace endpoint %q: missing host", endpoint)
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	switch u.Scheme {
+	case "http":
+		opts = append(opts, otlptracehttp.WithInsecure())
+	case "https":
+	default:
+		return nil, fmt.Errorf("invalid trace endpoint %q: unsupported scheme %q", endpoint, u.Scheme)
+	}
+	if u.Path != "" && u.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+	}
+	return otlptracehttp.New(context.Background(), opts...)
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/internal/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestSetupFile(t *testing.T) {
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	spans := traceToFile(t, Config{SampleRatio: 1})
+	if len(spans) != 2 || spans[0].Name != "child" || spans[1].Name != "parent" {
+		t.Fatalf("wrong spans exported: %+v", spans)
+	}
+	if spans[0].ParentID != spans[1].SpanID || spans[0].TraceID != spans[1].TraceID {
+		t.Errorf("child not linked to parent: %+v", spans)
+	}
+	if spans[1].ParentID != "" {
+		t.Errorf("root span has a parent: %+v", spans[1])
+	}
+	if spans[0].Status.Code != "Error" || spans[0].Status.Description != "failed" || spans[1].Status.Code != "Unset" {
+		t.Errorf("wrong span status: %+v", spans)
+	}
+	if spans[0].Tracer != "test" || spans[0].Attributes["number"] != float64(1) {
+		t.Errorf("wrong span details: %+v", spans[0])
+	}
+	if len(spans[0].Events) != 1 || spans[0].Events[0].Name != "exception" {
+		t.Errorf("error not recorded: %+v", spans[0].Events)
+	}
+}
+
+// Tests that no traces are recorded with a zero sample ratio.
+func TestSetupFileNeverSample(t *testing.T) {
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	if spans := traceToFile(t, Config{SampleRatio: 0}); len(spans) != 0 {
+		t.Fatalf("spans recorded: %+v", spans)
+	}
+}
+
+// traceToFile sets up span exporting into a file with the given config, records
+// a failing child span and its parent, and returns the spans written.
+func traceToFile(t *testing.T, cfg Config) []jsonSpan {
+	t.Helper()
+
+	cfg.File = filepath.Join(t.TempDir(), "spans.json")
+	stop, err := Setup(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, parent := telemetry.StartSpan(context.Background(), "test", "parent")
+	_, child := telemetry.StartSpan(ctx, "test", "child", attribute.Int("number", 1))
+	telemetry.EndSpan(child, errors.New("failed"))
+	telemetry.EndSpan(parent, nil)
+
+	if err := stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	blob, err := os.ReadFile(cfg.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spans []jsonSpan
+	for _, line := range bytes.Split(bytes.TrimSpace(blob), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var s jsonSpan
+		if err := json.Unmarshal(line, &s); err != nil {
+			t.Fatalf("invalid span %q: %v", line, err)
+		}
+		spans = append(spans, s)
+	}
+	return spans
+}
+
+func TestSetupInvalid(t *testing.T) {
+	tests := []Config{
+		{Endpoint: "localhost:4318"},
+		{Endpoint: "grpc://localhost:4317"},
+		{File: filepath.Join(t.TempDir(), "missing", "spans.json")},
+		{File: filepath.Join(t.TempDir(), "spans.json"), SampleRatio: 2},
+	}
+	for _, cfg := range tests {
+		if _, err := Setup(cfg); err == nil {
+			t.Errorf("config %+v accepted", cfg)
+		}
+	}
+}
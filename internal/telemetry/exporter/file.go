@@ -0,0 +1,142 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// fileExporter appends the spans to a file as JSON objects, one per line.
+type fileExporter struct {
+	lock sync.Mutex
+	file *os.File // nil once shut down
+	enc  *json.Encoder
+}
+
+// newFileExporter opens the file at the given path for appending spans to it.
+func newFileExporter(path string) (*fileExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileExporter{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// ExportSpans implements sdktrace.SpanExporter, writing the spans to the file.
+func (e *fileExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.file == nil {
+		return nil
+	}
+	for _, span := range spans {
+		if err := e.enc.Encode(newJSONSpan(span)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanExporter, closing the file.
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.file == nil {
+		return nil
+	}
+	err := e.file.Close()
+	e.file = nil
+	return err
+}
+
+// jsonSpan is the JSON encoding of a span written by the file exporter.
+type jsonSpan struct {
+	Name       string                 `json:"name"`
+	Kind       string                 `json:"kind"`
+	Tracer     string                 `json:"tracer"`
+	TraceID    string                 `json:"traceId"`
+	SpanID     string                 `json:"spanId"`
+	ParentID   string                 `json:"parentSpanId,omitempty"`
+	StartTime  time.Time              `json:"startTime"`
+	EndTime    time.Time              `json:"endTime"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Events     []jsonSpanEvent        `json:"events,omitempty"`
+	Status     jsonSpanStatus         `json:"status"`
+}
+
+// jsonSpanEvent is the JSON encoding of an event recorded on a span.
+type jsonSpanEvent struct {
+	Name       string                 `json:"name"`
+	Time       time.Time              `json:"time"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// jsonSpanStatus is the JSON encoding of the status of a span.
+type jsonSpanStatus struct {
+	Code        string `json:"code"`
+	Description string `json:"description,omitempty"`
+}
+
+// newJSONSpan converts a finished span into its JSON encoding.
+func newJSONSpan(span sdktrace.ReadOnlySpan) *jsonSpan {
+	enc := &jsonSpan{
+		Name:       span.Name(),
+		Kind:       span.SpanKind().String(),
+		Tracer:     span.InstrumentationScope().Name,
+		TraceID:    span.SpanContext().TraceID().String(),
+		SpanID:     span.SpanContext().SpanID().String(),
+		StartTime:  span.StartTime(),
+		EndTime:    span.EndTime(),
+		Attributes: jsonAttributes(span.Attributes()),
+		Status: jsonSpanStatus{
+			Code:        span.Status().Code.String(),
+			Description: span.Status().Description,
+		},
+	}
+	if parent := span.Parent(); parent.HasSpanID() {
+		enc.ParentID = parent.SpanID().String()
+	}
+	for _, event := range span.Events() {
+		enc.Events = append(enc.Events, jsonSpanEvent{
+			Name:       event.Name,
+			Time:       event.Time,
+			Attributes: jsonAttributes(event.Attributes),
+		})
+	}
+	return enc
+}
+
+// jsonAttributes converts span or event attributes into a JSON object.
+func jsonAttributes(attrs []attribute.KeyValue) map[string]interface{} {
+	if len(attrs) == 0 {
+		return nil
+	}
+	enc := make(map[string]interface{}, len(attrs))
+	for _, attr := range attrs {
+		enc[string(attr.Key)] = attr.Value.AsInterface()
+	}
+	return enc
+}
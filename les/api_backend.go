@@ -216,6 +216,15 @@ func (b *LesApiBackend) GetTransaction(ctx context.Context, txHash common.Hash)
 	return light.GetTransaction(ctx, b.eth.odr, txHash)
 }
 
+func (b *LesApiBackend) GetTransactionBySender(ctx context.Context, sender common.Address, nonce uint64) (*types.Transaction, common.Hash, uint64, uint64, error) {
+	// Light clients only index the transactions they sent themselves
+	hash := rawdb.ReadTxSenderLookupEntry(b.eth.chainDb, sender, nonce)
+	if hash == nil {
+		return nil, common.Hash{}, 0, 0, nil
+	}
+	return b.GetTransaction(ctx, *hash)
+}
+
 func (b *LesApiBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
 	return b.eth.txPool.GetNonce(ctx, addr)
 }
@@ -303,6 +312,10 @@ func (b *LesApiBackend) RPCEVMTimeout() time.Duration {
 	return b.eth.config.RPCEVMTimeout
 }
 
+func (b *LesApiBackend) RPCTraceFilterRange() uint64 {
+	return b.eth.config.RPCTraceFilterRange
+}
+
 func (b *LesApiBackend) RPCTxFeeCap() float64 {
 	return b.eth.config.RPCTxFeeCap
 }
@@ -315,6 +328,12 @@ func (b *LesApiBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocksClient, sections
 }
 
+// LogIndexStatus returns no indexed sections as light clients do not maintain
+// the log index.
+func (b *LesApiBackend) LogIndexStatus() (uint64, uint64) {
+	return params.BloomBitsBlocksClient, 0
+}
+
 func (b *LesApiBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	for i := 0; i < bloomFilterThreads; i++ {
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.eth.bloomRequests)
@@ -0,0 +1,305 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// maxBundles is the maximum number of bundles the miner keeps track of across
+// all target blocks. Any bundle above this limit is rejected until the stale
+// ones get pruned on the next block.
+const maxBundles = 1024
+
+// maxBundleFutureBlocks is the number of blocks ahead of the chain head a bundle
+// may target. Bundles for blocks further away would occupy the pool long before
+// they could be included.
+const maxBundleFutureBlocks = 25
+
+var (
+	// errBundleEmpty is returned if a bundle is submitted without transactions.
+	errBundleEmpty = errors.New("bundle contains no transactions")
+
+	// errBundleStale is returned if a bundle is submitted for a block that is
+	// already part of the canonical chain.
+	errBundleStale = errors.New("bundle targets a past block")
+
+	// errBundleTooFar is returned if a bundle is submitted for a block too far
+	// ahead of the chain head.
+	errBundleTooFar = errors.New("bundle targets a block too far in the future")
+
+	// errBundlePoolFull is returned if a bundle is submitted while the miner is
+	// already tracking the maximum number of bundles.
+	errBundlePoolFull = errors.New("bundle pool full")
+
+	// errBundleReverted is returned if a transaction of a bundle reverted during
+	// execution without being marked as allowed to revert.
+	errBundleReverted = errors.New("bundle transaction reverted")
+
+	// errBundleUnprofitable is returned if a bundle does not increase the balance
+	// of the block's coinbase.
+	errBundleUnprofitable = errors.New("bundle not profitable")
+
+	// errBundleBlobTx is returned if a bundle contains a blob transaction, which
+	// block production is not able to include yet.
+	errBundleBlobTx = errors.New("bundle contains blob transaction")
+)
+
+// Bundle is a list of transactions that must be included into a block atomically,
+// in the given order and at the front of the block. Either all transactions are
+// included, or none.
+type Bundle struct {
+	Txs               types.Transactions // Transactions to include, in order
+	BlockNumber       uint64             // Block number the bundle is valid for
+	RevertingTxHashes []common.Hash      // Transactions allowed to revert without invalidating the bundle
+}
+
+// Hash returns a unique identifier of the bundle, computed as the hash of the
+// concatenation of the contained transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([]byte, 0, len(b.Txs)*common.HashLength)
+	for _, tx := range b.Txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// revertible returns whether the transaction with the given hash is allowed to
+// revert without invalidating the entire bundle.
+func (b *Bundle) revertible(hash common.Hash) bool {
+	for _, h := range b.RevertingTxHashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// bundlePool is a thread safe collection of bundles submitted to the miner,
+// waiting to be included in their target block.
+type bundlePool struct {
+	bundles []*Bundle
+	lock    sync.Mutex
+}
+
+// add inserts a new bundle into the pool, pruning stale ones beforehand. The
+// head is the number of the current chain head.
+func (p *bundlePool) add(bundle *Bundle, head uint64) error {
+	if len(bundle.Txs) == 0 {
+		return errBundleEmpty
+	}
+	if bundle.BlockNumber <= head {
+		return errBundleStale
+	}
+	if bundle.BlockNumber > head+maxBundleFutureBlocks {
+		return errBundleTooFar
+	}
+	for _, tx := range bundle.Txs {
+		if tx.Type() == types.BlobTxType {
+			return errBundleBlobTx
+		}
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.prune(head + 1)
+	if len(p.bundles) >= maxBundles {
+		return errBundlePoolFull
+	}
+	p.bundles = append(p.bundles, bundle)
+	return nil
+}
+
+// pending prunes all the bundles targeting blocks below number and returns the
+// ones targeting exactly number.
+func (p *bundlePool) pending(number uint64) []*Bundle {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.prune(number)
+
+	var bundles []*Bundle
+	for _, bundle := range p.bundles {
+		if bundle.BlockNumber == number {
+			bundles = append(bundles, bundle)
+		}
+	}
+	return bundles
+}
+
+// prune drops all the bundles targeting blocks below number. The caller must
+// hold the pool lock.
+func (p *bundlePool) prune(number uint64) {
+	bundles := p.bundles[:0]
+	for _, bundle := range p.bundles {
+		if bundle.BlockNumber >= number {
+			bundles = append(bundles, bundle)
+		}
+	}
+	for i := len(bundles); i < len(p.bundles); i++ {
+		p.bundles[i] = nil
+	}
+	p.bundles = bundles
+}
+
+// addBundle inserts a bundle into the worker to be included ahead of the pool
+// transactions when building its target block.
+func (w *worker) addBundle(bundle *Bundle) error {
+	return w.bundles.add(bundle, w.chain.CurrentBlock().Number.Uint64())
+}
+
+// simulatedBundle is a bundle that was executed on top of the pending block
+// together with the outcome of the execution.
+type simulatedBundle struct {
+	bundle   *Bundle
+	profit   *big.Int // Coinbase balance increase caused by the bundle
+	payments *big.Int // Part of the profit paid directly, not via gas fees
+	gasUsed  uint64   // Total gas used by the bundle's transactions
+}
+
+// applyBundle executes all the transactions of a bundle on top of the given
+// environment, returning the coinbase profit of the bundle and the gas used.
+//
+// Note, the state modifications of a partially executed bundle cannot be rolled
+// back via state snapshots as the state is finalised after every transaction, so
+// an erroring bundle leaves the environment in an unusable state. Callers should
+// execute bundles on a copy, or keep a copy to restore the environment from.
+func (w *worker) applyBundle(env *environment, bundle *Bundle) (*simulatedBundle, error) {
+	var (
+		balance = new(big.Int).Set(env.state.GetBalance(env.coinbase))
+		gasUsed = env.header.GasUsed
+		fees    = new(big.Int)
+	)
+	for _, tx := range bundle.Txs {
+		env.state.SetTxContext(tx.Hash(), env.tcount)
+
+		receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &env.coinbase, env.gasPool, env.state, env.header, tx, &env.header.GasUsed, *w.chain.GetVMConfig())
+		if err != nil {
+			return nil, err
+		}
+		if receipt.Status == types.ReceiptStatusFailed && !bundle.revertible(tx.Hash()) {
+			return nil, errBundleReverted
+		}
+		env.txs = append(env.txs, tx)
+		env.receipts = append(env.receipts, receipt)
+		env.tcount++
+
+		tip, _ := tx.EffectiveGasTip(env.header.BaseFee)
+		fees.Add(fees, new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), tip))
+	}
+	profit := new(big.Int).Sub(env.state.GetBalance(env.coinbase), balance)
+	if profit.Sign() <= 0 {
+		return nil, errBundleUnprofitable
+	}
+	return &simulatedBundle{
+		bundle:   bundle,
+		profit:   profit,
+		payments: new(big.Int).Sub(profit, fees),
+		gasUsed:  env.header.GasUsed - gasUsed,
+	}, nil
+}
+
+// simulateBundle executes a bundle on a copy of the environment, returning the
+// outcome of the execution without modifying the environment itself.
+func (w *worker) simulateBundle(env *environment, bundle *Bundle) (*simulatedBundle, error) {
+	cpy := env.copy()
+	defer cpy.discard()
+
+	return w.applyBundle(cpy, bundle)
+}
+
+// commitBundle executes a bundle on the live environment. If the bundle fails,
+// the environment is restored from a copy taken beforehand.
+func (w *worker) commitBundle(env *environment, bundle *Bundle) (*simulatedBundle, error) {
+	snap := env.copy()
+	sim, err := w.applyBundle(env, bundle)
+	if err != nil {
+		env.discard()
+		*env = *snap
+		return nil, err
+	}
+	snap.discard()
+	return sim, nil
+}
+
+// simulateBundles executes each bundle individually on top of the current state
+// of the environment and returns the profitable ones, sorted by their effective
+// gas price (coinbase profit per gas unit) in decreasing order.
+func (w *worker) simulateBundles(env *environment, bundles []*Bundle) []*simulatedBundle {
+	var sims []*simulatedBundle
+	for _, bundle := range bundles {
+		sim, err := w.simulateBundle(env, bundle)
+		if err != nil {
+			log.Trace("Discarding invalid bundle", "hash", bundle.Hash(), "err", err)
+			continue
+		}
+		sims = append(sims, sim)
+	}
+	sort.SliceStable(sims, func(i, j int) bool {
+		// Compare profit_i / gas_i > profit_j / gas_j without losing precision
+		pi := new(big.Int).Mul(sims[i].profit, new(big.Int).SetUint64(sims[j].gasUsed))
+		pj := new(big.Int).Mul(sims[j].profit, new(big.Int).SetUint64(sims[i].gasUsed))
+		return pi.Cmp(pj) > 0
+	})
+	return sims
+}
+
+// commitBundles simulates all the bundles targeting the block being built and
+// commits the profitable ones, most profitable first. Bundles that become invalid
+// or unprofitable due to an earlier included one are skipped.
+//
+// Every bundle is executed twice: once on its own to rank it by profit per gas,
+// which is only known after execution, and once more when committed, since the
+// bundles included ahead of it may change its outcome.
+func (w *worker) commitBundles(env *environment, bundles []*Bundle, interrupt *atomic.Int32) error {
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	for _, sim := range w.simulateBundles(env, bundles) {
+		// Check interruption signal and abort building if it's fired.
+		if interrupt != nil {
+			if signal := interrupt.Load(); signal != commitInterruptNone {
+				return signalToErr(signal)
+			}
+		}
+		// If we don't have enough gas for any further transactions then we're done.
+		if env.gasPool.Gas() < params.TxGas {
+			log.Trace("Not enough gas for further bundles", "have", env.gasPool, "want", params.TxGas)
+			break
+		}
+		committed, err := w.commitBundle(env, sim.bundle)
+		if err != nil {
+			log.Debug("Bundle invalidated, skipped", "hash", sim.bundle.Hash(), "err", err)
+			continue
+		}
+		env.payments.Add(env.payments, committed.payments)
+
+		log.Trace("Included bundle", "hash", sim.bundle.Hash(), "txs", len(sim.bundle.Txs), "profit", committed.profit)
+	}
+	return nil
+}
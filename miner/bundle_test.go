@@ -0,0 +1,225 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var testBundleCoinbase = common.HexToAddress("0xc0ffee")
+
+// makeBundleTx creates a transaction from the test bank paying no tip to the
+// coinbase, so that any coinbase profit is a direct payment.
+func makeBundleTx(nonce uint64, to *common.Address, value *big.Int, gas uint64, data []byte) *types.Transaction {
+	return types.MustSignNewTx(testBankKey, types.LatestSigner(params.TestChainConfig), &types.DynamicFeeTx{
+		ChainID:   params.TestChainConfig.ChainID,
+		Nonce:     nonce,
+		To:        to,
+		Value:     value,
+		Gas:       gas,
+		GasTipCap: new(big.Int),
+		GasFeeCap: big.NewInt(params.InitialBaseFee),
+		Data:      data,
+	})
+}
+
+// Tests that invalid bundles are rejected on submission.
+func TestBundleAdd(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	tx := makeBundleTx(0, &testBundleCoinbase, big.NewInt(1), params.TxGas, nil)
+	tests := []struct {
+		bundle *Bundle
+		err    error
+	}{
+		{&Bundle{BlockNumber: 1}, errBundleEmpty},
+		{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 0}, errBundleStale},
+		{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 1}, nil},
+		{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 5}, nil},
+		{&Bundle{Txs: types.Transactions{tx}, BlockNumber: maxBundleFutureBlocks}, nil},
+		{&Bundle{Txs: types.Transactions{tx}, BlockNumber: maxBundleFutureBlocks + 1}, errBundleTooFar},
+		{&Bundle{Txs: types.Transactions{tx}, BlockNumber: 1 << 63}, errBundleTooFar},
+	}
+	for i, tt := range tests {
+		if err := w.addBundle(tt.bundle); !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	if bundles := w.bundles.pending(1); len(bundles) != 1 {
+		t.Errorf("pending bundle count mismatch: have %d, want %d", len(bundles), 1)
+	}
+	// Building a later block should prune the bundles targeting earlier ones
+	if bundles := w.bundles.pending(2); len(bundles) != 0 {
+		t.Errorf("pending bundle count mismatch: have %d, want %d", len(bundles), 0)
+	}
+	if len(w.bundles.bundles) != 2 {
+		t.Errorf("tracked bundle count mismatch: have %d, want %d", len(w.bundles.bundles), 2)
+	}
+}
+
+// Tests that bundles are included atomically at the front of the block only if
+// they are valid and increase the coinbase profit.
+func TestBundleInclusion(t *testing.T) {
+	var (
+		payment   = big.NewInt(params.GWei)
+		revertTx  = makeBundleTx(0, nil, new(big.Int), 100000, common.FromHex("0x60006000fd")) // PUSH1 0 PUSH1 0 REVERT
+		paymentTx = makeBundleTx(1, &testBundleCoinbase, payment, params.TxGas, nil)
+		freeTx    = makeBundleTx(0, &testUserAddress, big.NewInt(1), params.TxGas, nil)
+	)
+	tests := []struct {
+		name    string
+		bundles []*Bundle
+		want    []common.Hash // expected leading transactions of the block
+		payment *big.Int      // expected direct coinbase payment
+	}{
+		{
+			name:    "unprofitable",
+			bundles: []*Bundle{{Txs: types.Transactions{freeTx}, BlockNumber: 1}},
+			payment: new(big.Int),
+		},
+		{
+			name:    "reverting",
+			bundles: []*Bundle{{Txs: types.Transactions{revertTx, paymentTx}, BlockNumber: 1}},
+			payment: new(big.Int),
+		},
+		{
+			name: "revert-allowed",
+			bundles: []*Bundle{{
+				Txs:               types.Transactions{revertTx, paymentTx},
+				BlockNumber:       1,
+				RevertingTxHashes: []common.Hash{revertTx.Hash()},
+			}},
+			want:    []common.Hash{revertTx.Hash(), paymentTx.Hash()},
+			payment: payment,
+		},
+		{
+			name: "conflicting",
+			bundles: []*Bundle{
+				{Txs: types.Transactions{makeBundleTx(0, &testBundleCoinbase, big.NewInt(1), params.TxGas, nil)}, BlockNumber: 1},
+				{Txs: types.Transactions{makeBundleTx(0, &testBundleCoinbase, payment, params.TxGas, nil)}, BlockNumber: 1},
+			},
+			want:    []common.Hash{makeBundleTx(0, &testBundleCoinbase, payment, params.TxGas, nil).Hash()},
+			payment: payment,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := ethash.NewFaker()
+			defer engine.Close()
+
+			w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+			defer w.close()
+
+			for _, bundle := range tt.bundles {
+				if err := w.addBundle(bundle); err != nil {
+					t.Fatalf("failed to add bundle: %v", err)
+				}
+			}
+			block, fees, err := w.getSealingBlock(b.chain.Genesis().Hash(), uint64(time.Now().Unix()), testBundleCoinbase, common.Hash{}, nil, false)
+			if err != nil {
+				t.Fatalf("failed to build block: %v", err)
+			}
+			txs := block.Transactions()
+			if len(txs) < len(tt.want) {
+				t.Fatalf("transaction count mismatch: have %d, want at least %d", len(txs), len(tt.want))
+			}
+			for i, hash := range tt.want {
+				if txs[i].Hash() != hash {
+					t.Errorf("transaction %d mismatch: have %x, want %x", i, txs[i].Hash(), hash)
+				}
+			}
+			// Bundle-less blocks should fall back to the pool transactions
+			if len(tt.want) == 0 && (len(txs) != 1 || txs[0].Hash() != pendingTxs[0].Hash()) {
+				t.Errorf("expected pool transaction only, have %d transactions", len(txs))
+			}
+			if fees.Cmp(tt.payment) < 0 {
+				t.Errorf("block value too low: have %v, want at least %v", fees, tt.payment)
+			}
+		})
+	}
+}
+
+// Tests that a bundle failing halfway when committed leaves the block being built
+// untouched, and that the payments of the committed bundles are accounted exactly.
+func TestBundleCommitRestore(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	env, err := w.prepareWork(&generateParams{
+		timestamp:  uint64(time.Now().Unix()),
+		parentHash: b.chain.Genesis().Hash(),
+		coinbase:   testBundleCoinbase,
+	})
+	if err != nil {
+		t.Fatalf("failed to prepare work: %v", err)
+	}
+	defer env.discard()
+	env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+
+	payment := big.NewInt(params.GWei)
+	broken := &Bundle{Txs: types.Transactions{
+		makeBundleTx(0, &testBundleCoinbase, payment, params.TxGas, nil),
+		makeBundleTx(5, &testBundleCoinbase, payment, params.TxGas, nil),
+	}}
+	if _, err := w.commitBundle(env, broken); err == nil {
+		t.Fatal("committed bundle with nonce gap")
+	}
+	if len(env.txs) != 0 || len(env.receipts) != 0 || env.tcount != 0 || env.header.GasUsed != 0 {
+		t.Fatalf("environment modified: %d txs, %d receipts, %d gas used", len(env.txs), len(env.receipts), env.header.GasUsed)
+	}
+	if nonce := env.state.GetNonce(testBankAddress); nonce != 0 {
+		t.Fatalf("state modified: nonce %d", nonce)
+	}
+	if gas := env.gasPool.Gas(); gas != env.header.GasLimit {
+		t.Fatalf("gas pool modified: have %d, want %d", gas, env.header.GasLimit)
+	}
+	// Only the more profitable of two conflicting bundles may be included, and
+	// only its payment may be accounted.
+	bundles := []*Bundle{
+		{Txs: types.Transactions{makeBundleTx(0, &testBundleCoinbase, big.NewInt(1), params.TxGas, nil)}},
+		{Txs: types.Transactions{makeBundleTx(0, &testBundleCoinbase, payment, params.TxGas, nil)}},
+	}
+	if err := w.commitBundles(env, bundles, nil); err != nil {
+		t.Fatalf("failed to commit bundles: %v", err)
+	}
+	if len(env.txs) != 1 || env.txs[0].Hash() != bundles[1].Txs[0].Hash() {
+		t.Fatalf("wrong transactions included: %d", len(env.txs))
+	}
+	if env.payments.Cmp(payment) != 0 {
+		t.Fatalf("wrong payments: have %v, want %v", env.payments, payment)
+	}
+	if balance := env.state.GetBalance(testBundleCoinbase); balance.Cmp(payment) != 0 {
+		t.Fatalf("wrong coinbase balance: have %v, want %v", balance, payment)
+	}
+}
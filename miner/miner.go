@@ -234,6 +234,12 @@ func (miner *Miner) SetGasCeil(ceil uint64) {
 	miner.worker.setGasCeil(ceil)
 }
 
+// AddBundle submits a transaction bundle to be included atomically at the front
+// of its target block, if it raises the profit of the block's coinbase.
+func (miner *Miner) AddBundle(bundle *Bundle) error {
+	return miner.worker.addBundle(bundle)
+}
+
 // SubscribePendingLogs starts delivering logs from pending transactions
 // to the given channel.
 func (miner *Miner) SubscribePendingLogs(ch chan<- []*types.Log) event.Subscription {
@@ -176,6 +176,9 @@ func (api *adminAPI) StartHTTP(host *string, port *int, cors *string, apis *stri
 		CorsAllowedOrigins: api.node.config.HTTPCors,
 		Vhosts:             api.node.config.HTTPVirtualHosts,
 		Modules:            api.node.config.HTTPModules,
+		AllowedMethods:     api.node.config.HTTPAllowedMethods,
+		DeniedMethods:      api.node.config.HTTPDeniedMethods,
+		rpcEndpointConfig:  api.node.rpcEndpointConfig(),
 	}
 	if cors != nil {
 		config.CorsAllowedOrigins = nil
@@ -247,9 +250,12 @@ func (api *adminAPI) StartWS(host *string, port *int, allowedOrigins *string, ap
 
 	// Determine config.
 	config := wsConfig{
-		Modules: api.node.config.WSModules,
-		Origins: api.node.config.WSOrigins,
+		Modules:        api.node.config.WSModules,
+		AllowedMethods: api.node.config.WSAllowedMethods,
+		DeniedMethods:  api.node.config.WSDeniedMethods,
+		Origins:        api.node.config.WSOrigins,
 		// ExposeAll: api.node.config.WSExposeAll,
+		rpcEndpointConfig: api.node.rpcEndpointConfig(),
 	}
 	if apis != nil {
 		config.Modules = nil
@@ -312,6 +318,21 @@ func (api *adminAPI) Datadir() string {
 	return api.node.DataDir()
 }
 
+// RateLimits retrieves the per-client quotas of the HTTP and WebSocket endpoints.
+func (api *adminAPI) RateLimits() RateLimitConfig {
+	return api.node.rateLimiter.limits()
+}
+
+// SetRateLimits replaces the per-client quotas of the HTTP and WebSocket endpoints.
+// The new limits apply to running endpoints immediately.
+func (api *adminAPI) SetRateLimits(config RateLimitConfig) (bool, error) {
+	if err := api.node.rateLimiter.setConfig(config); err != nil {
+		return false, err
+	}
+	log.Info("Updated RPC rate limits", "rate", config.Rate, "burst", config.Burst, "methods", len(config.MethodCosts))
+	return true, nil
+}
+
 // web3API offers helper utils
 type web3API struct {
 	stack *Node
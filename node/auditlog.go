@@ -0,0 +1,138 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// AuditLogStdout is the AuditLogConfig.Output value writing the audit log to the
+// standard output.
+const AuditLogStdout = "stdout"
+
+// AuditLogConfig contains the settings of the RPC audit log, which records every
+// call served over HTTP, WebSocket and IPC as a JSON object on its own line.
+type AuditLogConfig struct {
+	// Output is the path of the log file, or "stdout". An empty value disables
+	// the audit log. Log files are rotated once they reach MaxSize.
+	Output string `toml:",omitempty"`
+
+	// SlowCallThreshold is the duration above which calls are logged along with
+	// their full parameters, instead of a hash of them only (0 = never).
+	SlowCallThreshold time.Duration `toml:",omitempty"`
+
+	MaxSize    int  `toml:",omitempty"` // Size of a log file in megabytes before it is rotated (default = 100)
+	MaxBackups int  `toml:",omitempty"` // Number of rotated log files to retain (default = all)
+	MaxAge     int  `toml:",omitempty"` // Days to retain rotated log files (default = forever)
+	Compress   bool `toml:",omitempty"` // Whether to gzip the rotated log files
+}
+
+// auditLogEntry is the JSON form of a served call in the audit log.
+type auditLogEntry struct {
+	Time       time.Time       `json:"time"`
+	Method     string          `json:"method"`
+	ParamsHash common.Hash     `json:"paramsHash"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	Transport  string          `json:"transport"`
+	RemoteAddr string          `json:"remoteAddr,omitempty"`
+	Subject    string          `json:"subject,omitempty"`
+	Duration   float64         `json:"durationMs"`
+	Size       int             `json:"responseSize"`
+	ErrorCode  int             `json:"errorCode,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Slow       bool            `json:"slow,omitempty"`
+}
+
+// auditLog implements rpc.AuditLogger, writing the served calls as JSON lines.
+type auditLog struct {
+	slow time.Duration
+
+	mu     sync.Mutex
+	out    io.Writer
+	closer io.Closer // nil when writing to stdout
+	enc    *json.Encoder
+}
+
+// newAuditLog creates an audit log according to the config, or returns nil if it
+// is disabled.
+func newAuditLog(config AuditLogConfig) *auditLog {
+	if config.Output == "" {
+		return nil
+	}
+	l := &auditLog{slow: config.SlowCallThreshold}
+	if config.Output == AuditLogStdout {
+		l.out = os.Stdout
+	} else {
+		file := &lumberjack.Logger{
+			Filename:   config.Output,
+			MaxSize:    config.MaxSize,
+			MaxBackups: config.MaxBackups,
+			MaxAge:     config.MaxAge,
+			Compress:   config.Compress,
+		}
+		l.out, l.closer = file, file
+	}
+	l.enc = json.NewEncoder(l.out)
+	return l
+}
+
+// LogCall implements rpc.AuditLogger.
+func (l *auditLog) LogCall(info *rpc.CallInfo) {
+	entry := &auditLogEntry{
+		Time:       info.Start,
+		Method:     info.Method,
+		ParamsHash: crypto.Keccak256Hash(info.Params),
+		Transport:  info.Peer.Transport,
+		RemoteAddr: info.Peer.RemoteAddr,
+		Subject:    info.Peer.Subject,
+		Duration:   float64(info.Duration) / float64(time.Millisecond),
+		Size:       info.ResponseSize,
+		ErrorCode:  info.ErrorCode,
+		Error:      info.ErrorMessage,
+	}
+	if l.slow > 0 && info.Duration >= l.slow {
+		entry.Slow = true
+		entry.Params = info.Params
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.enc.Encode(entry); err != nil {
+		log.Warn("Failed to write RPC audit log", "err", err)
+	}
+}
+
+// close flushes and closes the log file.
+func (l *auditLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit := newAuditLog(AuditLogConfig{Output: path, SlowCallThreshold: time.Second})
+	srv := createAndStartServer(t, &httpConfig{rpcEndpointConfig: rpcEndpointConfig{auditLogger: audit}}, false, nil, nil)
+
+	client, err := rpc.Dial(fmt.Sprintf("http://%s", srv.listenAddr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(nil, "test_greet"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(nil, "test_sleep"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(nil, "test_missing", 1); err == nil {
+		t.Fatal("call of missing method succeeded")
+	}
+	client.Close()
+	srv.stop()
+	if err := audit.close(); err != nil {
+		t.Fatal(err)
+	}
+	// Check the logged entries
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var entries []auditLogEntry
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		var entry auditLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("wrong number of entries: have %d, want 3", len(entries))
+	}
+	greet, sleep, missing := entries[0], entries[1], entries[2]
+	if greet.Method != "test_greet" || greet.Transport != "http" || greet.RemoteAddr == "" || greet.Size != len(`"Hello"`) || greet.Slow || greet.Params != nil {
+		t.Errorf("wrong entry for fast call: %+v", greet)
+	}
+	if sleep.Method != "test_sleep" || !sleep.Slow || sleep.Duration < 1000 {
+		t.Errorf("wrong entry for slow call: %+v", sleep)
+	}
+	if missing.ErrorCode != -32601 || missing.Error == "" || missing.Size != 0 {
+		t.Errorf("wrong entry for failed call: %+v", missing)
+	}
+	if missing.ParamsHash != crypto.Keccak256Hash([]byte("[1]")) {
+		t.Errorf("wrong params hash %x", missing.ParamsHash)
+	}
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -101,6 +102,13 @@ type Config struct {
 	// relative), then that specific path is enforced. An empty path disables IPC.
 	IPCPath string
 
+	// IPCAllowedMethods and IPCDeniedMethods restrict the methods served over IPC.
+	// The patterns match full method names and support wildcards, e.g. "debug_*".
+	// Denied methods are never served, and if the allow list is non-empty, only
+	// the methods matching it are. The rpc_* metadata methods are always served.
+	IPCAllowedMethods []string `toml:",omitempty"`
+	IPCDeniedMethods  []string `toml:",omitempty"`
+
 	// HTTPHost is the host interface on which to start the HTTP RPC server. If this
 	// field is empty, no HTTP API endpoint will be started.
 	HTTPHost string
@@ -129,6 +137,11 @@ type Config struct {
 	// exposed.
 	HTTPModules []string
 
+	// HTTPAllowedMethods and HTTPDeniedMethods restrict the methods of the exposed
+	// modules served over HTTP, in the same way as IPCAllowedMethods and IPCDeniedMethods.
+	HTTPAllowedMethods []string `toml:",omitempty"`
+	HTTPDeniedMethods  []string `toml:",omitempty"`
+
 	// HTTPTimeouts allows for customization of the timeout values used by the HTTP RPC
 	// interface.
 	HTTPTimeouts rpc.HTTPTimeouts
@@ -168,6 +181,12 @@ type Config struct {
 	// exposed.
 	WSModules []string
 
+	// WSAllowedMethods and WSDeniedMethods restrict the methods of the exposed
+	// modules served over websocket, in the same way as IPCAllowedMethods and
+	// IPCDeniedMethods.
+	WSAllowedMethods []string `toml:",omitempty"`
+	WSDeniedMethods  []string `toml:",omitempty"`
+
 	// WSExposeAll exposes all API modules via the WebSocket RPC interface rather
 	// than just the public ones.
 	//
@@ -197,6 +216,26 @@ type Config struct {
 	// AllowUnprotectedTxs allows non EIP-155 protected transactions to be send over RPC.
 	AllowUnprotectedTxs bool `toml:",omitempty"`
 
+	// BatchRequestLimit is the maximum number of requests in a batch.
+	BatchRequestLimit int `toml:",omitempty"`
+
+	// BatchResponseMaxSize is the maximum number of bytes returned from a batched rpc call.
+	BatchResponseMaxSize int `toml:",omitempty"`
+
+	// RPCRateLimit contains the per-client quotas of the HTTP and WebSocket endpoints.
+	// The authenticated endpoints are never limited.
+	RPCRateLimit RateLimitConfig `toml:",omitempty"`
+
+	// RPCClientJWTSecret is the path to the hex-encoded jwt secret with which clients
+	// of the HTTP and WebSocket endpoints may authenticate, to have their calls charged
+	// to the subject of their token instead of their IP address. Requests without a
+	// token are still served. It must differ from JWTSecret, which grants access to
+	// the authenticated endpoints.
+	RPCClientJWTSecret string `toml:",omitempty"`
+
+	// RPCAuditLog configures the log of the calls served over HTTP, WebSocket and IPC.
+	RPCAuditLog AuditLogConfig `toml:",omitempty"`
+
 	// JWTSecret is the path to the hex-encoded jwt secret.
 	JWTSecret string `toml:",omitempty"`
 
@@ -204,6 +243,10 @@ type Config struct {
 	EnablePersonal bool `toml:"-"`
 
 	DBEngine string `toml:",omitempty"`
+
+	// DBPebble contains the tuning parameters of the pebble database engine,
+	// applied to the chain database only.
+	DBPebble *pebble.Config `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
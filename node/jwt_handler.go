@@ -21,14 +21,16 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/golang-jwt/jwt/v4"
 )
 
 const jwtExpiryTimeout = 60 * time.Second
 
 type jwtHandler struct {
-	keyFunc func(token *jwt.Token) (interface{}, error)
-	next    http.Handler
+	keyFunc  func(token *jwt.Token) (interface{}, error)
+	next     http.Handler
+	optional bool // whether requests without a token are let through
 }
 
 // newJWTHandler creates a http.Handler with jwt authentication support.
@@ -41,6 +43,15 @@ func newJWTHandler(secret []byte, next http.Handler) http.Handler {
 	}
 }
 
+// newOptionalJWTHandler creates a http.Handler which only authenticates the requests
+// carrying a jwt token, exposing the subject of the token to the RPC server. Requests
+// without a token are passed on unauthenticated.
+func newOptionalJWTHandler(secret []byte, next http.Handler) http.Handler {
+	handler := newJWTHandler(secret, next).(*jwtHandler)
+	handler.optional = true
+	return handler
+}
+
 // ServeHTTP implements http.Handler
 func (handler *jwtHandler) ServeHTTP(out http.ResponseWriter, r *http.Request) {
 	var (
@@ -51,6 +62,10 @@ func (handler *jwtHandler) ServeHTTP(out http.ResponseWriter, r *http.Request) {
 		strToken = strings.TrimPrefix(auth, "Bearer ")
 	}
 	if len(strToken) == 0 {
+		if handler.optional {
+			handler.next.ServeHTTP(out, r)
+			return
+		}
 		http.Error(out, "missing token", http.StatusUnauthorized)
 		return
 	}
@@ -75,6 +90,9 @@ func (handler *jwtHandler) ServeHTTP(out http.ResponseWriter, r *http.Request) {
 	case time.Until(claims.IssuedAt.Time) > jwtExpiryTimeout:
 		http.Error(out, "future token", http.StatusUnauthorized)
 	default:
+		if claims.Subject != "" {
+			r = r.WithContext(rpc.WithPeerSubject(r.Context(), claims.Subject))
+		}
 		handler.next.ServeHTTP(out, r)
 	}
 }
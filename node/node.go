@@ -31,8 +31,10 @@ import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -64,7 +66,10 @@ type Node struct {
 	ipc           *ipcServer  // Stores information about the ipc http server
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
 
-	databases map[*closeTrackingDB]struct{} // All open databases
+	rateLimiter     *rateLimiter                  // Quotas of the calls served over HTTP and WebSocket
+	clientJWTSecret []byte                        // Secret of the tokens identifying HTTP and WebSocket clients
+	auditLog        *auditLog                     // Log of the served calls, nil if disabled
+	databases       map[*closeTrackingDB]struct{} // All open databases
 }
 
 const (
@@ -147,11 +152,21 @@ func New(conf *Config) (*Node, error) {
 	}
 
 	// Configure RPC servers.
+	limiter, err := newRateLimiter(conf.RPCRateLimit, mclock.System{})
+	if err != nil {
+		return nil, err
+	}
+	node.rateLimiter = limiter
+	node.auditLog = newAuditLog(conf.RPCAuditLog)
 	node.http = newHTTPServer(node.log, conf.HTTPTimeouts)
 	node.httpAuth = newHTTPServer(node.log, conf.HTTPTimeouts)
 	node.ws = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
 	node.wsAuth = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
 	node.ipc = newIPCServer(node.log, conf.IPCEndpoint())
+	node.ipc.allowedMethods, node.ipc.deniedMethods = conf.IPCAllowedMethods, conf.IPCDeniedMethods
+	if node.auditLog != nil {
+		node.ipc.auditLogger = node.auditLog
+	}
 
 	return node, nil
 }
@@ -238,6 +253,11 @@ func (n *Node) doClose(errs []error) error {
 	if err := n.accman.Close(); err != nil {
 		errs = append(errs, err)
 	}
+	if n.auditLog != nil {
+		if err := n.auditLog.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	if n.keyDirTemp {
 		if err := os.RemoveAll(n.keyDir); err != nil {
 			errs = append(errs, err)
@@ -372,6 +392,20 @@ func (n *Node) obtainJWTSecret(cliParam string) ([]byte, error) {
 	return jwtSecret, nil
 }
 
+// rpcEndpointConfig returns the settings of the public HTTP and WebSocket endpoints.
+func (n *Node) rpcEndpointConfig() rpcEndpointConfig {
+	config := rpcEndpointConfig{
+		batchItemLimit:         n.config.BatchRequestLimit,
+		batchResponseSizeLimit: n.config.BatchResponseMaxSize,
+		rateLimiter:            n.rateLimiter,
+		clientJWTSecret:        n.clientJWTSecret,
+	}
+	if n.auditLog != nil {
+		config.auditLogger = n.auditLog
+	}
+	return config
+}
+
 // startRPC is a helper method to configure all the various RPC endpoints during node
 // startup. It's not meant to be called at any time afterwards as it makes certain
 // assumptions about the state of the node.
@@ -402,6 +436,14 @@ func (n *Node) startRPC() error {
 		servers           []*httpServer
 		openAPIs, allAPIs = n.getAPIs()
 	)
+	if n.config.RPCClientJWTSecret != "" {
+		secret, err := n.obtainJWTSecret(n.config.RPCClientJWTSecret)
+		if err != nil {
+			return err
+		}
+		n.clientJWTSecret = secret
+	}
+	rpcConfig := n.rpcEndpointConfig()
 
 	initHttp := func(server *httpServer, port int) error {
 		if err := server.setListenAddr(n.config.HTTPHost, port); err != nil {
@@ -411,7 +453,10 @@ func (n *Node) startRPC() error {
 			CorsAllowedOrigins: n.config.HTTPCors,
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
+			AllowedMethods:     n.config.HTTPAllowedMethods,
+			DeniedMethods:      n.config.HTTPDeniedMethods,
 			prefix:             n.config.HTTPPathPrefix,
+			rpcEndpointConfig:  rpcConfig,
 		}); err != nil {
 			return err
 		}
@@ -425,9 +470,12 @@ func (n *Node) startRPC() error {
 			return err
 		}
 		if err := server.enableWS(openAPIs, wsConfig{
-			Modules: n.config.WSModules,
-			Origins: n.config.WSOrigins,
-			prefix:  n.config.WSPathPrefix,
+			Modules:           n.config.WSModules,
+			AllowedMethods:    n.config.WSAllowedMethods,
+			DeniedMethods:     n.config.WSDeniedMethods,
+			Origins:           n.config.WSOrigins,
+			prefix:            n.config.WSPathPrefix,
+			rpcEndpointConfig: rpcConfig,
 		}); err != nil {
 			return err
 		}
@@ -436,6 +484,14 @@ func (n *Node) startRPC() error {
 	}
 
 	initAuth := func(port int, secret []byte) error {
+		sharedConfig := rpcEndpointConfig{
+			jwtSecret:              secret,
+			batchItemLimit:         engineAPIBatchItemLimit,
+			batchResponseSizeLimit: engineAPIBatchResponseSizeLimit,
+		}
+		if n.auditLog != nil {
+			sharedConfig.auditLogger = n.auditLog
+		}
 		// Enable auth via HTTP
 		server := n.httpAuth
 		if err := server.setListenAddr(n.config.AuthAddr, port); err != nil {
@@ -446,7 +502,7 @@ func (n *Node) startRPC() error {
 			Vhosts:             n.config.AuthVirtualHosts,
 			Modules:            DefaultAuthModules,
 			prefix:             DefaultAuthPrefix,
-			jwtSecret:          secret,
+			rpcEndpointConfig:  sharedConfig,
 		}); err != nil {
 			return err
 		}
@@ -457,10 +513,10 @@ func (n *Node) startRPC() error {
 			return err
 		}
 		if err := server.enableWS(allAPIs, wsConfig{
-			Modules:   DefaultAuthModules,
-			Origins:   DefaultAuthOrigins,
-			prefix:    DefaultAuthPrefix,
-			jwtSecret: secret,
+			Modules:           DefaultAuthModules,
+			Origins:           DefaultAuthOrigins,
+			prefix:            DefaultAuthPrefix,
+			rpcEndpointConfig: sharedConfig,
 		}); err != nil {
 			return err
 		}
@@ -716,6 +772,7 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string, r
 			Cache:     cache,
 			Handles:   handles,
 			ReadOnly:  readonly,
+			Pebble:    n.pebbleConfig(name),
 		})
 	}
 
@@ -749,6 +806,7 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 			Cache:             cache,
 			Handles:           handles,
 			ReadOnly:          readonly,
+			Pebble:            n.pebbleConfig(name),
 		})
 	}
 
@@ -758,6 +816,17 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 	return db, err
 }
 
+// pebbleConfig returns the pebble tuning of the database with the given name.
+// The configured tuning, including the write-ahead log directory, is meant for
+// the chain database, all other databases use the defaults so that they don't
+// share a write-ahead log directory with it.
+func (n *Node) pebbleConfig(name string) *pebble.Config {
+	if name != "chaindata" && name != "lightchaindata" {
+		return nil
+	}
+	return n.config.DBPebble
+}
+
 // ResolvePath returns the absolute path of a resource in the instance directory.
 func (n *Node) ResolvePath(x string) string {
 	return n.config.ResolvePath(x)
@@ -22,12 +22,15 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
 
@@ -166,6 +169,38 @@ func TestNodeCloseClosesDB(t *testing.T) {
 	}
 }
 
+// Tests that the pebble tuning, including the write-ahead log directory, is only
+// applied to the chain database.
+func TestNodeOpenDatabasePebbleWALDir(t *testing.T) {
+	if !rawdb.PebbleEnabled {
+		t.Skip("pebble not supported")
+	}
+	var (
+		datadir = t.TempDir()
+		waldir  = t.TempDir()
+		config  = &Config{Name: "unit-test", DataDir: datadir, DBEngine: "pebble", DBPebble: &pebble.Config{WALDir: waldir}}
+	)
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	defer stack.Close()
+
+	for _, name := range []string{"chaindata", "les.server"} {
+		db, err := stack.OpenDatabase(name, 0, 0, "", false)
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", name, err)
+		}
+		db.Close()
+	}
+	if logs, _ := filepath.Glob(filepath.Join(waldir, "*.log")); len(logs) != 1 {
+		t.Fatalf("write-ahead log count mismatch in shared directory: have %d, want 1", len(logs))
+	}
+	if logs, _ := filepath.Glob(filepath.Join(stack.ResolvePath("les.server"), "*.log")); len(logs) == 0 {
+		t.Fatalf("write-ahead log of les.server not in its database directory")
+	}
+}
+
 // This test checks that OpenDatabase can be used from within a Lifecycle Start method.
 func TestNodeOpenDatabaseFromLifecycleStart(t *testing.T) {
 	stack, _ := New(testNodeConfig())
@@ -0,0 +1,245 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RateLimitConfig contains the quotas applied to the calls served over the HTTP and
+// WebSocket endpoints.
+//
+// Every client, identified by the subject of the JWT token it authenticated with
+// (see Config.RPCClientJWTSecret) or otherwise by its IP address, owns a bucket holding up to Burst tokens which is refilled at Rate tokens
+// per second. Each call takes the cost of its method out of the bucket, calls finding
+// too few tokens are rejected.
+type RateLimitConfig struct {
+	Rate  float64 `toml:",omitempty" json:"rate"`  // Tokens added to a client's bucket per second (0 = unlimited)
+	Burst float64 `toml:",omitempty" json:"burst"` // Capacity of a client's bucket (default = Rate)
+
+	// MethodCosts overrides the number of tokens taken by the calls of a method. Keys
+	// are method names or name prefixes ending in '*', e.g. "debug_trace*". Methods
+	// matching neither these nor the built-in costs take a single token.
+	MethodCosts map[string]float64 `toml:",omitempty" json:"methodCosts,omitempty"`
+}
+
+// defaultMethodCosts are the built-in costs of the methods which are much more
+// expensive to serve than a plain lookup. The engine API is never limited.
+var defaultMethodCosts = map[string]float64{
+	"eth_call":             5,
+	"eth_estimateGas":      5,
+	"eth_createAccessList": 5,
+	"eth_getLogs":          20,
+	"eth_getFilterLogs":    20,
+	"debug_trace*":         50,
+	"engine_*":             0,
+}
+
+// rateLimitSweepInterval is the interval at which the buckets of idle clients are
+// dropped from the limiter.
+const rateLimitSweepInterval = time.Minute
+
+// validate checks the limits for values the limiter cannot work with.
+func (c *RateLimitConfig) validate() error {
+	if c.Rate < 0 {
+		return fmt.Errorf("invalid rate limit %v", c.Rate)
+	}
+	if c.Burst < 0 {
+		return fmt.Errorf("invalid rate limit burst %v", c.Burst)
+	}
+	for method, cost := range c.MethodCosts {
+		if cost < 0 {
+			return fmt.Errorf("invalid cost %v of method %q", cost, method)
+		}
+	}
+	return nil
+}
+
+// tokenBucket is the quota of a single client.
+type tokenBucket struct {
+	tokens  float64
+	updated mclock.AbsTime
+}
+
+// rateLimiter implements rpc.RateLimiter with a token bucket per client. Its limits
+// can be replaced while the RPC servers using it are running.
+type rateLimiter struct {
+	clock mclock.Clock
+
+	mu        sync.Mutex
+	config    RateLimitConfig
+	buckets   map[string]*tokenBucket
+	lastSweep mclock.AbsTime
+}
+
+// newRateLimiter creates a limiter enforcing the given limits.
+func newRateLimiter(config RateLimitConfig, clock mclock.Clock) (*rateLimiter, error) {
+	l := &rateLimiter{
+		clock:     clock,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: clock.Now(),
+	}
+	if err := l.setConfig(config); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// setConfig replaces the limits. The buckets of known clients are retained, their
+// tokens are capped to the new burst the next time they are charged.
+func (l *rateLimiter) setConfig(config RateLimitConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+	costs := make(map[string]float64, len(config.MethodCosts))
+	for method, cost := range config.MethodCosts {
+		costs[method] = cost
+	}
+	config.MethodCosts = costs
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.config = config
+	return nil
+}
+
+// limits returns a copy of the current limits.
+func (l *rateLimiter) limits() RateLimitConfig {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	config := l.config
+	config.MethodCosts = make(map[string]float64, len(l.config.MethodCosts))
+	for method, cost := range l.config.MethodCosts {
+		config.MethodCosts[method] = cost
+	}
+	return config
+}
+
+// Allow implements rpc.RateLimiter, charging the cost of the method to the bucket
+// of the calling client.
+func (l *rateLimiter) Allow(ctx context.Context, method string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rate := l.config.Rate
+	if rate == 0 {
+		return 0, true
+	}
+	cost := l.cost(method)
+	if cost == 0 {
+		return 0, true
+	}
+	burst := l.config.Burst
+	if burst == 0 {
+		burst = rate
+	}
+	// Let calls more expensive than the whole bucket through once it is full,
+	// otherwise they could never be served.
+	if cost > burst {
+		cost = burst
+	}
+	now := l.clock.Now()
+	if time.Duration(now-l.lastSweep) >= rateLimitSweepInterval {
+		l.sweep(now, rate, burst)
+	}
+	key := rateLimitKey(rpc.PeerInfoFromContext(ctx))
+	bucket := l.buckets[key]
+	if bucket == nil {
+		bucket = &tokenBucket{tokens: burst, updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens += time.Duration(now-bucket.updated).Seconds() * rate
+	if bucket.tokens > burst {
+		bucket.tokens = burst
+	}
+	bucket.updated = now
+
+	if bucket.tokens < cost {
+		wait := (cost - bucket.tokens) / rate
+		return time.Duration(wait * float64(time.Second)), false
+	}
+	bucket.tokens -= cost
+	return 0, true
+}
+
+// cost returns the number of tokens taken by a call of the given method. This is
+// internal, the caller must hold l.mu.
+func (l *rateLimiter) cost(method string) float64 {
+	if cost, ok := lookupMethodCost(l.config.MethodCosts, method); ok {
+		return cost
+	}
+	if cost, ok := lookupMethodCost(defaultMethodCosts, method); ok {
+		return cost
+	}
+	return 1
+}
+
+// sweep drops the buckets which have been refilled completely, as those are the
+// same as fresh ones. This is internal, the caller must hold l.mu.
+func (l *rateLimiter) sweep(now mclock.AbsTime, rate, burst float64) {
+	for key, bucket := range l.buckets {
+		if bucket.tokens+time.Duration(now-bucket.updated).Seconds()*rate >= burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// lookupMethodCost finds the cost of a method in the given table, preferring an
+// exact match over the longest matching prefix.
+func lookupMethodCost(costs map[string]float64, method string) (float64, bool) {
+	if cost, ok := costs[method]; ok {
+		return cost, true
+	}
+	var (
+		match string
+		found bool
+		cost  float64
+	)
+	for pattern, c := range costs {
+		prefix := strings.TrimSuffix(pattern, "*")
+		if prefix == pattern || !strings.HasPrefix(method, prefix) {
+			continue
+		}
+		if !found || len(prefix) > len(match) {
+			match, found, cost = prefix, true, c
+		}
+	}
+	return cost, found
+}
+
+// rateLimitKey returns the identity a client's calls are charged to: the subject of
+// its JWT token if authenticated, or its IP address otherwise.
+func rateLimitKey(info rpc.PeerInfo) string {
+	if info.Subject != "" {
+		return "sub:" + info.Subject
+	}
+	host, _, err := net.SplitHostPort(info.RemoteAddr)
+	if err != nil {
+		host = info.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,267 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// dialRateLimited connects to the server, authenticating with a JWT token of the
+// given subject if a secret is provided.
+func dialRateLimited(t *testing.T, srv *httpServer, secret []byte, subject string) *rpc.Client {
+	t.Helper()
+
+	var opts []rpc.ClientOption
+	if secret != nil {
+		opts = append(opts, rpc.WithHTTPAuth(subjectJWTAuth(secret, subject)))
+	}
+	client, err := rpc.DialOptions(context.Background(), fmt.Sprintf("http://%s", srv.listenAddr()), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// subjectJWTAuth creates an rpc.HTTPAuth authenticating with a JWT token of the
+// given subject.
+func subjectJWTAuth(secret []byte, subject string) rpc.HTTPAuth {
+	return func(h http.Header) error {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"iat": &jwt.NumericDate{Time: time.Now()},
+			"sub": subject,
+		})
+		s, err := token.SignedString(secret)
+		if err != nil {
+			return err
+		}
+		h.Set("Authorization", "Bearer "+s)
+		return nil
+	}
+}
+
+// checkRateLimited calls the greeter, checking whether it was rejected.
+func checkRateLimited(t *testing.T, client *rpc.Client, method string, limited bool) {
+	t.Helper()
+
+	err := client.Call(nil, method)
+	if !limited {
+		if err != nil {
+			t.Fatalf("call rejected: %v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatal("call not rejected")
+	}
+	if code := err.(rpc.Error).ErrorCode(); code != -32005 {
+		t.Fatalf("wrong error code %d", code)
+	}
+	data, _ := err.(rpc.DataError).ErrorData().(map[string]interface{})
+	if retry, ok := data["retryAfter"].(float64); !ok || retry < 1 {
+		t.Fatalf("missing retry-after hint: %v", err.(rpc.DataError).ErrorData())
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	clock := new(mclock.Simulated)
+	limiter, err := newRateLimiter(RateLimitConfig{Rate: 1, Burst: 3, MethodCosts: map[string]float64{"test_sleep": 100}}, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := createAndStartServer(t, &httpConfig{rpcEndpointConfig: rpcEndpointConfig{rateLimiter: limiter}}, false, nil, nil)
+	defer srv.stop()
+
+	client := dialRateLimited(t, srv, nil, "")
+	for i := 0; i < 3; i++ {
+		checkRateLimited(t, client, "test_greet", false)
+	}
+	checkRateLimited(t, client, "test_greet", true)
+
+	// Other connections from the same address share the quota.
+	checkRateLimited(t, dialRateLimited(t, srv, nil, ""), "test_greet", true)
+
+	// Calls costing more than the burst take the whole bucket once full.
+	clock.Run(2 * time.Second)
+	checkRateLimited(t, client, "test_sleep", true)
+	clock.Run(time.Second)
+	checkRateLimited(t, client, "test_sleep", false)
+	checkRateLimited(t, client, "test_greet", true)
+
+	// Reloading the limits applies them to the running server.
+	if err := limiter.setConfig(RateLimitConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		checkRateLimited(t, client, "test_greet", false)
+	}
+	if err := limiter.setConfig(RateLimitConfig{Rate: -1}); err == nil {
+		t.Fatal("negative rate accepted")
+	}
+}
+
+func TestRateLimiterSubject(t *testing.T) {
+	var (
+		clock  = new(mclock.Simulated)
+		secret = []byte("secret")
+	)
+	limiter, err := newRateLimiter(RateLimitConfig{Rate: 1, Burst: 2}, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := createAndStartServer(t, &httpConfig{rpcEndpointConfig: rpcEndpointConfig{clientJWTSecret: secret, rateLimiter: limiter}}, false, nil, nil)
+	defer srv.stop()
+
+	alice := dialRateLimited(t, srv, secret, "alice")
+	checkRateLimited(t, alice, "test_greet", false)
+	checkRateLimited(t, alice, "test_greet", false)
+	checkRateLimited(t, alice, "test_greet", true)
+
+	// Clients are told apart by their JWT subject, not their address.
+	bob := dialRateLimited(t, srv, secret, "bob")
+	checkRateLimited(t, bob, "test_greet", false)
+
+	// Clients without a token are still served, charged to their address.
+	anon := dialRateLimited(t, srv, nil, "")
+	checkRateLimited(t, anon, "test_greet", false)
+	checkRateLimited(t, anon, "test_greet", false)
+	checkRateLimited(t, anon, "test_greet", true)
+
+	// Tokens signed with another secret are rejected.
+	if err := dialRateLimited(t, srv, []byte("other"), "carol").Call(nil, "test_greet"); err == nil {
+		t.Fatal("call with invalid token accepted")
+	}
+}
+
+func TestRateLimitMethodCost(t *testing.T) {
+	limiter, err := newRateLimiter(RateLimitConfig{Rate: 1, MethodCosts: map[string]float64{
+		"debug_*":          2,
+		"debug_traceCall":  7,
+		"eth_getLogs":      3,
+		"eth_blockNumber*": 4,
+	}}, new(mclock.Simulated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]float64{
+		"eth_blockNumber":        4, // configured prefix
+		"eth_getLogs":            3, // configured override of a default
+		"eth_call":               5, // default
+		"debug_traceCall":        7, // configured exact match
+		"debug_traceTransaction": 2, // configured prefix over the default one
+		"engine_newPayloadV2":    0, // default prefix
+		"net_version":            1, // unlisted
+		"debug_getRawBlock":      2, // configured prefix
+	}
+	for method, want := range tests {
+		if have := limiter.cost(method); have != want {
+			t.Errorf("%s: cost mismatch: have %v, want %v", method, have, want)
+		}
+	}
+}
+
+// Tests that the quotas are not applied to the authenticated endpoints.
+func TestRateLimitAuthEndpoints(t *testing.T) {
+	secret := [32]byte{1}
+	jwtPath := filepath.Join(t.TempDir(), "jwt_secret")
+	if err := os.WriteFile(jwtPath, []byte(hexutil.Encode(secret[:])), 0600); err != nil {
+		t.Fatal(err)
+	}
+	node, err := New(&Config{
+		HTTPHost:     "127.0.0.1",
+		HTTPModules:  []string{"eth"},
+		AuthAddr:     "127.0.0.1",
+		JWTSecret:    jwtPath,
+		RPCRateLimit: RateLimitConfig{Rate: 0.001, Burst: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.RegisterAPIs([]rpc.API{
+		{Namespace: "eth", Service: helloRPC("hello eth")},
+		{Namespace: "engine", Service: helloRPC("hello engine"), Authenticated: true},
+	})
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	auth, err := rpc.DialOptions(context.Background(), node.HTTPAuthEndpoint(), rpc.WithHTTPAuth(NewJWTAuth(secret)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auth.Close()
+	for i := 0; i < 3; i++ {
+		checkRateLimited(t, auth, "eth_helloWorld", false)
+	}
+	public, err := rpc.Dial(node.HTTPEndpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer public.Close()
+	checkRateLimited(t, public, "eth_helloWorld", false)
+	checkRateLimited(t, public, "eth_helloWorld", true)
+}
+
+// Tests that the clients of the public endpoints may authenticate with the client
+// JWT secret, to be rate limited by the subject of their token.
+func TestRateLimitClientJWT(t *testing.T) {
+	secret := [32]byte{2}
+	jwtPath := filepath.Join(t.TempDir(), "client_jwt_secret")
+	if err := os.WriteFile(jwtPath, []byte(hexutil.Encode(secret[:])), 0600); err != nil {
+		t.Fatal(err)
+	}
+	node, err := New(&Config{
+		HTTPHost:           "127.0.0.1",
+		HTTPModules:        []string{"eth"},
+		RPCRateLimit:       RateLimitConfig{Rate: 0.001, Burst: 1},
+		RPCClientJWTSecret: jwtPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.RegisterAPIs([]rpc.API{{Namespace: "eth", Service: helloRPC("hello eth")}})
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Close()
+
+	for _, subject := range []string{"alice", "bob", ""} {
+		var opts []rpc.ClientOption
+		if subject != "" {
+			opts = append(opts, rpc.WithHTTPAuth(subjectJWTAuth(secret[:], subject)))
+		}
+		client, err := rpc.DialOptions(context.Background(), node.HTTPEndpoint(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		checkRateLimited(t, client, "eth_helloWorld", false)
+		checkRateLimited(t, client, "eth_helloWorld", true)
+	}
+}
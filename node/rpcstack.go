@@ -38,18 +38,32 @@ import (
 // httpConfig is the JSON-RPC/HTTP configuration.
 type httpConfig struct {
 	Modules            []string
+	AllowedMethods     []string // method patterns to serve, empty = all
+	DeniedMethods      []string // method patterns to never serve
 	CorsAllowedOrigins []string
 	Vhosts             []string
 	prefix             string // path prefix on which to mount http handler
-	jwtSecret          []byte // optional JWT secret
+	rpcEndpointConfig
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
 type wsConfig struct {
-	Origins   []string
-	Modules   []string
-	prefix    string // path prefix on which to mount ws handler
-	jwtSecret []byte // optional JWT secret
+	Origins        []string
+	Modules        []string
+	AllowedMethods []string // method patterns to serve, empty = all
+	DeniedMethods  []string // method patterns to never serve
+	prefix         string   // path prefix on which to mount ws handler
+	rpcEndpointConfig
+}
+
+// rpcEndpointConfig is the configuration shared by the HTTP and websocket endpoints.
+type rpcEndpointConfig struct {
+	jwtSecret              []byte // optional JWT secret
+	batchItemLimit         int
+	batchResponseSizeLimit int
+	rateLimiter            rpc.RateLimiter // optional per-client quotas
+	clientJWTSecret        []byte          // optional JWT secret identifying the clients
+	auditLogger            rpc.AuditLogger // optional log of the served calls
 }
 
 type rpcHandler struct {
@@ -297,12 +311,22 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
+	if config.rateLimiter != nil {
+		srv.SetRateLimiter(config.rateLimiter)
+	}
+	if config.auditLogger != nil {
+		srv.SetAuditLogger(config.auditLogger)
+	}
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
+	if err := srv.SetMethodFilter(config.AllowedMethods, config.DeniedMethods); err != nil {
+		return err
+	}
 	h.httpConfig = config
 	h.httpHandler.Store(&rpcHandler{
-		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret),
+		Handler: NewHTTPHandlerStack(withClientJWT(srv, config.clientJWTSecret), config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret),
 		server:  srv,
 	})
 	return nil
@@ -328,12 +352,22 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	}
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
+	if config.rateLimiter != nil {
+		srv.SetRateLimiter(config.rateLimiter)
+	}
+	if config.auditLogger != nil {
+		srv.SetAuditLogger(config.auditLogger)
+	}
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
+	if err := srv.SetMethodFilter(config.AllowedMethods, config.DeniedMethods); err != nil {
+		return err
+	}
 	h.wsConfig = config
 	h.wsHandler.Store(&rpcHandler{
-		Handler: NewWSHandlerStack(srv.WebsocketHandler(config.Origins), config.jwtSecret),
+		Handler: NewWSHandlerStack(withClientJWT(srv.WebsocketHandler(config.Origins), config.clientJWTSecret), config.jwtSecret),
 		server:  srv,
 	})
 	return nil
@@ -388,6 +422,15 @@ func NewHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string, jwtSe
 	return newGzipHandler(handler)
 }
 
+// withClientJWT wraps the handler to identify the clients authenticating with a
+// JWT token of the given secret, if any. Requests without a token are let through.
+func withClientJWT(srv http.Handler, secret []byte) http.Handler {
+	if len(secret) == 0 {
+		return srv
+	}
+	return newOptionalJWTHandler(secret, srv)
+}
+
 // NewWSHandlerStack returns a wrapped ws-related handler.
 func NewWSHandlerStack(srv http.Handler, jwtSecret []byte) http.Handler {
 	if len(jwtSecret) != 0 {
@@ -572,6 +615,10 @@ type ipcServer struct {
 	log      log.Logger
 	endpoint string
 
+	allowedMethods []string        // method patterns to serve, empty = all
+	deniedMethods  []string        // method patterns to never serve
+	auditLogger    rpc.AuditLogger // optional log of the served calls
+
 	mu       sync.Mutex
 	listener net.Listener
 	srv      *rpc.Server
@@ -589,7 +636,14 @@ func (is *ipcServer) start(apis []rpc.API) error {
 	if is.listener != nil {
 		return nil // already running
 	}
-	listener, srv, err := rpc.StartIPCEndpoint(is.endpoint, apis)
+	srv := rpc.NewServer()
+	if err := srv.SetMethodFilter(is.allowedMethods, is.deniedMethods); err != nil {
+		return err
+	}
+	if is.auditLogger != nil {
+		srv.SetAuditLogger(is.auditLogger)
+	}
+	listener, err := rpc.ServeIPCEndpoint(srv, is.endpoint, apis)
 	if err != nil {
 		is.log.Warn("IPC opening failed", "url", is.endpoint, "error", err)
 		return err
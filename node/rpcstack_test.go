@@ -339,8 +339,8 @@ func TestJWT(t *testing.T) {
 		ss, _ := jwt.NewWithClaims(method, testClaim(input)).SignedString(secret)
 		return ss
 	}
-	srv := createAndStartServer(t, &httpConfig{jwtSecret: []byte("secret")},
-		true, &wsConfig{Origins: []string{"*"}, jwtSecret: []byte("secret")}, nil)
+	srv := createAndStartServer(t, &httpConfig{rpcEndpointConfig: rpcEndpointConfig{jwtSecret: []byte("secret")}},
+		true, &wsConfig{Origins: []string{"*"}, rpcEndpointConfig: rpcEndpointConfig{jwtSecret: []byte("secret")}}, nil)
 	wsUrl := fmt.Sprintf("ws://%v", srv.listenAddr())
 	htUrl := fmt.Sprintf("http://%v", srv.listenAddr())
 
@@ -594,6 +594,33 @@ func TestHTTPWriteTimeout(t *testing.T) {
 	})
 }
 
+func TestHTTPMethodFilter(t *testing.T) {
+	const (
+		greetRes   = `{"jsonrpc":"2.0","id":1,"result":"Hello"}`
+		blockedRes = `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"the method test_sleep does not exist/is not available"}}`
+	)
+	srv := createAndStartServer(t, &httpConfig{Modules: []string{"test"}, AllowedMethods: []string{"test_*"}, DeniedMethods: []string{"test_sl*"}}, false, &wsConfig{}, nil)
+	defer srv.stop()
+	url := fmt.Sprintf("http://%v", srv.listenAddr())
+
+	for method, want := range map[string]string{"test_greet": greetRes, "test_sleep": blockedRes} {
+		resp := rpcRequest(t, url, method)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(string(body)) != want {
+			t.Errorf("%s: wrong response. have %s, want %s", method, string(body), want)
+		}
+	}
+	// Invalid patterns must be rejected when enabling the endpoint.
+	bad := newHTTPServer(testlog.Logger(t, log.LvlDebug), rpc.DefaultHTTPTimeouts)
+	if err := bad.enableRPC(apis(), httpConfig{DeniedMethods: []string{"test_["}}); err == nil {
+		t.Fatal("invalid method pattern accepted")
+	}
+}
+
 func apis() []rpc.API {
 	return []rpc.API{
 		{
@@ -0,0 +1,62 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CallInfo describes a method call served by the server.
+type CallInfo struct {
+	Method       string          // Name of the called method
+	Params       json.RawMessage // Raw parameters of the call, must not be modified
+	Peer         PeerInfo        // Connection the call was received on
+	Start        time.Time       // Time the call was started
+	Duration     time.Duration   // Time taken to serve the call
+	Notification bool            // Whether the call was a notification without a response
+	ResponseSize int             // Size of the result in bytes, zero on error
+	ErrorCode    int             // Code of the returned error, zero on success
+	ErrorMessage string          // Message of the returned error
+}
+
+// AuditLogger records the method calls served by a server. LogCall is invoked once
+// every call completes, on the goroutine that served it, so implementations must be
+// safe for concurrent use and should return quickly.
+type AuditLogger interface {
+	LogCall(info *CallInfo)
+}
+
+// auditCall reports a served call to the audit logger of the handler.
+func (h *handler) auditCall(cp *callProc, msg, resp *jsonrpcMessage, start time.Time) {
+	info := &CallInfo{
+		Method:       msg.Method,
+		Params:       msg.Params,
+		Peer:         PeerInfoFromContext(cp.ctx),
+		Start:        start,
+		Duration:     time.Since(start),
+		Notification: resp == nil,
+	}
+	if resp != nil {
+		if resp.Error != nil {
+			info.ErrorCode, info.ErrorMessage = resp.Error.Code, resp.Error.Message
+		} else {
+			info.ResponseSize = len(resp.Result)
+		}
+	}
+	h.auditLogger.LogCall(info)
+}
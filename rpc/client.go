@@ -79,6 +79,11 @@ type Client struct {
 	isHTTP   bool      // connection type: http, ws or ipc
 	services *serviceRegistry
 
+	batchItemLimit     int         // maximum number of calls in a served batch, 0 = unlimited
+	batchResponseLimit int         // maximum response size of a served batch, 0 = unlimited
+	rateLimiter        RateLimiter // limiter of served calls, nil = unlimited
+	auditLogger        AuditLogger // logger of served calls, nil = none
+
 	idCounter atomic.Uint32
 
 	// This function, if non-nil, is called when the connection is lost.
@@ -114,7 +119,9 @@ func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, clientContextKey{}, c)
 	ctx = context.WithValue(ctx, peerInfoContextKey{}, conn.peerInfo())
-	handler := newHandler(ctx, conn, c.idgen, c.services)
+	handler := newHandler(ctx, conn, c.idgen, c.services, c.batchItemLimit, c.batchResponseLimit)
+	handler.rateLimiter = c.rateLimiter
+	handler.auditLogger = c.auditLogger
 	return &clientConn{conn, handler}
 }
 
@@ -226,27 +233,31 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(conn, new(serviceRegistry), &clientConfig{idgen: randomIDGenerator()})
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(conn ServerCodec, services *serviceRegistry, cfg *clientConfig) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
-		isHTTP:      isHTTP,
-		idgen:       idgen,
-		services:    services,
-		writeConn:   conn,
-		close:       make(chan struct{}),
-		closing:     make(chan struct{}),
-		didClose:    make(chan struct{}),
-		reconnected: make(chan ServerCodec),
-		readOp:      make(chan readOp),
-		readErr:     make(chan error),
-		reqInit:     make(chan *requestOp),
-		reqSent:     make(chan error, 1),
-		reqTimeout:  make(chan *requestOp),
+		isHTTP:             isHTTP,
+		idgen:              cfg.idgen,
+		services:           services,
+		batchItemLimit:     cfg.batchItemLimit,
+		batchResponseLimit: cfg.batchResponseLimit,
+		rateLimiter:        cfg.rateLimiter,
+		auditLogger:        cfg.auditLogger,
+		writeConn:          conn,
+		close:              make(chan struct{}),
+		closing:            make(chan struct{}),
+		didClose:           make(chan struct{}),
+		reconnected:        make(chan ServerCodec),
+		readOp:             make(chan readOp),
+		readErr:            make(chan error),
+		reqInit:            make(chan *requestOp),
+		reqSent:            make(chan error, 1),
+		reqTimeout:         make(chan *requestOp),
 	}
 	if !isHTTP {
 		go c.dispatch(conn)
@@ -33,6 +33,14 @@ type clientConfig struct {
 	httpAuth    HTTPAuth
 
 	wsDialer *websocket.Dialer
+
+	// Settings of the handler serving calls from the remote end, set by the
+	// server for its connections.
+	idgen              func() ID
+	batchItemLimit     int
+	batchResponseLimit int
+	rateLimiter        RateLimiter
+	auditLogger        AuditLogger
 }
 
 func (cfg *clientConfig) initHeaders() {
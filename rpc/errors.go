@@ -16,7 +16,10 @@
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // HTTPError is returned by client operations when the HTTP status code of the
 // response is not a 2xx status.
@@ -55,18 +58,24 @@ var (
 	_ Error = new(invalidMessageError)
 	_ Error = new(invalidParamsError)
 	_ Error = new(internalServerError)
+	_ Error = new(rateLimitedError)
 )
 
 const (
 	errcodeDefault                  = -32000
 	errcodeNotificationsUnsupported = -32001
 	errcodeTimeout                  = -32002
+	errcodeResponseTooLarge         = -32003
+	errcodeLimitExceeded            = -32005
 	errcodePanic                    = -32603
 	errcodeMarshalError             = -32603
 )
 
 const (
-	errMsgTimeout = "request timed out"
+	errMsgTimeout          = "request timed out"
+	errMsgResponseTooLarge = "response too large"
+	errMsgBatchTooLarge    = "batch too large"
+	errMsgRateLimited      = "rate limit exceeded"
 )
 
 type methodNotFoundError struct{ method string }
@@ -122,3 +131,24 @@ type internalServerError struct {
 func (e *internalServerError) ErrorCode() int { return e.code }
 
 func (e *internalServerError) Error() string { return e.message }
+
+// rateLimitedError is returned for calls rejected by the server's rate limiter.
+type rateLimitedError struct{ retryAfter time.Duration }
+
+// rateLimitedData is the error data of a rate limited call, telling the client
+// how many seconds to wait before retrying.
+type rateLimitedData struct {
+	RetryAfter int64 `json:"retryAfter"`
+}
+
+func (e *rateLimitedError) ErrorCode() int { return errcodeLimitExceeded }
+
+func (e *rateLimitedError) Error() string { return errMsgRateLimited }
+
+func (e *rateLimitedError) ErrorData() interface{} {
+	secs := int64((e.retryAfter + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	return rateLimitedData{RetryAfter: secs}
+}
@@ -25,9 +25,14 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/internal/telemetry"
 	"github.com/ethereum/go-ethereum/log"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// tracerName is the name of the tracer creating the spans of served calls.
+const tracerName = "github.com/ethereum/go-ethereum/rpc"
+
 // handler handles JSON-RPC messages. There is one handler per connection. Note that
 // handler is not safe for concurrent use. Message handling never blocks indefinitely
 // because RPCs are processed on background goroutines launched by handler.
@@ -60,6 +65,11 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	rateLimiter    RateLimiter
+	auditLogger    AuditLogger
+
+	batchRequestLimit    int // maximum number of calls in a batch, 0 = unlimited
+	batchResponseMaxSize int // maximum size of the results of a batch, 0 = unlimited
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -70,19 +80,21 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, batchRequestLimit, batchResponseMaxSize int) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
-		reg:            reg,
-		idgen:          idgen,
-		conn:           conn,
-		respWait:       make(map[string]*requestOp),
-		clientSubs:     make(map[string]*ClientSubscription),
-		rootCtx:        rootCtx,
-		cancelRoot:     cancelRoot,
-		allowSubscribe: true,
-		serverSubs:     make(map[ID]*Subscription),
-		log:            log.Root(),
+		reg:                  reg,
+		idgen:                idgen,
+		conn:                 conn,
+		respWait:             make(map[string]*requestOp),
+		clientSubs:           make(map[string]*ClientSubscription),
+		rootCtx:              rootCtx,
+		cancelRoot:           cancelRoot,
+		allowSubscribe:       true,
+		serverSubs:           make(map[ID]*Subscription),
+		log:                  log.Root(),
+		batchRequestLimit:    batchRequestLimit,
+		batchResponseMaxSize: batchResponseMaxSize,
 	}
 	if conn.remoteAddr() != "" {
 		h.log = h.log.New("conn", conn.remoteAddr())
@@ -137,13 +149,18 @@ func (b *batchCallBuffer) write(ctx context.Context, conn jsonWriter) {
 // timeout sends the responses added so far. For the remaining unanswered call
 // messages, it sends a timeout error response.
 func (b *batchCallBuffer) timeout(ctx context.Context, conn jsonWriter) {
+	b.respondWithError(ctx, conn, &internalServerError{errcodeTimeout, errMsgTimeout})
+}
+
+// respondWithError sends the responses added so far. For the remaining unanswered
+// call messages, it responds with the given error.
+func (b *batchCallBuffer) respondWithError(ctx context.Context, conn jsonWriter, err error) {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
 	for _, msg := range b.calls {
 		if !msg.isNotification() {
-			resp := msg.errorResponse(&internalServerError{errcodeTimeout, errMsgTimeout})
-			b.resp = append(b.resp, resp)
+			b.resp = append(b.resp, msg.errorResponse(err))
 		}
 	}
 	b.doWrite(ctx, conn, true)
@@ -203,6 +220,7 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 			})
 		}
 
+		var handled, responseBytes int
 		for {
 			// No need to handle rest of calls if timed out.
 			if cp.ctx.Err() != nil {
@@ -212,7 +230,27 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 			if msg == nil {
 				break
 			}
+			// Reject the calls exceeding the batch limit
+			if h.batchRequestLimit != 0 && handled == h.batchRequestLimit {
+				callBuffer.respondWithError(cp.ctx, h.conn, &invalidRequestError{errMsgBatchTooLarge})
+				break
+			}
+			handled++
+
+			notifiers := len(cp.notifiers)
 			resp := h.handleCallMsg(cp, msg)
+			if resp != nil && h.batchResponseMaxSize != 0 {
+				// Reject the call exceeding the response size limit, and all the
+				// ones following it. A subscription created by the rejected call is
+				// cancelled, as the client never learns its ID.
+				responseBytes += len(resp.Result)
+				if responseBytes > h.batchResponseMaxSize {
+					h.dropSubscriptions(cp.notifiers[notifiers:])
+					cp.notifiers = cp.notifiers[:notifiers]
+					callBuffer.respondWithError(cp.ctx, h.conn, &internalServerError{errcodeResponseTooLarge, errMsgResponseTooLarge})
+					break
+				}
+			}
 			callBuffer.pushResponse(resp)
 		}
 		if timer != nil {
@@ -326,6 +364,16 @@ func (h *handler) addSubscriptions(nn []*Notifier) {
 	}
 }
 
+// dropSubscriptions closes the subscriptions of the given notifiers without ever
+// activating them, used if the subscribe call's response is not sent.
+func (h *handler) dropSubscriptions(nn []*Notifier) {
+	for _, n := range nn {
+		if sub := n.takeSubscription(); sub != nil {
+			close(sub.err)
+		}
+	}
+}
+
 // cancelServerSubscriptions removes all subscriptions and closes their error channels.
 func (h *handler) cancelServerSubscriptions(err error) {
 	h.subLock.Lock()
@@ -415,9 +463,15 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 	case msg.isNotification():
 		h.handleCall(ctx, msg)
 		h.log.Debug("Served "+msg.Method, "duration", time.Since(start))
+		if h.auditLogger != nil {
+			h.auditCall(ctx, msg, nil, start)
+		}
 		return nil
 	case msg.isCall():
 		resp := h.handleCall(ctx, msg)
+		if h.auditLogger != nil {
+			h.auditCall(ctx, msg, resp, start)
+		}
 		var ctx []interface{}
 		ctx = append(ctx, "reqid", idForLog{msg.ID}, "duration", time.Since(start))
 		if resp.Error != nil {
@@ -438,7 +492,27 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 }
 
 // handleCall processes method calls.
-func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) (answer *jsonrpcMessage) {
+	ctx, span := telemetry.StartSpan(cp.ctx, tracerName, msg.Method,
+		attribute.String("rpc.system", "jsonrpc"),
+		attribute.String("rpc.method", msg.Method),
+		attribute.String("rpc.transport", PeerInfoFromContext(cp.ctx).Transport),
+	)
+	defer func() {
+		var err error
+		if answer != nil && answer.Error != nil {
+			span.SetAttributes(attribute.Int("rpc.jsonrpc.error_code", answer.Error.Code))
+			err = answer.Error
+		}
+		telemetry.EndSpan(span, err)
+	}()
+
+	if h.rateLimiter != nil && !msg.isUnsubscribe() {
+		if retry, ok := h.rateLimiter.Allow(cp.ctx, msg.Method); !ok {
+			rateLimitedCounter.Inc(1)
+			return msg.errorResponse(&rateLimitedError{retry})
+		}
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -456,7 +530,7 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
 	start := time.Now()
-	answer := h.runMethod(cp.ctx, msg, callb, args)
+	answer = h.runMethod(ctx, msg, callb, args)
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
 	if callb != h.unsubscribeCb {
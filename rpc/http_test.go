@@ -23,6 +23,12 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func confirmStatusCode(t *testing.T, got, want int) {
@@ -202,6 +208,53 @@ func TestHTTPPeerInfo(t *testing.T) {
 	}
 }
 
+func TestHTTPTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(context.Background())
+
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	s := newTestServer()
+	defer s.Stop()
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := Dial(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetHeader("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	if err := c.Call(nil, "test_returnError"); err == nil {
+		t.Fatal("expected error")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("wrong number of spans: have %d, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "test_returnError" {
+		t.Errorf("wrong span name %q", span.Name())
+	}
+	if have := span.SpanContext().TraceID().String(); have != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace context not propagated: have trace %s", have)
+	}
+	if have := span.Parent().SpanID().String(); have != "00f067aa0ba902b7" {
+		t.Errorf("trace context not propagated: have parent %s", have)
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("failed call not marked as error: %v", span.Status())
+	}
+}
+
 func TestNewContextWithHeaders(t *testing.T) {
 	expectedHeaders := 0
 	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
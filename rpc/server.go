@@ -46,9 +46,13 @@ type Server struct {
 	services serviceRegistry
 	idgen    func() ID
 
-	mutex  sync.Mutex
-	codecs map[ServerCodec]struct{}
-	run    atomic.Bool
+	mutex              sync.Mutex
+	codecs             map[ServerCodec]struct{}
+	run                atomic.Bool
+	batchItemLimit     int
+	batchResponseLimit int
+	rateLimiter        RateLimiter
+	auditLogger        AuditLogger
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -65,6 +69,55 @@ func NewServer() *Server {
 	return server
 }
 
+// SetBatchLimits sets limits applied to batch requests. There are two limits: 'itemLimit'
+// is the maximum number of items in a batch. 'maxResponseSize' is the maximum number of
+// response bytes across all requests in a batch. The calls exceeding either of them are
+// answered with an error. A limit of zero disables it.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetBatchLimits(itemLimit, maxResponseSize int) {
+	s.batchItemLimit = itemLimit
+	s.batchResponseLimit = maxResponseSize
+}
+
+// SetRateLimiter sets the limiter consulted before every method call. Calls rejected
+// by it are answered with a 'limit exceeded' error carrying a retry-after hint.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetRateLimiter(limiter RateLimiter) {
+	s.rateLimiter = limiter
+}
+
+// SetAuditLogger sets the logger notified of every served method call.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetAuditLogger(logger AuditLogger) {
+	s.auditLogger = logger
+}
+
+// SetMethodFilter restricts the methods served to those matching the allow list but
+// not the deny list. The patterns match full method names, wildcards are supported
+// using the syntax of path.Match, e.g. "eth_*" or "debug_trace*". An empty allow list
+// permits all methods. Filtered out methods are reported as not existing. The methods
+// of the metadata API, e.g. "rpc_modules", are never filtered.
+//
+// Subscriptions are filtered by the name of the subscribe method, e.g. "eth_subscribe".
+func (s *Server) SetMethodFilter(allow, deny []string) error {
+	if len(allow) == 0 && len(deny) == 0 {
+		s.services.setFilter(nil)
+		return nil
+	}
+	filter, err := newMethodFilter(allow, deny)
+	if err != nil {
+		return err
+	}
+	s.services.setFilter(filter)
+	return nil
+}
+
 // RegisterName creates a service for the given receiver type under the given name. When no
 // methods on the given receiver match the criteria to be either a RPC method or a
 // subscription an error is returned. Otherwise a new service is created and added to the
@@ -86,7 +139,13 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	}
 	defer s.untrackCodec(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, &s.services, &clientConfig{
+		idgen:              s.idgen,
+		batchItemLimit:     s.batchItemLimit,
+		batchResponseLimit: s.batchResponseLimit,
+		rateLimiter:        s.rateLimiter,
+		auditLogger:        s.auditLogger,
+	})
 	<-codec.closed()
 	c.Close()
 }
@@ -118,8 +177,10 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services)
+	h := newHandler(ctx, codec, s.idgen, &s.services, s.batchItemLimit, s.batchResponseLimit)
 	h.allowSubscribe = false
+	h.rateLimiter = s.rateLimiter
+	h.auditLogger = s.auditLogger
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.readBatch()
@@ -183,6 +244,11 @@ type PeerInfo struct {
 	// Address of client. This will usually contain the IP address and port.
 	RemoteAddr string
 
+	// Subject is the identity of the client established by an authentication layer in
+	// front of the server, e.g. the 'sub' claim of a JWT token. It is empty for
+	// unauthenticated connections.
+	Subject string
+
 	// Additional information for HTTP and WebSocket connections.
 	HTTP struct {
 		// Protocol version, i.e. "HTTP/1.1". This is not set for WebSocket.
@@ -196,6 +262,21 @@ type PeerInfo struct {
 
 type peerInfoContextKey struct{}
 
+type peerSubjectContextKey struct{}
+
+// WithPeerSubject returns a copy of ctx carrying the authenticated identity of the client.
+// HTTP middleware wrapping the server can use this on the request context to expose the
+// identity to method handlers through PeerInfo.Subject.
+func WithPeerSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, peerSubjectContextKey{}, subject)
+}
+
+// peerSubjectFromContext returns the client identity set by WithPeerSubject.
+func peerSubjectFromContext(ctx context.Context) string {
+	subject, _ := ctx.Value(peerSubjectContextKey{}).(string)
+	return subject
+}
+
 // PeerInfoFromContext returns information about the client's network connection.
 // Use this with the context passed to RPC method handler functions.
 //
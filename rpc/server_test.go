@@ -19,11 +19,13 @@ package rpc
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"io"
 	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -152,3 +154,232 @@ func TestServerShortLivedConn(t *testing.T) {
 		}
 	}
 }
+
+func TestServerBatchLimits(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	if err := server.RegisterName("large", largeRespService{length: 100}); err != nil {
+		t.Fatal(err)
+	}
+	// Calls past the item limit must be rejected individually.
+	server.SetBatchLimits(2, 0)
+	client := DialInProc(server)
+	defer client.Close()
+
+	batch := make([]BatchElem, 4)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{"x", i, &echoArgs{"y"}}, Result: new(echoResult)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal("error sending batch:", err)
+	}
+	for i, elem := range batch {
+		if i < 2 {
+			if elem.Error != nil {
+				t.Errorf("batch elem %d: unexpected error %v", i, elem.Error)
+			}
+			continue
+		}
+		checkBatchLimitError(t, i, elem.Error, -32600, errMsgBatchTooLarge)
+	}
+	// Calls after the response size limit is exceeded must be rejected too.
+	server.SetBatchLimits(0, 250)
+	client = DialInProc(server)
+	defer client.Close()
+
+	batch = make([]BatchElem, 4)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "large_largeResp", Result: new(string)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal("error sending batch:", err)
+	}
+	for i, elem := range batch {
+		if i < 2 {
+			if elem.Error != nil {
+				t.Errorf("batch elem %d: unexpected error %v", i, elem.Error)
+			}
+			continue
+		}
+		checkBatchLimitError(t, i, elem.Error, errcodeResponseTooLarge, errMsgResponseTooLarge)
+	}
+}
+
+// Tests that a subscription whose ID was cut from a batch response by the size
+// limit is not left running.
+func TestServerBatchLimitsSubscription(t *testing.T) {
+	var (
+		server  = newTestServer()
+		service = &notificationTestService{unsubscribed: make(chan string, 1)}
+	)
+	defer server.Stop()
+	if err := server.RegisterName("large", largeRespService{length: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("nfsub", service); err != nil {
+		t.Fatal(err)
+	}
+	// The two large responses fit into the limit, the subscription ID doesn't.
+	server.SetBatchLimits(0, 206)
+	client := DialInProc(server)
+	defer client.Close()
+
+	batch := []BatchElem{
+		{Method: "large_largeResp", Result: new(string)},
+		{Method: "large_largeResp", Result: new(string)},
+		{Method: "nfsub_subscribe", Args: []interface{}{"someSubscription", 0, 1}, Result: new(string)},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal("error sending batch:", err)
+	}
+	for i, elem := range batch[:2] {
+		if elem.Error != nil {
+			t.Errorf("batch elem %d: unexpected error %v", i, elem.Error)
+		}
+	}
+	checkBatchLimitError(t, 2, batch[2].Error, errcodeResponseTooLarge, errMsgResponseTooLarge)
+
+	select {
+	case <-service.unsubscribed:
+	case <-time.After(time.Second):
+		t.Fatal("subscription of rejected call still running")
+	}
+}
+
+func checkBatchLimitError(t *testing.T, index int, err error, code int, msg string) {
+	t.Helper()
+
+	rpcErr, ok := err.(Error)
+	if !ok {
+		t.Errorf("batch elem %d: want error %q, got %v", index, msg, err)
+		return
+	}
+	if rpcErr.ErrorCode() != code || rpcErr.Error() != msg {
+		t.Errorf("batch elem %d: wrong error: code %d %q, want %d %q", index, rpcErr.ErrorCode(), rpcErr.Error(), code, msg)
+	}
+}
+
+type denyLimiter struct{ retry time.Duration }
+
+func (l denyLimiter) Allow(ctx context.Context, method string) (time.Duration, bool) {
+	return l.retry, method == "rpc_modules"
+}
+
+func TestServerRateLimiter(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetRateLimiter(denyLimiter{1500 * time.Millisecond})
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	if err := client.Call(nil, "rpc_modules"); err != nil {
+		t.Fatal("allowed call failed:", err)
+	}
+	err := client.Call(nil, "test_echo", "x", 1)
+	rpcErr, ok := err.(Error)
+	if !ok || rpcErr.ErrorCode() != errcodeLimitExceeded || rpcErr.Error() != errMsgRateLimited {
+		t.Fatalf("wrong error for rate limited call: %v", err)
+	}
+	data, _ := err.(DataError).ErrorData().(map[string]interface{})
+	if retry := data["retryAfter"]; retry != float64(2) {
+		t.Fatalf("wrong retry-after hint: %v", retry)
+	}
+}
+
+func TestServerMethodFilter(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	if err := server.SetMethodFilter([]string{"test_*", "nftest_subscribe"}, []string{"test_echo*", "*_block", "test_subscribe", "rpc_*"}); err != nil {
+		t.Fatal(err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	tests := map[string]bool{
+		"test_null":         true,
+		"test_echo":         false,
+		"test_echoWithCtx":  false,
+		"test_block":        false,
+		"nftest_echo":       false,
+		"rpc_modules":       true,
+		"test_noArgsRets":   true,
+		"test_unknownThing": false,
+	}
+	for method, allowed := range tests {
+		err := client.Call(nil, method)
+		if allowed {
+			if err != nil {
+				t.Errorf("%s: call failed: %v", method, err)
+			}
+			continue
+		}
+		if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32601 {
+			t.Errorf("%s: want method-not-found error, got %v", method, err)
+		}
+	}
+	// Subscriptions are filtered by the subscribe method of their namespace.
+	sub, err := client.Subscribe(context.Background(), "nftest", make(chan int), "someSubscription", 1, 1)
+	if err != nil {
+		t.Fatal("allowed subscription failed:", err)
+	}
+	sub.Unsubscribe()
+	if _, err := client.Subscribe(context.Background(), "test", make(chan int), "subscription"); err == nil {
+		t.Fatal("filtered subscription succeeded")
+	}
+	// Lifting the filter makes all methods available again.
+	if err := server.SetMethodFilter(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(nil, "rpc_modules"); err != nil {
+		t.Fatal("call failed after lifting the filter:", err)
+	}
+	if err := server.SetMethodFilter(nil, []string{"["}); err == nil {
+		t.Fatal("invalid pattern accepted")
+	}
+}
+
+type recordingAuditLogger struct {
+	mu    sync.Mutex
+	calls []*CallInfo
+}
+
+func (l *recordingAuditLogger) LogCall(info *CallInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, info)
+}
+
+func TestServerAuditLogger(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	logger := new(recordingAuditLogger)
+	server.SetAuditLogger(logger)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	var res echoResult
+	if err := client.Call(&res, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(nil, "test_returnError"); err == nil {
+		t.Fatal("expected error")
+	}
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	if len(logger.calls) != 2 {
+		t.Fatalf("wrong number of logged calls: have %d, want 2", len(logger.calls))
+	}
+	ok, failed := logger.calls[0], logger.calls[1]
+	if ok.Method != "test_echo" || string(ok.Params) != `["hello",10,{"S":"world"}]` || ok.ErrorCode != 0 || ok.ResponseSize == 0 {
+		t.Errorf("wrong info for successful call: %+v", ok)
+	}
+	if ok.Peer.Transport != "ipc" {
+		t.Errorf("wrong transport %q", ok.Peer.Transport)
+	}
+	if failed.Method != "test_returnError" || failed.ErrorCode != 444 || failed.ErrorMessage != "testError" || failed.ResponseSize != 0 {
+		t.Errorf("wrong info for failed call: %+v", failed)
+	}
+}
@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"fmt"
+	"path"
 	"reflect"
 	"runtime"
 	"strings"
@@ -38,6 +39,49 @@ var (
 type serviceRegistry struct {
 	mu       sync.Mutex
 	services map[string]service
+	filter   *methodFilter // optional restriction of the served methods
+}
+
+// methodFilter restricts the methods served by a registry using lists of patterns
+// matching full method names, e.g. "eth_sign*". Denied methods are never served. If
+// the allow list is non-empty, only the methods matching it are served. The methods
+// of the metadata API are always served, clients rely on them to discover the
+// available modules.
+type methodFilter struct {
+	allow []string
+	deny  []string
+}
+
+// newMethodFilter creates a filter from the given patterns, which use the syntax
+// of path.Match.
+func newMethodFilter(allow, deny []string) (*methodFilter, error) {
+	for _, pattern := range append(append([]string{}, allow...), deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid method pattern %q: %v", pattern, err)
+		}
+	}
+	return &methodFilter{allow: allow, deny: deny}, nil
+}
+
+// permits reports whether the given method may be served.
+func (f *methodFilter) permits(method string) bool {
+	if strings.HasPrefix(method, MetadataApi+serviceMethodSeparator) {
+		return true
+	}
+	for _, pattern := range f.deny {
+		if ok, _ := path.Match(pattern, method); ok {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, pattern := range f.allow {
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+	return false
 }
 
 // service represents a registered object.
@@ -99,16 +143,30 @@ func (r *serviceRegistry) callback(method string) *callback {
 	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.filter != nil && !r.filter.permits(method) {
+		return nil
+	}
 	return r.services[elem[0]].callbacks[elem[1]]
 }
 
-// subscription returns a subscription callback in the given service.
+// subscription returns a subscription callback in the given service. Subscriptions
+// are filtered by the name of the subscribe method of the service.
 func (r *serviceRegistry) subscription(service, name string) *callback {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.filter != nil && !r.filter.permits(service+subscribeMethodSuffix) {
+		return nil
+	}
 	return r.services[service].subscriptions[name]
 }
 
+// setFilter restricts the methods served by the registry, nil lifts the restriction.
+func (r *serviceRegistry) setFilter(filter *methodFilter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.filter = filter
+}
+
 // suitableCallbacks iterates over the methods of the given type. It determines if a method
 // satisfies the criteria for a RPC callback or a subscription callback and adds it to the
 // collection of callbacks. See server documentation for a summary of these criteria.
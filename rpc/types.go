@@ -23,6 +23,7 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -60,6 +61,15 @@ type jsonWriter interface {
 	remoteAddr() string
 }
 
+// RateLimiter decides whether the server may serve a method call. Implementations must
+// be safe for concurrent use.
+type RateLimiter interface {
+	// Allow charges the call of the given method to the client, which can be identified
+	// through PeerInfoFromContext(ctx). If the client is over its quota, Allow returns
+	// false along with the time the client should wait before retrying.
+	Allow(ctx context.Context, method string) (time.Duration, bool)
+}
+
 type BlockNumber int64
 
 const (
@@ -0,0 +1,261 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+// nodeView is the full set of trie nodes belonging to a state, keyed by the
+// trie owner and the node path.
+type nodeView map[common.Hash]map[string][]byte
+
+func (v nodeView) copy() nodeView {
+	cpy := make(nodeView)
+	for owner, subset := range v {
+		cpy[owner] = make(map[string][]byte)
+		for path, blob := range subset {
+			cpy[owner][path] = blob
+		}
+	}
+	return cpy
+}
+
+type tester struct {
+	db     *Database
+	diskdb ethdb.Database
+	roots  []common.Hash
+	views  map[common.Hash]nodeView
+	owners []common.Hash
+	paths  map[common.Hash]map[string]struct{} // all paths ever touched
+}
+
+func newTester(t *testing.T, layers int) *tester {
+	var (
+		disk = rawdb.NewMemoryDatabase()
+		obj  = &tester{
+			db:     New(disk, nil, &Config{StateLimit: 0, DirtySize: 256 * 1024}),
+			diskdb: disk,
+			views:  map[common.Hash]nodeView{types.EmptyRootHash: make(nodeView)},
+			owners: []common.Hash{{}, common.HexToHash("0xa"), common.HexToHash("0xb"), common.HexToHash("0xc")},
+			paths:  make(map[common.Hash]map[string]struct{}),
+		}
+	)
+	parent := types.EmptyRootHash
+	for i := 0; i < layers; i++ {
+		root, nodes := obj.generate(parent)
+		if err := obj.db.Update(root, parent, nodes); err != nil {
+			t.Fatalf("Failed to update state changes, err: %v", err)
+		}
+		obj.roots = append(obj.roots, root)
+		parent = root
+	}
+	return obj
+}
+
+// generate produces a random state transition on top of the parent state.
+// The root node of the account trie is always rewritten so that the state
+// root is unique and derivable from the persisted nodes.
+func (t *tester) generate(parent common.Hash) (common.Hash, *trienode.MergedNodeSet) {
+	var (
+		view   = t.views[parent].copy()
+		merged = trienode.NewMergedNodeSet()
+		root   common.Hash
+	)
+	for _, owner := range t.owners {
+		if owner != (common.Hash{}) && rand.Intn(2) == 0 {
+			continue
+		}
+		if _, ok := view[owner]; !ok {
+			view[owner] = make(map[string][]byte)
+		}
+		if _, ok := t.paths[owner]; !ok {
+			t.paths[owner] = make(map[string]struct{})
+		}
+		set := trienode.NewNodeSet(owner)
+		for j := 0; j < 1+rand.Intn(8); j++ {
+			path := randomPath()
+			if owner == (common.Hash{}) && len(path) == 0 {
+				continue // root node is handled separately
+			}
+			if _, ok := set.Nodes[string(path)]; ok {
+				continue
+			}
+			prev := view[owner][string(path)]
+			if len(prev) != 0 && rand.Intn(4) == 0 {
+				set.AddNode(path, trienode.NewWithPrev(common.Hash{}, nil, prev))
+				delete(view[owner], string(path))
+			} else {
+				blob := randomBlob()
+				set.AddNode(path, trienode.NewWithPrev(crypto.Keccak256Hash(blob), blob, prev))
+				view[owner][string(path)] = blob
+			}
+			t.paths[owner][string(path)] = struct{}{}
+		}
+		if owner == (common.Hash{}) {
+			blob := randomBlob()
+			root = crypto.Keccak256Hash(blob)
+			set.AddNode(nil, trienode.NewWithPrev(root, blob, view[owner][""]))
+			view[owner][""] = blob
+			t.paths[owner][""] = struct{}{}
+		}
+		merged.Merge(set)
+	}
+	t.views[root] = view
+	return root, merged
+}
+
+// verifyState checks that all the trie nodes of the given state are
+// accessible through the layer with the given state root.
+func (t *tester) verifyState(root common.Hash) error {
+	reader, err := t.db.Reader(root)
+	if err != nil {
+		return err
+	}
+	for owner, subset := range t.views[root] {
+		for path, blob := range subset {
+			got, err := reader.Node(owner, []byte(path), crypto.Keccak256Hash(blob))
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(got, blob) {
+				return fmt.Errorf("unexpected node, owner: %x, path: %x, want: %x, got: %x", owner, path, blob, got)
+			}
+		}
+	}
+	return nil
+}
+
+// verifyDisk checks that the trie nodes persisted in the disk are exactly
+// matched with the given state.
+func (t *tester) verifyDisk(root common.Hash) error {
+	view := t.views[root]
+	for owner, paths := range t.paths {
+		for path := range paths {
+			var blob []byte
+			if owner == (common.Hash{}) {
+				blob, _ = rawdb.ReadAccountTrieNode(t.diskdb, []byte(path))
+			} else {
+				blob, _ = rawdb.ReadStorageTrieNode(t.diskdb, owner, []byte(path))
+			}
+			if want := view[owner][path]; !bytes.Equal(blob, want) {
+				return fmt.Errorf("unexpected disk node, owner: %x, path: %x, want: %x, got: %x", owner, path, want, blob)
+			}
+		}
+	}
+	return nil
+}
+
+func randomPath() []byte {
+	path := make([]byte, rand.Intn(4))
+	for i := range path {
+		path[i] = byte(rand.Intn(16))
+	}
+	return path
+}
+
+func randomBlob() []byte {
+	blob := make([]byte, 16+rand.Intn(32))
+	rand.Read(blob)
+	return blob
+}
+
+func TestDatabaseUpdate(t *testing.T) {
+	tester := newTester(t, 2*maxDiffLayers)
+	for i, root := range tester.roots {
+		err := tester.verifyState(root)
+		if i < len(tester.roots)-maxDiffLayers-1 {
+			if err == nil {
+				t.Fatalf("Expected flattened state to be unavailable, index: %d", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Failed to verify state, index: %d, err: %v", i, err)
+		}
+	}
+	if n := tester.db.tree.len(); n != maxDiffLayers+1 {
+		t.Fatalf("Unexpected layer number, want: %d, got: %d", maxDiffLayers+1, n)
+	}
+}
+
+func TestDatabaseCommit(t *testing.T) {
+	tester := newTester(t, maxDiffLayers/2)
+	head := tester.roots[len(tester.roots)-1]
+	if err := tester.db.Commit(head, false); err != nil {
+		t.Fatalf("Failed to commit state, err: %v", err)
+	}
+	if err := tester.verifyDisk(head); err != nil {
+		t.Fatalf("Failed to verify disk state, err: %v", err)
+	}
+	if id := rawdb.ReadPersistentStateID(tester.diskdb); id != uint64(len(tester.roots)) {
+		t.Fatalf("Unexpected persistent state id, want: %d, got: %d", len(tester.roots), id)
+	}
+}
+
+func TestDatabaseRollback(t *testing.T) {
+	tester := newTester(t, maxDiffLayers/2)
+	if err := tester.db.Commit(tester.roots[len(tester.roots)-1], false); err != nil {
+		t.Fatalf("Failed to commit state, err: %v", err)
+	}
+	for i := len(tester.roots) - 2; i >= 0; i-- {
+		root := tester.roots[i]
+		if !tester.db.Recoverable(root) {
+			t.Fatalf("State is not recoverable, index: %d", i)
+		}
+		if err := tester.db.Recover(root); err != nil {
+			t.Fatalf("Failed to revert state, index: %d, err: %v", i, err)
+		}
+		if err := tester.verifyDisk(root); err != nil {
+			t.Fatalf("Failed to verify disk state, index: %d, err: %v", i, err)
+		}
+		if err := tester.verifyState(root); err != nil {
+			t.Fatalf("Failed to verify state, index: %d, err: %v", i, err)
+		}
+	}
+	// The histories above the recovered state should be truncated.
+	if blob := rawdb.ReadTrieHistory(tester.diskdb, 2); len(blob) != 0 {
+		t.Fatal("Unexpected trie history")
+	}
+}
+
+func TestDatabaseJournal(t *testing.T) {
+	tester := newTester(t, maxDiffLayers/2)
+	head := tester.roots[len(tester.roots)-1]
+	if err := tester.db.Journal(head); err != nil {
+		t.Fatalf("Failed to journal layers, err: %v", err)
+	}
+	if err := tester.db.Update(common.Hash{0x1}, head, trienode.NewMergedNodeSet()); err == nil {
+		t.Fatal("Expected mutation to be rejected after journaling")
+	}
+	tester.db = New(tester.diskdb, nil, &Config{DirtySize: 256 * 1024})
+	for i, root := range tester.roots {
+		if err := tester.verifyState(root); err != nil {
+			t.Fatalf("Failed to verify journaled state, index: %d, err: %v", i, err)
+		}
+	}
+}
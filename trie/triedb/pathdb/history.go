@@ -0,0 +1,248 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+// Trie history records the original value of every trie node touched by a
+// state transition, which is also known as the "reverse diff". With the trie
+// history, the persistent state can be rolled back from the state with id N
+// to the state with id N-1 by writing the recorded values back.
+//
+// Every time a diff layer is merged into the disk layer, a trie history is
+// generated and stored in the key-value store, keyed by the state id of the
+// diff layer. The histories of the recent states are kept around, the older
+// ones are pruned once the configured limit is exceeded.
+
+// historyVersion is the initial version of trie history structure.
+const historyVersion = uint8(0)
+
+// historyNode represents the original value of a trie node with the given path.
+type historyNode struct {
+	Path []byte // Path of the trie node within the trie
+	Prev []byte // RLP-encoded original node value, empty means non-existent
+}
+
+// historyNodes represents the original values of all trie nodes in a trie.
+type historyNodes struct {
+	Owner common.Hash   // Identifier of the trie, empty for the account trie
+	Nodes []historyNode // List of original trie node values, sorted by path
+}
+
+// history represents a set of trie node reverse diffs belonging to the state
+// transition from parent to root.
+type history struct {
+	Version uint8          // Version tag of the history format
+	Parent  common.Hash    // State root before the state transition
+	Root    common.Hash    // State root after the state transition
+	Nodes   []historyNodes // List of node reverse diffs, sorted by owner
+}
+
+// newHistory constructs the trie history with the provided trie nodes.
+func newHistory(root common.Hash, parent common.Hash, nodes map[common.Hash]map[string]*trienode.WithPrev) *history {
+	var owners []common.Hash
+	for owner := range nodes {
+		owners = append(owners, owner)
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		return bytes.Compare(owners[i][:], owners[j][:]) < 0
+	})
+	h := &history{
+		Version: historyVersion,
+		Parent:  parent,
+		Root:    root,
+	}
+	for _, owner := range owners {
+		var (
+			subset = nodes[owner]
+			paths  = make([]string, 0, len(subset))
+		)
+		for path := range subset {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		entry := historyNodes{Owner: owner}
+		for _, path := range paths {
+			entry.Nodes = append(entry.Nodes, historyNode{
+				Path: []byte(path),
+				Prev: subset[path].Prev,
+			})
+		}
+		h.Nodes = append(h.Nodes, entry)
+	}
+	return h
+}
+
+// nodes returns the trie nodes which should be written into the disk for
+// reverting the state transition.
+func (h *history) nodes() map[common.Hash]map[string]*trienode.Node {
+	ret := make(map[common.Hash]map[string]*trienode.Node)
+	for _, entry := range h.Nodes {
+		subset := make(map[string]*trienode.Node)
+		for _, n := range entry.Nodes {
+			if len(n.Prev) == 0 {
+				subset[string(n.Path)] = trienode.New(common.Hash{}, nil)
+			} else {
+				subset[string(n.Path)] = trienode.New(crypto.Keccak256Hash(n.Prev), n.Prev)
+			}
+		}
+		ret[entry.Owner] = subset
+	}
+	return ret
+}
+
+// readHistory reads and decodes the trie history object by the given id.
+func readHistory(db ethdb.KeyValueReader, id uint64) (*history, error) {
+	blob := rawdb.ReadTrieHistory(db, id)
+	if len(blob) == 0 {
+		return nil, fmt.Errorf("trie history not found %d", id)
+	}
+	var h history
+	if err := rlp.DecodeBytes(blob, &h); err != nil {
+		return nil, err
+	}
+	if h.Version != historyVersion {
+		return nil, fmt.Errorf("unexpected trie history version %d", h.Version)
+	}
+	return &h, nil
+}
+
+// writeHistory writes the trie history of the provided diff layer into the
+// database, along with the lookup from the state root to the state id.
+func writeHistory(db ethdb.KeyValueWriter, dl *diffLayer) error {
+	var (
+		start = time.Now()
+		h     = newHistory(dl.rootHash(), dl.parentLayer().rootHash(), dl.nodes)
+	)
+	blob, err := rlp.EncodeToBytes(h)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteTrieHistory(db, dl.stateID(), blob)
+	rawdb.WriteStateID(db, dl.rootHash(), dl.stateID())
+
+	historyDataBytesMeter.Mark(int64(len(blob)))
+	historyBuildTimeMeter.UpdateSince(start)
+	log.Debug("Stored trie history", "id", dl.stateID(), "size", common.StorageSize(len(blob)), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// truncateFromHead removes the extra trie histories from the head with the
+// given parameters. It returns the number of items removed from the head.
+func truncateFromHead(db ethdb.KeyValueStore, head uint64) (int, error) {
+	var (
+		pruned int
+		batch  = db.NewBatch()
+		it     = rawdb.IterateTrieHistories(db, head+1)
+	)
+	defer it.Release()
+
+	for it.Next() {
+		id, h, err := decodeHistoryEntry(it.Key(), it.Value())
+		if err != nil {
+			return 0, err
+		}
+		// The state represented by the history root is no longer
+		// reachable, drop the lookup as well.
+		rawdb.DeleteTrieHistory(batch, id)
+		rawdb.DeleteStateID(batch, h.Root)
+		pruned++
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return 0, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return 0, err
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	return pruned, nil
+}
+
+// truncateFromTail removes the extra trie histories from the tail with the
+// given parameters. It returns the number of items removed from the tail.
+func truncateFromTail(db ethdb.KeyValueStore, tail uint64) (int, error) {
+	var (
+		pruned int
+		batch  = db.NewBatch()
+		it     = rawdb.IterateTrieHistories(db, 0)
+	)
+	defer it.Release()
+
+	for it.Next() {
+		id, h, err := decodeHistoryEntry(it.Key(), it.Value())
+		if err != nil {
+			return 0, err
+		}
+		if id > tail {
+			break
+		}
+		// The state before the transition can't be recovered
+		// anymore, drop the lookup of the parent state.
+		rawdb.DeleteTrieHistory(batch, id)
+		rawdb.DeleteStateID(batch, h.Parent)
+		pruned++
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return 0, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return 0, err
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	return pruned, nil
+}
+
+// decodeHistoryEntry decodes the trie history and the associated state id
+// from the given database entry.
+func decodeHistoryEntry(key []byte, val []byte) (uint64, *history, error) {
+	if len(key) < 8 {
+		return 0, nil, errors.New("invalid trie history key")
+	}
+	var h history
+	if err := rlp.DecodeBytes(val, &h); err != nil {
+		return 0, nil, err
+	}
+	return binary.BigEndian.Uint64(key[len(key)-8:]), &h, nil
+}